@@ -19,6 +19,8 @@ type AlertEvent struct {
 	Timestamp     int64                  // 时间戳
 	FaultCode     string                 // 故障编号
 	MetricValue   float64                // 触发告警的指标值
+	TMCode        string                 // 遥测参数代号（业务层告警）
+	Unit          string                 // 指标工程单位
 	RelatedAlerts []string               // 关联的其他告警ID
 	Metadata      map[string]interface{} // 额外的元数据信息
 }
@@ -834,6 +834,98 @@ func (a *StartContainerAction) fetchAvailableNodeNames(ctx context.Context) ([]s
 	return all, nil
 }
 
+// nodeHealth 健康监控管理接口返回的节点健康分（GET /api/v1/health?kind=node）
+type nodeHealth struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+	Level string  `json:"level"`
+}
+
+// selectHealthiestNode 按健康监控的节点健康分在候选节点中选择得分最高的节点，不健康的节点仅在全部候选都不健康时才选择。
+// 未配置 RECOVERY_HEALTH_URL 或候选节点都没有健康分时返回 false（沿用 master 优先）。
+func (a *StartContainerAction) selectHealthiestNode(ctx context.Context, nodeNames []string) (string, bool) {
+	healthURL := os.Getenv("RECOVERY_HEALTH_URL")
+	if healthURL == "" {
+		return "", false
+	}
+	scores, err := a.fetchNodeHealth(ctx, healthURL)
+	if err != nil {
+		fmt.Printf("[recovery] fetch node health failed: %v\n", err)
+		return "", false
+	}
+
+	// 健康分按节点ID记录，候选为节点名称
+	page, err := a.fetcher.ListNode(ctx, microservice.NodeListOptions{PageNum: 1, PageSize: -1})
+	if err != nil {
+		fmt.Printf("[recovery] fetch node list failed: %v\n", err)
+		return "", false
+	}
+	ids := make(map[string]string, len(page.Items))
+	for _, node := range page.Items {
+		ids[node.Name] = node.ID
+	}
+
+	var best *nodeHealth
+	bestName := ""
+	for _, name := range nodeNames {
+		h, ok := scores[ids[name]]
+		if !ok {
+			h, ok = scores[name]
+		}
+		if !ok {
+			continue
+		}
+		better := best == nil || h.Score > best.Score
+		if best != nil && (h.Level == "unhealthy") != (best.Level == "unhealthy") {
+			better = best.Level == "unhealthy"
+		}
+		if better {
+			best, bestName = &h, name
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	fmt.Printf("[recovery] 按健康分选择节点 %s (score=%.1f level=%s, 候选 %v)\n", bestName, best.Score, best.Level, nodeNames)
+	return bestName, true
+}
+
+// fetchNodeHealth 获取节点健康分（节点ID → 健康分）
+func (a *StartContainerAction) fetchNodeHealth(ctx context.Context, healthURL string) (map[string]nodeHealth, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("health http status=%d body=%s", resp.StatusCode, string(body))
+	}
+
+	var list []struct {
+		Kind string `json:"kind"`
+		nodeHealth
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+	scores := make(map[string]nodeHealth, len(list))
+	for _, h := range list {
+		if h.Kind == "node" {
+			scores[h.ID] = h.nodeHealth
+		}
+	}
+	return scores, nil
+}
+
 func (a *StartContainerAction) createService(ctx context.Context, reqBody interface{}) (string, error) {
 	payload, err := json.Marshal(reqBody)
 	if err != nil {
@@ -1044,10 +1136,14 @@ func (a *StartContainerAction) Execute(ctx context.Context, event DiagnosisResul
 		return errors.New("missing nodeNames")
 	}
 	if len(nodeNames) > 1 {
-		for _, name := range nodeNames {
-			if strings.EqualFold(name, "master") {
-				nodeNames = []string{"master"}
-				break
+		if name, ok := a.selectHealthiestNode(ctx, nodeNames); ok {
+			nodeNames = []string{name}
+		} else {
+			for _, name := range nodeNames {
+				if strings.EqualFold(name, "master") {
+					nodeNames = []string{"master"}
+					break
+				}
 			}
 		}
 	}
@@ -179,52 +179,46 @@ func main() {
 
 // buildPowerPacket 构建供电服务报文
 func buildPowerPacket(v12, vBat, vCPU, current float64) []byte {
-	packet := make([]byte, 3+14)
-	packet[0] = 0x03 // 供电服务
-	packet[1] = 0x00
-	packet[2] = 14 // 长度
+	payload := make([]byte, 14)
 	
 	// 12V电压
-	binary.BigEndian.PutUint16(packet[3:5], uint16(v12*1000))
+	binary.BigEndian.PutUint16(payload[0:2], uint16(v12*1000))
 	// 蓄电池电压
-	binary.BigEndian.PutUint16(packet[5:7], uint16(vBat*1000))
+	binary.BigEndian.PutUint16(payload[2:4], uint16(vBat*1000))
 	// 母线电压
-	binary.BigEndian.PutUint16(packet[7:9], uint16(vBat*1000))
+	binary.BigEndian.PutUint16(payload[4:6], uint16(vBat*1000))
 	// CPU电压
-	binary.BigEndian.PutUint16(packet[9:11], uint16(vCPU*1000))
+	binary.BigEndian.PutUint16(payload[6:8], uint16(vCPU*1000))
 	// 热敏基准电压
-	binary.BigEndian.PutUint16(packet[11:13], uint16(5.0*1000))
+	binary.BigEndian.PutUint16(payload[8:10], uint16(5.0*1000))
 	// 12V电流
-	binary.BigEndian.PutUint16(packet[13:15], uint16(1.2*1000))
+	binary.BigEndian.PutUint16(payload[10:12], uint16(1.2*1000))
 	// 负载电流
-	binary.BigEndian.PutUint16(packet[15:17], uint16(current*1000))
+	binary.BigEndian.PutUint16(payload[12:14], uint16(current*1000))
 	
-	return packet
+	return business.EncodeFrame(0x03, payload)
 }
 
 // buildThermalPacket 构建热控服务报文
 func buildThermalPacket(temps []float64) []byte {
-	packet := make([]byte, 3+31)
-	packet[0] = 0x06 // 热控服务
-	packet[1] = 0x00
-	packet[2] = 31 // 长度
+	payload := make([]byte, 31)
 	
 	// 10个温度点
 	for i := 0; i < 10 && i < len(temps); i++ {
-		binary.BigEndian.PutUint16(packet[3+i*2:5+i*2], uint16(temps[i]*10))
+		binary.BigEndian.PutUint16(payload[i*2:i*2+2], uint16(temps[i]*10))
 	}
 	
 	// 蓄电池温度
-	binary.BigEndian.PutUint16(packet[23:25], uint16(25.0*10))
-	binary.BigEndian.PutUint16(packet[25:27], uint16(26.0*10))
+	binary.BigEndian.PutUint16(payload[20:22], uint16(25.0*10))
+	binary.BigEndian.PutUint16(payload[22:24], uint16(26.0*10))
 	
 	// 其他温度
-	binary.BigEndian.PutUint16(packet[27:29], uint16(30.0*10))
-	binary.BigEndian.PutUint16(packet[29:31], uint16(28.0*10))
-	binary.BigEndian.PutUint16(packet[31:33], uint16(25.0*10))
+	binary.BigEndian.PutUint16(payload[24:26], uint16(30.0*10))
+	binary.BigEndian.PutUint16(payload[26:28], uint16(28.0*10))
+	binary.BigEndian.PutUint16(payload[28:30], uint16(25.0*10))
 	
 	// 开关状态
-	packet[33] = 0x07 // 所有开关打开
+	payload[30] = 0x07 // 所有开关打开
 	
-	return packet
+	return business.EncodeFrame(0x06, payload)
 }
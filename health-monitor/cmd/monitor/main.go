@@ -5,15 +5,21 @@ import (
 	"encoding/binary"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	//"health-monitor/pkg/alert"
+	"health-monitor/pkg/admin"
+	"health-monitor/pkg/alert"
 	"health-monitor/pkg/business"
+	"health-monitor/pkg/config"
 	"health-monitor/pkg/microservice"
 	"health-monitor/pkg/state"
+	"health-monitor/pkg/telemetry"
 )
 
 func main() {
@@ -23,6 +29,30 @@ func main() {
 	interval := flag.Int("interval", 5, "监控采集间隔(秒)")
 	testBusiness := flag.Bool("test-business", false, "测试模式：模拟业务层报文")
 	testInterval := flag.Int("test-interval", 5, "测试模式下报文发送间隔(秒)")
+	layoutsPath := flag.String("layouts", "", "业务报文布局文件(JSON/YAML)，留空使用内置布局")
+	catalogPath := flag.String("catalog", "", "遥测参数库文件(JSON)，留空使用内置参数库")
+	rulesPath := flag.String("rules", "", "告警规则文件(JSON/YAML)，留空使用内置规则")
+	listenSpecs := flag.String("listen", "", "业务报文监听地址，逗号分隔，如 udp://0.0.0.0:9000,tcp://:9001,unixgram:///run/hm.sock")
+	allowPeers := flag.String("allow", "", "业务报文对端白名单（IP/CIDR，unixgram 为套接字路径），逗号分隔，留空不限制")
+	ccsdsPath := flag.String("ccsds", "", "CCSDS 空间包 APID 映射配置(JSON)，留空不启用空间包接入")
+	timeCodePath := flag.String("timecode", "", "星上时间码配置(JSON，CUC/CDS)，留空使用接收时间")
+	maxClockSkew := flag.Duration("max-clock-skew", state.DefaultMaxClockSkew, "测量时间与本地时间允许的最大偏差，超过时改用接收时间（0 不检查）")
+	linkLossRate := flag.Float64("link-loss-threshold", business.DefaultLossRateThreshold, "业务报文丢包率告警阈值(0-1)，按序列计数统计")
+	linkWindow := flag.Int("link-window", business.DefaultSeqWindow, "丢包率评估窗口（期望报文数）")
+	captureDir := flag.String("capture-dir", "", "业务报文抓包目录，留空不抓包（回放见 cmd/replay）")
+	captureSize := flag.Int64("capture-max-size", business.DefaultCaptureFileSize>>20, "单个抓包文件上限(MB)")
+	captureFiles := flag.Int("capture-max-files", business.DefaultCaptureFiles, "保留的抓包文件数，超出时删除最旧的文件")
+	maxPacket := flag.Int("max-packet", business.DefaultMaxPacketSize, "业务报文单帧最大字节数")
+	adminAddr := flag.String("admin", "", "管理接口监听地址，如 127.0.0.1:8090（配置热加载 POST /api/v1/reload，待定告警 GET /api/v1/alerts/pending，规则生效条件 GET /api/v1/rules/conditions，静默 /api/v1/silences，数据新鲜度 GET /api/v1/telemetry/freshness，异常检测基线 GET/DELETE /api/v1/anomaly/baselines，轨道周期基线 GET/DELETE /api/v1/anomaly/orbits，健康分 GET /api/v1/health、/api/v1/health/history，关联事件 GET /api/v1/alerts/incidents），只写端口时只监听本机回环地址，留空不启用。接口不鉴权，需要时设置 -admin-token")
+	adminToken := flag.String("admin-token", "", "管理接口访问令牌，设置后请求须携带 Authorization: Bearer <token>，留空不鉴权")
+	silencePath := flag.String("silences", "", "告警静默规则文件，重启后恢复（留空只保存在内存中）")
+	watchdogPath := flag.String("watchdog", "", "遥测中断看门狗配置文件(JSON，各组件/实体的期望上报周期)，留空时业务组件按 -telemetry-period、ECSM 实体按 -interval")
+	telemetryPeriod := flag.Duration("telemetry-period", 10*time.Second, "未配置看门狗时业务组件的期望上报周期（0 不监视）")
+	watchdogInterval := flag.Duration("watchdog-interval", time.Second, "遥测中断检查间隔（0 不检查）")
+	healthPath := flag.String("health", "", "健康分配置文件(JSON，扣分、等级阈值、类别/实体权重、历史保留)，留空使用默认配置")
+	correlationPath := flag.String("correlation", "", "告警关联配置文件(JSON，时间窗、关联标签、建立事件的最少告警数)，留空使用默认配置（60s，按节点/服务/业务组件）")
+	healthInterval := flag.Duration("health-interval", 10*time.Second, "系统健康分评估间隔（0 不评估，各实体健康分仍随指标更新）")
+	reloadInterval := flag.Duration("reload-interval", config.DefaultWatchInterval, "规则/参数库/布局文件变化检查间隔（0 不检查，仍可通过 SIGHUP 或管理接口重新加载）")
 	flag.Parse()
 
 	fmt.Printf("========== 健康监控系统启动 ==========\n")
@@ -36,7 +66,7 @@ func main() {
 	if *testBusiness {
 		fmt.Printf("业务层测试模式: 已启用（报文间隔: %d秒）\n", *testInterval)
 	}
-	fmt.Println("======================================\n")
+	fmt.Print("======================================\n\n")
 
 	// 创建 context，用于优雅关闭
 	ctx, cancel := context.WithCancel(context.Background())
@@ -50,30 +80,273 @@ func main() {
 		os.Exit(1)
 	}
 	defer sm.Close()
+	sm.SetMaxClockSkew(*maxClockSkew)
 
 	// 2. 初始化业务层组件
 	fmt.Println("初始化业务层监控...")
 	businessDispatcher := business.NewDispatcher(sm)
 	businessReceiver := business.NewReceiver(businessDispatcher)
+	businessReceiver.SetSequenceConfig(business.SequenceConfig{
+		Window:            *linkWindow,
+		LossRateThreshold: *linkLossRate,
+	})
+
+	// 微服务层派发器先于配置加载创建，规则切换时需通知其解除已删除规则的告警
+	fetcher := microservice.NewFetcher(*ecsmURL)
+	microDispatcher := microservice.NewDispatcher(fetcher, sm)
+	health := alert.NewHealthScorer(sm)
+	businessDispatcher.SetHealthScorer(health)
+	microDispatcher.SetHealthScorer(health)
+	correlator := alert.NewCorrelator(nil)
+	businessDispatcher.SetCorrelator(correlator)
+	microDispatcher.SetCorrelator(correlator)
+
+	// 加载遥测参数库、告警规则、报文布局；运行时文件变化、SIGHUP、管理接口均可触发重新加载
+	reloader := config.NewReloader()
+	reloader.Add("遥测参数库", *catalogPath, func(path string) (func(), error) {
+		catalog, err := telemetry.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			telemetry.SetDefault(catalog)
+			fmt.Printf("已加载遥测参数库: %s (version=%s, %d 个参数)\n", path, catalog.Version, len(catalog.Parameters))
+		}, nil
+	})
+	reloader.Add("告警规则", *rulesPath, func(path string) (func(), error) {
+		rules, err := alert.LoadRules(path)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			old := alert.DefaultRules()
+			alert.SetDefaultRules(rules)
+			businessDispatcher.RulesChanged(old, rules)
+			microDispatcher.RulesChanged(old, rules)
+			fmt.Printf("已加载告警规则: %s (version=%s, %d 条规则)\n", path, rules.Version, len(rules.Rules))
+		}, nil
+	})
+	// 遥测中断看门狗：各组件/实体的期望上报周期，超时未收到数据时告警
+	var watchdog atomic.Pointer[alert.WatchdogConfig]
+	defaultWatchdog := alert.DefaultWatchdogConfig(*telemetryPeriod, time.Duration(*interval)*time.Second)
+	defaultWatchdog.Apply(sm)
+	watchdog.Store(defaultWatchdog)
+	reloader.Add("遥测看门狗", *watchdogPath, func(path string) (func(), error) {
+		cfg, err := alert.LoadWatchdogConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			cfg.Apply(sm)
+			watchdog.Store(cfg)
+			fmt.Printf("已加载遥测看门狗配置: %s (%d 个业务组件, %d 类/个实体)\n", path, len(cfg.Business), len(cfg.Entities))
+		}, nil
+	})
+	reloader.Add("健康评估", *healthPath, func(path string) (func(), error) {
+		cfg, err := alert.LoadHealthConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			health.SetConfig(cfg)
+			fmt.Printf("已加载健康分配置: %s (degraded=%.0f, unhealthy=%.0f, %d 个实体权重)\n", path, cfg.Degraded, cfg.Unhealthy, len(cfg.Entities))
+		}, nil
+	})
+	reloader.Add("告警关联", *correlationPath, func(path string) (func(), error) {
+		cfg, err := alert.LoadCorrelationConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			correlator.SetConfig(cfg)
+			fmt.Printf("已加载告警关联配置: %s (window=%s, labels=%v, min_alerts=%d)\n", path, cfg.WindowDuration(), cfg.Labels, cfg.MinAlerts)
+		}, nil
+	})
+	reloader.Add("报文布局", *layoutsPath, func(path string) (func(), error) {
+		layouts, err := business.LoadLayouts(path)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			businessReceiver.SetLayouts(layouts)
+			fmt.Printf("已加载报文布局: %s (version=%s)\n", path, layouts.Version)
+		}, nil
+	})
+	if reloader.Len() > 0 {
+		if err := reloader.Reload("startup"); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		if *reloadInterval > 0 {
+			go reloader.Watch(ctx, *reloadInterval)
+		}
+	}
+	go reloader.WatchSignal(ctx, syscall.SIGHUP)
+
+	// 告警静默规则（维护窗口），通过管理接口创建
+	silences, err := alert.NewSilenceStore(*silencePath)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	businessDispatcher.SetSilences(silences)
+	microDispatcher.SetSilences(silences)
+
+	if *adminAddr != "" {
+		adminServer := admin.NewServer(*adminAddr)
+		adminServer.SetToken(*adminToken)
+		adminServer.Handle("/api/v1/reload", reloader)
+		adminServer.Handle("/api/v1/silences", silences)
+		adminServer.Handle("/api/v1/silences/", silences)
+		adminServer.HandleFunc("/api/v1/alerts/pending", func(w http.ResponseWriter, r *http.Request) {
+			admin.WriteJSON(w, http.StatusOK, sm.GetPendingAlerts())
+		})
+		adminServer.HandleFunc("/api/v1/alerts/incidents", func(w http.ResponseWriter, r *http.Request) {
+			admin.WriteJSON(w, http.StatusOK, correlator.Incidents())
+		})
+		adminServer.HandleFunc("/api/v1/rules/conditions", func(w http.ResponseWriter, r *http.Request) {
+			admin.WriteJSON(w, http.StatusOK, sm.GetRuleConditions())
+		})
+		adminServer.HandleFunc("/api/v1/telemetry/freshness", func(w http.ResponseWriter, r *http.Request) {
+			admin.WriteJSON(w, http.StatusOK, sm.CheckFreshness(time.Now()))
+		})
+		adminServer.HandleFunc("/api/v1/anomaly/baselines", func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				admin.WriteJSON(w, http.StatusOK, sm.GetAnomalyBaselines())
+			case http.MethodDelete:
+				// 删除基线重新学习，alert_id、source 为空时作用于全部
+				alertID, source := r.URL.Query().Get("alert_id"), r.URL.Query().Get("source")
+				n := sm.ResetAnomalyBaselines(alertID, source)
+				fmt.Printf("[统计异常检测] 删除基线 %d 份 (alert_id=%q, source=%q)\n", n, alertID, source)
+				admin.WriteJSON(w, http.StatusOK, map[string]int{"reset": n})
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		})
+		adminServer.HandleFunc("/api/v1/anomaly/orbits", func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				admin.WriteJSON(w, http.StatusOK, sm.GetOrbitBaselines())
+			case http.MethodDelete:
+				alertID, source := r.URL.Query().Get("alert_id"), r.URL.Query().Get("source")
+				n := sm.ResetOrbitBaselines(alertID, source)
+				fmt.Printf("[轨道周期基线] 删除基线 %d 份 (alert_id=%q, source=%q)\n", n, alertID, source)
+				admin.WriteJSON(w, http.StatusOK, map[string]int{"reset": n})
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		})
+		adminServer.HandleFunc("/api/v1/health", func(w http.ResponseWriter, r *http.Request) {
+			// kind 为 business / node / container / service / system，留空返回全部
+			admin.WriteJSON(w, http.StatusOK, sm.GetHealthScores(r.URL.Query().Get("kind")))
+		})
+		adminServer.HandleFunc("/api/v1/health/history", func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			since := time.Hour
+			if s := q.Get("since"); s != "" {
+				d, err := time.ParseDuration(s)
+				if err != nil || d <= 0 {
+					http.Error(w, "since 须为正的时长，如 30m、6h", http.StatusBadRequest)
+					return
+				}
+				since = d
+			}
+			kind := q.Get("kind")
+			if kind == "" {
+				kind = state.HealthKindSystem
+			}
+			admin.WriteJSON(w, http.StatusOK, sm.GetHealthHistory(kind, q.Get("id"), since))
+		})
+		if err := adminServer.Start(); err != nil {
+			fmt.Printf("❌ 启动管理接口失败: %v\n", err)
+			os.Exit(1)
+		}
+		defer adminServer.Close()
+	}
+	if *timeCodePath != "" {
+		tc, err := business.LoadTimeCodeConfig(*timeCodePath)
+		if err != nil {
+			fmt.Printf("❌ 加载时间码配置失败: %v\n", err)
+			os.Exit(1)
+		}
+		businessReceiver.SetTimeCode(tc)
+		fmt.Printf("已启用星上时间码: %s (format=%s)\n", *timeCodePath, tc.Format)
+	}
+	if *ccsdsPath != "" {
+		ccsdsCfg, err := business.LoadCCSDSConfig(*ccsdsPath)
+		if err != nil {
+			fmt.Printf("❌ 加载CCSDS配置失败: %v\n", err)
+			os.Exit(1)
+		}
+		businessReceiver.SetCCSDS(business.NewCCSDSDecoder(ccsdsCfg))
+		fmt.Printf("已启用CCSDS空间包接入: %s (%d 个APID)\n", *ccsdsPath, len(ccsdsCfg.APIDs))
+	}
+	if *captureDir != "" {
+		capture, err := business.NewCaptureWriter(business.CaptureConfig{
+			Dir:         *captureDir,
+			MaxFileSize: *captureSize << 20,
+			MaxFiles:    *captureFiles,
+		})
+		if err != nil {
+			fmt.Printf("❌ 启用报文抓包失败: %v\n", err)
+			os.Exit(1)
+		}
+		defer capture.Close()
+		businessReceiver.SetCapture(capture)
+		fmt.Printf("已启用报文抓包: %s (%dMB x %d)\n", *captureDir, *captureSize, *captureFiles)
+	}
 	businessReceiver.Start(ctx)
 
+	// 启动业务报文网络监听
+	if *listenSpecs != "" {
+		defaults := business.ListenerConfig{MaxPacketSize: *maxPacket}
+		if *allowPeers != "" {
+			defaults.AllowPeers = strings.Split(*allowPeers, ",")
+		}
+		for _, spec := range strings.Split(*listenSpecs, ",") {
+			cfg, err := business.ParseListenerSpec(spec, defaults)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+			listener, err := business.NewListener(cfg, businessReceiver)
+			if err == nil {
+				err = listener.Start(ctx)
+			}
+			if err != nil {
+				fmt.Printf("❌ 启动业务报文监听失败: %v\n", err)
+				os.Exit(1)
+			}
+			defer listener.Close()
+		}
+	}
+
 	// 3. 如果启用测试模式，启动业务层报文模拟
 	if *testBusiness {
 		fmt.Println("启动业务层报文模拟...")
 		go businessTestLoop(ctx, businessReceiver, time.Duration(*testInterval)*time.Second)
 	}
 
-	// 4. 初始化微服务层组件
+	// 4. 初始化微服务层组件（派发器已在配置加载前创建）
 	fmt.Println("初始化微服务层监控...")
-	fetcher := microservice.NewFetcher(*ecsmURL)
-	microDispatcher := microservice.NewDispatcher(fetcher, sm)
 
 	// 5. 启动微服务层定期采集
-	fmt.Println("启动微服务层定期采集...\n")
+	fmt.Print("启动微服务层定期采集...\n\n")
 	go microServiceMonitorLoop(ctx, microDispatcher, time.Duration(*interval)*time.Second)
 
-	// 6. 监听系统信号，优雅退出
-	fmt.Println("✅ 系统运行中，按 Ctrl+C 停止\n")
+	// 6. 遥测中断看门狗
+	if *watchdogInterval > 0 {
+		go watchdogLoop(ctx, &watchdog, businessDispatcher, microDispatcher, *watchdogInterval)
+	}
+
+	// 7. 系统健康分
+	if *healthInterval > 0 {
+		go healthLoop(ctx, health, *healthInterval)
+	}
+
+	// 8. 监听系统信号，优雅退出
+	fmt.Print("✅ 系统运行中，按 Ctrl+C 停止\n\n")
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -103,6 +376,39 @@ func microServiceMonitorLoop(ctx context.Context, dispatcher *microservice.Dispa
 	}
 }
 
+// 遥测中断检查循环
+func watchdogLoop(ctx context.Context, watchdog *atomic.Pointer[alert.WatchdogConfig], bd *business.Dispatcher, md *microservice.Dispatcher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cfg := watchdog.Load()
+			bd.CheckTelemetryLoss(ctx, cfg)
+			md.CheckTelemetryLoss(ctx, cfg)
+		}
+	}
+}
+
+// 系统健康分评估循环
+func healthLoop(ctx context.Context, health *alert.HealthScorer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report := health.Evaluate(time.Now())
+			fmt.Printf("[健康评估] %s\n", report.Summary(3))
+		}
+	}
+}
+
 // 采集并报告
 func collectAndReport(ctx context.Context, dispatcher *microservice.Dispatcher) {
 	startTime := time.Now()
@@ -169,64 +475,55 @@ func sendTestPackets(receiver *business.Receiver, count *int) {
 
 // buildPowerPacket 构建供电服务报文
 func buildPowerPacket(v12, vBat, vCPU, current float64) []byte {
-	packet := make([]byte, 3+14)
-	packet[0] = 0x03 // 供电服务
-	packet[1] = 0x00
-	packet[2] = 14 // 长度
+	payload := make([]byte, 14)
 	
 	// 12V电压
-	binary.BigEndian.PutUint16(packet[3:5], uint16(v12*1000))
+	binary.BigEndian.PutUint16(payload[0:2], uint16(v12*1000))
 	// 蓄电池电压
-	binary.BigEndian.PutUint16(packet[5:7], uint16(vBat*1000))
+	binary.BigEndian.PutUint16(payload[2:4], uint16(vBat*1000))
 	// 母线电压
-	binary.BigEndian.PutUint16(packet[7:9], uint16(vBat*1000))
+	binary.BigEndian.PutUint16(payload[4:6], uint16(vBat*1000))
 	// CPU电压
-	binary.BigEndian.PutUint16(packet[9:11], uint16(vCPU*1000))
+	binary.BigEndian.PutUint16(payload[6:8], uint16(vCPU*1000))
 	// 热敏基准电压
-	binary.BigEndian.PutUint16(packet[11:13], uint16(5.0*1000))
+	binary.BigEndian.PutUint16(payload[8:10], uint16(5.0*1000))
 	// 12V电流
-	binary.BigEndian.PutUint16(packet[13:15], uint16(1.2*1000))
+	binary.BigEndian.PutUint16(payload[10:12], uint16(1.2*1000))
 	// 负载电流
-	binary.BigEndian.PutUint16(packet[15:17], uint16(current*1000))
+	binary.BigEndian.PutUint16(payload[12:14], uint16(current*1000))
 	
-	return packet
+	return business.EncodeFrame(0x03, payload)
 }
 
 // buildThermalPacket 构建热控服务报文
 func buildThermalPacket(temps []float64) []byte {
-	packet := make([]byte, 3+31)
-	packet[0] = 0x06 // 热控服务
-	packet[1] = 0x00
-	packet[2] = 31 // 长度
+	payload := make([]byte, 31)
 	
 	// 10个温度点
 	for i := 0; i < 10 && i < len(temps); i++ {
-		binary.BigEndian.PutUint16(packet[3+i*2:5+i*2], uint16(temps[i]*10))
+		binary.BigEndian.PutUint16(payload[i*2:i*2+2], uint16(temps[i]*10))
 	}
 	
 	// 蓄电池温度
-	binary.BigEndian.PutUint16(packet[23:25], uint16(25.0*10))
-	binary.BigEndian.PutUint16(packet[25:27], uint16(26.0*10))
+	binary.BigEndian.PutUint16(payload[20:22], uint16(25.0*10))
+	binary.BigEndian.PutUint16(payload[22:24], uint16(26.0*10))
 	
 	// 其他温度
-	binary.BigEndian.PutUint16(packet[27:29], uint16(30.0*10))
-	binary.BigEndian.PutUint16(packet[29:31], uint16(28.0*10))
-	binary.BigEndian.PutUint16(packet[31:33], uint16(25.0*10))
+	binary.BigEndian.PutUint16(payload[24:26], uint16(30.0*10))
+	binary.BigEndian.PutUint16(payload[26:28], uint16(28.0*10))
+	binary.BigEndian.PutUint16(payload[28:30], uint16(25.0*10))
 	
 	// 开关状态
-	packet[33] = 0x07 // 所有开关打开
+	payload[30] = 0x07 // 所有开关打开
 	
-	return packet
+	return business.EncodeFrame(0x06, payload)
 }
 
 // buildCommPacket 构建通信服务报文
 func buildCommPacket(status, errorCode byte) []byte {
-	packet := make([]byte, 3+2)
-	packet[0] = 0x07 // 通信服务
-	packet[1] = 0x00
-	packet[2] = 2 // 长度
-	packet[3] = status
-	packet[4] = errorCode
+	payload := make([]byte, 2)
+	payload[0] = status
+	payload[1] = errorCode
 	
-	return packet
+	return business.EncodeFrame(0x07, payload)
 }
@@ -0,0 +1,214 @@
+/*
+业务报文回放工具
+
+将 monitor -capture-dir 录制的抓包文件重新送入 Receiver → Dispatcher → Generator，
+用于复现联试中出现的故障，以及用真实数据回归验证阈值、告警规则、故障树等改动。
+
+用法：
+	replay [参数] <抓包文件或目录>...
+
+	replay -speed 1  captures/                 # 按原始间隔实时回放
+	replay -speed 20 captures/business-*.hmcap # 20 倍速回放
+	replay -speed 0 -alerts out.jsonl captures/ # 尽快回放，告警逐行写入 JSON 便于比对
+	replay -speed 0 -rules new_rules.yaml captures/ # 用修改后的告警规则回放
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"health-monitor/pkg/alert"
+	"health-monitor/pkg/business"
+	"health-monitor/pkg/state"
+	"health-monitor/pkg/telemetry"
+)
+
+// alertRecorder 将告警逐行写入 JSON（实现 alert.DiagnosisReceiver）
+type alertRecorder struct {
+	enc    *json.Encoder
+	firing int
+	total  int
+}
+
+func (a *alertRecorder) SendAlert(alert interface{}) error {
+	a.total++
+	if m, ok := alert.(map[string]interface{}); ok && fmt.Sprint(m["Status"]) != "resolved" {
+		a.firing++
+	}
+	if a.enc == nil {
+		return nil
+	}
+	return a.enc.Encode(alert)
+}
+
+func main() {
+	speed := flag.Float64("speed", 1, "回放速度：1 实时，>1 加速倍数，0 不等待尽快回放")
+	layoutsPath := flag.String("layouts", "", "业务报文布局文件(JSON/YAML)，留空使用内置布局")
+	catalogPath := flag.String("catalog", "", "遥测参数库文件(JSON)，留空使用内置参数库")
+	rulesPath := flag.String("rules", "", "告警规则文件(JSON/YAML)，留空使用内置规则")
+	ccsdsPath := flag.String("ccsds", "", "CCSDS 空间包 APID 映射配置(JSON)，抓包中含空间包时需要")
+	timeCodePath := flag.String("timecode", "", "星上时间码配置(JSON，CUC/CDS)，留空使用接收时间")
+	maxClockSkew := flag.Duration("max-clock-skew", 0, "测量时间与本地时间允许的最大偏差（回放历史数据时默认不检查）")
+	linkLossRate := flag.Float64("link-loss-threshold", business.DefaultLossRateThreshold, "业务报文丢包率告警阈值(0-1)")
+	linkWindow := flag.Int("link-window", business.DefaultSeqWindow, "丢包率评估窗口（期望报文数）")
+	alertsPath := flag.String("alerts", "", "告警输出文件(JSON Lines)，留空只打印")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [参数] <抓包文件或目录>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// 展开目录
+	var files []string
+	for _, arg := range flag.Args() {
+		info, err := os.Stat(arg)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		list, err := business.ListCaptureFiles(arg, "")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		files = append(files, list...)
+	}
+	if len(files) == 0 {
+		fmt.Println("❌ 未找到抓包文件")
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\n收到退出信号，停止回放...")
+		cancel()
+	}()
+
+	// 纯内存状态管理器，回放之间互不影响
+	sm, err := state.NewStateManager()
+	if err != nil {
+		fmt.Printf("❌ 初始化状态管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer sm.Close()
+	sm.SetMaxClockSkew(*maxClockSkew)
+
+	if *catalogPath != "" {
+		catalog, err := telemetry.Load(*catalogPath)
+		if err != nil {
+			fmt.Printf("❌ 加载遥测参数库失败: %v\n", err)
+			os.Exit(1)
+		}
+		telemetry.SetDefault(catalog)
+	}
+	if *rulesPath != "" {
+		rules, err := alert.LoadRules(*rulesPath)
+		if err != nil {
+			fmt.Printf("❌ 加载告警规则失败: %v\n", err)
+			os.Exit(1)
+		}
+		alert.SetDefaultRules(rules)
+	}
+
+	dispatcher := business.NewDispatcher(sm)
+	receiver := business.NewReceiver(dispatcher)
+	receiver.SetSequenceConfig(business.SequenceConfig{
+		Window:            *linkWindow,
+		LossRateThreshold: *linkLossRate,
+	})
+	if *layoutsPath != "" {
+		layouts, err := business.LoadLayouts(*layoutsPath)
+		if err != nil {
+			fmt.Printf("❌ 加载报文布局失败: %v\n", err)
+			os.Exit(1)
+		}
+		receiver.SetLayouts(layouts)
+	}
+	if *timeCodePath != "" {
+		tc, err := business.LoadTimeCodeConfig(*timeCodePath)
+		if err != nil {
+			fmt.Printf("❌ 加载时间码配置失败: %v\n", err)
+			os.Exit(1)
+		}
+		receiver.SetTimeCode(tc)
+	}
+	if *ccsdsPath != "" {
+		ccsdsCfg, err := business.LoadCCSDSConfig(*ccsdsPath)
+		if err != nil {
+			fmt.Printf("❌ 加载CCSDS配置失败: %v\n", err)
+			os.Exit(1)
+		}
+		receiver.SetCCSDS(business.NewCCSDSDecoder(ccsdsCfg))
+	}
+
+	recorder := &alertRecorder{}
+	if *alertsPath != "" {
+		f, err := os.Create(*alertsPath)
+		if err != nil {
+			fmt.Printf("❌ 创建告警输出文件失败: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		recorder.enc = json.NewEncoder(f)
+	}
+	dispatcher.SetDiagnosisReceiver(recorder)
+
+	mode := "尽快"
+	if *speed > 0 {
+		mode = fmt.Sprintf("%gx", *speed)
+	}
+	fmt.Printf("========== 回放 %d 个抓包文件（速度: %s）==========\n", len(files), mode)
+
+	stats, err := business.NewReplayer(receiver, *speed).Run(ctx, files...)
+
+	fmt.Println("\n========== 回放统计 ==========")
+	fmt.Printf("记录: %d（帧 %d，空间包 %d，字节流 %d，跳过 %d）\n",
+		stats.Records, stats.Frames, stats.SpacePackets, stats.StreamChunks, stats.Skipped)
+	fmt.Printf("抓包时长: %v，回放耗时: %v\n", stats.Span, stats.Elapsed)
+	if stats.Truncated > 0 {
+		fmt.Printf("末尾不完整的文件: %d\n", stats.Truncated)
+	}
+	fmt.Printf("告警: %d（触发 %d）\n", recorder.total, recorder.firing)
+
+	frameStats := receiver.FrameStats()
+	comps := make([]int, 0, len(frameStats))
+	for comp := range frameStats {
+		comps = append(comps, int(comp))
+	}
+	sort.Ints(comps)
+	for _, comp := range comps {
+		fmt.Printf("组件 0x%02X: %+v\n", comp, frameStats[uint8(comp)])
+	}
+	if un := receiver.Counters().Unattributed(); un != (business.FrameStats{}) {
+		fmt.Printf("未归属组件: %+v\n", un)
+	}
+	for _, lq := range receiver.LinkQuality() {
+		fmt.Printf("链路 0x%02X: 收到 %d 丢失 %d 重复 %d 乱序 %d\n",
+			lq.ComponentType, lq.Received, lq.Lost, lq.Duplicates, lq.Reordered)
+	}
+
+	if err != nil && err != context.Canceled {
+		fmt.Printf("❌ 回放失败: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -2,7 +2,10 @@ module health-monitor
 
 go 1.25.0
 
-require go.etcd.io/etcd/client/v3 v3.5.11
+require (
+	go.etcd.io/etcd/client/v3 v3.5.11
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/coreos/go-semver v0.3.0 // indirect
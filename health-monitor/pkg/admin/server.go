@@ -0,0 +1,111 @@
+/*
+管理接口
+
+monitor 运行时的 HTTP 管理接口（配置热加载、状态查询等），各功能模块实现 http.Handler 后通过 Handle 挂载：
+
+	POST     /api/v1/reload          重新加载告警规则、遥测参数库、报文布局（config.Reloader）
+	GET      /api/v1/alerts/pending  持续性判定中的告警（StateManager.GetPendingAlerts）
+	GET/POST /api/v1/silences        告警静默规则的查询、创建（alert.SilenceStore）
+	DELETE   /api/v1/silences/{id}   提前结束静默
+
+监听地址由 monitor 的 -admin 参数指定，留空不启用；只写端口（如 :8090）时只监听本机回环地址。
+接口本身不鉴权：设置访问令牌（-admin-token）后所有请求须携带 Authorization: Bearer <token>，
+否则任何能访问监听地址的人都可以重新加载配置、创建或结束静默。
+*/
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Server 管理接口 HTTP 服务
+type Server struct {
+	mux    *http.ServeMux
+	server *http.Server
+	addr   string
+	token  string // 访问令牌，为空时不鉴权
+}
+
+// NewServer 创建管理接口服务，addr 未指定主机时只监听本机回环地址
+func NewServer(addr string) *Server {
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+	s := &Server{mux: http.NewServeMux(), addr: addr}
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// SetToken 设置访问令牌（Start 之前调用），为空时不鉴权
+func (s *Server) SetToken(token string) {
+	s.token = token
+}
+
+// ServeHTTP 校验访问令牌后交给挂载的处理器
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" {
+		auth := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			WriteJSON(w, http.StatusUnauthorized, map[string]string{"error": "未授权"})
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Handle 挂载处理器
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleFunc 挂载处理函数
+func (s *Server) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// Addr 实际监听地址（Start 之后有效，端口为 0 时为系统分配的端口）
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// Start 开始监听并在后台提供服务
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("admin listen %s: %w", s.server.Addr, err)
+	}
+	s.addr = ln.Addr().String()
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("[管理接口] 服务异常退出: %v\n", err)
+		}
+	}()
+	fmt.Printf("[管理接口] 监听 %s\n", s.addr)
+	return nil
+}
+
+// Close 关闭服务，等待进行中的请求完成（最多 5 秒）
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// WriteJSON 以 JSON 格式输出响应
+func WriteJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
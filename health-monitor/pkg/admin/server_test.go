@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestServerLoopback 测试只写端口时只监听本机回环地址
+func TestServerLoopback(t *testing.T) {
+	s := NewServer(":0")
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if !strings.HasPrefix(s.Addr(), "127.0.0.1:") {
+		t.Errorf("应只监听回环地址: %s", s.Addr())
+	}
+}
+
+// TestServerToken 测试设置访问令牌后未携带或携带错误令牌的请求被拒绝
+func TestServerToken(t *testing.T) {
+	s := NewServer("127.0.0.1:0")
+	s.HandleFunc("/api/v1/reload", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	do := func(auth string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/reload", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := do(""); code != http.StatusOK {
+		t.Errorf("未设置令牌时不鉴权: %d", code)
+	}
+	s.SetToken("secret")
+	for _, auth := range []string{"", "Bearer wrong", "secret"} {
+		if code := do(auth); code != http.StatusUnauthorized {
+			t.Errorf("%q: 期望 401，得到 %d", auth, code)
+		}
+	}
+	if code := do("Bearer secret"); code != http.StatusOK {
+		t.Errorf("正确令牌应放行: %d", code)
+	}
+}
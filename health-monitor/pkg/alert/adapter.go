@@ -59,6 +59,8 @@ func ConvertToDiagnosisAlert(alert *model.AlertEvent) map[string]interface{} {
 		"Timestamp":     alert.Timestamp,
 		"FaultCode":     alert.FaultCode,
 		"MetricValue":   alert.MetricValue,
+		"TMCode":        alert.TMCode,
+		"Unit":          alert.Unit,
 		"RelatedAlerts": alert.RelatedAlerts,
 		"Metadata":      alert.Metadata,
 	}
@@ -80,6 +82,8 @@ func ConvertToDiagnosisAlertDirect(alert *model.AlertEvent) interface{} {
 		Timestamp     int64
 		FaultCode     string
 		MetricValue   float64
+		TMCode        string
+		Unit          string
 		RelatedAlerts []string
 		Metadata      map[string]interface{}
 	}{
@@ -92,6 +96,8 @@ func ConvertToDiagnosisAlertDirect(alert *model.AlertEvent) interface{} {
 		Timestamp:     alert.Timestamp,
 		FaultCode:     alert.FaultCode,
 		MetricValue:   alert.MetricValue,
+		TMCode:        alert.TMCode,
+		Unit:          alert.Unit,
 		RelatedAlerts: alert.RelatedAlerts,
 		Metadata:      alert.Metadata,
 	}
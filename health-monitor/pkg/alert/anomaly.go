@@ -0,0 +1,522 @@
+/* 统计异常检测
+
+阈值规则只能发现越过限值的故障，限值内的缓慢退化（如蓄电池电压数天内逐渐下降）与突变无法发现。
+规则集的 anomalies 为单个参数声明异常检测器，按该参数自身的历史学习基线：
+
+	alert_id    告警ID（与规则、传感器组的 alert_id 不能重复）
+	layer、component、field   指标选择，写法同规则（计算字段、派生参数、[*] 均可）
+	methods     检测方法：zscore（突变）、cusum（缓慢漂移），默认两者
+	direction   检测方向：both（默认）、up、down
+	alpha       EWMA 平滑系数，默认 0.05（越小基线越稳定、适应越慢）
+	z           z-score 阈值，默认 4
+	cusum_k     CUSUM 容许偏移（以参考标准差为单位），默认 0.5
+	cusum_h     CUSUM 判定阈值（以参考标准差为单位），默认 8
+	warmup      学习样本数，学满前不判定，默认 30
+	min_std     标准差下限，默认为 max(|均值|, 1) 的千分之一（避免恒定参数的微小波动被判为异常）
+	name、unit、format、type（默认 statistical_anomaly）、severity、fault_code、source（同规则）
+
+每个检测器、每个来源一份基线（state.AnomalyBaseline，由 StateManager 保存并随快照持久化）：
+
+	学习期    前 warmup 个样本计算算术均值与方差，学满时冻结为 CUSUM 的参考均值与参考标准差；
+	          新建基线时先用 StateManager 中该实体的历史（最近 10 分钟）学习，进程启动后无需从头等待
+	z-score   z = (x - 均值) / 标准差，|z| 超过阈值为突变（outlier_high / outlier_low），
+	          之后按 EWMA 更新均值与方差；异常样本按阈值截断后再学习，避免单个跳点拉偏基线
+	CUSUM     相对参考均值的累积和，持续偏离超过 cusum_h 为漂移（drift_up / drift_down）。
+	          EWMA 基线会跟随缓慢漂移，z-score 无法发现，因此 CUSUM 以学习期的参考值为准；
+	          单个样本的贡献不超过 z 阈值，单个跳点不会判为漂移；
+	          累积和上限为 2 × cusum_h，参数回到参考值附近后逐渐回落并解除
+
+漂移优先于突变。同一来源的异常类型变化时以同一告警ID发送更新，回到正常时发送恢复告警。
+参数确实进入新的正常水平（如更换蓄电池）时，用 StateManager.ResetAnomalyBaselines 删除基线重新学习。
+告警 Metadata["anomaly"] 带 method、z、mean、std、ref_mean、ref_std、cusum_high、cusum_low、count。
+*/
+package alert
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+	"health-monitor/pkg/telemetry"
+)
+
+// StatisticalAnomalyType 统计异常告警类型
+const StatisticalAnomalyType = "statistical_anomaly"
+
+// 统计异常检测默认参数
+const (
+	DefaultAnomalyAlpha  = 0.05
+	DefaultAnomalyZ      = 4.0
+	DefaultAnomalyCusumK = 0.5
+	DefaultAnomalyCusumH = 8.0
+	DefaultAnomalyWarmup = 30
+)
+
+// 检测方法
+const (
+	AnomalyZScore = "zscore"
+	AnomalyCusum  = "cusum"
+)
+
+// AnomalyDetector 单个参数的统计异常检测器
+type AnomalyDetector struct {
+	AlertID   string              `json:"alert_id"`             // 告警ID
+	Disabled  bool                `json:"disabled,omitempty"`   // 停用
+	Layer     string              `json:"layer"`                // 层级
+	Component uint8               `json:"component,omitempty"`  // 业务组件编号（仅 business）
+	Field     string              `json:"field"`                // 字段
+	Methods   []string            `json:"methods,omitempty"`    // 检测方法，默认 zscore、cusum
+	Direction string              `json:"direction,omitempty"`  // 检测方向 both/up/down，默认 both
+	Alpha     float64             `json:"alpha,omitempty"`      // EWMA 平滑系数
+	Z         float64             `json:"z,omitempty"`          // z-score 阈值
+	CusumK    float64             `json:"cusum_k,omitempty"`    // CUSUM 容许偏移
+	CusumH    float64             `json:"cusum_h,omitempty"`    // CUSUM 判定阈值
+	Warmup    int                 `json:"warmup,omitempty"`     // 学习样本数
+	MinStd    float64             `json:"min_std,omitempty"`    // 标准差下限
+	Name      string              `json:"name,omitempty"`       // 参数名，默认取参数库名称或字段名
+	Unit      string              `json:"unit,omitempty"`       // 单位
+	Format    string              `json:"format,omitempty"`     // 数值格式，默认最短表示
+	Type      string              `json:"type,omitempty"`       // 告警类型，默认 statistical_anomaly
+	Severity  model.AlertSeverity `json:"severity,omitempty"`   // 严重程度，默认 warning
+	FaultCode string              `json:"fault_code,omitempty"` // 故障码
+	Source    string              `json:"source,omitempty"`     // 告警来源模板，默认同规则
+
+	sel    numericField
+	zscore bool
+	cusum  bool
+	source *template.Template
+}
+
+// numericField 按规则 field 写法选择的数值字段（异常检测器与轨道周期包络共用）
+type numericField struct {
+	field    string
+	path     []pathStep
+	computed computedField
+	derived  *Derived
+	wildcard bool
+}
+
+// anomalyResult 一次检测结果
+type anomalyResult struct {
+	level  string // 空为正常，否则为 drift_up / drift_down / outlier_high / outlier_low
+	z      float64
+	std    float64 // 判定时的标准差
+	before state.AnomalyBaseline
+}
+
+// compileAnomalies 校验异常检测器
+func (rs *RuleSet) compileAnomalies() error {
+	ids := make(map[string]bool, len(rs.Anomalies))
+	for _, g := range rs.SensorGroups {
+		ids[g.AlertID] = true
+	}
+	for i := range rs.Anomalies {
+		d := &rs.Anomalies[i]
+		if d.AlertID == "" {
+			return fmt.Errorf("第 %d 个异常检测器缺少 alert_id", i+1)
+		}
+		if _, dup := rs.byID[d.AlertID]; dup || ids[d.AlertID] {
+			return fmt.Errorf("异常检测器 %s 与其他告警 alert_id 重复", d.AlertID)
+		}
+		ids[d.AlertID] = true
+		if err := d.compile(rs); err != nil {
+			return fmt.Errorf("异常检测器 %s: %w", d.AlertID, err)
+		}
+	}
+	return nil
+}
+
+func (d *AnomalyDetector) compile(rs *RuleSet) error {
+	var err error
+	if d.sel, err = rs.numericField(d.Layer, d.Component, d.Field); err != nil {
+		return err
+	}
+
+	// 检测参数
+	if len(d.Methods) == 0 {
+		d.Methods = []string{AnomalyZScore, AnomalyCusum}
+	}
+	d.zscore, d.cusum = false, false
+	for _, m := range d.Methods {
+		switch m {
+		case AnomalyZScore:
+			d.zscore = true
+		case AnomalyCusum:
+			d.cusum = true
+		default:
+			return fmt.Errorf("未知检测方法 %q", m)
+		}
+	}
+	switch d.Direction {
+	case "":
+		d.Direction = "both"
+	case "both", "up", "down":
+	default:
+		return fmt.Errorf("direction 须为 both、up 或 down")
+	}
+	if d.Alpha == 0 {
+		d.Alpha = DefaultAnomalyAlpha
+	}
+	if d.Alpha <= 0 || d.Alpha >= 1 {
+		return fmt.Errorf("alpha 须在 0~1 之间")
+	}
+	if d.Z == 0 {
+		d.Z = DefaultAnomalyZ
+	}
+	if d.CusumK == 0 {
+		d.CusumK = DefaultAnomalyCusumK
+	}
+	if d.CusumH == 0 {
+		d.CusumH = DefaultAnomalyCusumH
+	}
+	if d.Z < 0 || d.CusumK < 0 || d.CusumH < 0 || d.MinStd < 0 {
+		return fmt.Errorf("z、cusum_k、cusum_h、min_std 不能为负")
+	}
+	if d.Warmup == 0 {
+		d.Warmup = DefaultAnomalyWarmup
+	}
+	if d.Warmup < 2 {
+		return fmt.Errorf("warmup 须不小于 2")
+	}
+
+	// 告警属性
+	if d.Type == "" {
+		d.Type = StatisticalAnomalyType
+	}
+	if d.Severity == "" {
+		d.Severity = model.SeverityWarning
+	}
+	if !validSeverity(d.Severity) {
+		return fmt.Errorf("未知严重程度 %q", d.Severity)
+	}
+	d.source, err = d.sel.sourceTemplate(d.Layer, d.Source)
+	return err
+}
+
+// numericField 解析字段选择：计算字段、派生参数或字段路径（可含 [*]）
+func (rs *RuleSet) numericField(layer string, component uint8, field string) (numericField, error) {
+	f := numericField{field: field}
+	typ, err := metricType(layer, component)
+	if err != nil {
+		return f, err
+	}
+	if field == "" {
+		return f, fmt.Errorf("缺少 field")
+	}
+	if fn, ok := computedFields[layer][field]; ok {
+		f.computed = fn
+		return f, nil
+	}
+	if d, ok := rs.derivedField(layer, component, field); ok {
+		f.computed, f.derived = d.value, d
+		return f, nil
+	}
+	path, err := parseFieldPath(field)
+	if err != nil {
+		return f, err
+	}
+	if err := checkFieldPath(typ, path); err != nil {
+		return f, fmt.Errorf("field %s: %w", field, err)
+	}
+	if fieldKind(typ, path) != kindNum {
+		return f, fmt.Errorf("field %s 不是数值字段", field)
+	}
+	f.path = path
+	for _, s := range path {
+		if s.index == wildcardIndex {
+			f.wildcard = true
+		}
+	}
+	return f, nil
+}
+
+// sourceTemplate 解析来源模板，默认同规则（业务层为字段名，微服务层为实体ID）
+func (f *numericField) sourceTemplate(layer, source string) (*template.Template, error) {
+	if source == "" {
+		if layer == LayerBusiness {
+			source = strings.Replace(f.field, "[*]", "{{.N}}", 1)
+		} else {
+			source = "{{.ID}}"
+		}
+	}
+	if f.wildcard && !strings.Contains(source, "{{.N}}") {
+		// 各元素须有不同来源，否则基线与状态会互相覆盖
+		return nil, fmt.Errorf("field 含 [*] 时 source 须包含 {{.N}}")
+	}
+	return parseRuleTemplate("source", source)
+}
+
+// values 取出字段值（只保留有限的数值）
+func (f *numericField) values(data interface{}) []fieldValue {
+	if f.computed != nil {
+		if v, ok := f.computed(data); ok {
+			return []fieldValue{{field: f.field, value: v}}
+		}
+		return nil
+	}
+	var out []fieldValue
+	for _, fv := range pathValues(f.field, f.path, data) {
+		if x, ok := fv.value.(float64); ok && !math.IsNaN(x) && !math.IsInf(x, 0) {
+			out = append(out, fv)
+		}
+	}
+	return out
+}
+
+// minStd 标准差下限
+func (d *AnomalyDetector) minStd(mean float64) float64 {
+	if d.MinStd > 0 {
+		return d.MinStd
+	}
+	return math.Max(math.Abs(mean), 1) * 1e-3
+}
+
+// learn 学习一个样本：学习期内返回 false，之后返回检测结果
+func (d *AnomalyDetector) learn(b *state.AnomalyBaseline, x float64, ts int64) (anomalyResult, bool) {
+	b.Updated = ts
+	if b.Count < int64(d.Warmup) {
+		// 学习期：算术均值与总体方差（Welford）
+		b.Count++
+		delta := x - b.Mean
+		b.Mean += delta / float64(b.Count)
+		b.Variance += (delta*(x-b.Mean) - b.Variance) / float64(b.Count)
+		if b.Count == int64(d.Warmup) {
+			b.RefMean = b.Mean
+			b.RefStd = math.Max(math.Sqrt(b.Variance), d.minStd(b.Mean))
+		}
+		return anomalyResult{}, false
+	}
+
+	res := anomalyResult{before: *b}
+	res.std = math.Max(math.Sqrt(b.Variance), d.minStd(b.Mean))
+	res.z = (x - b.Mean) / res.std
+
+	// CUSUM 相对学习期的参考值累积，单个样本的贡献截断到 z 阈值（单个跳点判为突变而非漂移）
+	u := (x - b.RefMean) / b.RefStd
+	if d.Z > 0 {
+		u = math.Max(-d.Z, math.Min(u, d.Z))
+	}
+	limit := 2 * d.CusumH
+	b.CusumHigh = math.Min(math.Max(0, b.CusumHigh+u-d.CusumK), limit)
+	b.CusumLow = math.Min(math.Max(0, b.CusumLow-u-d.CusumK), limit)
+
+	up, down := d.Direction != "down", d.Direction != "up"
+	switch {
+	case d.cusum && up && b.CusumHigh > d.CusumH:
+		res.level = "drift_up"
+	case d.cusum && down && b.CusumLow > d.CusumH:
+		res.level = "drift_down"
+	case d.zscore && up && res.z > d.Z:
+		res.level = "outlier_high"
+	case d.zscore && down && res.z < -d.Z:
+		res.level = "outlier_low"
+	}
+
+	// EWMA 更新，异常样本截断到阈值处
+	if bound := d.Z * res.std; d.Z > 0 && math.Abs(x-b.Mean) > bound {
+		x = b.Mean + math.Copysign(bound, x-b.Mean)
+	}
+	diff := x - b.Mean
+	incr := d.Alpha * diff
+	b.Mean += incr
+	b.Variance = (1 - d.Alpha) * (b.Variance + diff*incr)
+	b.Count++
+	return res, true
+}
+
+// anomalyHistory 评估对象所属实体的历史样本（按时间排序），供新建基线时学习
+func anomalyHistory(t ruleTarget, sm *state.StateManager) []trendSample {
+	typ, id := entityLayers[t.layer], t.id
+	if t.layer == LayerBusiness {
+		typ, id = state.MetricTypeBusiness, string(rune(t.component))
+	}
+	if typ == "" {
+		return nil
+	}
+	return historySamples(sm.QueryHistory(typ, id, state.HistoryRetention))
+}
+
+// historySamples 取出历史中的指标结构体并按时间排序（业务层解开 BusinessMetrics）
+func historySamples(history []state.HistoryEntry) []trendSample {
+	samples := make([]trendSample, 0, len(history))
+	for _, entry := range history {
+		data := entry.Data
+		if bm, ok := data.(*model.BusinessMetrics); ok && bm != nil {
+			data = bm.Data
+		}
+		if !indirect(reflect.ValueOf(data)).IsValid() {
+			continue
+		}
+		samples = append(samples, trendSample{ts: entry.Timestamp, data: data})
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].ts < samples[j].ts })
+	return samples
+}
+
+// evaluateAnomalies 评估异常检测器，异常类型变化时返回告警
+func (rs *RuleSet) evaluateAnomalies(t ruleTarget, sm *state.StateManager) []*model.AlertEvent {
+	var alerts []*model.AlertEvent
+	var history []trendSample
+	loaded := false
+	for i := range rs.Anomalies {
+		d := &rs.Anomalies[i]
+		if d.Disabled || d.Layer != t.layer || (d.Layer == LayerBusiness && d.Component != t.component) {
+			continue
+		}
+		for _, fv := range d.sel.values(t.data) {
+			x, ok := fv.value.(float64)
+			if !ok {
+				continue
+			}
+			data := d.sel.ruleData(t, fv, d.Name, d.Unit)
+			source := execRuleTemplate(d.source, data)
+
+			var res anomalyResult
+			judged := false
+			b := sm.UpdateAnomalyBaseline(d.AlertID, source, func(b *state.AnomalyBaseline) {
+				if b.Field != fv.field {
+					// 新建基线（或检测器改为其他字段）：先用已有历史学习
+					*b = state.AnomalyBaseline{AlertID: d.AlertID, Source: source, Field: fv.field}
+					if !loaded {
+						history, loaded = anomalyHistory(t, sm), true
+					}
+					d.seed(b, fv.field, history, t.timestamp)
+				}
+				if t.timestamp < b.Updated {
+					return // 乱序的旧样本不学习
+				}
+				res, judged = d.learn(b, x, t.timestamp)
+			})
+			if !judged {
+				continue
+			}
+
+			shouldSend, firing, _ := sm.CheckAndUpdateAlertLevel(d.AlertID, source, res.level)
+			if !shouldSend {
+				continue
+			}
+			alerts = append(alerts, d.alert(t, data, source, x, res, b, firing))
+		}
+	}
+	return alerts
+}
+
+// seed 用历史中早于 before 的样本学习（不判定）
+func (d *AnomalyDetector) seed(b *state.AnomalyBaseline, field string, history []trendSample, before int64) {
+	for _, s := range history {
+		if s.ts >= before {
+			break
+		}
+		for _, fv := range d.sel.values(s.data) {
+			if x, ok := fv.value.(float64); ok && fv.field == field {
+				d.learn(b, x, s.ts)
+			}
+		}
+	}
+	if b.Count > 0 {
+		// 学满后的样本已参与 CUSUM 累积，历史中的异常不应在首次判定前就触发漂移
+		b.CusumHigh, b.CusumLow = 0, 0
+	}
+}
+
+// ruleData 模板数据（名称、单位未配置时取派生参数或参数库）
+func (f *numericField) ruleData(t ruleTarget, fv fieldValue, name, unit string) ruleData {
+	data := ruleData{ID: t.id, N: fv.n, Name: name, Unit: unit}
+	if f.derived != nil {
+		if data.Name == "" {
+			data.Name = f.derived.Description
+		}
+		if data.Unit == "" {
+			data.Unit = f.derived.Unit
+		}
+	}
+	if t.layer == LayerBusiness {
+		if p, ok := telemetry.Default().LookupField(t.component, fv.field); ok {
+			data.Code = p.Code
+			if data.Name == "" {
+				data.Name = p.Name
+			}
+			if data.Unit == "" {
+				data.Unit = p.Unit
+			}
+		}
+	}
+	if data.Name == "" {
+		data.Name = fv.field
+	}
+	return data
+}
+
+// formatNumber 按格式输出数值，格式为空时取最短表示
+func formatNumber(format string, x float64) string {
+	if format != "" {
+		return fmt.Sprintf(format, x)
+	}
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+// alert 生成异常（或恢复）告警
+func (d *AnomalyDetector) alert(t ruleTarget, data ruleData, source string, x float64, res anomalyResult, b state.AnomalyBaseline, firing bool) *model.AlertEvent {
+	method := ""
+	switch {
+	case strings.HasPrefix(res.level, "drift"):
+		method = AnomalyCusum
+	case res.level != "":
+		method = AnomalyZScore
+	}
+	info := map[string]interface{}{
+		"field":      b.Field,
+		"method":     method,
+		"z":          res.z,
+		"mean":       res.before.Mean,
+		"std":        res.std,
+		"ref_mean":   b.RefMean,
+		"ref_std":    b.RefStd,
+		"cusum_high": b.CusumHigh,
+		"cusum_low":  b.CusumLow,
+		"count":      b.Count,
+	}
+	alert := &model.AlertEvent{
+		AlertID:     d.AlertID,
+		Type:        d.Type,
+		Source:      source,
+		Timestamp:   t.timestamp,
+		FaultCode:   d.FaultCode,
+		TMCode:      data.Code,
+		MetricValue: x,
+		Unit:        data.Unit,
+		Metadata:    withMetadata(t.metadata, "anomaly", info),
+	}
+	name := data.Name
+	if t.id != "" {
+		name = t.id + " " + name
+	}
+	if !firing {
+		alert.Status = model.AlertStatusResolved
+		alert.Severity = model.SeverityInfo
+		alert.Message = fmt.Sprintf("%s统计异常已解除: 当前%s%s", name, formatNumber(d.Format, x), data.Unit)
+		return alert
+	}
+
+	alert.Status = model.AlertStatusFiring
+	alert.Severity = d.Severity
+	switch res.level {
+	case "drift_up", "drift_down":
+		dir := "偏高"
+		if res.level == "drift_down" {
+			dir = "偏低"
+		}
+		alert.Message = fmt.Sprintf("%s缓慢漂移: 持续%s于基线%s%s，当前%s%s", name, dir,
+			formatNumber(d.Format, b.RefMean), data.Unit, formatNumber(d.Format, x), data.Unit)
+	default:
+		alert.Message = fmt.Sprintf("%s统计异常: 当前%s%s，偏离基线%s%s %.1f倍标准差", name, formatNumber(d.Format, x), data.Unit,
+			formatNumber(d.Format, res.before.Mean), data.Unit, math.Abs(res.z))
+	}
+	return alert
+}
@@ -0,0 +1,182 @@
+package alert
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+func battery(ts int64, v float64) *model.BusinessMetrics {
+	return &model.BusinessMetrics{ComponentType: 0x03, Timestamp: ts, Data: &model.PowerMetrics{BatteryVoltage: v}}
+}
+
+// noise 交替 ±0.05 的测量噪声
+func noise(i int) float64 {
+	if i%2 == 0 {
+		return 0.05
+	}
+	return -0.05
+}
+
+// TestAnomalyZScore 测试学习期不判定、突变触发 z-score 告警且不拉偏基线、回到正常后解除
+func TestAnomalyZScore(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[],"anomalies":[{"alert_id":"BV","layer":"business","component":3,"field":"BatteryVoltage",
+		"methods":["zscore"],"warmup":20,"name":"蓄电池电压","format":"%.2f"}]}`)
+
+	ts := int64(1700000000)
+	for i := 0; i < 20; i++ {
+		if alerts := rs.EvaluateBusiness(battery(ts+int64(i), 25+noise(i)), sm); len(alerts) != 0 {
+			t.Fatalf("学习期不应判定: %d %+v", i, alerts)
+		}
+	}
+	// 学满后首次判定正常，以恢复告警上报
+	if a := findAlert(rs.EvaluateBusiness(battery(ts+20, 25.05), sm), "BV"); a == nil || a.IsFiring() {
+		t.Fatalf("首次判定应为正常: %+v", a)
+	}
+
+	a := findAlert(rs.EvaluateBusiness(battery(ts+21, 24), sm), "BV")
+	if a == nil || !a.IsFiring() || a.Type != StatisticalAnomalyType || a.Source != "BatteryVoltage" ||
+		!strings.HasPrefix(a.Message, "蓄电池电压统计异常: 当前24.00V，偏离基线25.00V") {
+		t.Fatalf("突变告警不符: %+v", a)
+	}
+	info := a.Metadata["anomaly"].(map[string]interface{})
+	if info["method"] != AnomalyZScore || info["z"].(float64) > -4 {
+		t.Errorf("检测信息不符: %+v", info)
+	}
+
+	a = findAlert(rs.EvaluateBusiness(battery(ts+22, 25), sm), "BV")
+	if a == nil || !a.IsResolved() || a.Message != "蓄电池电压统计异常已解除: 当前25.00V" {
+		t.Errorf("回到正常后应解除: %+v", a)
+	}
+	b := sm.GetAnomalyBaselines()
+	if len(b) != 1 || b[0].Count != 23 || b[0].Mean < 24.95 {
+		t.Errorf("跳点不应明显拉偏基线: %+v", b)
+	}
+}
+
+// TestAnomalyCusumDrift 测试限值内的缓慢漂移：z-score 无法发现，CUSUM 触发；基线随快照恢复后继续判定
+func TestAnomalyCusumDrift(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[],"anomalies":[{"alert_id":"BV","layer":"business","component":3,"field":"BatteryVoltage",
+		"direction":"down","warmup":30,"name":"蓄电池电压","format":"%.2f","source":"battery"}]}`)
+
+	ts := int64(1700000000)
+	for i := 0; i < 31; i++ {
+		rs.EvaluateBusiness(battery(ts+int64(i), 25+noise(i)), sm)
+	}
+
+	// 每个样本下降 0.003V，淹没在 ±0.05V 的噪声中
+	var drift *model.AlertEvent
+	i := 31
+	for ; i < 100 && drift == nil; i++ {
+		v := 25 - 0.003*float64(i-30) + noise(i)
+		for _, a := range rs.EvaluateBusiness(battery(ts+int64(i), v), sm) {
+			if a.Metadata["anomaly"].(map[string]interface{})["method"] == AnomalyZScore {
+				t.Fatalf("缓慢漂移不应触发 z-score: %+v", a)
+			}
+			if a.IsFiring() {
+				drift = a
+			}
+		}
+	}
+	if drift == nil || drift.Source != "battery" || !strings.HasPrefix(drift.Message, "蓄电池电压缓慢漂移: 持续偏低于基线25.00V") {
+		t.Fatalf("应检测到缓慢漂移: %+v", drift)
+	}
+	if v := drift.MetricValue; v < 24.8 {
+		t.Errorf("漂移应在越限前很早发现: %v", v)
+	}
+
+	// 快照经 JSON 往返后恢复到新的 StateManager，无需重新学习
+	data, err := json.Marshal(sm.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot state.StateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	restored := newTestStateManager(t)
+	restored.RestoreSnapshot(&snapshot)
+	before := sm.GetAnomalyBaselines()
+	if !reflect.DeepEqual(restored.GetAnomalyBaselines(), before) || len(before) != 1 || before[0].Field != "BatteryVoltage" {
+		t.Fatalf("基线恢复不符: %+v %+v", restored.GetAnomalyBaselines(), before)
+	}
+	a := findAlert(rs.EvaluateBusiness(battery(ts+int64(i), 24.7), restored), "BV")
+	if a == nil || !a.IsFiring() || a.Metadata["anomaly"].(map[string]interface{})["count"] != before[0].Count+1 {
+		t.Errorf("恢复后应直接判定: %+v", a)
+	}
+
+	// 删除基线后重新学习
+	if n := restored.ResetAnomalyBaselines("BV", ""); n != 1 {
+		t.Errorf("应删除 1 份基线: %d", n)
+	}
+	if alerts := rs.EvaluateBusiness(battery(ts+int64(i)+1, 24.7), restored); len(alerts) != 0 {
+		t.Errorf("重新学习期间不应判定: %+v", alerts)
+	}
+}
+
+// TestAnomalyHistorySeed 测试新建基线时用已有历史学习，微服务层按实体区分来源
+func TestAnomalyHistorySeed(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[],"anomalies":[{"alert_id":"MEM","layer":"node","field":"memory_percent",
+		"direction":"up","warmup":10,"min_std":1,"name":"节点内存使用率","unit":"%","format":"%.1f"}]}`)
+
+	node := func(free int64) *model.NodeMetrics {
+		return &model.NodeMetrics{ID: "n1", MemoryTotal: 1000, MemoryFree: free}
+	}
+	start := time.Now().Unix() - 300
+	for i := 0; i < 12; i++ {
+		if err := sm.UpdateMetric(&state.NodeMetric{Data: node(600 + int64(i%2)*10), Timestamp: start + int64(i)*20}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := findAlert(rs.EvaluateNode(node(300), sm), "MEM")
+	if a == nil || !a.IsFiring() || a.Source != "n1" || !strings.HasPrefix(a.Message, "n1 节点内存使用率统计异常: 当前70.0%") {
+		t.Fatalf("已有历史时应直接判定: %+v", a)
+	}
+	if b := sm.GetAnomalyBaselines(); len(b) != 1 || b[0].Source != "n1" || b[0].Count != 13 || b[0].RefMean != 39.5 {
+		t.Errorf("基线应由历史学习: %+v", b)
+	}
+}
+
+// TestParseAnomalies 测试异常检测器校验与内置检测器
+func TestParseAnomalies(t *testing.T) {
+	base := `"alert_id":"A",`
+	cases := map[string]string{
+		"缺少字段":    `"layer":"business","component":3,"field":""`,
+		"非数值字段":   `"layer":"node","field":"Status"`,
+		"平滑系数":    `"layer":"business","component":3,"field":"BatteryVoltage","alpha":1.5`,
+		"检测方向":    `"layer":"business","component":3,"field":"BatteryVoltage","direction":"sideways"`,
+		"检测方法":    `"layer":"business","component":3,"field":"BatteryVoltage","methods":["ewma"]`,
+		"学习样本数":   `"layer":"business","component":3,"field":"BatteryVoltage","warmup":1`,
+		"负阈值":     `"layer":"business","component":3,"field":"BatteryVoltage","z":-1`,
+		"严重程度":    `"layer":"business","component":3,"field":"BatteryVoltage","severity":"fatal"`,
+		"数组来源":    `"layer":"business","component":6,"field":"ThermalTemps[*]","source":"thermal"`,
+		"未知层级":    `"layer":"satellite","field":"BatteryVoltage"`,
+		"与规则重复ID": `"layer":"business","component":3,"field":"BatteryVoltage","alert_id":"R"`,
+	}
+	for name, d := range cases {
+		data := `{"rules":[{"alert_id":"R","layer":"node","field":"cpu_percent","op":">","value":90,"type":"t"}],"anomalies":[{` + base + d + `}]}`
+		if _, err := ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+
+	rs := DefaultRules()
+	var found *AnomalyDetector
+	for i := range rs.Anomalies {
+		if rs.Anomalies[i].AlertID == "BATTERY_VOLTAGE_DRIFT" {
+			found = &rs.Anomalies[i]
+		}
+	}
+	if found == nil || found.Direction != "down" || found.Warmup != 300 || !found.zscore || !found.cusum || found.Z != DefaultAnomalyZ {
+		t.Errorf("内置规则集应包含蓄电池电压漂移检测: %+v", found)
+	}
+}
@@ -0,0 +1,218 @@
+/*
+告警规则生效条件（模式相关限值）
+
+很多限值只在特定工作模式下有效：加热器开启（PlatformHeaterSwitch）时热控温度限值不同，
+飞轮转速的合理范围取决于姿控模式（AttCtrlMetrics.ControlMode）、轨控模式（RailCtrlMetrics.OrbitMode）
+与载荷工作模式（PayloadMetrics.WorkMode）。规则可配置：
+
+	when   生效条件列表（须全部成立），每项为业务组件某字段的当前值比较：
+	       component  业务组件编号，business 层规则默认为规则自身组件；微服务层规则必填
+	       field      字段（写法同规则 field，不支持 [*]）
+	       op         > >= < <= == != in
+	       value      比较值，in 为候选值数组
+	grace  条件由不成立变为成立（模式切换）后的宽限期，如 "30s"，期间不判定，避免切换过渡过程误告警
+
+条件读取的是 StateManager 中各组件的最新值（规则自身组件取本次采样），组件尚未收到时视为不成立。
+同一告警的不同模式限值写成多条规则（不同 alert_id，when 互斥），例如：
+
+	{"alert_id": "THERMAL_TEMP_ALERT", "component": 6, "field": "ThermalTemps[*]", "op": "outside", "min": -10, "max": 45,
+	 "when": [{"field": "PlatformHeaterSwitch", "op": "==", "value": false}], ...}
+	{"alert_id": "THERMAL_TEMP_HEATER_ALERT", "component": 6, "field": "ThermalTemps[*]", "op": "outside", "min": 0, "max": 60,
+	 "when": [{"field": "PlatformHeaterSwitch", "op": "==", "value": true}], "grace": "60s", ...}
+
+条件不再成立时，该规则的活跃告警立即解除（恢复告警），告警状态与持续性判定清零；
+模式遥测所在组件的采样到达时即重新判定依赖它的规则，不必等待规则自身组件的下一次采样。
+判定状态保存在 StateManager，可通过 GetRuleConditions 查询；未提供 StateManager 时只判定条件，不计宽限期。
+*/
+package alert
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// OpIn 生效条件：当前值属于候选值之一
+const OpIn = "in"
+
+// Condition 规则生效条件：业务组件某字段的当前值满足比较条件
+type Condition struct {
+	Component uint8       `json:"component,omitempty"` // 业务组件编号，business 层规则默认为规则自身组件
+	Field     string      `json:"field"`               // 字段
+	Op        string      `json:"op"`                  // > >= < <= == != in
+	Value     interface{} `json:"value"`               // 比较值（in 为数组）
+
+	path []pathStep
+}
+
+// compileConditions 校验生效条件与宽限期
+func (r *Rule) compileConditions() error {
+	if r.Grace != "" {
+		if len(r.When) == 0 {
+			return fmt.Errorf("grace 需要 when")
+		}
+		d, err := time.ParseDuration(r.Grace)
+		if err != nil || d < 0 {
+			return fmt.Errorf("grace 格式错误: %q", r.Grace)
+		}
+		r.grace = d
+	}
+	for i := range r.When {
+		c := &r.When[i]
+		if c.Component == 0 {
+			if r.Layer != LayerBusiness {
+				return fmt.Errorf("when[%d] 需要 component", i)
+			}
+			c.Component = r.Component
+		}
+		typ, ok := businessTypes[c.Component]
+		if !ok {
+			return fmt.Errorf("when[%d]: 未知业务组件编号 %d", i, c.Component)
+		}
+		if c.Field == "" {
+			return fmt.Errorf("when[%d] 缺少 field", i)
+		}
+		path, err := parseFieldPath(c.Field)
+		if err != nil {
+			return fmt.Errorf("when[%d]: %w", i, err)
+		}
+		for _, s := range path {
+			if s.index == wildcardIndex {
+				return fmt.Errorf("when[%d]: field 不支持 [*]", i)
+			}
+		}
+		if err := checkFieldPath(typ, path); err != nil {
+			return fmt.Errorf("when[%d] field %s: %w", i, c.Field, err)
+		}
+		c.path = path
+
+		switch c.Op {
+		case OpGT, OpGE, OpLT, OpLE:
+			if _, ok := c.Value.(float64); !ok {
+				return fmt.Errorf("when[%d]: %s 需要数值 value", i, c.Op)
+			}
+		case OpEQ, OpNE:
+			if !conditionValue(c.Value) {
+				return fmt.Errorf("when[%d]: %s 需要数值、字符串或布尔 value", i, c.Op)
+			}
+		case OpIn:
+			list, ok := c.Value.([]interface{})
+			if !ok || len(list) == 0 {
+				return fmt.Errorf("when[%d]: in 需要非空数组 value", i)
+			}
+			for _, v := range list {
+				if !conditionValue(v) {
+					return fmt.Errorf("when[%d]: in 的候选值须为数值、字符串或布尔", i)
+				}
+			}
+		default:
+			return fmt.Errorf("when[%d]: 未知判定条件 %q", i, c.Op)
+		}
+	}
+	return nil
+}
+
+func conditionValue(v interface{}) bool {
+	switch v.(type) {
+	case float64, string, bool:
+		return true
+	}
+	return false
+}
+
+// test 判定当前值，类型不匹配时不成立
+func (c *Condition) test(v interface{}) bool {
+	switch c.Op {
+	case OpEQ:
+		return v == c.Value
+	case OpNE:
+		return v != c.Value
+	case OpIn:
+		for _, want := range c.Value.([]interface{}) {
+			if v == want {
+				return true
+			}
+		}
+		return false
+	}
+	num, ok := v.(float64)
+	return ok && compare(c.Op, num, c.Value.(float64))
+}
+
+// dependsOn 规则的生效条件是否读取该业务组件
+func (r *Rule) dependsOn(component uint8) bool {
+	for i := range r.When {
+		if r.When[i].Component == component {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionsHold 判定生效条件是否全部成立，所需组件尚未收到时视为不成立
+func (r *Rule) conditionsHold(t ruleTarget, sm *state.StateManager) bool {
+	for i := range r.When {
+		c := &r.When[i]
+		var data interface{}
+		switch {
+		case t.layer == LayerBusiness && c.Component == t.component:
+			data = t.data
+		case sm != nil:
+			bm, ok := sm.GetLatestBusiness(c.Component)
+			if !ok {
+				return false
+			}
+			data = bm.Data
+		default:
+			return false
+		}
+		v, ok := scalar(resolvePath(reflect.ValueOf(data), c.path))
+		if !ok || !c.test(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// active 判定规则当前是否生效（条件成立且已过宽限期）
+// 条件由成立变为不成立时，返回该规则活跃告警的恢复告警
+func (r *Rule) active(t ruleTarget, sm *state.StateManager) (bool, []*model.AlertEvent) {
+	hold := r.conditionsHold(t, sm)
+	if sm == nil {
+		return hold, nil
+	}
+	since, changed := sm.UpdateRuleCondition(r.AlertID, hold, t.timestamp)
+	if !hold {
+		if !changed {
+			return false, nil
+		}
+		fmt.Printf("[告警规则] %s 生效条件不再满足，停止判定\n", r.AlertID)
+		return false, r.resolveActive(sm, fmt.Sprintf("告警规则 %s 生效条件不再满足（模式切换），告警解除", r.AlertID), t.timestamp)
+	}
+	if changed {
+		fmt.Printf("[告警规则] %s 生效条件满足，宽限期 %s\n", r.AlertID, r.grace)
+	}
+	// 模式切换后的宽限期内不判定
+	if r.grace > 0 && since != 0 && time.Duration(t.timestamp-since)*time.Second < r.grace {
+		return false, nil
+	}
+	return true, nil
+}
+
+// reevaluateConditions 业务组件采样到达后，重新判定生效条件读取该组件的其它规则，
+// 使模式切换时失效规则的告警及时解除（规则自身组件的采样在 evaluate 中判定）
+func (rs *RuleSet) reevaluateConditions(t ruleTarget, sm *state.StateManager) []*model.AlertEvent {
+	var alerts []*model.AlertEvent
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Disabled || !r.dependsOn(t.component) || (r.Layer == LayerBusiness && r.Component == t.component) {
+			continue
+		}
+		_, resolved := r.active(t, sm)
+		alerts = append(alerts, resolved...)
+	}
+	return alerts
+}
@@ -0,0 +1,114 @@
+package alert
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// thermalSample 评估一帧热控遥测
+func thermalSample(rs *RuleSet, sm *state.StateManager, ts int64, heater bool, temp float64) []*model.AlertEvent {
+	bm := &model.BusinessMetrics{ComponentType: 0x06, Timestamp: ts,
+		Data: &model.ThermalMetrics{Timestamp: ts, PlatformHeaterSwitch: heater, PlatformThermalTemp: temp}}
+	sm.UpdateMetric(&state.BusinessMetric{Data: bm, Timestamp: ts})
+	return rs.EvaluateBusiness(bm, sm)
+}
+
+// TestRuleConditionMode 测试同一组件内按加热器状态切换限值，切换后的宽限期内不判定
+func TestRuleConditionMode(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[
+		{"alert_id":"HEATER_OFF","layer":"business","component":6,"field":"PlatformThermalTemp","op":">","value":45,"type":"t",
+		 "when":[{"field":"PlatformHeaterSwitch","op":"==","value":false}]},
+		{"alert_id":"HEATER_ON","layer":"business","component":6,"field":"PlatformThermalTemp","op":">","value":60,"type":"t",
+		 "when":[{"field":"PlatformHeaterSwitch","op":"==","value":true}],"grace":"30s"}]}`)
+	ts := time.Now().Unix()
+
+	alerts := thermalSample(rs, sm, ts, false, 50)
+	if len(alerts) != 1 || alerts[0].AlertID != "HEATER_OFF" || !alerts[0].IsFiring() {
+		t.Fatalf("加热器关闭时应按 45 判定: %+v", alerts)
+	}
+
+	// 加热器打开：关闭模式的告警解除，开启模式的规则处于宽限期
+	alerts = thermalSample(rs, sm, ts+10, true, 65)
+	if len(alerts) != 1 || alerts[0].AlertID != "HEATER_OFF" || !alerts[0].IsResolved() ||
+		!strings.Contains(alerts[0].Message, "生效条件不再满足") {
+		t.Fatalf("模式切换应解除原模式告警且宽限期内不判定: %+v", alerts)
+	}
+	if sm.GetAlertState("HEATER_OFF") {
+		t.Error("原模式告警状态应清除")
+	}
+	if alerts := thermalSample(rs, sm, ts+30, true, 65); len(alerts) != 0 {
+		t.Fatalf("宽限期内不应告警: %+v", alerts)
+	}
+
+	alerts = thermalSample(rs, sm, ts+40, true, 65)
+	if len(alerts) != 1 || alerts[0].AlertID != "HEATER_ON" || !alerts[0].IsFiring() {
+		t.Fatalf("宽限期后应按 60 判定: %+v", alerts)
+	}
+	conds := sm.GetRuleConditions()
+	if len(conds) != 2 || conds[0].AlertID != "HEATER_OFF" || conds[0].Active || conds[1].Since != ts+10 {
+		t.Errorf("生效条件状态不符: %+v", conds)
+	}
+}
+
+// TestRuleConditionCrossComponent 测试读取其它组件模式遥测的规则，模式遥测到达时即重新判定
+func TestRuleConditionCrossComponent(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[
+		{"alert_id":"WHEEL","layer":"business","component":11,"field":"WheelSpeedX","op":">","value":200,"type":"t","source":"wheel_x",
+		 "when":[{"component":7,"field":"ControlMode","op":"in","value":[1,2]}]}]}`)
+	ts := time.Now().Unix()
+	wheel := func(ts int64, speed int16) []*model.AlertEvent {
+		bm := &model.BusinessMetrics{ComponentType: 0x0B, Timestamp: ts, Data: &model.ActuatorMetrics{WheelSpeedX: speed}}
+		sm.UpdateMetric(&state.BusinessMetric{Data: bm, Timestamp: ts})
+		return rs.EvaluateBusiness(bm, sm)
+	}
+	mode := func(ts int64, m uint8) []*model.AlertEvent {
+		bm := &model.BusinessMetrics{ComponentType: 0x07, Timestamp: ts, Data: &model.AttCtrlMetrics{ControlMode: m}}
+		sm.UpdateMetric(&state.BusinessMetric{Data: bm, Timestamp: ts})
+		return rs.EvaluateBusiness(bm, sm)
+	}
+
+	if alerts := wheel(ts, 300); len(alerts) != 0 {
+		t.Fatalf("未收到模式遥测时规则不应生效: %+v", alerts)
+	}
+	mode(ts+1, 1)
+	if alerts := wheel(ts+2, 300); len(alerts) != 1 || !alerts[0].IsFiring() {
+		t.Fatalf("模式 1 下应触发: %+v", alerts)
+	}
+	alerts := mode(ts+3, 5)
+	if len(alerts) != 1 || alerts[0].AlertID != "WHEEL" || !alerts[0].IsResolved() || alerts[0].Source != "wheel_x" {
+		t.Fatalf("模式遥测到达时应解除告警: %+v", alerts)
+	}
+	if alerts := wheel(ts+4, 300); len(alerts) != 0 {
+		t.Fatalf("模式 5 下规则不应生效: %+v", alerts)
+	}
+
+	// 无 StateManager 时读不到其它组件，规则不生效
+	bm := &model.BusinessMetrics{ComponentType: 0x0B, Data: &model.ActuatorMetrics{WheelSpeedX: 300}}
+	if alerts := rs.EvaluateBusiness(bm, nil); len(alerts) != 0 {
+		t.Errorf("无 StateManager 时不应告警: %+v", alerts)
+	}
+}
+
+// TestParseConditions 测试生效条件校验
+func TestParseConditions(t *testing.T) {
+	cases := map[string]string{
+		"微服务缺组件": `{"rules":[{"alert_id":"A","layer":"node","field":"cpu_percent","op":">","value":1,"type":"t","when":[{"field":"ControlMode","op":"==","value":1}]}]}`,
+		"字段不存在":  `{"rules":[{"alert_id":"A","layer":"business","component":6,"field":"BatteryTemp1","op":">","value":1,"type":"t","when":[{"field":"Nope","op":"==","value":1}]}]}`,
+		"通配字段":   `{"rules":[{"alert_id":"A","layer":"business","component":6,"field":"BatteryTemp1","op":">","value":1,"type":"t","when":[{"field":"ThermalTemps[*]","op":">","value":1}]}]}`,
+		"in非数组":  `{"rules":[{"alert_id":"A","layer":"business","component":6,"field":"BatteryTemp1","op":">","value":1,"type":"t","when":[{"component":7,"field":"ControlMode","op":"in","value":1}]}]}`,
+		"未知条件":   `{"rules":[{"alert_id":"A","layer":"business","component":6,"field":"BatteryTemp1","op":">","value":1,"type":"t","when":[{"field":"BatteryTemp2","op":"outside","value":1}]}]}`,
+		"宽限期格式":  `{"rules":[{"alert_id":"A","layer":"business","component":6,"field":"BatteryTemp1","op":">","value":1,"type":"t","when":[{"field":"BatteryTemp2","op":">","value":1}],"grace":"x"}]}`,
+		"宽限期无条件": `{"rules":[{"alert_id":"A","layer":"business","component":6,"field":"BatteryTemp1","op":">","value":1,"type":"t","grace":"10s"}]}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+}
@@ -1,20 +1,597 @@
-/* 包括两个核心函数：
-暂时不做
-① 时间相关性
+/* 告警关联
 
-CheckTemporalCorrelation(events []Event)
+节点宕机时，其上的容器、所属服务会在同一轮采集中产生几十条相互独立的告警。Correlator 把滑动时间窗（window，默认 60s）内
+共享标签的触发告警归并为一个关联事件（Incident），故障诊断与值班人员看到的是一个事件而不是告警风暴：
 
-判断是否在同一 30-60 秒窗口集中发生。
+	标签    告警 Metadata 中的实体标识，默认 nodeId、serviceId、component_type。Generator 输出告警时补充所属实体
+	        （见 labelAlerts），容器告警另带所属服务与所在节点，因此 节点告警 ← 容器告警 → 服务告警 可传递地归并
+	成立    窗口内相关的触发告警达到 min_alerts（默认 2）个时建立事件；之后与事件共享标签的告警在事件最近一次
+	        加入告警后的窗口内继续加入
+	根因    最早的成员告警（同一批次按 节点、容器、服务 的评估顺序），事件故障码取根因告警的故障码
+	输出    建立事件、严重程度升高、全部成员恢复时输出父告警（ALERT_INCIDENT，来源为事件ID，RelatedAlerts 为成员告警）；
+	        成员告警的 RelatedAlerts 为事件ID与其他成员，Metadata["incident"] 为事件ID。已归入事件的触发告警
+	        不再单独转发故障诊断（forward_members 为 true 时仍转发），恢复告警总是转发
 
-② 空间相关性（拓扑链路）不好做
+成员告警以 告警ID:来源 标识（同 StateManager 的告警状态键）。空间相关性（沿 节点 → 容器 → 服务 → 业务 拓扑传播）
+暂不实现，由共享标签近似。配置示例：
 
-CheckSpatialCorrelation(events, topo)
+	{"window": "60s", "labels": ["nodeId", "serviceId", "component_type"], "min_alerts": 2, "forward_members": false, "history": 100}
+*/
+package alert
 
-判断：
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-节点 → 容器 → 服务 → 业务
-是否沿链路传播。 */
+	"health-monitor/pkg/models"
+)
 
-package alert
+// 关联事件父告警
+const (
+	IncidentAlertID = "ALERT_INCIDENT"
+	IncidentType    = "incident"
+)
+
+// 默认参数
+const (
+	DefaultCorrelationWindow    = 60 * time.Second
+	DefaultCorrelationMinAlerts = 2
+	DefaultIncidentHistory      = 100
+)
+
+// defaultCorrelationLabels 默认关联标签
+var defaultCorrelationLabels = []string{"nodeId", "serviceId", "component_type"}
+
+// entityLabels 各层级告警的所属实体标签（与遥测中断告警、容器告警的所属服务一致）
+var entityLabels = map[string]string{
+	LayerBusiness:  "component_type",
+	LayerNode:      "nodeId",
+	LayerContainer: "containerId",
+	LayerService:   "serviceId",
+}
+
+// severityRank 严重程度排序（事件取成员中最高的）
+var severityRank = map[model.AlertSeverity]int{
+	model.SeverityInfo:     1,
+	model.SeverityWarning:  2,
+	model.SeverityCritical: 3,
+}
+
+// labelAlerts 在告警 Metadata 中补充所属实体（业务组件编号或节点/容器/服务ID），供告警关联与静默按标签匹配
+func labelAlerts(layer string, entity interface{}, alerts []*model.AlertEvent) {
+	key, ok := entityLabels[layer]
+	if !ok {
+		return
+	}
+	for _, a := range alerts {
+		if _, ok := a.Metadata[key]; !ok {
+			a.Metadata = withMetadata(a.Metadata, key, entity)
+		}
+	}
+}
+
+// CheckTemporalCorrelation 判断告警是否集中发生在 window 时间窗内（按告警时间戳）
+func CheckTemporalCorrelation(events []*model.AlertEvent, window time.Duration) bool {
+	if len(events) < 2 {
+		return false
+	}
+	first, last := events[0].Timestamp, events[0].Timestamp
+	for _, e := range events[1:] {
+		if e.Timestamp < first {
+			first = e.Timestamp
+		}
+		if e.Timestamp > last {
+			last = e.Timestamp
+		}
+	}
+	return time.Duration(last-first)*time.Second <= window
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              配置
+////////////////////////////////////////////////////////////////////////////////
+
+// CorrelationConfig 告警关联配置
+type CorrelationConfig struct {
+	Window         string   `json:"window,omitempty"`          // 滑动时间窗，默认 60s
+	Labels         []string `json:"labels,omitempty"`          // 关联标签（告警 Metadata 键），默认 nodeId、serviceId、component_type
+	MinAlerts      int      `json:"min_alerts,omitempty"`      // 建立事件的最少告警数，默认 2
+	ForwardMembers bool     `json:"forward_members,omitempty"` // 已归入事件的触发告警仍单独转发故障诊断
+	History        int      `json:"history,omitempty"`         // 保留的已解除事件数，默认 100
+
+	window time.Duration
+}
+
+// DefaultCorrelationConfig 默认配置
+func DefaultCorrelationConfig() *CorrelationConfig {
+	cfg := &CorrelationConfig{}
+	cfg.Validate() // 全部为默认值，不会失败
+	return cfg
+}
+
+// ParseCorrelationConfig 解析并校验告警关联配置 JSON
+func ParseCorrelationConfig(data []byte) (*CorrelationConfig, error) {
+	var cfg CorrelationConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析告警关联配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("告警关联配置校验失败: %w", err)
+	}
+	return &cfg, nil
+}
+
+// LoadCorrelationConfig 从文件加载告警关联配置
+func LoadCorrelationConfig(path string) (*CorrelationConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取告警关联配置文件失败: %w", err)
+	}
+	return ParseCorrelationConfig(data)
+}
+
+// Validate 校验配置并填充默认值
+func (c *CorrelationConfig) Validate() error {
+	c.window = DefaultCorrelationWindow
+	if c.Window != "" {
+		d, err := time.ParseDuration(c.Window)
+		if err != nil {
+			return fmt.Errorf("window %q 非法", c.Window)
+		}
+		c.window = d
+	}
+	if c.window < time.Second || c.window > 10*time.Minute {
+		return fmt.Errorf("window 须在 1s~10m 之间")
+	}
+
+	if len(c.Labels) == 0 {
+		c.Labels = append([]string(nil), defaultCorrelationLabels...)
+	}
+	seen := make(map[string]bool, len(c.Labels))
+	for _, l := range c.Labels {
+		if l == "" || seen[l] {
+			return fmt.Errorf("labels 不能为空或重复: %q", l)
+		}
+		seen[l] = true
+	}
+
+	if c.MinAlerts == 0 {
+		c.MinAlerts = DefaultCorrelationMinAlerts
+	}
+	if c.MinAlerts < 2 {
+		return fmt.Errorf("min_alerts 不能小于 2")
+	}
+	if c.History == 0 {
+		c.History = DefaultIncidentHistory
+	}
+	if c.History < 0 {
+		return fmt.Errorf("history 不能为负数")
+	}
+	return nil
+}
+
+// WindowDuration 生效的时间窗
+func (c *CorrelationConfig) WindowDuration() time.Duration {
+	return c.window
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              关联事件
+////////////////////////////////////////////////////////////////////////////////
+
+// Incident 关联事件
+type Incident struct {
+	ID        string              `json:"id"`
+	Status    model.AlertStatus   `json:"status"`
+	Severity  model.AlertSeverity `json:"severity"`             // 成员中最高的严重程度
+	Root      string              `json:"root"`                 // 根因候选（最早的成员告警）
+	FaultCode string              `json:"fault_code,omitempty"` // 根因告警的故障码
+	Labels    []string            `json:"labels"`               // 关联依据（标签=值）
+	Alerts    []IncidentAlert     `json:"alerts"`
+	Start     int64               `json:"start"`
+	Updated   int64               `json:"updated"` // 最近一次有告警加入或再次触发
+	End       int64               `json:"end,omitempty"`
+}
+
+// IncidentAlert 关联事件的成员告警
+type IncidentAlert struct {
+	Key       string              `json:"key"` // 告警ID:来源
+	AlertID   string              `json:"alert_id"`
+	Source    string              `json:"source,omitempty"`
+	Severity  model.AlertSeverity `json:"severity"`
+	FaultCode string              `json:"fault_code,omitempty"`
+	Message   string              `json:"message,omitempty"`
+	Firing    bool                `json:"firing"`
+	Timestamp int64               `json:"timestamp"`
+}
+
+// recentAlert 窗口内尚未归入事件的触发告警
+type recentAlert struct {
+	member IncidentAlert
+	labels []string
+	seen   time.Time
+}
+
+// Correlator 告警关联器（可由多个 Generator 共用）
+type Correlator struct {
+	config atomic.Pointer[CorrelationConfig]
+
+	mu      sync.Mutex
+	seq     int
+	open    []*Incident          // 未解除的事件（按建立顺序）
+	members map[string]*Incident // 成员告警 → 未解除的事件
+	active  map[*Incident]time.Time
+	recent  []*recentAlert
+	history []*Incident // 已解除的事件（最新在后）
+}
+
+// NewCorrelator 创建告警关联器，cfg 为 nil 时使用默认配置
+func NewCorrelator(cfg *CorrelationConfig) *Correlator {
+	if cfg == nil {
+		cfg = DefaultCorrelationConfig()
+	}
+	c := &Correlator{
+		members: make(map[string]*Incident),
+		active:  make(map[*Incident]time.Time),
+	}
+	c.config.Store(cfg)
+	return c
+}
+
+// SetConfig 替换配置（运行时可替换）
+func (c *Correlator) SetConfig(cfg *CorrelationConfig) {
+	c.config.Store(cfg)
+}
+
+// Config 返回当前配置
+func (c *Correlator) Config() *CorrelationConfig {
+	return c.config.Load()
+}
+
+// correlationKey 成员告警标识（同 StateManager 的告警状态键）
+func correlationKey(a *model.AlertEvent) string {
+	if a.Source == "" {
+		return a.AlertID
+	}
+	return a.AlertID + ":" + a.Source
+}
+
+// alertLabels 告警的关联标签（标签=值）
+func alertLabels(a *model.AlertEvent, keys []string) []string {
+	var labels []string
+	for _, k := range keys {
+		v, ok := a.Metadata[k]
+		if !ok {
+			continue
+		}
+		if s := fmt.Sprint(v); s != "" {
+			labels = append(labels, k+"="+s)
+		}
+	}
+	return labels
+}
+
+// sharedLabels a、b 共有的标签
+func sharedLabels(a, b []string) []string {
+	var shared []string
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				shared = append(shared, x)
+				break
+			}
+		}
+	}
+	return shared
+}
+
+// Correlate 按时间窗与共享标签归并一批告警：归入事件的触发告警填写 RelatedAlerts 与 Metadata["incident"]，
+// 返回需要输出的事件父告警（建立、严重程度升高、解除）
+func (c *Correlator) Correlate(alerts []*model.AlertEvent, now time.Time) []*model.AlertEvent {
+	cfg := c.Config()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 超出时间窗的未归并告警不再参与
+	cutoff := now.Add(-cfg.window)
+	k := 0
+	for _, r := range c.recent {
+		if !r.seen.Before(cutoff) {
+			c.recent[k] = r
+			k++
+		}
+	}
+	c.recent = c.recent[:k]
+
+	var changed []*Incident
+	severity := make(map[*Incident]model.AlertSeverity)
+	mark := func(inc *Incident) {
+		if _, ok := severity[inc]; !ok {
+			severity[inc] = inc.Severity
+			changed = append(changed, inc)
+		}
+	}
+
+	for _, a := range alerts {
+		key := correlationKey(a)
+		if !a.IsFiring() {
+			c.dropRecent(key)
+			if inc := c.members[key]; inc != nil {
+				mark(inc)
+				c.resolveMember(inc, key, now)
+			}
+			continue
+		}
+
+		labels := alertLabels(a, cfg.Labels)
+		inc := c.members[key]
+		if inc == nil && len(labels) > 0 {
+			inc = c.matchIncident(labels, cutoff)
+		}
+		if inc == nil && len(labels) > 0 {
+			related := c.matchRecent(labels)
+			if len(related)+1 < cfg.MinAlerts {
+				c.dropRecent(key)
+				c.recent = append(c.recent, &recentAlert{member: newIncidentAlert(a), labels: labels, seen: now})
+				continue
+			}
+			inc = c.openIncident(related, now)
+			severity[inc] = "" // 新建立的事件
+			changed = append(changed, inc)
+		}
+		if inc == nil {
+			continue
+		}
+		mark(inc)
+		c.join(inc, a, labels, now)
+	}
+
+	// 成员告警填写关联信息（同一批次的成员互相可见）
+	for _, a := range alerts {
+		if !a.IsFiring() {
+			continue
+		}
+		key := correlationKey(a)
+		inc := c.members[key]
+		if inc == nil {
+			continue
+		}
+		related := []string{inc.ID}
+		for _, m := range inc.Alerts {
+			if m.Key != key {
+				related = append(related, m.Key)
+			}
+		}
+		a.RelatedAlerts = related
+		a.Metadata = withMetadata(a.Metadata, "incident", inc.ID)
+	}
+
+	var events []*model.AlertEvent
+	for _, inc := range changed {
+		prev := severity[inc]
+		switch {
+		case inc.Status == model.AlertStatusResolved:
+			if prev != "" {
+				events = append(events, inc.event())
+			}
+		case prev == "" || severityRank[inc.Severity] > severityRank[prev]:
+			events = append(events, inc.event())
+		}
+	}
+	c.trimHistory(cfg.History)
+	return events
+}
+
+func newIncidentAlert(a *model.AlertEvent) IncidentAlert {
+	return IncidentAlert{
+		Key:       correlationKey(a),
+		AlertID:   a.AlertID,
+		Source:    a.Source,
+		Severity:  a.Severity,
+		FaultCode: a.FaultCode,
+		Message:   a.Message,
+		Firing:    true,
+		Timestamp: a.Timestamp,
+	}
+}
+
+// matchIncident 时间窗内仍有告警加入、与 labels 共享标签的最早事件
+func (c *Correlator) matchIncident(labels []string, cutoff time.Time) *Incident {
+	for _, inc := range c.open {
+		if !c.active[inc].Before(cutoff) && len(sharedLabels(inc.Labels, labels)) > 0 {
+			return inc
+		}
+	}
+	return nil
+}
+
+// matchRecent 与 labels 共享标签的未归并告警
+func (c *Correlator) matchRecent(labels []string) []*recentAlert {
+	var related []*recentAlert
+	for _, r := range c.recent {
+		if len(sharedLabels(r.labels, labels)) > 0 {
+			related = append(related, r)
+		}
+	}
+	return related
+}
+
+// dropRecent 从未归并告警中删除
+func (c *Correlator) dropRecent(key string) {
+	for i, r := range c.recent {
+		if r.member.Key == key {
+			c.recent = append(c.recent[:i], c.recent[i+1:]...)
+			return
+		}
+	}
+}
+
+// openIncident 以窗口内相关的未归并告警建立事件（新告警随后由 join 加入）
+func (c *Correlator) openIncident(related []*recentAlert, now time.Time) *Incident {
+	c.seq++
+	inc := &Incident{
+		ID:     fmt.Sprintf("INC-%s-%d", now.Format("20060102150405"), c.seq),
+		Status: model.AlertStatusFiring,
+		Start:  now.Unix(),
+	}
+	c.open = append(c.open, inc)
+	for _, r := range related {
+		c.dropRecent(r.member.Key)
+		c.add(inc, r.member, r.labels)
+	}
+	return inc
+}
+
+// join 触发告警加入事件（已是成员时更新为触发）
+func (c *Correlator) join(inc *Incident, a *model.AlertEvent, labels []string, now time.Time) {
+	c.dropRecent(correlationKey(a))
+	c.active[inc] = now
+	inc.Updated = now.Unix()
+
+	m := newIncidentAlert(a)
+	for i := range inc.Alerts {
+		if inc.Alerts[i].Key == m.Key {
+			inc.Alerts[i] = m
+			c.raise(inc, m.Severity)
+			return
+		}
+	}
+	c.add(inc, m, labels)
+}
+
+// add 添加成员，记录与已有成员共享的标签
+func (c *Correlator) add(inc *Incident, m IncidentAlert, labels []string) {
+	if len(inc.Alerts) == 0 {
+		inc.Root, inc.FaultCode = m.Key, m.FaultCode
+		inc.Labels = append([]string(nil), labels...)
+	} else {
+		for _, l := range labels {
+			if !containsString(inc.Labels, l) {
+				inc.Labels = append(inc.Labels, l)
+			}
+		}
+	}
+	inc.Alerts = append(inc.Alerts, m)
+	c.members[m.Key] = inc
+	c.raise(inc, m.Severity)
+}
+
+// raise 事件严重程度取成员中最高的
+func (c *Correlator) raise(inc *Incident, s model.AlertSeverity) {
+	if severityRank[s] > severityRank[inc.Severity] {
+		inc.Severity = s
+	}
+}
+
+// resolveMember 成员告警恢复，全部恢复时事件解除
+func (c *Correlator) resolveMember(inc *Incident, key string, now time.Time) {
+	firing := false
+	for i := range inc.Alerts {
+		if inc.Alerts[i].Key == key {
+			inc.Alerts[i].Firing = false
+		}
+		firing = firing || inc.Alerts[i].Firing
+	}
+	if firing {
+		return
+	}
+
+	inc.Status, inc.End = model.AlertStatusResolved, now.Unix()
+	for _, m := range inc.Alerts {
+		delete(c.members, m.Key)
+	}
+	delete(c.active, inc)
+	for i, o := range c.open {
+		if o == inc {
+			c.open = append(c.open[:i], c.open[i+1:]...)
+			break
+		}
+	}
+	c.history = append(c.history, inc)
+}
+
+func (c *Correlator) trimHistory(n int) {
+	if len(c.history) > n {
+		c.history = append(c.history[:0], c.history[len(c.history)-n:]...)
+	}
+}
+
+// event 事件父告警
+func (inc *Incident) event() *model.AlertEvent {
+	related := make([]string, 0, len(inc.Alerts))
+	var faultCodes []string
+	for _, m := range inc.Alerts {
+		related = append(related, m.Key)
+		if m.FaultCode != "" && !containsString(faultCodes, m.FaultCode) {
+			faultCodes = append(faultCodes, m.FaultCode)
+		}
+	}
+	subject := strings.Join(inc.Labels, ",")
+
+	e := &model.AlertEvent{
+		AlertID:       IncidentAlertID,
+		Type:          IncidentType,
+		Status:        inc.Status,
+		Severity:      inc.Severity,
+		Source:        inc.ID,
+		Timestamp:     inc.Updated,
+		FaultCode:     inc.FaultCode,
+		MetricValue:   float64(len(inc.Alerts)),
+		RelatedAlerts: related,
+		Metadata: map[string]interface{}{
+			"incident":    inc.ID,
+			"labels":      append([]string(nil), inc.Labels...),
+			"root_alert":  inc.Root,
+			"fault_codes": faultCodes,
+		},
+	}
+	if inc.Status == model.AlertStatusResolved {
+		e.Timestamp = inc.End
+		e.Message = fmt.Sprintf("关联事件已解除: %s 相关 %d 个告警均已恢复", subject, len(inc.Alerts))
+	} else {
+		e.Message = fmt.Sprintf("关联事件: %s 相关 %d 个告警，最早: %s", subject, len(inc.Alerts), inc.Alerts[0].Message)
+	}
+	return e
+}
+
+// Incidents 未解除的事件（按建立顺序）与最近解除的事件（最新在前）
+func (c *Correlator) Incidents() []Incident {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	incidents := make([]Incident, 0, len(c.open)+len(c.history))
+	for _, inc := range c.open {
+		incidents = append(incidents, inc.copy())
+	}
+	for i := len(c.history) - 1; i >= 0; i-- {
+		incidents = append(incidents, c.history[i].copy())
+	}
+	return incidents
+}
+
+func (inc *Incident) copy() Incident {
+	cp := *inc
+	cp.Labels = append([]string(nil), inc.Labels...)
+	cp.Alerts = append([]IncidentAlert(nil), inc.Alerts...)
+	sort.Strings(cp.Labels)
+	return cp
+}
 
-// TODO: 实现关联分析功能
\ No newline at end of file
+// dropIncidentMembers 去掉已归入事件的触发告警（由事件父告警代为转发）
+func dropIncidentMembers(alerts []*model.AlertEvent) []*model.AlertEvent {
+	result := make([]*model.AlertEvent, 0, len(alerts))
+	for _, a := range alerts {
+		if _, ok := a.Metadata["incident"]; ok && a.IsFiring() {
+			continue
+		}
+		result = append(result, a)
+	}
+	return result
+}
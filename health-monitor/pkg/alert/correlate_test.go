@@ -0,0 +1,147 @@
+package alert
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+)
+
+func correlateAlert(id, source string, severity model.AlertSeverity, labels map[string]interface{}) *model.AlertEvent {
+	return &model.AlertEvent{AlertID: id, Source: source, Status: model.AlertStatusFiring, Severity: severity,
+		FaultCode: "F-" + id, Message: id + " " + source, Metadata: labels}
+}
+
+func resolvedAlert(id, source string) *model.AlertEvent {
+	return &model.AlertEvent{AlertID: id, Source: source, Status: model.AlertStatusResolved}
+}
+
+// TestCorrelatorWindow 测试时间窗内共享标签的告警建立事件、后续加入、严重程度升高与解除
+func TestCorrelatorWindow(t *testing.T) {
+	c := NewCorrelator(nil)
+	t0 := time.Unix(1700000000, 0)
+	n3 := map[string]interface{}{"nodeId": "n3"}
+
+	if events := c.Correlate([]*model.AlertEvent{correlateAlert("A", "n3", model.SeverityWarning, n3)}, t0); len(events) != 0 {
+		t.Fatalf("单个告警不应建立事件: %+v", events)
+	}
+	// 超出时间窗的告警不关联
+	b := correlateAlert("B", "n3", model.SeverityWarning, n3)
+	if events := c.Correlate([]*model.AlertEvent{b}, t0.Add(90*time.Second)); len(events) != 0 || b.RelatedAlerts != nil {
+		t.Fatalf("超出时间窗不应关联: %+v", events)
+	}
+
+	cAlert := correlateAlert("C", "c1", model.SeverityWarning, map[string]interface{}{"nodeId": "n3", "serviceId": "s1"})
+	events := c.Correlate([]*model.AlertEvent{cAlert}, t0.Add(100*time.Second))
+	if len(events) != 1 || events[0].AlertID != IncidentAlertID || !events[0].IsFiring() || events[0].FaultCode != "F-B" {
+		t.Fatalf("应建立事件: %+v", events)
+	}
+	id := events[0].Source
+	if got := strings.Join(events[0].RelatedAlerts, ","); got != "B:n3,C:c1" {
+		t.Errorf("事件成员不符: %s", got)
+	}
+	if got := strings.Join(cAlert.RelatedAlerts, ","); got != id+",B:n3" || cAlert.Metadata["incident"] != id {
+		t.Errorf("成员告警关联信息不符: %s %+v", got, cAlert.Metadata)
+	}
+
+	// 通过 serviceId 加入；warning 不输出更新，critical 使严重程度升高
+	d := correlateAlert("D", "s1", model.SeverityWarning, map[string]interface{}{"serviceId": "s1"})
+	if events := c.Correlate([]*model.AlertEvent{d}, t0.Add(150*time.Second)); len(events) != 0 || d.Metadata["incident"] != id {
+		t.Errorf("共享服务标签应加入事件且不输出更新: %+v %+v", events, d.Metadata)
+	}
+	events = c.Correlate([]*model.AlertEvent{correlateAlert("E", "s1", model.SeverityCritical, map[string]interface{}{"serviceId": "s1"})}, t0.Add(200*time.Second))
+	if len(events) != 1 || events[0].Severity != model.SeverityCritical || len(events[0].RelatedAlerts) != 4 {
+		t.Errorf("严重程度升高应输出事件: %+v", events)
+	}
+
+	// 全部成员恢复后事件解除
+	for i, key := range [][2]string{{"B", "n3"}, {"C", "c1"}, {"D", "s1"}} {
+		if events := c.Correlate([]*model.AlertEvent{resolvedAlert(key[0], key[1])}, t0.Add(time.Duration(300+i)*time.Second)); len(events) != 0 {
+			t.Errorf("仍有成员触发时不应解除: %+v", events)
+		}
+	}
+	events = c.Correlate([]*model.AlertEvent{resolvedAlert("E", "s1")}, t0.Add(400*time.Second))
+	if len(events) != 1 || !events[0].IsResolved() || events[0].Source != id || !strings.HasPrefix(events[0].Message, "关联事件已解除") {
+		t.Fatalf("全部恢复应解除事件: %+v", events)
+	}
+	incidents := c.Incidents()
+	if len(incidents) != 1 || incidents[0].Status != model.AlertStatusResolved || incidents[0].Root != "B:n3" || incidents[0].End != t0.Unix()+400 {
+		t.Errorf("事件记录不符: %+v", incidents)
+	}
+}
+
+// TestCorrelateNodeFailure 测试节点离线时节点、容器、服务告警归并为一个事件转发故障诊断
+func TestCorrelateNodeFailure(t *testing.T) {
+	recv := &recordingReceiver{}
+	g := NewGeneratorWithDiagnosis(newTestStateManager(t), recv)
+	g.SetRules(mustParseRules(t, `{"rules":[
+		{"alert_id":"NODE_OFFLINE","layer":"node","field":"Status","op":"!=","value":"online","severity":"critical","type":"node_offline","fault_code":"MS-NO-FL-1"},
+		{"alert_id":"CONTAINER_DOWN","layer":"container","field":"Status","op":"!=","value":"running","severity":"warning","type":"container_down","fault_code":"MS-CN-FL-1"},
+		{"alert_id":"SERVICE_DOWN","layer":"service","field":"Status","op":"!=","value":"running","severity":"warning","type":"service_down","fault_code":"MS-SV-FL-1"}]}`))
+	g.SetCorrelator(NewCorrelator(nil))
+
+	metrics := func(node, container, service string) *model.MicroServiceMetricsSet {
+		return &model.MicroServiceMetricsSet{
+			NodeMetrics: []model.NodeMetrics{{ID: "n1", Status: node}, {ID: "n2", Status: "online"}},
+			ContainerMetrics: []model.ContainerMetrics{
+				{ID: "c1", Status: container, ServiceID: "s1", NodeID: "n1"},
+				{ID: "c2", Status: container, ServiceID: "s1", NodeID: "n1"},
+				{ID: "c3", Status: "running", ServiceID: "s2", NodeID: "n2"},
+			},
+			ServiceMetrics: []model.ServiceMetrics{{ID: "s1", Status: service}, {ID: "s2", Status: "running"}},
+		}
+	}
+
+	// 首次判定以恢复告警上报
+	g.ProcessMicroserviceMetrics(context.Background(), metrics("online", "running", "running"))
+	recv.alerts = nil
+
+	g.ProcessMicroserviceMetrics(context.Background(), metrics("offline", "exited", "failed"))
+	if len(recv.alerts) != 1 {
+		t.Fatalf("应只转发一个关联事件: %+v", recv.alerts)
+	}
+	incident := recv.alerts[0]
+	related := strings.Join(incident["RelatedAlerts"].([]string), ",")
+	if incident["AlertID"] != IncidentAlertID || incident["Severity"] != "critical" || incident["FaultCode"] != "MS-NO-FL-1" ||
+		related != "NODE_OFFLINE:n1,CONTAINER_DOWN:c1,CONTAINER_DOWN:c2,SERVICE_DOWN:s1" {
+		t.Fatalf("关联事件不符: %+v", incident)
+	}
+	labels := strings.Join(incident["Metadata"].(map[string]interface{})["labels"].([]string), ",")
+	if labels != "nodeId=n1,serviceId=s1" {
+		t.Errorf("关联依据不符: %s", labels)
+	}
+
+	// 恢复告警逐个转发，随后输出事件解除
+	recv.alerts = nil
+	g.ProcessMicroserviceMetrics(context.Background(), metrics("online", "running", "running"))
+	if n := len(recv.alerts); n != 5 || recv.alerts[n-1]["AlertID"] != IncidentAlertID || recv.alerts[n-1]["Status"] != "resolved" {
+		t.Errorf("恢复后应转发成员恢复告警与事件解除: %+v", recv.alerts)
+	}
+}
+
+// TestParseCorrelationConfig 测试告警关联配置校验与时间相关性判断
+func TestParseCorrelationConfig(t *testing.T) {
+	cfg := DefaultCorrelationConfig()
+	if cfg.WindowDuration() != DefaultCorrelationWindow || cfg.MinAlerts != 2 || len(cfg.Labels) != 3 || cfg.History != DefaultIncidentHistory {
+		t.Errorf("默认配置不符: %+v", cfg)
+	}
+	cases := map[string]string{
+		"时间窗非法": `{"window":"abc"}`,
+		"时间窗过长": `{"window":"1h"}`,
+		"标签重复":  `{"labels":["nodeId","nodeId"]}`,
+		"告警数":   `{"min_alerts":1}`,
+		"历史为负":  `{"history":-1}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseCorrelationConfig([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+
+	events := []*model.AlertEvent{{Timestamp: 100}, {Timestamp: 140}, {Timestamp: 120}}
+	if !CheckTemporalCorrelation(events, 40*time.Second) || CheckTemporalCorrelation(events, 30*time.Second) || CheckTemporalCorrelation(events[:1], time.Minute) {
+		t.Error("时间相关性判断不符")
+	}
+}
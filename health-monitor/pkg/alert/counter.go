@@ -0,0 +1,180 @@
+/*
+计数器增长率
+
+串口校验错、帧头错、帧长度错、串口复位、接收命令等计数器是 uint16/uint32 累计值，长期运行后其绝对值没有意义。
+规则设置 rate（统计窗口，如 5m）后，field 须为无符号整数计数器，判定的值改为该窗口内的每分钟增量：
+
+	增量    由 StateManager 中该组件的历史遥测逐点累加，加上本次采样
+	回绕    当前值小于前值、且按字段位宽（uint16 为 65536）回绕后的增量不超过量程的 1/4 时按回绕计算
+	复位    其余减小视为计数器复位（设备重启），增量取当前值
+	窗口    历史须覆盖整个窗口（最早样本不晚于本次采样时间 - rate），否则本次不判定；
+	        窗口不能超过 StateManager 的历史保留时长（state.HistoryRetention）
+
+"停滞"用 op <= 0 表示窗口内无增长，配合 when（如串口通信正常时应持续收到命令）与 grace。
+未提供 StateManager 时不判定。告警 Metadata["counter"] 带 value（当前计数）、increase（窗口内增量）、
+window（秒）及 wraps、resets（窗口内回绕/复位次数）。参数库单位自动加 "/分"，默认参数名加 "增长率"。
+*/
+package alert
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// counterSample 计数器的一次采样
+type counterSample struct {
+	ts    int64
+	value float64
+}
+
+// counterRate 窗口内的增长统计
+type counterRate struct {
+	perMinute float64
+	increase  float64
+	wraps     int
+	resets    int
+}
+
+// compileRate 校验计数器增长率配置：field 须为无符号整数字段，记录其量程
+func (r *Rule) compileRate(typ reflect.Type) error {
+	r.rate = 0
+	if r.Rate == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(r.Rate)
+	if err != nil || d < time.Second {
+		return fmt.Errorf("rate %q 非法（如 5m，至少 1s）", r.Rate)
+	}
+	if d > state.HistoryRetention {
+		return fmt.Errorf("rate 不能超过历史保留时长 %s", state.HistoryRetention)
+	}
+	if r.path == nil || r.wildcard {
+		return fmt.Errorf("rate 仅适用于单个计数器字段")
+	}
+	if r.Op == OpCatalog {
+		return fmt.Errorf("rate 不能与 catalog 同用")
+	}
+	kind := fieldType(typ, r.path)
+	switch kind.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+	default:
+		return fmt.Errorf("rate 需要无符号整数计数器字段，%s 为 %s", r.Field, kind)
+	}
+	r.rate = d
+	r.counterMax = math.Exp2(float64(kind.Bits())) - 1
+	return nil
+}
+
+// fieldType 字段路径对应的类型（路径已由 checkFieldPath 校验）
+func fieldType(t reflect.Type, path []pathStep) reflect.Type {
+	for _, s := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		f, _ := t.FieldByName(s.name)
+		t = f.Type
+		if s.index != -1 {
+			t = t.Elem()
+		}
+	}
+	return t
+}
+
+// counterDelta 相邻两次采样之间的增量，返回是否发生回绕、复位
+func (r *Rule) counterDelta(prev, cur float64) (delta float64, wrapped, reset bool) {
+	if cur >= prev {
+		return cur - prev, false, false
+	}
+	if wrap := r.counterMax - prev + 1 + cur; wrap <= (r.counterMax+1)/4 {
+		return wrap, true, false
+	}
+	return cur, false, true
+}
+
+// counterRate 按历史采样计算本次采样时刻窗口内的增长率，历史不足一个窗口时返回 false
+func (r *Rule) counterRate(samples []counterSample) (counterRate, bool) {
+	var cr counterRate
+	if len(samples) < 2 {
+		return cr, false
+	}
+	last := samples[len(samples)-1]
+	start := -1
+	for i, s := range samples {
+		if s.ts <= last.ts-int64(r.rate/time.Second) {
+			start = i
+		}
+	}
+	if start < 0 || last.ts <= samples[start].ts {
+		return cr, false
+	}
+	for i := start + 1; i < len(samples); i++ {
+		delta, wrapped, reset := r.counterDelta(samples[i-1].value, samples[i].value)
+		cr.increase += delta
+		if wrapped {
+			cr.wraps++
+		}
+		if reset {
+			cr.resets++
+		}
+	}
+	cr.perMinute = cr.increase / float64(last.ts-samples[start].ts) * 60
+	return cr, true
+}
+
+// rateValues 计数器规则的取值：本次采样时刻的每分钟增量，及附加到告警的计数器信息
+func (r *Rule) rateValues(t ruleTarget, sm *state.StateManager) ([]fieldValue, map[string]interface{}) {
+	if sm == nil {
+		return nil, nil
+	}
+	current := pathValues(r.Field, r.path, t.data)
+	if len(current) != 1 {
+		return nil, nil
+	}
+	value, ok := current[0].value.(float64)
+	if !ok {
+		return nil, nil
+	}
+
+	// 历史中可能已含本次采样（Dispatcher 先入库再评估），只取更早的采样
+	lookback := 2 * r.rate
+	if age := time.Now().Unix() - t.timestamp; age > 0 {
+		lookback += time.Duration(age) * time.Second
+	}
+	var samples []counterSample
+	for _, entry := range sm.QueryHistory(state.MetricTypeBusiness, string(rune(t.component)), lookback) {
+		bm, ok := entry.Data.(*model.BusinessMetrics)
+		if !ok || bm == nil || entry.Timestamp >= t.timestamp {
+			continue
+		}
+		for _, fv := range pathValues(r.Field, r.path, bm.Data) {
+			if v, ok := fv.value.(float64); ok {
+				samples = append(samples, counterSample{ts: entry.Timestamp, value: v})
+			}
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].ts < samples[j].ts })
+	samples = append(samples, counterSample{ts: t.timestamp, value: value})
+
+	cr, ok := r.counterRate(samples)
+	if !ok {
+		return nil, nil
+	}
+	info := map[string]interface{}{
+		"value":    value,
+		"increase": cr.increase,
+		"window":   int64(r.rate / time.Second),
+	}
+	if cr.wraps > 0 {
+		info["wraps"] = cr.wraps
+	}
+	if cr.resets > 0 {
+		info["resets"] = cr.resets
+	}
+	return []fieldValue{{field: r.Field, value: cr.perMinute}}, info
+}
@@ -0,0 +1,131 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// feedComm 像 Dispatcher 一样先入库再评估一帧通信服务遥测
+func feedComm(t *testing.T, rs *RuleSet, sm *state.StateManager, ts int64, m *model.CommMetrics) []*model.AlertEvent {
+	t.Helper()
+	bm := &model.BusinessMetrics{ComponentType: 0x02, Timestamp: ts, Data: m}
+	if err := sm.UpdateMetric(&state.BusinessMetric{Data: bm, Timestamp: ts}); err != nil {
+		t.Fatal(err)
+	}
+	return rs.EvaluateBusiness(bm, sm)
+}
+
+// TestCounterRate 测试计数器按窗口内每分钟增量判定，uint16 回绕不产生跳变
+func TestCounterRate(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[{"alert_id":"PARITY","layer":"business","component":2,"field":"ParityErrorCount",
+		"rate":"1m","op":">","value":5,"type":"comm_error_rate","format":"%.1f"}]}`)
+
+	start := time.Now().Unix() - 300
+	counts := []uint16{65500, 65500, 65500, 65500, 65500, 65500, 65500, 2}
+	var alerts []*model.AlertEvent
+	for i, c := range counts {
+		alerts = feedComm(t, rs, sm, start+int64(i)*10, &model.CommMetrics{ParityErrorCount: c})
+		if i < 6 && len(alerts) != 0 {
+			t.Fatalf("历史不足一个窗口时不应判定: %d %+v", i, alerts)
+		}
+	}
+	// 第 7 帧起覆盖窗口；回绕后窗口内增量为 65536-65500+2=38 → 38/分
+	a := findAlert(alerts, "PARITY")
+	if a == nil || !a.IsFiring() || a.Message != "串口校验错计数增长率异常: 38.0次/分 (正常≤5次/分)" {
+		t.Fatalf("增长率告警不符: %+v", alerts)
+	}
+	counter := a.Metadata["counter"].(map[string]interface{})
+	if counter["value"] != 2.0 || counter["increase"] != 38.0 || counter["wraps"] != 1 || counter["window"] != int64(60) {
+		t.Errorf("计数器信息不符: %+v", counter)
+	}
+
+	// 计数停止增长后恢复
+	for i := 0; i < 6; i++ {
+		alerts = feedComm(t, rs, sm, start+int64(len(counts)+i)*10, &model.CommMetrics{ParityErrorCount: 2})
+	}
+	if a := findAlert(alerts, "PARITY"); a == nil || !a.IsResolved() {
+		t.Errorf("增长停止后应恢复: %+v", alerts)
+	}
+
+	// 无 StateManager 时不判定
+	if alerts := rs.EvaluateBusiness(&model.BusinessMetrics{ComponentType: 0x02, Data: &model.CommMetrics{ParityErrorCount: 100}}, nil); len(alerts) != 0 {
+		t.Errorf("无历史时不应告警: %+v", alerts)
+	}
+}
+
+// TestCounterDelta 测试回绕与复位的区分
+func TestCounterDelta(t *testing.T) {
+	r := &Rule{counterMax: 65535}
+	cases := []struct {
+		prev, cur, delta float64
+		wrapped, reset   bool
+	}{
+		{10, 15, 5, false, false},
+		{65530, 4, 10, true, false},
+		{1000, 3, 3, false, true},
+		{40000, 0, 0, false, true},
+	}
+	for _, c := range cases {
+		delta, wrapped, reset := r.counterDelta(c.prev, c.cur)
+		if delta != c.delta || wrapped != c.wrapped || reset != c.reset {
+			t.Errorf("%v→%v: 得到 %v %v %v", c.prev, c.cur, delta, wrapped, reset)
+		}
+	}
+
+	r = &Rule{counterMax: 4294967295, rate: time.Minute}
+	samples := []counterSample{{0, 4294967290}, {30, 4294967295}, {60, 10}, {90, 2}}
+	cr, ok := r.counterRate(samples)
+	if !ok || cr.increase != 13 || cr.wraps != 1 || cr.resets != 1 || cr.perMinute != 13 {
+		t.Errorf("uint32 窗口统计不符: %+v %v", cr, ok)
+	}
+}
+
+// TestCounterStalled 测试串口通信正常时接收命令计数停滞告警
+func TestCounterStalled(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[{"alert_id":"STALLED","layer":"business","component":2,"field":"ReceiveCmdCount",
+		"rate":"1m","op":"<=","value":0,"when":[{"component":2,"field":"SerialStatus","op":"==","value":1}],
+		"type":"counter_stalled","message":"接收命令计数停滞","resolved_message":"接收命令计数已恢复增长: {{.Value}}{{.Unit}}"}]}`)
+
+	start := time.Now().Unix() - 300
+	var alerts []*model.AlertEvent
+	for i := 0; i < 7; i++ {
+		// 串口无遥测时不期望收到命令
+		alerts = feedComm(t, rs, sm, start+int64(i)*10, &model.CommMetrics{ReceiveCmdCount: 100})
+		if len(alerts) != 0 {
+			t.Fatalf("生效条件不成立时不应告警: %+v", alerts)
+		}
+	}
+	alerts = feedComm(t, rs, sm, start+70, &model.CommMetrics{SerialStatus: 1, ReceiveCmdCount: 100})
+	if a := findAlert(alerts, "STALLED"); a == nil || !a.IsFiring() || a.Message != "接收命令计数停滞" {
+		t.Fatalf("停滞告警不符: %+v", alerts)
+	}
+	alerts = feedComm(t, rs, sm, start+80, &model.CommMetrics{SerialStatus: 1, ReceiveCmdCount: 106})
+	if a := findAlert(alerts, "STALLED"); a == nil || !a.IsResolved() || a.Message != "接收命令计数已恢复增长: 6次/分" {
+		t.Errorf("恢复增长告警不符: %+v", alerts)
+	}
+}
+
+// TestParseCounterRules 测试计数器规则校验
+func TestParseCounterRules(t *testing.T) {
+	cases := map[string]string{
+		"窗口非法": `{"alert_id":"A","layer":"business","component":2,"field":"ParityErrorCount","rate":"abc","op":">","value":1,"type":"t"}`,
+		"窗口过短": `{"alert_id":"A","layer":"business","component":2,"field":"ParityErrorCount","rate":"10ms","op":">","value":1,"type":"t"}`,
+		"非计数器": `{"alert_id":"A","layer":"business","component":3,"field":"BusVoltage","rate":"1m","op":">","value":1,"type":"t"}`,
+		"参数库":  `{"alert_id":"A","layer":"business","component":2,"field":"ParityErrorCount","rate":"1m","op":"catalog","type":"t"}`,
+		"窗口过长": `{"alert_id":"A","layer":"business","component":2,"field":"ParityErrorCount","rate":"1h","op":">","value":1,"type":"t"}`,
+		"计算字段": `{"alert_id":"A","layer":"node","field":"cpu_percent","rate":"1m","op":">","value":1,"type":"t"}`,
+	}
+	for name, rule := range cases {
+		if _, err := ParseRules([]byte(`{"rules":[` + rule + `]}`)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+	if r, ok := DefaultRules().Rule("COMM_CMD_STALLED"); !ok || r.rate != 5*time.Minute || r.counterMax != 4294967295 {
+		t.Errorf("内置规则集应包含接收命令计数停滞规则: %+v", r)
+	}
+}
@@ -1,9 +1,175 @@
-/* 
-暂时不做
-阀值触发后设定"静默时间"
+/*
+告警持续性与回差（去抖动）
 
-避免频繁重复告警 */
+单个噪声采样（如 ADC 跳变）不应直接触发或恢复告警。规则可配置：
 
+	persistence.fire     触发所需的越限次数，默认 1
+	persistence.window   在最近 window 个样本中累计 fire 次越限即触发（M/N），默认等于 fire，即连续 fire 次
+	persistence.resolve  恢复所需的连续正常次数，默认 1
+	deadband             回差：告警触发后，数值须回到限值以内 deadband 才视为回到该限值带以内
+	                     （仅数值条件：> >= < <= outside catalog）
+
+已触发的告警在黄线/红线之间变化时：升级需连续 fire 次，降级需连续 resolve 次。
+判定中的状态保存在 StateManager，可通过 GetPendingAlerts 查询；未提供 StateManager 时不做持续性判定。
+*/
 package alert
 
-// TODO: 实现去抖动功能
\ No newline at end of file
+import (
+	"fmt"
+
+	"health-monitor/pkg/state"
+	"health-monitor/pkg/telemetry"
+)
+
+// MaxPersistenceWindow M/N 判定的最大窗口
+const MaxPersistenceWindow = 64
+
+// Persistence 规则的持续性要求
+type Persistence struct {
+	Fire    int `json:"fire,omitempty"`    // 触发所需的越限次数
+	Window  int `json:"window,omitempty"`  // 统计越限次数的最近样本数（M/N 中的 N）
+	Resolve int `json:"resolve,omitempty"` // 恢复所需的连续正常次数
+}
+
+// compileDebounce 校验持续性与回差配置并补全默认值
+func (r *Rule) compileDebounce() error {
+	if r.Deadband < 0 {
+		return fmt.Errorf("deadband 不能为负")
+	}
+	if r.Deadband > 0 {
+		switch r.Op {
+		case OpGT, OpGE, OpLT, OpLE, OpOutside, OpCatalog:
+		default:
+			return fmt.Errorf("deadband 仅适用于数值条件")
+		}
+	}
+
+	ps := r.Persistence
+	if ps == nil {
+		return nil
+	}
+	if ps.Fire < 0 || ps.Window < 0 || ps.Resolve < 0 {
+		return fmt.Errorf("persistence 次数不能为负")
+	}
+	if ps.Fire == 0 {
+		ps.Fire = 1
+	}
+	if ps.Window == 0 {
+		ps.Window = ps.Fire
+	}
+	if ps.Resolve == 0 {
+		ps.Resolve = 1
+	}
+	if ps.Window < ps.Fire {
+		return fmt.Errorf("persistence.window 须不小于 fire")
+	}
+	if ps.Window > MaxPersistenceWindow {
+		return fmt.Errorf("persistence.window 不能超过 %d", MaxPersistenceWindow)
+	}
+	return nil
+}
+
+// debounce 按回差与持续性要求过滤本次判定的限值带，返回应提交给状态跟踪的限值带；
+// 次数未满足时返回当前已确认的限值带，判定进度记录在 StateManager
+func (r *Rule) debounce(sm *state.StateManager, source string, band telemetry.Band, num float64, p *telemetry.Parameter, ts int64) telemetry.Band {
+	if r.Persistence == nil && r.Deadband == 0 {
+		return band
+	}
+
+	commit := band
+	sm.UpdatePendingAlert(r.AlertID, source, func(pa *state.PendingAlert, level string) bool {
+		confirmed := telemetry.Band(level)
+		band = r.hysteresis(confirmed, band, num, p)
+		commit = band
+		ps := r.Persistence
+		if ps == nil {
+			return false
+		}
+
+		// 未触发：最近 window 个样本中越限达到 fire 次即触发
+		if confirmed == telemetry.BandNominal {
+			pa.Recent = append(pa.Recent, band != telemetry.BandNominal)
+			if len(pa.Recent) > ps.Window {
+				pa.Recent = pa.Recent[len(pa.Recent)-ps.Window:]
+			}
+			hits := 0
+			for _, v := range pa.Recent {
+				if v {
+					hits++
+				}
+			}
+			if band != telemetry.BandNominal && hits >= ps.Fire {
+				return false
+			}
+			commit = telemetry.BandNominal
+			if hits == 0 {
+				return false
+			}
+			if band != telemetry.BandNominal {
+				if !pa.Pending {
+					pa.Since = ts
+				}
+				pa.Target = string(band)
+			}
+			pa.Pending, pa.Count, pa.Required = true, hits, ps.Fire
+			return true
+		}
+
+		// 已触发：恢复、降级需连续 resolve 次，升级需连续 fire 次
+		if band == confirmed {
+			return false
+		}
+		required := ps.Resolve
+		if bandRank(band) > bandRank(confirmed) {
+			required = ps.Fire
+		}
+		count := 1
+		if pa.Pending && pa.Target == string(band) {
+			count = pa.Count + 1
+		} else {
+			pa.Since = ts
+		}
+		if count >= required {
+			return false
+		}
+		commit = confirmed
+		pa.Pending, pa.Target, pa.Count, pa.Required, pa.Recent = true, string(band), count, required, nil
+		return true
+	})
+	return commit
+}
+
+// hysteresis 回差：已确认的限值带比本次判定更严重时，数值须再越过 deadband 才降级
+func (r *Rule) hysteresis(confirmed, band telemetry.Band, num float64, p *telemetry.Parameter) telemetry.Band {
+	if r.Deadband <= 0 || bandRank(band) >= bandRank(confirmed) {
+		return band
+	}
+	shifted := num
+	switch bandSide(confirmed) {
+	case 1:
+		shifted += r.Deadband
+	case -1:
+		shifted -= r.Deadband
+	default:
+		return band
+	}
+	held, _, ok := r.test(shifted, p)
+	if !ok || bandSide(held) != bandSide(confirmed) || bandRank(held) <= bandRank(band) {
+		return band
+	}
+	if bandRank(held) > bandRank(confirmed) {
+		return confirmed
+	}
+	return held
+}
+
+// bandSide 限值带的方向：高于上限为 1，低于下限为 -1，其余为 0
+func bandSide(b telemetry.Band) int {
+	switch b {
+	case telemetry.BandSoftHigh, telemetry.BandHardHigh:
+		return 1
+	case telemetry.BandSoftLow, telemetry.BandHardLow:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package alert
+
+import (
+	"testing"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// cpuSeries 依次评估节点 CPU 使用率，返回每个样本产生的告警（无告警为 nil）
+func cpuSeries(t *testing.T, rs *RuleSet, sm *state.StateManager, values ...float64) []*model.AlertEvent {
+	t.Helper()
+	out := make([]*model.AlertEvent, len(values))
+	for i, v := range values {
+		alerts := rs.EvaluateNode(&model.NodeMetrics{ID: "n1", CPUUsage: v}, sm)
+		if len(alerts) > 1 {
+			t.Fatalf("样本 %v 产生多条告警: %+v", v, alerts)
+		}
+		if len(alerts) == 1 {
+			out[i] = alerts[0]
+		}
+	}
+	return out
+}
+
+func mustParseRules(t *testing.T, data string) *RuleSet {
+	t.Helper()
+	rs, err := ParseRules([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+// TestPersistenceConsecutive 测试连续 N 次越限才触发、连续 M 次正常才恢复
+func TestPersistenceConsecutive(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[{"alert_id":"CPU","layer":"node","field":"cpu_percent","op":">","value":80,
+		"persistence":{"fire":3,"resolve":2},"type":"t"}]}`)
+
+	out := cpuSeries(t, rs, sm, 10, 90, 90)
+	if out[1] != nil || out[2] != nil {
+		t.Fatalf("未满 3 次不应触发: %+v", out)
+	}
+	pending := sm.GetPendingAlerts()
+	if len(pending) != 1 || pending[0].AlertID != "CPU" || pending[0].Source != "n1" || pending[0].Target != "soft_high" ||
+		pending[0].Count != 2 || pending[0].Required != 3 {
+		t.Fatalf("待触发状态不符: %+v", pending)
+	}
+
+	// 中间出现正常样本，连续计数中断
+	out = cpuSeries(t, rs, sm, 10, 90, 90)
+	if out[2] != nil {
+		t.Fatalf("连续计数应被中断: %+v", out)
+	}
+	out = cpuSeries(t, rs, sm, 90)
+	if out[0] == nil || !out[0].IsFiring() {
+		t.Fatalf("连续 3 次越限应触发: %+v", out)
+	}
+	if len(sm.GetPendingAlerts()) != 0 {
+		t.Errorf("触发后不应有待定状态: %+v", sm.GetPendingAlerts())
+	}
+
+	// 单个正常样本不恢复
+	out = cpuSeries(t, rs, sm, 10, 90, 10)
+	if out[0] != nil || out[1] != nil || out[2] != nil {
+		t.Fatalf("单个正常样本不应恢复: %+v", out)
+	}
+	if p := sm.GetPendingAlerts(); len(p) != 1 || p[0].Level != "soft_high" || p[0].Target != "" || p[0].Count != 1 {
+		t.Fatalf("待恢复状态不符: %+v", p)
+	}
+	out = cpuSeries(t, rs, sm, 10)
+	if out[0] == nil || !out[0].IsResolved() {
+		t.Fatalf("连续 2 次正常应恢复: %+v", out)
+	}
+}
+
+// TestPersistenceMofN 测试最近 N 个样本中累计 M 次越限即触发
+func TestPersistenceMofN(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[{"alert_id":"CPU","layer":"node","field":"cpu_percent","op":">","value":80,
+		"persistence":{"fire":2,"window":3},"type":"t"}]}`)
+
+	out := cpuSeries(t, rs, sm, 10, 90, 10, 10, 90)
+	for i, a := range out[1:] {
+		if a != nil {
+			t.Fatalf("样本 %d: 窗口内不足 2 次不应触发: %+v", i+1, a)
+		}
+	}
+	out = cpuSeries(t, rs, sm, 10, 90)
+	if out[0] != nil || out[1] == nil || !out[1].IsFiring() {
+		t.Fatalf("3 个样本中 2 次越限应触发: %+v", out)
+	}
+}
+
+// TestDeadband 测试回差：恢复与降级需越过限值 deadband
+func TestDeadband(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[{"alert_id":"CPU","layer":"node","field":"cpu_percent","op":">","value":80,"hard_value":90,
+		"deadband":3,"type":"t"}]}`)
+
+	out := cpuSeries(t, rs, sm, 10, 95, 88, 86, 79, 76)
+	if out[1] == nil || out[1].Severity != model.SeverityCritical {
+		t.Fatalf("95 应触发严重: %+v", out[1])
+	}
+	if out[2] != nil {
+		t.Errorf("88 在红线回差内，应保持严重: %+v", out[2])
+	}
+	if out[3] == nil || out[3].Severity != model.SeverityWarning {
+		t.Errorf("86 应降级为警告: %+v", out[3])
+	}
+	if out[4] != nil {
+		t.Errorf("79 在黄线回差内，应保持告警: %+v", out[4])
+	}
+	if out[5] == nil || !out[5].IsResolved() {
+		t.Errorf("76 应恢复: %+v", out[5])
+	}
+}
+
+// TestPersistenceEscalation 测试已触发告警升级需连续 fire 次
+func TestPersistenceEscalation(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[{"alert_id":"CPU","layer":"node","field":"cpu_percent","op":">","value":80,"hard_value":90,
+		"persistence":{"fire":2},"type":"t"}]}`)
+
+	out := cpuSeries(t, rs, sm, 10, 85, 85, 95, 85, 95, 95)
+	if out[2] == nil || out[2].Severity != model.SeverityWarning {
+		t.Fatalf("应触发警告: %+v", out)
+	}
+	if out[3] != nil || out[5] != nil {
+		t.Errorf("单次越过红线不应升级: %+v", out)
+	}
+	if out[6] == nil || out[6].Severity != model.SeverityCritical {
+		t.Errorf("连续 2 次越过红线应升级: %+v", out[6])
+	}
+
+	// 无 StateManager 时不做持续性判定
+	if alerts := rs.EvaluateNode(&model.NodeMetrics{ID: "n2", CPUUsage: 85.0}, nil); len(alerts) != 1 {
+		t.Errorf("无状态评估应直接触发: %+v", alerts)
+	}
+
+	for name, data := range map[string]string{
+		"窗口过小": `{"rules":[{"alert_id":"A","layer":"node","field":"cpu_percent","op":">","value":1,"persistence":{"fire":3,"window":2},"type":"t"}]}`,
+		"窗口过大": `{"rules":[{"alert_id":"A","layer":"node","field":"cpu_percent","op":">","value":1,"persistence":{"window":100},"type":"t"}]}`,
+		"回差条件": `{"rules":[{"alert_id":"A","layer":"node","field":"Status","op":"!=","value":"online","deadband":1,"type":"t"}]}`,
+		"回差为负": `{"rules":[{"alert_id":"A","layer":"node","field":"cpu_percent","op":">","value":1,"deadband":-1,"type":"t"}]}`,
+	} {
+		if _, err := ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+}
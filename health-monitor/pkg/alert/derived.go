@@ -0,0 +1,176 @@
+/*
+派生参数
+
+没有报文直接携带、但由现有遥测/ECSM 指标计算得到的参数，如负载功率 BusVoltage * LoadCurrent、
+两路蓄电池温度之差。在规则集的 derived 中定义（与规则一起校验、热加载）：
+
+	name         参数名，同一层级（业务层为同一组件）内唯一，不能与指标字段、计算字段重名
+	layer        business / node / container / service
+	component    业务组件编号（仅 business）
+	expr         表达式，写法见 expr.go；可引用本组件/实体的字段、计算字段及在它之前定义的派生参数
+	unit         单位
+	description  描述（规则未指定 name 时用作告警中的参数名）
+
+每次评估指标时计算该组件/实体的全部派生参数与计算字段（cpu_percent、memory_percent 等），
+有 StateManager 时作为 derived 类型指标保存最新值与历史（ID 见 state.DerivedID），供趋势分析使用；
+规则的 field 可直接写派生参数名。
+*/
+package alert
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"health-monitor/pkg/state"
+)
+
+// Derived 派生参数定义
+type Derived struct {
+	Name        string `json:"name"`                  // 参数名
+	Layer       string `json:"layer"`                 // 所属层级
+	Component   uint8  `json:"component,omitempty"`   // 业务组件编号（仅 business）
+	Expr        string `json:"expr"`                  // 表达式
+	Unit        string `json:"unit,omitempty"`        // 单位
+	Description string `json:"description,omitempty"` // 描述
+
+	expr *exprNode
+}
+
+// derivedValue 一次计算得到的派生参数值
+type derivedValue struct {
+	name  string
+	value float64
+	unit  string
+}
+
+var derivedName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// value 计算派生参数，变量不可用或结果无效时返回 false
+func (d *Derived) value(data interface{}) (float64, bool) {
+	return d.expr.number(data)
+}
+
+// derivedKey 派生参数的查找键：层级 + 组件 + 参数名
+func derivedKey(layer string, component uint8, name string) string {
+	return fmt.Sprintf("%s/%d/%s", layer, component, name)
+}
+
+// metricType 层级（业务组件）对应的指标结构体
+func metricType(layer string, component uint8) (reflect.Type, error) {
+	switch layer {
+	case LayerBusiness:
+		t, ok := businessTypes[component]
+		if !ok {
+			return nil, fmt.Errorf("未知业务组件编号 %d", component)
+		}
+		return t, nil
+	case LayerNode, LayerContainer, LayerService:
+		if component != 0 {
+			return nil, fmt.Errorf("component 仅适用于 business 层")
+		}
+		return entityTypes[layer], nil
+	}
+	return nil, fmt.Errorf("未知层级 %q", layer)
+}
+
+// compileDerived 校验派生参数并编译表达式（按定义顺序，只能引用之前定义的派生参数）
+func (rs *RuleSet) compileDerived() error {
+	rs.derived = make(map[string]*Derived, len(rs.Derived))
+	rs.derivedByLayer = make(map[string][]*Derived)
+
+	for i := range rs.Derived {
+		d := &rs.Derived[i]
+		if !derivedName.MatchString(d.Name) {
+			return fmt.Errorf("第 %d 个派生参数名称 %q 非法", i+1, d.Name)
+		}
+		typ, err := metricType(d.Layer, d.Component)
+		if err != nil {
+			return fmt.Errorf("派生参数 %s: %w", d.Name, err)
+		}
+		if _, ok := computedFields[d.Layer][d.Name]; ok {
+			return fmt.Errorf("派生参数 %s 与计算字段重名", d.Name)
+		}
+		if _, ok := typ.FieldByName(d.Name); ok {
+			return fmt.Errorf("派生参数 %s 与 %s 字段重名", d.Name, typ.Name())
+		}
+		key := derivedKey(d.Layer, d.Component, d.Name)
+		if _, dup := rs.derived[key]; dup {
+			return fmt.Errorf("派生参数 %s 重复定义", d.Name)
+		}
+		if d.expr, err = compileExpr(d.Expr, rs.exprResolver(d.Layer, d.Component, typ)); err != nil {
+			return fmt.Errorf("派生参数 %s: %w", d.Name, err)
+		}
+		rs.derived[key] = d
+		rs.derivedByLayer[d.Layer] = append(rs.derivedByLayer[d.Layer], d)
+	}
+	return nil
+}
+
+// exprResolver 表达式变量解析：计算字段 → 已定义的派生参数 → 指标字段
+func (rs *RuleSet) exprResolver(layer string, component uint8, typ reflect.Type) exprResolver {
+	return func(name string) (*exprNode, error) {
+		if fn, ok := computedFields[layer][name]; ok {
+			return &exprNode{kind: kindNum, eval: func(d interface{}) (exprValue, bool) {
+				v, ok := fn(d)
+				return exprValue{num: v}, ok
+			}}, nil
+		}
+		if ref, ok := rs.derived[derivedKey(layer, component, name)]; ok {
+			return &exprNode{kind: kindNum, eval: func(d interface{}) (exprValue, bool) {
+				v, ok := ref.value(d)
+				return exprValue{num: v}, ok
+			}}, nil
+		}
+		return fieldNode(typ, name)
+	}
+}
+
+// derivedField 规则 field 引用的派生参数
+func (rs *RuleSet) derivedField(layer string, component uint8, name string) (*Derived, bool) {
+	d, ok := rs.derived[derivedKey(layer, component, name)]
+	return d, ok
+}
+
+// derive 计算评估对象的计算字段与派生参数（按名称排序的计算字段在前，派生参数按定义顺序）
+func (rs *RuleSet) derive(t ruleTarget) []derivedValue {
+	var out []derivedValue
+	names := make([]string, 0, len(computedFields[t.layer]))
+	for name := range computedFields[t.layer] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v, ok := computedFields[t.layer][name](t.data); ok {
+			out = append(out, derivedValue{name: name, value: v, unit: "%"})
+		}
+	}
+	for _, d := range rs.derivedByLayer[t.layer] {
+		if d.Layer == LayerBusiness && d.Component != t.component {
+			continue
+		}
+		if v, ok := d.value(t.data); ok {
+			out = append(out, derivedValue{name: d.Name, value: v, unit: d.Unit})
+		}
+	}
+	return out
+}
+
+// storeDerived 计算派生参数并保存到 StateManager
+func (rs *RuleSet) storeDerived(t ruleTarget, sm *state.StateManager) {
+	entity := t.id
+	if t.layer == LayerBusiness {
+		entity = fmt.Sprintf("0x%02X", t.component)
+	}
+	for _, v := range rs.derive(t) {
+		sm.UpdateMetric(&state.DerivedMetric{
+			Layer:     t.layer,
+			Entity:    entity,
+			Name:      v.name,
+			Value:     v.value,
+			Unit:      v.unit,
+			Timestamp: t.timestamp,
+		})
+	}
+}
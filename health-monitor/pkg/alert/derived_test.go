@@ -0,0 +1,147 @@
+package alert
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// TestExpr 测试表达式编译与求值
+func TestExpr(t *testing.T) {
+	rs := &RuleSet{derived: map[string]*Derived{}}
+	typ := reflect.TypeOf(model.ThermalMetrics{})
+	resolve := rs.exprResolver(LayerBusiness, 6, typ)
+	data := &model.ThermalMetrics{BatteryTemp1: 12.5, BatteryTemp2: 15, PlatformHeaterSwitch: true, ThermalTemps: [10]float64{3: 40}}
+
+	values := map[string]float64{
+		"abs(BatteryTemp1 - BatteryTemp2)":           2.5,
+		"1 + 2 * 3":                                  7,
+		"(1 + 2) * 3":                                9,
+		"-BatteryTemp1 + 2":                          -10.5,
+		"10 % 4":                                     2,
+		"max(1, ThermalTemps[3], 2) / min(4, 8)":     10,
+		"sqrt(16) - 1e1":                             -6,
+		"PlatformHeaterSwitch && BatteryTemp1 < 20":  1,
+		"!PlatformHeaterSwitch || BatteryTemp2 > 20": 0,
+		"BatteryTemp1 == 12.5":                       1,
+	}
+	for src, want := range values {
+		n, err := compileExpr(src, resolve)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if got, ok := n.number(data); !ok || math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s = %v (%v)，期望 %v", src, got, ok, want)
+		}
+	}
+
+	// 除以 0 不产生值
+	n, err := compileExpr("BatteryTemp1 / (BatteryTemp2 - 15)", resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := n.number(data); ok {
+		t.Errorf("除以 0 不应产生值: %v", v)
+	}
+
+	invalid := []string{
+		"BatteryTemp1 +",
+		"Nope * 2",
+		"BatteryTemp1 && true",
+		"PlatformHeaterSwitch + 1",
+		`"text"`,
+		"foo(1)",
+		"abs(1, 2)",
+		"ThermalTemps[*]",
+		"1 $ 2",
+		"(1 + 2",
+		"1 2",
+	}
+	for _, src := range invalid {
+		if _, err := compileExpr(src, resolve); err == nil {
+			t.Errorf("%s: 期望编译失败", src)
+		}
+	}
+}
+
+// TestDerivedRules 测试派生参数用于规则、保存到 StateManager 历史并用于趋势分析
+func TestDerivedRules(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"derived":[
+		{"name":"LoadPower","layer":"business","component":3,"expr":"BusVoltage * LoadCurrent","unit":"W","description":"负载功率"},
+		{"name":"LoadPowerMargin","layer":"business","component":3,"expr":"150 - LoadPower","unit":"W"},
+		{"name":"mem_limit_ratio","layer":"container","expr":"MemoryLimit > 0 && MemoryUsage * 10 > MemoryLimit * 9"}],
+		"rules":[
+		{"alert_id":"LOAD_POWER","layer":"business","component":3,"field":"LoadPower","op":">","value":100,"type":"t","format":"%.1f"},
+		{"alert_id":"MEM_LIMIT","layer":"container","field":"mem_limit_ratio","op":"==","value":1,"type":"t"}]}`)
+
+	now := time.Now().Unix()
+	for i, current := range []float64{2, 2.5, 3, 3.5, 4, 4.5, 5} {
+		bm := &model.BusinessMetrics{ComponentType: 0x03, Timestamp: now - 7 + int64(i),
+			Data: &model.PowerMetrics{BusVoltage: 28, LoadCurrent: current}}
+		alerts := rs.EvaluateBusiness(bm, sm)
+		if current == 4 {
+			if len(alerts) != 1 || !alerts[0].IsFiring() || alerts[0].Message != "负载功率异常: 112.0W (正常≤100W)" {
+				t.Fatalf("负载功率告警不符: %+v", alerts)
+			}
+		}
+	}
+
+	m, ok := sm.GetLatestState(state.MetricTypeDerived, state.DerivedID(LayerBusiness, "0x03", "LoadPowerMargin"))
+	if !ok || m.GetData().(float64) != 10 {
+		t.Fatalf("派生参数最新值不符: %+v", m)
+	}
+	ts, values := NewTrendAnalyzer(sm).DerivedSeries(LayerBusiness, "0x03", "LoadPower")
+	if len(values) != 7 || values[0] != 56 || values[6] != 140 || ts[6] != now-1 {
+		t.Fatalf("派生参数历史不符: %v %v", ts, values)
+	}
+	ta := NewTrendAnalyzer(sm)
+	ta.trendWindowSize = 5
+	if trend := ta.AnalyzeDerivedTrend(LayerBusiness, "0x03", "LoadPower"); trend == nil || trend.Type != "increasing" {
+		t.Errorf("应识别为上升趋势: %+v", trend)
+	}
+
+	// 微服务层：计算字段一并保存，布尔表达式结果为 1/0
+	alerts := rs.EvaluateContainer(&model.ContainerMetrics{ID: "c1", MemoryUsage: 95, MemoryLimit: 100}, sm)
+	if len(alerts) != 1 || alerts[0].AlertID != "MEM_LIMIT" || alerts[0].Source != "c1" {
+		t.Fatalf("容器派生参数告警不符: %+v", alerts)
+	}
+	if m, ok := sm.GetLatestState(state.MetricTypeDerived, state.DerivedID(LayerContainer, "c1", "memory_percent")); !ok || m.GetData().(float64) != 95 {
+		t.Errorf("计算字段应保存到 StateManager: %+v", m)
+	}
+	if alerts := rs.EvaluateContainer(&model.ContainerMetrics{ID: "c2"}, nil); len(alerts) != 0 {
+		t.Errorf("内存限制为 0 时不应告警: %+v", alerts)
+	}
+}
+
+// TestParseDerived 测试派生参数校验
+func TestParseDerived(t *testing.T) {
+	rule := `{"alert_id":"A","layer":"business","component":3,"field":"X","op":">","value":1,"type":"t"}`
+	cases := map[string]string{
+		"名称非法":   `{"derived":[{"name":"a-b","layer":"business","component":3,"expr":"1"}],"rules":[]}`,
+		"与字段重名":  `{"derived":[{"name":"BusVoltage","layer":"business","component":3,"expr":"1"}],"rules":[]}`,
+		"与计算字段重名": `{"derived":[{"name":"cpu_percent","layer":"node","expr":"1"}],"rules":[]}`,
+		"重复定义":   `{"derived":[{"name":"X","layer":"business","component":3,"expr":"1"},{"name":"X","layer":"business","component":3,"expr":"2"}],"rules":[]}`,
+		"未知变量":   `{"derived":[{"name":"X","layer":"business","component":3,"expr":"Nope"}],"rules":[]}`,
+		"引用在后定义": `{"derived":[{"name":"X","layer":"business","component":3,"expr":"Y"},{"name":"Y","layer":"business","component":3,"expr":"1"}],"rules":[]}`,
+		"未知组件":   `{"derived":[{"name":"X","layer":"business","component":99,"expr":"1"}],"rules":[]}`,
+		"其他组件":   `{"derived":[{"name":"X","layer":"business","component":6,"expr":"1"}],"rules":[` + rule + `]}`,
+		"参数库判定":  `{"derived":[{"name":"X","layer":"business","component":3,"expr":"1"}],"rules":[` + strings.Replace(rule, `"op":">","value":1`, `"op":"catalog"`, 1) + `]}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+
+	// 内置派生参数
+	if _, ok := DefaultRules().derivedField(LayerBusiness, 3, "LoadPower"); !ok {
+		t.Error("内置规则集应包含 LoadPower")
+	}
+}
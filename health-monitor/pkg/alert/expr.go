@@ -0,0 +1,590 @@
+/*
+派生参数表达式
+
+只支持算术、比较与逻辑运算（不能调用任意函数、访问任意对象），用于派生参数（见 derived.go）：
+
+	数值      12、3.5、1e-3
+	字符串    "running"（只能参与 == !=）
+	布尔      true、false
+	变量      指标字段（写法同规则 field，不支持 [*]）、计算字段、先定义的派生参数
+	运算      + - * / %   == != < <= > >=   && || !   ( )
+	函数      abs(x)、sqrt(x)、min(x, y, ...)、max(x, y, ...)
+
+编译时按指标结构体检查变量是否存在以及运算的类型；求值时变量不可用、除以 0 或结果不是有限数时不产生值。
+*/
+package alert
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// exprKind 表达式的值类型
+type exprKind int
+
+const (
+	kindNum exprKind = iota
+	kindBool
+	kindStr
+)
+
+func (k exprKind) String() string {
+	switch k {
+	case kindBool:
+		return "布尔"
+	case kindStr:
+		return "字符串"
+	}
+	return "数值"
+}
+
+// exprValue 表达式的值，布尔值以 num 非 0 表示
+type exprValue struct {
+	num float64
+	str string
+}
+
+// exprNode 编译后的表达式节点，eval 返回 false 表示本次无法求值
+type exprNode struct {
+	kind exprKind
+	eval func(data interface{}) (exprValue, bool)
+}
+
+// exprResolver 将变量名解析为表达式节点
+type exprResolver func(name string) (*exprNode, error)
+
+// compileExpr 编译表达式，结果须为数值或布尔
+func compileExpr(src string, resolve exprResolver) (*exprNode, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens, resolve: resolve}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokEOF {
+		return nil, fmt.Errorf("表达式在 %q 处多余", t.text)
+	}
+	if n.kind == kindStr {
+		return nil, fmt.Errorf("表达式结果须为数值或布尔")
+	}
+	return n, nil
+}
+
+// number 求值为数值（布尔为 1/0），结果不是有限数时返回 false
+func (n *exprNode) number(data interface{}) (float64, bool) {
+	v, ok := n.eval(data)
+	if !ok || math.IsNaN(v.num) || math.IsInf(v.num, 0) {
+		return 0, false
+	}
+	return v.num, true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                                 词法分析
+////////////////////////////////////////////////////////////////////////////////
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokNum
+	tokStr
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	typ  tokenType
+	text string
+	num  float64
+}
+
+// exprOps 运算符，两个字符的排在前面
+var exprOps = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!"}
+
+func tokenizeExpr(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{typ: tokLParen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{typ: tokRParen, text: ")"})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{typ: tokComma, text: ","})
+			i++
+		case c == '"':
+			end := strings.IndexByte(src[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("字符串缺少结束引号")
+			}
+			text := src[i+1 : i+1+end]
+			tokens = append(tokens, token{typ: tokStr, text: text})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(src) && (isDigit(src[j]) || src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				((src[j] == '+' || src[j] == '-') && j > i && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				j++
+			}
+			v, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("数值 %q 格式错误", src[i:j])
+			}
+			tokens = append(tokens, token{typ: tokNum, text: src[i:j], num: v})
+			i = j
+		case isIdentStart(c):
+			// 变量名含字段路径：CPUUsage.Total、ThermalTemps[3]
+			j, depth := i, 0
+			for ; j < len(src); j++ {
+				c := src[j]
+				if c == '[' {
+					depth++
+				} else if c == ']' && depth > 0 {
+					depth--
+				} else if !isIdentStart(c) && !isDigit(c) && c != '.' && !(c == '*' && depth > 0) {
+					break
+				}
+			}
+			tokens = append(tokens, token{typ: tokIdent, text: src[i:j]})
+			i = j
+		default:
+			matched := false
+			for _, op := range exprOps {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{typ: tokOp, text: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("无法识别的字符 %q", c)
+			}
+		}
+	}
+	return append(tokens, token{typ: tokEOF, text: "结尾"}), nil
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentStart(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+////////////////////////////////////////////////////////////////////////////////
+//                                 语法分析
+////////////////////////////////////////////////////////////////////////////////
+
+// exprParser 递归下降解析，优先级从低到高：|| && 比较 +- */% 单目
+type exprParser struct {
+	tokens  []token
+	pos     int
+	resolve exprResolver
+}
+
+func (p *exprParser) peek() token { return p.tokens[p.pos] }
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// acceptOp 当前为指定运算符之一时读取并返回
+func (p *exprParser) acceptOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.typ != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr() (*exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical("||", left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseAnd() (*exprNode, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical("&&", left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseCompare() (*exprNode, error) {
+	left, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOp("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	return comparison(op, left, right)
+}
+
+func (p *exprParser) parseAdd() (*exprNode, error) {
+	left, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseMul() (*exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (*exprNode, error) {
+	op, ok := p.acceptOp("-", "!")
+	if !ok {
+		return p.parsePrimary()
+	}
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if op == "-" {
+		if x.kind != kindNum {
+			return nil, fmt.Errorf("- 需要数值")
+		}
+		return &exprNode{kind: kindNum, eval: func(d interface{}) (exprValue, bool) {
+			v, ok := x.eval(d)
+			return exprValue{num: -v.num}, ok
+		}}, nil
+	}
+	if x.kind != kindBool {
+		return nil, fmt.Errorf("! 需要布尔值")
+	}
+	return &exprNode{kind: kindBool, eval: func(d interface{}) (exprValue, bool) {
+		v, ok := x.eval(d)
+		return boolValue(v.num == 0), ok
+	}}, nil
+}
+
+func (p *exprParser) parsePrimary() (*exprNode, error) {
+	t := p.next()
+	switch t.typ {
+	case tokNum:
+		return constNode(kindNum, exprValue{num: t.num}), nil
+	case tokStr:
+		return constNode(kindStr, exprValue{str: t.text}), nil
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().typ != tokRParen {
+			return nil, fmt.Errorf("缺少 )")
+		}
+		return n, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return constNode(kindBool, boolValue(true)), nil
+		case "false":
+			return constNode(kindBool, boolValue(false)), nil
+		}
+		if p.peek().typ == tokLParen {
+			p.next()
+			return p.parseCall(t.text)
+		}
+		return p.resolve(t.text)
+	}
+	return nil, fmt.Errorf("表达式在 %q 处不完整", t.text)
+}
+
+// parseCall 解析函数调用（已读取函数名与左括号）
+func (p *exprParser) parseCall(name string) (*exprNode, error) {
+	var args []*exprNode
+	if p.peek().typ != tokRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if arg.kind != kindNum {
+				return nil, fmt.Errorf("%s 的参数须为数值", name)
+			}
+			args = append(args, arg)
+			if p.peek().typ != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.next().typ != tokRParen {
+		return nil, fmt.Errorf("%s 缺少 )", name)
+	}
+
+	var fn func(xs []float64) float64
+	switch name {
+	case "abs":
+		fn = func(xs []float64) float64 { return math.Abs(xs[0]) }
+	case "sqrt":
+		fn = func(xs []float64) float64 { return math.Sqrt(xs[0]) }
+	case "min":
+		fn = func(xs []float64) float64 {
+			m := xs[0]
+			for _, x := range xs[1:] {
+				m = math.Min(m, x)
+			}
+			return m
+		}
+	case "max":
+		fn = func(xs []float64) float64 {
+			m := xs[0]
+			for _, x := range xs[1:] {
+				m = math.Max(m, x)
+			}
+			return m
+		}
+	default:
+		return nil, fmt.Errorf("未知函数 %s", name)
+	}
+	if (name == "abs" || name == "sqrt") && len(args) != 1 {
+		return nil, fmt.Errorf("%s 需要 1 个参数", name)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s 至少需要 1 个参数", name)
+	}
+	return &exprNode{kind: kindNum, eval: func(d interface{}) (exprValue, bool) {
+		xs := make([]float64, len(args))
+		for i, a := range args {
+			v, ok := a.eval(d)
+			if !ok {
+				return exprValue{}, false
+			}
+			xs[i] = v.num
+		}
+		return exprValue{num: fn(xs)}, true
+	}}, nil
+}
+
+func constNode(kind exprKind, v exprValue) *exprNode {
+	return &exprNode{kind: kind, eval: func(interface{}) (exprValue, bool) { return v, true }}
+}
+
+func boolValue(b bool) exprValue {
+	if b {
+		return exprValue{num: 1}
+	}
+	return exprValue{}
+}
+
+// arithmetic + - * / %，除以 0 时无法求值
+func arithmetic(op string, l, r *exprNode) (*exprNode, error) {
+	if l.kind != kindNum || r.kind != kindNum {
+		return nil, fmt.Errorf("%s 需要数值，实际为 %s 与 %s", op, l.kind, r.kind)
+	}
+	return &exprNode{kind: kindNum, eval: func(d interface{}) (exprValue, bool) {
+		a, ok := l.eval(d)
+		if !ok {
+			return exprValue{}, false
+		}
+		b, ok := r.eval(d)
+		if !ok {
+			return exprValue{}, false
+		}
+		switch op {
+		case "+":
+			return exprValue{num: a.num + b.num}, true
+		case "-":
+			return exprValue{num: a.num - b.num}, true
+		case "*":
+			return exprValue{num: a.num * b.num}, true
+		case "/":
+			if b.num == 0 {
+				return exprValue{}, false
+			}
+			return exprValue{num: a.num / b.num}, true
+		}
+		if b.num == 0 {
+			return exprValue{}, false
+		}
+		return exprValue{num: math.Mod(a.num, b.num)}, true
+	}}, nil
+}
+
+// comparison == != < <= > >=，== != 要求两侧类型相同
+func comparison(op string, l, r *exprNode) (*exprNode, error) {
+	if l.kind != r.kind {
+		return nil, fmt.Errorf("%s 两侧类型不同: %s 与 %s", op, l.kind, r.kind)
+	}
+	if op != "==" && op != "!=" && l.kind != kindNum {
+		return nil, fmt.Errorf("%s 需要数值", op)
+	}
+	return &exprNode{kind: kindBool, eval: func(d interface{}) (exprValue, bool) {
+		a, ok := l.eval(d)
+		if !ok {
+			return exprValue{}, false
+		}
+		b, ok := r.eval(d)
+		if !ok {
+			return exprValue{}, false
+		}
+		switch op {
+		case "==":
+			return boolValue(a == b), true
+		case "!=":
+			return boolValue(a != b), true
+		}
+		return boolValue(compare(op, a.num, b.num)), true
+	}}, nil
+}
+
+// logical && ||（短路求值）
+func logical(op string, l, r *exprNode) (*exprNode, error) {
+	if l.kind != kindBool || r.kind != kindBool {
+		return nil, fmt.Errorf("%s 需要布尔值，实际为 %s 与 %s", op, l.kind, r.kind)
+	}
+	return &exprNode{kind: kindBool, eval: func(d interface{}) (exprValue, bool) {
+		a, ok := l.eval(d)
+		if !ok {
+			return exprValue{}, false
+		}
+		if (op == "&&") == (a.num == 0) {
+			// && 左侧为假、|| 左侧为真时不再求右侧
+			return a, true
+		}
+		return r.eval(d)
+	}}, nil
+}
+
+// fieldNode 指标字段变量
+func fieldNode(typ reflect.Type, name string) (*exprNode, error) {
+	path, err := parseFieldPath(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range path {
+		if s.index == wildcardIndex {
+			return nil, fmt.Errorf("变量 %s 不支持 [*]", name)
+		}
+	}
+	if err := checkFieldPath(typ, path); err != nil {
+		return nil, fmt.Errorf("变量 %s: %w", name, err)
+	}
+	kind := fieldKind(typ, path)
+	return &exprNode{kind: kind, eval: func(d interface{}) (exprValue, bool) {
+		v, ok := scalar(resolvePath(reflect.ValueOf(d), path))
+		if !ok {
+			return exprValue{}, false
+		}
+		switch x := v.(type) {
+		case float64:
+			return exprValue{num: x}, kind == kindNum
+		case bool:
+			return boolValue(x), kind == kindBool
+		case string:
+			return exprValue{str: x}, kind == kindStr
+		}
+		return exprValue{}, false
+	}}, nil
+}
+
+// fieldKind 字段路径（已通过 checkFieldPath 校验）的值类型，interface 字段按数值处理
+func fieldKind(t reflect.Type, path []pathStep) exprKind {
+	for _, s := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Interface {
+			return kindNum
+		}
+		f, _ := t.FieldByName(s.name)
+		t = f.Type
+		if s.index != -1 {
+			t = t.Elem()
+		}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return kindBool
+	case reflect.String:
+		return kindStr
+	}
+	return kindNum
+}
@@ -24,12 +24,22 @@ import (
 	"fmt"
 	"health-monitor/pkg/models"
 	"health-monitor/pkg/state"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Generator 告警生成器
 type Generator struct {
 	trendAnalyzer *TrendAnalyzer // 趋势分析器
 	alertAdapter  *AlertAdapter   // 告警适配器（可选，用于直接发送到故障诊断）
+	rules         atomic.Pointer[RuleSet] // 告警规则集（可选，未设置时使用全局默认规则集）
+	silences      atomic.Pointer[SilenceStore] // 静默规则（可选）
+	correlator    atomic.Pointer[Correlator]   // 告警关联（可选）
+
+	suppressMu sync.Mutex
+	suppressed map[string]*model.AlertEvent // 被静默拦截、仍在触发的告警（alertID|source -> 最近一次触发告警）
+	forwarded  map[string]bool              // 已转发触发告警、尚未恢复的告警（alertID|source）
 }
 
 // NewGenerator 创建新的告警生成器
@@ -39,17 +49,19 @@ func NewGenerator() *Generator {
 
 // NewGeneratorWithStateManager 创建带状态管理的告警生成器
 func NewGeneratorWithStateManager(sm *state.StateManager) *Generator {
-	return &Generator{
-		trendAnalyzer: NewTrendAnalyzer(sm),
-	}
+	g := &Generator{trendAnalyzer: NewTrendAnalyzer(sm)}
+	g.trendAnalyzer.rules = g.Rules // 趋势预测规则随规则集一起替换
+	return g
 }
 
 // NewGeneratorWithDiagnosis 创建带故障诊断集成的告警生成器
 func NewGeneratorWithDiagnosis(sm *state.StateManager, diagnosisReceiver DiagnosisReceiver) *Generator {
-	return &Generator{
+	g := &Generator{
 		trendAnalyzer: NewTrendAnalyzer(sm),
 		alertAdapter:  NewAlertAdapter(diagnosisReceiver),
 	}
+	g.trendAnalyzer.rules = g.Rules
+	return g
 }
 
 // SetDiagnosisReceiver 设置故障诊断接收器（运行时配置）
@@ -57,9 +69,49 @@ func (g *Generator) SetDiagnosisReceiver(receiver DiagnosisReceiver) {
 	g.alertAdapter = NewAlertAdapter(receiver)
 }
 
+// SetRules 设置本生成器使用的规则集（运行时可替换），rs 为 nil 时恢复使用全局默认规则集
+func (g *Generator) SetRules(rs *RuleSet) {
+	g.rules.Store(rs)
+}
+
+// Rules 返回当前生效的规则集
+func (g *Generator) Rules() *RuleSet {
+	if rs := g.rules.Load(); rs != nil {
+		return rs
+	}
+	return DefaultRules()
+}
+
+// SetSilences 设置静默规则存储，匹配的触发告警不转发到故障诊断
+func (g *Generator) SetSilences(store *SilenceStore) {
+	g.silences.Store(store)
+}
+
+// SetCorrelator 设置告警关联器，时间窗内共享标签的告警归并为关联事件
+func (g *Generator) SetCorrelator(c *Correlator) {
+	g.correlator.Store(c)
+}
+
+// ApplyRuleChange 规则集切换后调用：已删除/停用规则的活跃告警输出恢复告警（仅处理 layers 指定的层级）
+func (g *Generator) ApplyRuleChange(old, next *RuleSet, layers ...string) {
+	var sm *state.StateManager
+	if g.trendAnalyzer != nil {
+		sm = g.trendAnalyzer.stateManager
+	}
+	
+	g.releaseSilenced()
+	alerts := ResolveRemovedRules(old, next, sm, layers...)
+	recordAlerts(sm, "", "", alerts)
+	if len(alerts) > 0 {
+		fmt.Printf("[告警规则] %d 个告警因规则删除/停用而解除\n", len(alerts))
+		g.outputAlerts(alerts)
+	}
+}
+
 // ProcessBusinessMetrics 处理业务层指标，生成告警事件
 func (g *Generator) ProcessBusinessMetrics(ctx context.Context, bm *model.BusinessMetrics) {
 	var alerts []*model.AlertEvent
+	g.releaseSilenced()
 	
 	// 获取状态管理器（如果有趋势分析器，说明有状态管理器）
 	var sm *state.StateManager
@@ -67,46 +119,83 @@ func (g *Generator) ProcessBusinessMetrics(ctx context.Context, bm *model.Busine
 		sm = g.trendAnalyzer.stateManager
 	}
 	
-	// 根据组件类型调用对应的阈值检查函数
-	switch bm.ComponentType {
-	case 0x03: // CompPower - 供电服务
-		if powerData, ok := bm.Data.(*model.PowerMetrics); ok {
-			if sm != nil {
-				// 使用有状态的检查（支持恢复告警）
-				alerts = CheckPowerThresholdsWithState(powerData, sm)
-			} else {
-				// 使用无状态的检查（仅触发告警）
-				alerts = CheckPowerThresholds(powerData)
-			}
-		}
-		
-	case 0x06: // CompThermal - 热控服务
-		if thermalData, ok := bm.Data.(*model.ThermalMetrics); ok {
-			alerts = CheckThermalThresholds(thermalData)
-		}
-		
-	case 0x02: // CompComm - 通信服务
-		if commData, ok := bm.Data.(*model.CommMetrics); ok {
-			alerts = CheckCommThresholds(commData)
-		}
-		
-	case 0x0B: // CompActuator - 姿态控制机构
-		if actuatorData, ok := bm.Data.(*model.ActuatorMetrics); ok {
-			alerts = CheckActuatorThresholds(actuatorData)
-		}
-		
-	// 可以继续添加其他组件类型的处理
+	// 按规则集评估（sm 为 nil 时只产生触发告警）
+	alerts = g.Rules().EvaluateBusiness(bm, sm)
+	
+	// 趋势预测（即将发生的越限）
+	if g.trendAnalyzer != nil {
+		alerts = append(alerts, g.trendAnalyzer.AnalyzeBusinessTrends(ctx, bm.ComponentType)...)
 	}
 	
+	// 补充所属组件，记录触发中的告警（健康分按组件统计）
+	labelAlerts(LayerBusiness, bm.ComponentType, alerts)
+	recordAlerts(sm, LayerBusiness, businessEntity(bm.ComponentType), alerts)
+	
 	// 如果有告警，进行处理和输出
 	if len(alerts) > 0 {
 		g.outputAlerts(alerts)
 	}
 }
 
+// ProcessLinkQuality 处理业务链路质量（每个评估窗口一次），丢包率超限时生成告警
+func (g *Generator) ProcessLinkQuality(ctx context.Context, lq *model.LinkQuality) {
+	var alerts []*model.AlertEvent
+	
+	var sm *state.StateManager
+	if g.trendAnalyzer != nil {
+		sm = g.trendAnalyzer.stateManager
+	}
+	
+	if sm != nil {
+		alerts = CheckLinkQualityWithState(lq, sm)
+	} else {
+		alerts = CheckLinkQuality(lq)
+	}
+	labelAlerts(LayerBusiness, lq.ComponentType, alerts)
+	recordAlerts(sm, LayerBusiness, businessEntity(lq.ComponentType), alerts)
+	
+	if len(alerts) > 0 {
+		g.outputAlerts(alerts)
+	}
+}
+
+// ProcessTelemetryLoss 检查 layers 层级的数据新鲜度，遥测中断或恢复时生成告警（需要状态管理器）
+func (g *Generator) ProcessTelemetryLoss(ctx context.Context, cfg *WatchdogConfig, layers ...string) {
+	if g.trendAnalyzer == nil || g.trendAnalyzer.stateManager == nil || cfg == nil {
+		return
+	}
+	g.releaseSilenced()
+	
+	alerts := cfg.Check(g.trendAnalyzer.stateManager, time.Now(), layers...)
+	for _, a := range alerts {
+		// 业务组件已带 component_type，ECSM 实体补充节点/容器/服务ID
+		if layer, ok := a.Metadata["layer"].(string); ok && a.Metadata["entity_id"] != nil {
+			labelAlerts(layer, a.Metadata["entity_id"], []*model.AlertEvent{a})
+		}
+	}
+	if len(alerts) > 0 {
+		g.outputAlerts(alerts)
+	}
+}
+
+// ProcessVanishedEntities 删除已从本轮 ECSM 采集结果中消失的实体，解除其遥测中断告警（需要状态管理器）
+func (g *Generator) ProcessVanishedEntities(ctx context.Context, ms *model.MicroServiceMetricsSet) {
+	if g.trendAnalyzer == nil || g.trendAnalyzer.stateManager == nil || ms == nil {
+		return
+	}
+	alerts := ForgetEntities(g.trendAnalyzer.stateManager, ms, time.Now())
+	for _, a := range alerts {
+		labelAlerts(a.Metadata["layer"].(string), a.Metadata["entity_id"], []*model.AlertEvent{a})
+	}
+	if len(alerts) > 0 {
+		g.outputAlerts(alerts)
+	}
+}
+
 // ProcessMicroserviceMetrics 处理微服务层指标，生成告警事件
 func (g *Generator) ProcessMicroserviceMetrics(ctx context.Context, ms *model.MicroServiceMetricsSet) {
 	var alerts []*model.AlertEvent
+	g.releaseSilenced()
 	
 	// 获取状态管理器
 	var sm *state.StateManager
@@ -114,58 +203,40 @@ func (g *Generator) ProcessMicroserviceMetrics(ctx context.Context, ms *model.Mi
 		sm = g.trendAnalyzer.stateManager
 	}
 	
-	// 1. 阈值告警检查（已经发生的故障）
-	// 处理节点指标
-	for _, nodeMetrics := range ms.NodeMetrics {
-		var nodeAlerts []*model.AlertEvent
-		if sm != nil {
-			nodeAlerts = CheckNodeThresholdsWithState(&nodeMetrics, sm)
-		} else {
-			nodeAlerts = CheckNodeThresholds(&nodeMetrics)
-		}
-		alerts = append(alerts, nodeAlerts...)
+	// 按实体收集告警：补充所属实体，记录触发中的告警（健康分按实体统计）
+	add := func(layer, id string, entityAlerts []*model.AlertEvent) {
+		labelAlerts(layer, id, entityAlerts)
+		recordAlerts(sm, layer, id, entityAlerts)
+		alerts = append(alerts, entityAlerts...)
 	}
 	
-	// 处理容器指标
-	for _, containerMetrics := range ms.ContainerMetrics {
-		var containerAlerts []*model.AlertEvent
-		if sm != nil {
-			containerAlerts = CheckContainerThresholdsWithState(&containerMetrics, sm)
-		} else {
-			containerAlerts = CheckContainerThresholds(&containerMetrics)
-		}
-		alerts = append(alerts, containerAlerts...)
+	// 1. 阈值告警检查（已经发生的故障），按规则集评估
+	rules := g.Rules()
+	for i := range ms.NodeMetrics {
+		add(LayerNode, ms.NodeMetrics[i].ID, rules.EvaluateNode(&ms.NodeMetrics[i], sm))
 	}
-	
-	// 处理服务指标
-	for _, serviceMetrics := range ms.ServiceMetrics {
-		var serviceAlerts []*model.AlertEvent
-		if sm != nil {
-			serviceAlerts = CheckServiceThresholdsWithState(&serviceMetrics, sm)
-		} else {
-			serviceAlerts = CheckServiceThresholds(&serviceMetrics)
-		}
-		alerts = append(alerts, serviceAlerts...)
+	for i := range ms.ContainerMetrics {
+		add(LayerContainer, ms.ContainerMetrics[i].ID, rules.EvaluateContainer(&ms.ContainerMetrics[i], sm))
+	}
+	for i := range ms.ServiceMetrics {
+		add(LayerService, ms.ServiceMetrics[i].ID, rules.EvaluateService(&ms.ServiceMetrics[i], sm))
 	}
 	
 	// 2. 趋势告警检查（即将发生的故障）
 	if g.trendAnalyzer != nil {
 		// 分析节点趋势
 		for _, nodeMetrics := range ms.NodeMetrics {
-			trendAlerts := g.trendAnalyzer.AnalyzeNodeTrends(ctx, nodeMetrics.ID)
-			alerts = append(alerts, trendAlerts...)
+			add(LayerNode, nodeMetrics.ID, g.trendAnalyzer.AnalyzeNodeTrends(ctx, nodeMetrics.ID))
 		}
 		
 		// 分析容器趋势
 		for _, containerMetrics := range ms.ContainerMetrics {
-			trendAlerts := g.trendAnalyzer.AnalyzeContainerTrends(ctx, containerMetrics.ID)
-			alerts = append(alerts, trendAlerts...)
+			add(LayerContainer, containerMetrics.ID, g.trendAnalyzer.AnalyzeContainerTrends(ctx, containerMetrics.ID))
 		}
 		
 		// 分析服务趋势
 		for _, serviceMetrics := range ms.ServiceMetrics {
-			trendAlerts := g.trendAnalyzer.AnalyzeServiceTrends(ctx, serviceMetrics.ID)
-			alerts = append(alerts, trendAlerts...)
+			add(LayerService, serviceMetrics.ID, g.trendAnalyzer.AnalyzeServiceTrends(ctx, serviceMetrics.ID))
 		}
 	}
 	
@@ -180,6 +251,19 @@ func (g *Generator) outputAlerts(alerts []*model.AlertEvent) {
 	// 告警压缩：去重和合并
 	alerts = g.deduplicateAlerts(alerts)
 	
+	// 静默：匹配的触发告警只输出到控制台，不转发到故障诊断
+	forward := g.applySilences(alerts)
+	
+	// 关联：时间窗内共享标签的告警归并为关联事件，成员告警由事件父告警代为转发
+	var incidents []*model.AlertEvent
+	if c := g.correlator.Load(); c != nil {
+		incidents = c.Correlate(alerts, time.Now())
+		if !c.Config().ForwardMembers {
+			forward = dropIncidentMembers(forward)
+		}
+		forward = append(forward, incidents...)
+	}
+	
 	// 过滤掉恢复告警（resolved状态），只输出 firing 告警
 	var firingAlerts []*model.AlertEvent
 	for _, alert := range alerts {
@@ -226,17 +310,15 @@ func (g *Generator) outputAlerts(alerts []*model.AlertEvent) {
 			}
 		}
 		
-		fmt.Println("==============================\n")
+		fmt.Println("==============================")
+		fmt.Println()
+	}
+	for _, incident := range incidents {
+		fmt.Printf("[告警关联] %s %s\n", incident.Source, incident.Message)
 	}
 	
 	// 发送告警到故障诊断模块（如果已配置）
-	if g.alertAdapter != nil {
-		if err := g.alertAdapter.SendAlerts(alerts); err != nil {
-			fmt.Printf("发送告警到故障诊断模块失败: %v\n", err)
-		} else {
-			fmt.Printf("已发送 %d 个告警到故障诊断模块\n", len(alerts))
-		}
-	}
+	g.sendToDiagnosis(forward)
 
 	// TODO: 这里还可以将告警发送到：
 	// 1. 消息队列 (MQ / etcd)
@@ -245,6 +327,104 @@ func (g *Generator) outputAlerts(alerts []*model.AlertEvent) {
 	// 4. 告警通知系统（邮件、短信等）
 }
 
+// sendToDiagnosis 发送告警到故障诊断模块（如果已配置）
+func (g *Generator) sendToDiagnosis(alerts []*model.AlertEvent) {
+	if g.alertAdapter == nil || len(alerts) == 0 {
+		return
+	}
+	if err := g.alertAdapter.SendAlerts(alerts); err != nil {
+		fmt.Printf("发送告警到故障诊断模块失败: %v\n", err)
+	} else {
+		fmt.Printf("已发送 %d 个告警到故障诊断模块\n", len(alerts))
+	}
+}
+
+// applySilences 拦截匹配静默规则的触发告警（Metadata 标注 silenced_by），返回需要转发的告警
+// 被拦截的告警记录下来，静默结束时仍在触发则补发；触发从未转发过的告警，恢复也不转发
+func (g *Generator) applySilences(alerts []*model.AlertEvent) []*model.AlertEvent {
+	store := g.silences.Load()
+	
+	g.suppressMu.Lock()
+	defer g.suppressMu.Unlock()
+	
+	forward := make([]*model.AlertEvent, 0, len(alerts))
+	for _, alert := range alerts {
+		key := alert.AlertID + "|" + alert.Source
+		if store != nil && alert.IsFiring() {
+			if id := store.Match(alert); id != "" {
+				store.recordSilenced(id)
+				alert.Metadata = withMetadata(alert.Metadata, "silenced_by", id)
+				if g.suppressed == nil {
+					g.suppressed = make(map[string]*model.AlertEvent)
+				}
+				g.suppressed[key] = alert
+				continue
+			}
+		}
+		if alert.IsResolved() {
+			_, held := g.suppressed[key]
+			delete(g.suppressed, key)
+			// 触发被静默拦截、未转发过的告警，恢复也不转发
+			if held && !g.forwarded[key] {
+				continue
+			}
+			delete(g.forwarded, key)
+		} else {
+			delete(g.suppressed, key)
+			g.markForwarded(key)
+		}
+		forward = append(forward, alert)
+	}
+	return forward
+}
+
+// markForwarded 记录已转发的触发告警；调用方持有 suppressMu
+func (g *Generator) markForwarded(key string) {
+	if g.forwarded == nil {
+		g.forwarded = make(map[string]bool)
+	}
+	g.forwarded[key] = true
+}
+
+// releaseSilenced 静默结束后补发仍在触发的被拦截告警
+func (g *Generator) releaseSilenced() {
+	store := g.silences.Load()
+	
+	g.suppressMu.Lock()
+	var released []*model.AlertEvent
+	for key, alert := range g.suppressed {
+		if store != nil && store.Match(alert) != "" {
+			continue
+		}
+		delete(g.suppressed, key)
+		g.markForwarded(key)
+		released = append(released, alert)
+	}
+	g.suppressMu.Unlock()
+	
+	if len(released) == 0 {
+		return
+	}
+	for i, alert := range released {
+		cp := *alert
+		cp.Metadata = withMetadata(alert.Metadata, "silence_expired", alert.Metadata["silenced_by"])
+		delete(cp.Metadata, "silenced_by")
+		released[i] = &cp
+	}
+	fmt.Printf("[告警静默] 静默结束，补发 %d 个仍在触发的告警\n", len(released))
+	g.sendToDiagnosis(released)
+}
+
+// withMetadata 复制元数据并设置一项（告警的 Metadata 可能与同一实体的其他告警共用）
+func withMetadata(metadata map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	cp := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		cp[k] = v
+	}
+	cp[key] = value
+	return cp
+}
+
 // printAlert 打印单个告警
 func (g *Generator) printAlert(alert *model.AlertEvent) {
 	serviceName := ""
@@ -259,6 +439,12 @@ func (g *Generator) printAlert(alert *model.AlertEvent) {
 	if serviceName != "" {
 		fmt.Printf("    服务名: %s\n", serviceName)
 	}
+	if id, ok := alert.Metadata["incident"].(string); ok {
+		fmt.Printf("    关联事件: %s\n", id)
+	}
+	if id, ok := alert.Metadata["silenced_by"].(string); ok {
+		fmt.Printf("    已静默: %s（不转发故障诊断）\n", id)
+	}
 	fmt.Printf("    消息: %s\n", alert.Message)
 	fmt.Printf("    指标值: %.2f\n", alert.MetricValue)
 	fmt.Printf("    时间戳: %d\n\n", alert.Timestamp)
@@ -266,12 +452,13 @@ func (g *Generator) printAlert(alert *model.AlertEvent) {
 
 // deduplicateAlerts 告警去重
 func (g *Generator) deduplicateAlerts(alerts []*model.AlertEvent) []*model.AlertEvent {
-	// 简单去重：基于 Source + Type + FaultCode
+	// 简单去重：基于 AlertID + Source + Type + FaultCode
+	// 同组规则（如三轴动量轮）共用来源与故障码，需按告警ID区分
 	seen := make(map[string]bool)
 	var result []*model.AlertEvent
 	
 	for _, alert := range alerts {
-		key := fmt.Sprintf("%s-%s-%s", alert.Source, alert.Type, alert.FaultCode)
+		key := fmt.Sprintf("%s-%s-%s-%s", alert.AlertID, alert.Source, alert.Type, alert.FaultCode)
 		if !seen[key] {
 			seen[key] = true
 			result = append(result, alert)
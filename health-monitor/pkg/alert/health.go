@@ -0,0 +1,591 @@
+/*
+健康分
+
+按实体计算 0~100 的健康分（业务组件、ECSM 节点/容器/服务），满分 100 逐项扣分：
+
+	告警    触发中的告警按严重程度扣分（critical 40 / warning 15 / info 5）
+	趋势    趋势预测告警（尚未越限）扣 trend 分（20），越限在即扣满，预计在外推时长末端越限扣一半
+	裕度    规则字段的最小限值裕度低于 margin_low（0.2）时扣分，到达限值扣满 margin 分（20）
+	新鲜度  遥测数据超时（见 watchdog.go）扣 stale 分（30），遥测中断告警本身不再重复扣分
+
+裕度 = 数值到较近限值的距离 / 正常范围半宽（outside、catalog），或到比较值的距离 / |比较值|（> >= < <=），
+1 为范围中心、0 为限值处；不含带生效条件的规则与计数器规则。触发中的告警由 Generator 输出时按层级与实体记录到 StateManager。
+
+健康分低于 degraded（80）为亚健康，低于 unhealthy（60）为不健康。
+系统健康分 = 各类实体的平均分（entities 可按实体设置权重，0 为不参与）按类别权重 weights 加权平均，没有实体的类别不参与。
+
+结果与历史保存在 StateManager（见 state/health.go），供管理接口、健康报告与故障修复的节点选择使用。配置示例：
+
+	{"penalties": {"critical": 40, "stale": 50}, "margin_low": 0.2, "degraded": 80, "unhealthy": 60,
+	 "weights": {"business": 4, "service": 3, "node": 2, "container": 1}, "entities": {"business/0x03": 2},
+	 "history": "24h", "history_interval": "1m"}
+*/
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+	"health-monitor/pkg/telemetry"
+)
+
+// 健康等级
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+)
+
+// 默认参数
+const (
+	DefaultHealthMarginLow = 0.2
+	DefaultHealthDegraded  = 80.0
+	DefaultHealthUnhealthy = 60.0
+)
+
+// 扣分项（告警按严重程度，其余按类别）
+const (
+	healthTrend  = "trend"
+	healthMargin = "margin"
+	healthStale  = "stale"
+)
+
+// defaultHealthPenalties 默认扣分
+var defaultHealthPenalties = map[string]float64{
+	string(model.SeverityCritical): 40,
+	string(model.SeverityWarning):  15,
+	string(model.SeverityInfo):     5,
+	healthTrend:                    20,
+	healthMargin:                   20,
+	healthStale:                    30,
+}
+
+// defaultHealthWeights 系统健康分的默认类别权重
+var defaultHealthWeights = map[string]float64{
+	LayerBusiness:  4,
+	LayerService:   3,
+	LayerNode:      2,
+	LayerContainer: 1,
+}
+
+// healthLayers 参与评估的层级（按报告顺序）
+var healthLayers = []string{LayerBusiness, LayerNode, LayerContainer, LayerService}
+
+// healthKindNames 报告中的类别名称
+var healthKindNames = map[string]string{
+	LayerBusiness:  "业务组件",
+	LayerNode:      "节点",
+	LayerContainer: "容器",
+	LayerService:   "服务",
+}
+
+// healthLevelNames 报告中的健康等级名称
+var healthLevelNames = map[string]string{
+	HealthHealthy:   "健康",
+	HealthDegraded:  "亚健康",
+	HealthUnhealthy: "不健康",
+}
+
+// severityNames 扣分原因中的严重程度名称
+var severityNames = map[string]string{
+	string(model.SeverityCritical): "严重",
+	string(model.SeverityWarning):  "警告",
+	string(model.SeverityInfo):     "信息",
+}
+
+// HealthConfig 健康分配置
+type HealthConfig struct {
+	Penalties       map[string]float64 `json:"penalties,omitempty"`        // 扣分：critical / warning / info / trend / margin / stale
+	MarginLow       float64            `json:"margin_low,omitempty"`       // 限值裕度低于此值开始扣分（0~1）
+	Degraded        float64            `json:"degraded,omitempty"`         // 低于此分为亚健康
+	Unhealthy       float64            `json:"unhealthy,omitempty"`        // 低于此分为不健康
+	Weights         map[string]float64 `json:"weights,omitempty"`          // 系统健康分的类别权重（business / node / container / service）
+	Entities        map[string]float64 `json:"entities,omitempty"`         // 实体权重（类别/ID，如 business/0x03、node/master），默认 1
+	History         string             `json:"history,omitempty"`          // 历史保留时长，默认 24h
+	HistoryInterval string             `json:"history_interval,omitempty"` // 历史记录间隔（每个间隔保留最低分），默认 1m
+
+	retention time.Duration
+	interval  time.Duration
+}
+
+// DefaultHealthConfig 默认配置
+func DefaultHealthConfig() *HealthConfig {
+	cfg := &HealthConfig{}
+	cfg.Validate() // 全部为默认值，不会失败
+	return cfg
+}
+
+// ParseHealthConfig 解析并校验健康分配置 JSON
+func ParseHealthConfig(data []byte) (*HealthConfig, error) {
+	var cfg HealthConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析健康分配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("健康分配置校验失败: %w", err)
+	}
+	return &cfg, nil
+}
+
+// LoadHealthConfig 从文件加载健康分配置
+func LoadHealthConfig(path string) (*HealthConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取健康分配置文件失败: %w", err)
+	}
+	return ParseHealthConfig(data)
+}
+
+// Validate 校验配置，未配置的扣分、权重取默认值
+func (c *HealthConfig) Validate() error {
+	penalties := make(map[string]float64, len(defaultHealthPenalties))
+	for k, v := range defaultHealthPenalties {
+		penalties[k] = v
+	}
+	for k, v := range c.Penalties {
+		if _, ok := defaultHealthPenalties[k]; !ok {
+			return fmt.Errorf("未知扣分项 %q", k)
+		}
+		if v < 0 || v > 100 {
+			return fmt.Errorf("扣分项 %s 须在 0~100 之间", k)
+		}
+		penalties[k] = v
+	}
+	c.Penalties = penalties
+
+	if c.MarginLow == 0 {
+		c.MarginLow = DefaultHealthMarginLow
+	}
+	if c.MarginLow < 0 || c.MarginLow > 1 {
+		return fmt.Errorf("margin_low 须在 0~1 之间")
+	}
+	if c.Degraded == 0 {
+		c.Degraded = DefaultHealthDegraded
+	}
+	if c.Unhealthy == 0 {
+		c.Unhealthy = DefaultHealthUnhealthy
+	}
+	if c.Unhealthy < 0 || c.Unhealthy > c.Degraded || c.Degraded > 100 {
+		return fmt.Errorf("须满足 0 <= unhealthy <= degraded <= 100")
+	}
+
+	weights := make(map[string]float64, len(defaultHealthWeights))
+	for k, v := range defaultHealthWeights {
+		weights[k] = v
+	}
+	for k, v := range c.Weights {
+		if _, ok := defaultHealthWeights[k]; !ok {
+			return fmt.Errorf("weights: 未知类别 %q", k)
+		}
+		if v < 0 {
+			return fmt.Errorf("weights: %s 权重不能为负数", k)
+		}
+		weights[k] = v
+	}
+	c.Weights = weights
+
+	entities := make(map[string]float64, len(c.Entities))
+	for key, w := range c.Entities {
+		kind, id, _ := strings.Cut(key, "/")
+		if _, ok := defaultHealthWeights[kind]; !ok || id == "" {
+			return fmt.Errorf("entities %q 须为 类别/ID（business、node、container、service）", key)
+		}
+		if kind == LayerBusiness {
+			comp, err := strconv.ParseUint(id, 0, 8)
+			if err != nil {
+				return fmt.Errorf("entities 业务组件编号 %q 非法", id)
+			}
+			if _, ok := businessTypes[uint8(comp)]; !ok {
+				return fmt.Errorf("entities: 未知业务组件编号 %s", id)
+			}
+			id = businessEntity(uint8(comp))
+		}
+		if w < 0 {
+			return fmt.Errorf("entities: %s 权重不能为负数", key)
+		}
+		entities[kind+"/"+id] = w
+	}
+	c.Entities = entities
+
+	var err error
+	if c.retention, err = healthDuration("history", c.History, state.DefaultHealthRetention); err != nil {
+		return err
+	}
+	if c.interval, err = healthDuration("history_interval", c.HistoryInterval, state.DefaultHealthInterval); err != nil {
+		return err
+	}
+	if c.interval < time.Second || c.interval > c.retention {
+		return fmt.Errorf("history_interval 须在 1s 与 history 之间")
+	}
+	return nil
+}
+
+func healthDuration(name, s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s %q 非法", name, s)
+	}
+	return d, nil
+}
+
+// level 健康等级
+func (c *HealthConfig) level(score float64) string {
+	switch {
+	case score < c.Unhealthy:
+		return HealthUnhealthy
+	case score < c.Degraded:
+		return HealthDegraded
+	}
+	return HealthHealthy
+}
+
+// entityWeight 实体在所属类别平均分中的权重
+func (c *HealthConfig) entityWeight(kind, id string) float64 {
+	if w, ok := c.Entities[kind+"/"+id]; ok {
+		return w
+	}
+	return 1
+}
+
+// businessEntity 业务组件的实体ID（与派生参数、告警记录一致）
+func businessEntity(component uint8) string {
+	return fmt.Sprintf("0x%02X", component)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              告警归属
+////////////////////////////////////////////////////////////////////////////////
+
+// recordAlerts 将 layer/entity 的告警触发、恢复同步到 StateManager，恢复告警只需告警ID与来源
+func recordAlerts(sm *state.StateManager, layer, entity string, alerts []*model.AlertEvent) {
+	if sm == nil {
+		return
+	}
+	for _, a := range alerts {
+		active := state.ActiveAlert{
+			AlertID:  a.AlertID,
+			Source:   a.Source,
+			Layer:    layer,
+			Entity:   entity,
+			Severity: string(a.Severity),
+			Type:     a.Type,
+			Message:  a.Message,
+			Updated:  a.Timestamp,
+		}
+		if info, ok := a.Metadata["trend"].(map[string]interface{}); ok {
+			active.Predicted = true
+			active.ETA, _ = info["eta"].(float64)
+			if horizon, ok := info["horizon"].(int64); ok {
+				active.Horizon = float64(horizon)
+			}
+		}
+		sm.RecordAlert(active, a.IsFiring())
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              限值裕度
+////////////////////////////////////////////////////////////////////////////////
+
+// fieldMargin 一个字段的限值裕度
+type fieldMargin struct {
+	name   string
+	margin float64
+}
+
+// margin 数值的限值裕度（1 为范围中心，0 为限值处，越限为负）；没有可比较的限值时返回 false
+func (r *Rule) margin(v float64, p *telemetry.Parameter) (float64, bool) {
+	switch r.Op {
+	case OpCatalog:
+		if p == nil || len(p.Valid) > 0 {
+			return 0, false
+		}
+		return rangeMargin(v, p.Limits())
+	case OpOutside:
+		return rangeMargin(v, r.limits())
+	case OpGT, OpGE, OpLT, OpLE:
+		limit, ok := r.Value.(float64)
+		if !ok || limit == 0 {
+			return 0, false
+		}
+		if r.Op == OpLT || r.Op == OpLE {
+			return (v - limit) / math.Abs(limit), true
+		}
+		return (limit - v) / math.Abs(limit), true
+	}
+	return 0, false
+}
+
+// rangeMargin 到较近限值的距离与正常范围半宽之比（未定义黄线的一侧取红线），单侧范围返回 false
+func rangeMargin(v float64, l telemetry.Limits) (float64, bool) {
+	lo, hi := l.Min, l.Max
+	if lo == nil {
+		lo = l.HardMin
+	}
+	if hi == nil {
+		hi = l.HardMax
+	}
+	if lo == nil || hi == nil || *hi <= *lo {
+		return 0, false
+	}
+	return math.Min(v-*lo, *hi-v) / ((*hi - *lo) / 2), true
+}
+
+// lowestMargin 实体各规则字段中最小的限值裕度（不含带生效条件的规则与计数器规则）
+func (rs *RuleSet) lowestMargin(t ruleTarget) (fieldMargin, bool) {
+	var lowest fieldMargin
+	found := false
+	for _, r := range rs.byLayer[t.layer] {
+		if (r.Layer == LayerBusiness && r.Component != t.component) || len(r.When) > 0 || r.rate > 0 {
+			continue
+		}
+		for _, fv := range r.values(t.data) {
+			v, ok := fv.value.(float64)
+			if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			p := r.param(t, fv.field)
+			m, ok := r.margin(v, p)
+			if !ok || (found && m >= lowest.margin) {
+				continue
+			}
+			name := r.Name
+			if name == "" && p != nil {
+				name = p.Name
+			}
+			if name == "" {
+				name = fv.field
+			}
+			lowest, found = fieldMargin{name: name, margin: m}, true
+		}
+	}
+	return lowest, found
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              健康分计算
+////////////////////////////////////////////////////////////////////////////////
+
+// HealthScorer 健康分计算器
+type HealthScorer struct {
+	sm     *state.StateManager
+	config atomic.Pointer[HealthConfig]
+	rules  atomic.Pointer[RuleSet] // 计算限值裕度的规则集（未设置时使用全局默认规则集）
+}
+
+// HealthReport 一次评估的结果
+type HealthReport struct {
+	System   state.HealthScore   `json:"system"`
+	Entities []state.HealthScore `json:"entities"`
+}
+
+// NewHealthScorer 创建健康分计算器（使用默认配置）
+func NewHealthScorer(sm *state.StateManager) *HealthScorer {
+	h := &HealthScorer{sm: sm}
+	h.SetConfig(DefaultHealthConfig())
+	return h
+}
+
+// SetConfig 替换配置（运行时可替换），同时设置 StateManager 的历史保留时长与记录间隔
+func (h *HealthScorer) SetConfig(cfg *HealthConfig) {
+	h.config.Store(cfg)
+	h.sm.SetHealthHistory(cfg.retention, cfg.interval)
+}
+
+// Config 返回当前配置
+func (h *HealthScorer) Config() *HealthConfig {
+	return h.config.Load()
+}
+
+// SetRules 设置计算限值裕度的规则集，rs 为 nil 时恢复使用全局默认规则集
+func (h *HealthScorer) SetRules(rs *RuleSet) {
+	h.rules.Store(rs)
+}
+
+// Rules 返回计算限值裕度的规则集
+func (h *HealthScorer) Rules() *RuleSet {
+	if rs := h.rules.Load(); rs != nil {
+		return rs
+	}
+	return DefaultRules()
+}
+
+// ScoreBusiness 计算并保存一个业务组件的健康分（业务层 Dispatcher 每次收到指标后调用），组件没有数据时返回 false
+func (h *HealthScorer) ScoreBusiness(component uint8, now time.Time) (state.HealthScore, bool) {
+	m, ok := h.sm.GetLatestState(state.MetricTypeBusiness, string(rune(component)))
+	if !ok {
+		return state.HealthScore{}, false
+	}
+	s := h.score(h.Config(), h.Rules(), LayerBusiness, m, groupAlerts(h.sm.GetFiringAlerts(LayerBusiness)), now)
+	h.sm.UpdateHealthScore(s)
+	return s, true
+}
+
+// Score 计算并保存 layers 层级（为空时为全部层级）全部实体的健康分，按层级、ID 排序返回
+func (h *HealthScorer) Score(now time.Time, layers ...string) []state.HealthScore {
+	if len(layers) == 0 {
+		layers = healthLayers
+	}
+	cfg, rs := h.Config(), h.Rules()
+	alerts := groupAlerts(h.sm.GetFiringAlerts(""))
+
+	var scores []state.HealthScore
+	for _, layer := range layers {
+		typ := state.MetricTypeBusiness
+		if layer != LayerBusiness {
+			typ = entityLayers[layer]
+		}
+		var layerScores []state.HealthScore
+		for _, m := range h.sm.GetAllLatestStates(typ) {
+			s := h.score(cfg, rs, layer, m, alerts, now)
+			h.sm.UpdateHealthScore(s)
+			layerScores = append(layerScores, s)
+		}
+		sort.Slice(layerScores, func(i, j int) bool { return layerScores[i].ID < layerScores[j].ID })
+		scores = append(scores, layerScores...)
+	}
+	return scores
+}
+
+// Evaluate 计算并保存全部实体与系统的健康分
+func (h *HealthScorer) Evaluate(now time.Time) *HealthReport {
+	report := &HealthReport{Entities: h.Score(now)}
+	report.System = h.Config().systemScore(report.Entities, now)
+	h.sm.UpdateHealthScore(report.System)
+	return report
+}
+
+// groupAlerts 触发中的告警按 层级/实体 分组
+func groupAlerts(alerts []state.ActiveAlert) map[string][]state.ActiveAlert {
+	groups := make(map[string][]state.ActiveAlert)
+	for _, a := range alerts {
+		key := a.Layer + "/" + a.Entity
+		groups[key] = append(groups[key], a)
+	}
+	return groups
+}
+
+// score 计算一个实体的健康分
+func (h *HealthScorer) score(cfg *HealthConfig, rs *RuleSet, layer string, m state.Metric, alerts map[string][]state.ActiveAlert, now time.Time) state.HealthScore {
+	t := ruleTarget{layer: layer, data: m.GetData(), id: m.GetID()}
+	if bm, ok := m.GetData().(*model.BusinessMetrics); ok {
+		t.component, t.data, t.id = bm.ComponentType, bm.Data, businessEntity(bm.ComponentType)
+	}
+	s := state.HealthScore{Kind: layer, ID: t.id, Factors: map[string]float64{}, Stale: state.IsStale(m), Timestamp: now.Unix()}
+
+	for _, a := range alerts[layer+"/"+t.id] {
+		s.Alerts++
+		if a.Predicted {
+			// 越限在即扣满，预计在外推时长末端越限扣一半
+			urgency := 1.0
+			if a.Horizon > 0 {
+				urgency -= 0.5 * math.Min(math.Max(a.ETA/a.Horizon, 0), 1)
+			}
+			s.Factors[healthTrend] += cfg.Penalties[healthTrend] * urgency
+			s.Reasons = append(s.Reasons, "趋势预测: "+a.Message)
+			continue
+		}
+		s.Factors["alerts"] += cfg.Penalties[a.Severity]
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%s告警: %s", severityNames[a.Severity], a.Message))
+	}
+	if rs != nil {
+		if fm, ok := rs.lowestMargin(t); ok && fm.margin < cfg.MarginLow {
+			s.Factors[healthMargin] = cfg.Penalties[healthMargin] * math.Min((cfg.MarginLow-fm.margin)/cfg.MarginLow, 1)
+			s.Reasons = append(s.Reasons, fmt.Sprintf("%s距限值裕度%.0f%%", fm.name, math.Max(fm.margin, 0)*100))
+		}
+	}
+	if s.Stale {
+		s.Factors[healthStale] = cfg.Penalties[healthStale]
+		s.Reasons = append(s.Reasons, "遥测数据超时")
+	}
+
+	score := 100.0
+	for k, p := range s.Factors {
+		s.Factors[k] = roundScore(p)
+		score -= p
+	}
+	if len(s.Factors) == 0 {
+		s.Factors = nil
+	}
+	s.Score = roundScore(math.Max(score, 0))
+	s.Level = cfg.level(s.Score)
+	return s
+}
+
+// systemScore 各类实体的加权平均分按类别权重加权平均，Factors 为各类别的平均分，Reasons 列出不健康、亚健康的实体
+func (c *HealthConfig) systemScore(scores []state.HealthScore, now time.Time) state.HealthScore {
+	type kindSum struct{ sum, weight float64 }
+	kinds := make(map[string]*kindSum)
+	sys := state.HealthScore{Kind: state.HealthKindSystem, Factors: map[string]float64{}, Timestamp: now.Unix()}
+
+	var flagged []state.HealthScore
+	for _, s := range scores {
+		sys.Alerts += s.Alerts
+		if s.Level != HealthHealthy {
+			flagged = append(flagged, s)
+		}
+		w := c.entityWeight(s.Kind, s.ID)
+		if w == 0 {
+			continue
+		}
+		k, ok := kinds[s.Kind]
+		if !ok {
+			k = &kindSum{}
+			kinds[s.Kind] = k
+		}
+		k.sum += w * s.Score
+		k.weight += w
+	}
+
+	var sum, weight float64
+	for kind, k := range kinds {
+		avg := k.sum / k.weight
+		sys.Factors[kind] = roundScore(avg)
+		sum += c.Weights[kind] * avg
+		weight += c.Weights[kind]
+	}
+	sys.Score = 100
+	if weight > 0 {
+		sys.Score = roundScore(sum / weight)
+	} else {
+		sys.Factors = nil
+	}
+	sys.Level = c.level(sys.Score)
+
+	sort.SliceStable(flagged, func(i, j int) bool { return flagged[i].Score < flagged[j].Score })
+	for _, s := range flagged {
+		sys.Reasons = append(sys.Reasons, fmt.Sprintf("%s %s %s %.1f", healthKindNames[s.Kind], s.ID, healthLevelNames[s.Level], s.Score))
+	}
+	return sys
+}
+
+// roundScore 保留一位小数
+func roundScore(x float64) float64 {
+	return math.Round(x*10) / 10
+}
+
+// Summary 健康报告摘要：系统健康分与最低的 n 个不健康、亚健康实体
+func (r *HealthReport) Summary(n int) string {
+	text := fmt.Sprintf("系统健康分 %.1f（%s，%d 个实体，%d 个触发中告警）",
+		r.System.Score, healthLevelNames[r.System.Level], len(r.Entities), r.System.Alerts)
+	reasons := r.System.Reasons
+	if len(reasons) == 0 {
+		return text
+	}
+	if len(reasons) > n {
+		reasons = append(reasons[:n:n], fmt.Sprintf("等 %d 个", len(r.System.Reasons)))
+	}
+	return text + "，" + strings.Join(reasons, "、")
+}
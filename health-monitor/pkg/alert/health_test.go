@@ -0,0 +1,186 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+const healthTestRules = `{"rules":[
+	{"alert_id":"BUS_V","layer":"business","component":3,"field":"BusVoltage","op":"outside","min":21,"max":29.4,"severity":"warning","type":"t","format":"%.1f"},
+	{"alert_id":"CPU","layer":"node","field":"cpu_percent","op":">","value":90,"severity":"critical","type":"t"}]}`
+
+// newHealthTest 使用相同规则集的告警生成器与健康分计算器
+func newHealthTest(t *testing.T) (*state.StateManager, *Generator, *HealthScorer) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, healthTestRules)
+	g := NewGeneratorWithStateManager(sm)
+	g.SetRules(rs)
+	h := NewHealthScorer(sm)
+	h.SetRules(rs)
+	return sm, g, h
+}
+
+func feedBusVoltage(t *testing.T, sm *state.StateManager, g *Generator, v float64) {
+	t.Helper()
+	bm := &model.BusinessMetrics{ComponentType: 0x03, Timestamp: time.Now().Unix(), Data: &model.PowerMetrics{BusVoltage: v}}
+	if err := sm.UpdateMetric(&state.BusinessMetric{Data: bm, Timestamp: bm.Timestamp}); err != nil {
+		t.Fatal(err)
+	}
+	g.ProcessBusinessMetrics(context.Background(), bm)
+}
+
+func feedNode(t *testing.T, sm *state.StateManager, g *Generator, id string, cpu float64) {
+	t.Helper()
+	n := model.NodeMetrics{ID: id, Status: "online", CPUUsage: cpu}
+	if err := sm.UpdateMetric(&state.NodeMetric{Data: &n, Timestamp: time.Now().Unix()}); err != nil {
+		t.Fatal(err)
+	}
+	g.ProcessMicroserviceMetrics(context.Background(), &model.MicroServiceMetricsSet{NodeMetrics: []model.NodeMetrics{n}})
+}
+
+// TestHealthScore 测试单个实体的扣分：限值裕度、触发中的告警、趋势预测、数据超时
+func TestHealthScore(t *testing.T) {
+	sm, g, h := newHealthTest(t)
+	score := func(v float64) state.HealthScore {
+		feedBusVoltage(t, sm, g, v)
+		s, ok := h.ScoreBusiness(0x03, time.Now())
+		if !ok || s.Kind != LayerBusiness || s.ID != "0x03" {
+			t.Fatalf("应计算组件健康分: %+v", s)
+		}
+		return s
+	}
+
+	if s := score(25); s.Score != 100 || s.Level != HealthHealthy || len(s.Factors) != 0 {
+		t.Errorf("范围中心应满分: %+v", s)
+	}
+
+	// 裕度 0.5/4.2 ≈ 0.12，扣 20 × (0.2-0.12)/0.2
+	s := score(21.5)
+	if s.Score != 91.9 || s.Factors["margin"] != 8.1 || len(s.Reasons) != 1 || !strings.HasSuffix(s.Reasons[0], "距限值裕度12%") {
+		t.Errorf("裕度扣分不符: %+v", s)
+	}
+
+	// 越限：告警 15 + 裕度扣满 20
+	s = score(20)
+	if s.Score != 65 || s.Level != HealthDegraded || s.Alerts != 1 || s.Factors["alerts"] != 15 || s.Factors["margin"] != 20 {
+		t.Errorf("越限扣分不符: %+v", s)
+	}
+	if a := sm.GetFiringAlerts(LayerBusiness); len(a) != 1 || a[0].AlertID != "BUS_V" || a[0].Entity != "0x03" || a[0].Since == 0 {
+		t.Errorf("应记录触发中的告警: %+v", a)
+	}
+
+	if s = score(25); s.Score != 100 || len(sm.GetFiringAlerts("")) != 0 {
+		t.Errorf("告警恢复后应满分: %+v", s)
+	}
+
+	// 预计在外推时长一半处越限，扣 20 × 0.75
+	sm.RecordAlert(state.ActiveAlert{AlertID: "TREND", Layer: LayerBusiness, Entity: "0x03", Severity: "warning",
+		Message: "母线电压预计越限", Predicted: true, ETA: 1800, Horizon: 3600}, true)
+	if s = score(25); s.Score != 85 || s.Factors["trend"] != 15 || s.Reasons[0] != "趋势预测: 母线电压预计越限" {
+		t.Errorf("趋势预测扣分不符: %+v", s)
+	}
+	sm.RecordAlert(state.ActiveAlert{AlertID: "TREND"}, false)
+
+	sm.SetStaleTimeout(state.MetricTypeBusiness, "", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if s, _ = h.ScoreBusiness(0x03, time.Now()); s.Score != 70 || !s.Stale || s.Reasons[0] != "遥测数据超时" {
+		t.Errorf("数据超时扣分不符: %+v", s)
+	}
+}
+
+// TestHealthSystemScore 测试系统健康分按类别、实体权重汇总，历史与快照
+func TestHealthSystemScore(t *testing.T) {
+	sm, g, h := newHealthTest(t)
+	cfg, err := ParseHealthConfig([]byte(`{"weights":{"business":1,"node":1},"entities":{"node/n2":3},"history":"10m"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetConfig(cfg)
+
+	feedBusVoltage(t, sm, g, 25)
+	feedNode(t, sm, g, "n1", 50)
+	feedNode(t, sm, g, "n2", 95) // 严重告警 40 + 裕度扣满 20
+
+	now := time.Unix(1700000040, 0)
+	report := h.Evaluate(now)
+	if len(report.Entities) != 3 || report.Entities[2].ID != "n2" || report.Entities[2].Score != 40 || report.Entities[2].Level != HealthUnhealthy {
+		t.Fatalf("实体健康分不符: %+v", report.Entities)
+	}
+	// 节点平均分 (100×1 + 40×3)/4 = 55，系统 (100 + 55)/2
+	sys := report.System
+	if sys.Score != 77.5 || sys.Level != HealthDegraded || sys.Factors["node"] != 55 || sys.Factors["business"] != 100 || sys.Alerts != 1 {
+		t.Errorf("系统健康分不符: %+v", sys)
+	}
+	if summary := report.Summary(3); summary != "系统健康分 77.5（亚健康，3 个实体，1 个触发中告警），节点 n2 不健康 40.0" {
+		t.Errorf("摘要不符: %s", summary)
+	}
+	if got, ok := sm.GetHealthScore(state.HealthKindSystem, ""); !ok || got.Score != 77.5 {
+		t.Errorf("应保存系统健康分: %+v", got)
+	}
+
+	// 同一记录间隔保留最低分，超过保留时长的历史删除
+	for i, v := range []float64{90, 80, 95, 60} {
+		ts := now.Unix() + []int64{0, 30, 60, 660}[i]
+		sm.UpdateHealthScore(state.HealthScore{Kind: LayerService, ID: "svc", Score: v, Timestamp: ts})
+	}
+	want := []state.HealthPoint{{T: now.Unix() + 60, Score: 95}, {T: now.Unix() + 660, Score: 60}}
+	if got := sm.GetHealthHistory(LayerService, "svc", time.Hour); !reflect.DeepEqual(got, want) {
+		t.Errorf("历史不符: %+v", got)
+	}
+
+	data, err := json.Marshal(sm.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot state.StateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	restored := newTestStateManager(t)
+	restored.RestoreSnapshot(&snapshot)
+	if got := restored.GetHealthScores(""); !reflect.DeepEqual(got, sm.GetHealthScores("")) || len(got) != 5 {
+		t.Errorf("健康分恢复不符: %+v", got)
+	}
+	if got := restored.GetHealthHistory(LayerService, "svc", time.Hour); !reflect.DeepEqual(got, want) {
+		t.Errorf("历史恢复不符: %+v", got)
+	}
+}
+
+// TestParseHealthConfig 测试健康分配置的默认值与校验
+func TestParseHealthConfig(t *testing.T) {
+	cfg, err := ParseHealthConfig([]byte(`{"penalties":{"stale":50},"entities":{"business/3":2}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Penalties["stale"] != 50 || cfg.Penalties["critical"] != 40 || cfg.Weights[LayerBusiness] != 4 || cfg.Entities["business/0x03"] != 2 {
+		t.Errorf("默认值合并不符: %+v", cfg)
+	}
+	if cfg.MarginLow != DefaultHealthMarginLow || cfg.retention != state.DefaultHealthRetention || cfg.interval != state.DefaultHealthInterval {
+		t.Errorf("默认参数不符: %+v", cfg)
+	}
+
+	cases := map[string]string{
+		"未知扣分项": `{"penalties":{"fatal":10}}`,
+		"扣分为负":  `{"penalties":{"critical":-1}}`,
+		"裕度范围":  `{"margin_low":1.5}`,
+		"等级阈值":  `{"degraded":50,"unhealthy":70}`,
+		"未知类别":  `{"weights":{"system":1}}`,
+		"权重为负":  `{"weights":{"node":-1}}`,
+		"实体格式":  `{"entities":{"n1":1}}`,
+		"未知组件":  `{"entities":{"business/0x99":1}}`,
+		"保留时长":  `{"history":"abc"}`,
+		"记录间隔":  `{"history":"1m","history_interval":"5m"}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseHealthConfig([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+}
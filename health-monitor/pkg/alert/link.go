@@ -0,0 +1,91 @@
+/*
+业务链路质量告警
+
+由 business.SequenceTracker 每个评估窗口结算一次丢包率，
+超过阈值时产生 LINK_QUALITY_ALERT（按组件区分），恢复到阈值以下时产生恢复告警。
+用于区分"遥测丢失"与"组件无数据"。
+*/
+package alert
+
+import (
+	"fmt"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// LinkQualityAlertID 链路质量告警ID
+const LinkQualityAlertID = "LINK_QUALITY_ALERT"
+
+// linkSource 链路质量告警源（按组件区分）
+func linkSource(lq *model.LinkQuality) string {
+	return fmt.Sprintf("link-0x%02X", lq.ComponentType)
+}
+
+// linkSeverity 丢包率超过阈值两倍时为严重
+func linkSeverity(lq *model.LinkQuality) model.AlertSeverity {
+	if lq.LossRate >= 2*lq.Threshold {
+		return model.SeverityCritical
+	}
+	return model.SeverityWarning
+}
+
+func linkMetadata(lq *model.LinkQuality) map[string]interface{} {
+	return map[string]interface{}{
+		"component_type": lq.ComponentType,
+		"received":       lq.Received,
+		"lost":           lq.Lost,
+		"duplicates":     lq.Duplicates,
+		"reordered":      lq.Reordered,
+		"wraps":          lq.Wraps,
+		"resets":         lq.Resets,
+		"threshold":      lq.Threshold,
+	}
+}
+
+// CheckLinkQuality 检查链路丢包率（无状态，仅触发告警）
+func CheckLinkQuality(lq *model.LinkQuality) []*model.AlertEvent {
+	if lq.LossRate <= lq.Threshold {
+		return nil
+	}
+	return []*model.AlertEvent{{
+		AlertID:     LinkQualityAlertID,
+		Type:        "link_degraded",
+		Status:      model.AlertStatusFiring,
+		Severity:    linkSeverity(lq),
+		Source:      linkSource(lq),
+		Message:     fmt.Sprintf("组件 0x%02X 遥测丢包率 %.1f%% 超过阈值 %.1f%%", lq.ComponentType, lq.LossRate*100, lq.Threshold*100),
+		Timestamp:   time.Now().Unix(),
+		MetricValue: lq.LossRate,
+		Metadata:    linkMetadata(lq),
+	}}
+}
+
+// CheckLinkQualityWithState 检查链路丢包率（支持恢复告警）
+func CheckLinkQualityWithState(lq *model.LinkQuality, sm *state.StateManager) []*model.AlertEvent {
+	isFiring := lq.LossRate > lq.Threshold
+	shouldSend, firing := sm.CheckAndUpdateAlertStateWithSource(LinkQualityAlertID, linkSource(lq), isFiring)
+	if !shouldSend {
+		return nil
+	}
+
+	alert := &model.AlertEvent{
+		AlertID:     LinkQualityAlertID,
+		Type:        "link_degraded",
+		Source:      linkSource(lq),
+		Timestamp:   time.Now().Unix(),
+		MetricValue: lq.LossRate,
+		Metadata:    linkMetadata(lq),
+	}
+	if firing {
+		alert.Status = model.AlertStatusFiring
+		alert.Severity = linkSeverity(lq)
+		alert.Message = fmt.Sprintf("组件 0x%02X 遥测丢包率 %.1f%% 超过阈值 %.1f%%", lq.ComponentType, lq.LossRate*100, lq.Threshold*100)
+	} else {
+		alert.Status = model.AlertStatusResolved
+		alert.Severity = model.SeverityInfo
+		alert.Message = fmt.Sprintf("组件 0x%02X 遥测链路已恢复: 丢包率 %.1f%%", lq.ComponentType, lq.LossRate*100)
+	}
+	return []*model.AlertEvent{alert}
+}
@@ -0,0 +1,527 @@
+/* 轨道周期基线
+
+热控温度、供电电流随轨道周期变化：光照区升温、阴影区降温，负载电流随加热器通断起伏。
+固定限值要么放得很宽（阴影区加热器失效时温度仍在限值内），要么在每个阴影区误报。
+规则集的 orbit_envelopes 为业务参数按轨道相位学习期望包络：
+
+	alert_id    告警ID（与规则、传感器组、异常检测器的 alert_id 不能重复）
+	component、field        业务组件编号与字段（写法同规则，可用 [*]、派生参数）
+	period      轨道周期（如 "94m"），或 "auto" 由历史自动估计
+	epoch       相位零点（Unix 秒，如某次进入阴影区的时间），默认 0
+	min_period、max_period  自动估计的周期范围，默认 80m ~ 130m
+	resolution、history     自动估计用的降采样间隔与序列时长，默认 1m、12h（须不小于 2 × max_period）
+	bins        每个周期划分的相位区间数，默认 36
+	k           包络半宽（标准差倍数），默认 4
+	min_std     标准差下限，默认为 max(|均值|, 1) 的千分之一
+	alpha       相位区间的 EWMA 平滑系数，默认 0.1（样本数不足 1/alpha 时为算术平均）
+	min_orbits  学满多少个周期后开始判定，默认 2
+	persistence 连续多少个样本越出（或回到）包络才改变状态，默认 3
+	name、unit、format、type（默认 orbit_envelope）、severity、fault_code、source（同异常检测器）
+
+相位 = ((t - epoch) mod period) / period。每个样本按相位落入一个区间，区间内学习均值与方差；
+判定时取该区间与相邻区间各自 均值 ± k × 标准差 的并集为包络（容忍周期误差与相位抖动），只用样本数足够的区间。
+越出包络的样本不参与学习，阴影区加热器失效这类持续偏离不会被学成新的正常。
+
+自动估计：按 resolution 降采样保留 history 时长的序列，覆盖两个 max_period 后去除线性趋势，
+在 [min_period, max_period] 内求自相关峰值（抛物线插值细化），相关系数不低于 0.5 时采用；
+估计值变化超过 0.5% 时按新周期用序列重建各区间。估计出周期之前不判定。
+
+基线（state.OrbitBaseline）由 StateManager 保存并随快照持久化，可用 ResetOrbitBaselines 删除后重新学习。
+告警 Metadata["orbit"] 带 period（秒）、phase（0~1）、bin、expected（区间均值）、lower、upper、orbits（已学习的周期数）。
+*/
+package alert
+
+import (
+	"fmt"
+	"math"
+	"text/template"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// OrbitEnvelopeType 轨道周期包络告警类型
+const OrbitEnvelopeType = "orbit_envelope"
+
+// 轨道周期基线默认参数
+const (
+	DefaultOrbitMinPeriod   = 80 * time.Minute
+	DefaultOrbitMaxPeriod   = 130 * time.Minute
+	DefaultOrbitResolution  = time.Minute
+	DefaultOrbitHistory     = 12 * time.Hour
+	DefaultOrbitBins        = 36
+	DefaultOrbitK           = 4.0
+	DefaultOrbitAlpha       = 0.1
+	DefaultOrbitMinOrbits   = 2
+	DefaultOrbitPersistence = 3
+)
+
+const (
+	orbitAuto          = "auto"
+	orbitMinBinCount   = 3    // 相位区间参与包络的最少样本数
+	orbitMinCorr       = 0.5  // 自动估计采用的最低自相关系数
+	orbitPeriodChange  = 5e-3 // 估计值相对变化超过此值时重建
+	orbitMaxSeriesSize = 5000 // 降采样序列最多点数（随快照持久化）
+)
+
+// OrbitEnvelope 按轨道相位学习期望包络的业务参数
+type OrbitEnvelope struct {
+	AlertID     string              `json:"alert_id"`              // 告警ID
+	Disabled    bool                `json:"disabled,omitempty"`    // 停用
+	Component   uint8               `json:"component"`             // 业务组件编号
+	Field       string              `json:"field"`                 // 字段
+	Period      string              `json:"period"`                // 轨道周期或 auto
+	Epoch       int64               `json:"epoch,omitempty"`       // 相位零点
+	MinPeriod   string              `json:"min_period,omitempty"`  // 自动估计的最短周期
+	MaxPeriod   string              `json:"max_period,omitempty"`  // 自动估计的最长周期
+	Resolution  string              `json:"resolution,omitempty"`  // 降采样间隔
+	History     string              `json:"history,omitempty"`     // 降采样序列时长
+	Bins        int                 `json:"bins,omitempty"`        // 相位区间数
+	K           float64             `json:"k,omitempty"`           // 包络半宽（标准差倍数）
+	MinStd      float64             `json:"min_std,omitempty"`     // 标准差下限
+	Alpha       float64             `json:"alpha,omitempty"`       // EWMA 平滑系数
+	MinOrbits   int                 `json:"min_orbits,omitempty"`  // 开始判定前学习的周期数
+	Persistence int                 `json:"persistence,omitempty"` // 改变状态需要的连续样本数
+	Name        string              `json:"name,omitempty"`        // 参数名，默认取参数库名称或字段名
+	Unit        string              `json:"unit,omitempty"`        // 单位
+	Format      string              `json:"format,omitempty"`      // 数值格式，默认最短表示
+	Type        string              `json:"type,omitempty"`        // 告警类型，默认 orbit_envelope
+	Severity    model.AlertSeverity `json:"severity,omitempty"`    // 严重程度，默认 warning
+	FaultCode   string              `json:"fault_code,omitempty"`  // 故障码
+	Source      string              `json:"source,omitempty"`      // 告警来源模板
+
+	sel        numericField
+	auto       bool
+	period     time.Duration
+	minPeriod  time.Duration
+	maxPeriod  time.Duration
+	resolution time.Duration
+	history    time.Duration
+	source     *template.Template
+}
+
+// orbitResult 一次判定使用的包络
+type orbitResult struct {
+	ready    bool // 包络是否可用（已学满）
+	period   float64
+	phase    float64
+	bin      int
+	orbits   float64
+	expected float64
+	lower    float64
+	upper    float64
+}
+
+// compileOrbitEnvelopes 校验轨道周期包络
+func (rs *RuleSet) compileOrbitEnvelopes() error {
+	ids := make(map[string]bool, len(rs.SensorGroups)+len(rs.Anomalies))
+	for _, g := range rs.SensorGroups {
+		ids[g.AlertID] = true
+	}
+	for _, d := range rs.Anomalies {
+		ids[d.AlertID] = true
+	}
+	for i := range rs.OrbitEnvelopes {
+		o := &rs.OrbitEnvelopes[i]
+		if o.AlertID == "" {
+			return fmt.Errorf("第 %d 个轨道周期包络缺少 alert_id", i+1)
+		}
+		if _, dup := rs.byID[o.AlertID]; dup || ids[o.AlertID] {
+			return fmt.Errorf("轨道周期包络 %s 与其他告警 alert_id 重复", o.AlertID)
+		}
+		ids[o.AlertID] = true
+		if err := o.compile(rs); err != nil {
+			return fmt.Errorf("轨道周期包络 %s: %w", o.AlertID, err)
+		}
+	}
+	return nil
+}
+
+func (o *OrbitEnvelope) compile(rs *RuleSet) error {
+	var err error
+	if o.sel, err = rs.numericField(LayerBusiness, o.Component, o.Field); err != nil {
+		return err
+	}
+
+	// 周期
+	duration := func(name, value string, def time.Duration) (time.Duration, error) {
+		if value == "" {
+			return def, nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("%s %q 非法", name, value)
+		}
+		return d, nil
+	}
+	o.auto = o.Period == orbitAuto
+	switch {
+	case o.Period == "":
+		return fmt.Errorf("缺少 period（轨道周期或 auto）")
+	case o.auto:
+		if o.minPeriod, err = duration("min_period", o.MinPeriod, DefaultOrbitMinPeriod); err != nil {
+			return err
+		}
+		if o.maxPeriod, err = duration("max_period", o.MaxPeriod, DefaultOrbitMaxPeriod); err != nil {
+			return err
+		}
+		if o.resolution, err = duration("resolution", o.Resolution, DefaultOrbitResolution); err != nil {
+			return err
+		}
+		if o.history, err = duration("history", o.History, DefaultOrbitHistory); err != nil {
+			return err
+		}
+		if o.minPeriod >= o.maxPeriod {
+			return fmt.Errorf("min_period 须小于 max_period")
+		}
+		if o.resolution < time.Second || o.resolution*4 > o.minPeriod {
+			return fmt.Errorf("resolution 须不小于 1s 且不大于 min_period 的 1/4")
+		}
+		if o.history < 2*o.maxPeriod {
+			return fmt.Errorf("history 须不小于 2 × max_period")
+		}
+		if o.history/o.resolution > orbitMaxSeriesSize {
+			return fmt.Errorf("history / resolution 不能超过 %d 点", orbitMaxSeriesSize)
+		}
+	default:
+		if o.MinPeriod != "" || o.MaxPeriod != "" || o.Resolution != "" || o.History != "" {
+			return fmt.Errorf("min_period、max_period、resolution、history 仅用于 period: auto")
+		}
+		if o.period, err = duration("period", o.Period, 0); err != nil {
+			return err
+		}
+		if o.period < time.Minute {
+			return fmt.Errorf("period 须不小于 1m")
+		}
+	}
+
+	// 包络参数
+	if o.Bins == 0 {
+		o.Bins = DefaultOrbitBins
+	}
+	if o.Bins < 4 || o.Bins > 360 {
+		return fmt.Errorf("bins 须在 4~360 之间")
+	}
+	if o.K == 0 {
+		o.K = DefaultOrbitK
+	}
+	if o.K < 0 || o.MinStd < 0 {
+		return fmt.Errorf("k、min_std 不能为负")
+	}
+	if o.Alpha == 0 {
+		o.Alpha = DefaultOrbitAlpha
+	}
+	if o.Alpha <= 0 || o.Alpha >= 1 {
+		return fmt.Errorf("alpha 须在 0~1 之间")
+	}
+	if o.MinOrbits == 0 {
+		o.MinOrbits = DefaultOrbitMinOrbits
+	}
+	if o.Persistence == 0 {
+		o.Persistence = DefaultOrbitPersistence
+	}
+	if o.MinOrbits < 1 || o.Persistence < 1 {
+		return fmt.Errorf("min_orbits、persistence 须不小于 1")
+	}
+
+	// 告警属性
+	if o.Type == "" {
+		o.Type = OrbitEnvelopeType
+	}
+	if o.Severity == "" {
+		o.Severity = model.SeverityWarning
+	}
+	if !validSeverity(o.Severity) {
+		return fmt.Errorf("未知严重程度 %q", o.Severity)
+	}
+	o.source, err = o.sel.sourceTemplate(LayerBusiness, o.Source)
+	return err
+}
+
+// minStd 标准差下限
+func (o *OrbitEnvelope) minStd(mean float64) float64 {
+	if o.MinStd > 0 {
+		return o.MinStd
+	}
+	return math.Max(math.Abs(mean), 1) * 1e-3
+}
+
+// learnBin 相位区间学习一个样本：样本数不足 1/alpha 时为算术平均，之后为 EWMA
+func (o *OrbitEnvelope) learnBin(bin *state.PhaseBin, x float64) {
+	bin.Count++
+	diff := x - bin.Mean
+	if float64(bin.Count) <= 1/o.Alpha {
+		bin.Mean += diff / float64(bin.Count)
+		bin.Variance += (diff*(x-bin.Mean) - bin.Variance) / float64(bin.Count)
+		return
+	}
+	incr := o.Alpha * diff
+	bin.Mean += incr
+	bin.Variance = (1 - o.Alpha) * (bin.Variance + diff*incr)
+}
+
+// binIndex 时间所在的相位与相位区间
+func binIndex(b *state.OrbitBaseline, ts int64) (float64, int) {
+	phase := math.Mod(float64(ts-b.Epoch), b.Period) / b.Period
+	if phase < 0 {
+		phase++
+	}
+	n := len(b.Bins)
+	i := int(phase * float64(n))
+	if i >= n {
+		i = n - 1
+	}
+	return phase, i
+}
+
+// update 在 StateManager 锁内学习一个样本并更新状态，包络可用时返回 true
+func (o *OrbitEnvelope) update(b *state.OrbitBaseline, field string, x float64, ts int64) (orbitResult, bool) {
+	if b.Field != field || len(b.Bins) != o.Bins || b.Epoch != o.Epoch || (!o.auto && b.Period != o.period.Seconds()) {
+		// 新建基线或检测器配置改变：重新学习
+		*b = state.OrbitBaseline{AlertID: b.AlertID, Source: b.Source, Field: field, Epoch: o.Epoch, Since: ts,
+			Bins: make([]state.PhaseBin, o.Bins)}
+		if !o.auto {
+			b.Period = o.period.Seconds()
+		}
+	}
+	if ts < b.Updated {
+		return orbitResult{}, false // 乱序的旧样本不学习
+	}
+	b.Updated = ts
+	if o.auto && o.track(b, x, ts) {
+		return orbitResult{}, false // 按新周期重建，本样本已在序列中学习
+	}
+	if b.Period <= 0 {
+		return orbitResult{}, false
+	}
+
+	res := o.envelope(b, ts)
+	level := ""
+	if res.ready {
+		switch {
+		case x > res.upper:
+			level = "above"
+		case x < res.lower:
+			level = "below"
+		}
+	}
+	switch {
+	case level == b.Level:
+		b.Candidate, b.Streak = "", 0
+	case level == b.Candidate:
+		b.Streak++
+	default:
+		b.Candidate, b.Streak = level, 1
+	}
+	if b.Streak >= o.Persistence {
+		b.Level, b.Candidate, b.Streak = level, "", 0
+	}
+	if level == "" {
+		o.learnBin(&b.Bins[res.bin], x)
+	}
+	return res, res.ready
+}
+
+// envelope 时间所在相位的包络（该区间与相邻区间的并集）
+func (o *OrbitEnvelope) envelope(b *state.OrbitBaseline, ts int64) orbitResult {
+	phase, i := binIndex(b, ts)
+	res := orbitResult{period: b.Period, phase: phase, bin: i, orbits: float64(ts-b.Since) / b.Period}
+	if res.orbits < float64(o.MinOrbits) || b.Bins[i].Count < orbitMinBinCount {
+		return res
+	}
+	res.ready = true
+	res.expected = b.Bins[i].Mean
+	res.lower, res.upper = math.Inf(1), math.Inf(-1)
+	n := len(b.Bins)
+	for _, j := range []int{(i + n - 1) % n, i, (i + 1) % n} {
+		bin := b.Bins[j]
+		if bin.Count < orbitMinBinCount {
+			continue
+		}
+		half := o.K * math.Max(math.Sqrt(bin.Variance), o.minStd(bin.Mean))
+		res.lower = math.Min(res.lower, bin.Mean-half)
+		res.upper = math.Max(res.upper, bin.Mean+half)
+	}
+	return res
+}
+
+// track 记录降采样序列并估计周期，周期改变并重建各区间时返回 true
+func (o *OrbitEnvelope) track(b *state.OrbitBaseline, x float64, ts int64) bool {
+	if n := len(b.Series); n > 0 && ts-b.Series[n-1].T < int64(o.resolution/time.Second) {
+		return false
+	}
+	b.Series = append(b.Series, state.OrbitPoint{T: ts, V: x})
+	cutoff := ts - int64(o.history/time.Second)
+	if k := firstPointFrom(b.Series, cutoff); k > 0 {
+		b.Series = append([]state.OrbitPoint(nil), b.Series[k:]...)
+	}
+	if float64(ts-b.Series[0].T) < 2*o.maxPeriod.Seconds() {
+		return false
+	}
+
+	period, ok := estimatePeriod(b.Series, o.resolution.Seconds(), o.minPeriod.Seconds(), o.maxPeriod.Seconds())
+	if !ok || (b.Period > 0 && math.Abs(period-b.Period)/b.Period <= orbitPeriodChange) {
+		return false
+	}
+	fmt.Printf("[轨道周期基线] %s %s 轨道周期估计为 %.1f 分钟\n", b.AlertID, b.Source, period/60)
+	b.Period = period
+	b.Since = b.Series[0].T
+	b.Bins = make([]state.PhaseBin, o.Bins)
+	for _, p := range b.Series {
+		_, i := binIndex(b, p.T)
+		o.learnBin(&b.Bins[i], p.V)
+	}
+	return true
+}
+
+// firstPointFrom 序列中第一个时间不早于 t 的点的下标
+func firstPointFrom(series []state.OrbitPoint, t int64) int {
+	k := 0
+	for k < len(series) && series[k].T < t {
+		k++
+	}
+	return k
+}
+
+// estimatePeriod 用去除线性趋势后的自相关估计周期（秒），相关性不足时返回 false
+func estimatePeriod(series []state.OrbitPoint, resolution, minPeriod, maxPeriod float64) (float64, bool) {
+	timestamps := make([]int64, len(series))
+	values := make([]float64, len(series))
+	for i, p := range series {
+		timestamps[i], values[i] = p.T, p.V
+	}
+	fit, ok := fitTrend(timestamps, values)
+	if !ok {
+		return 0, false
+	}
+
+	// 按降采样间隔对齐到等间距网格，缺失的格子不参与计算
+	t0 := series[0].T
+	n := int(math.Round(float64(series[len(series)-1].T-t0)/resolution)) + 1
+	grid := make([]float64, n)
+	have := make([]bool, n)
+	count := 0
+	for i, p := range series {
+		j := int(math.Round(float64(p.T-t0) / resolution))
+		if j >= n || have[j] {
+			continue
+		}
+		grid[j] = values[i] - fit.at(float64(p.T-timestamps[len(timestamps)-1]))
+		have[j] = true
+		count++
+	}
+
+	// 重叠部分的皮尔逊相关系数，避免重叠长度随滞后缩短带来的偏差
+	corr := func(lag int) (float64, bool) {
+		var sx, sy, sxx, syy, sxy float64
+		pairs := 0
+		for i := 0; i+lag < n; i++ {
+			if have[i] && have[i+lag] {
+				x, y := grid[i], grid[i+lag]
+				sx, sy, sxx, syy, sxy = sx+x, sy+y, sxx+x*x, syy+y*y, sxy+x*y
+				pairs++
+			}
+		}
+		if pairs < count/4 {
+			return 0, false
+		}
+		m := float64(pairs)
+		vx, vy := sxx-sx*sx/m, syy-sy*sy/m
+		if vx <= 0 || vy <= 0 {
+			return 0, false
+		}
+		return (sxy - sx*sy/m) / math.Sqrt(vx*vy), true
+	}
+
+	lo, hi := int(math.Ceil(minPeriod/resolution)), int(math.Floor(maxPeriod/resolution))
+	best, bestR := -1, 0.0
+	for lag := lo; lag <= hi; lag++ {
+		if r, ok := corr(lag); ok && (best < 0 || r > bestR) {
+			best, bestR = lag, r
+		}
+	}
+	if best < 0 || bestR < orbitMinCorr {
+		return 0, false
+	}
+	lag := float64(best)
+	r0, ok0 := corr(best - 1)
+	r2, ok2 := corr(best + 1)
+	if d := r0 - 2*bestR + r2; ok0 && ok2 && d < 0 {
+		lag += 0.5 * (r0 - r2) / d
+	}
+	return lag * resolution, true
+}
+
+// evaluateOrbits 评估业务组件的轨道周期包络，状态变化时返回告警
+func (rs *RuleSet) evaluateOrbits(t ruleTarget, sm *state.StateManager) []*model.AlertEvent {
+	var alerts []*model.AlertEvent
+	for i := range rs.OrbitEnvelopes {
+		o := &rs.OrbitEnvelopes[i]
+		if o.Disabled || o.Component != t.component {
+			continue
+		}
+		for _, fv := range o.sel.values(t.data) {
+			x, ok := fv.value.(float64)
+			if !ok {
+				continue
+			}
+			data := o.sel.ruleData(t, fv, o.Name, o.Unit)
+			source := execRuleTemplate(o.source, data)
+
+			var res orbitResult
+			judged := false
+			b := sm.UpdateOrbitBaseline(o.AlertID, source, func(b *state.OrbitBaseline) {
+				res, judged = o.update(b, fv.field, x, t.timestamp)
+			})
+			if !judged {
+				continue
+			}
+			shouldSend, firing, _ := sm.CheckAndUpdateAlertLevel(o.AlertID, source, b.Level)
+			if !shouldSend {
+				continue
+			}
+			alerts = append(alerts, o.alert(t, data, source, x, res, b.Level, firing))
+		}
+	}
+	return alerts
+}
+
+// alert 生成越出包络（或回到包络内）告警
+func (o *OrbitEnvelope) alert(t ruleTarget, data ruleData, source string, x float64, res orbitResult, level string, firing bool) *model.AlertEvent {
+	alert := &model.AlertEvent{
+		AlertID:     o.AlertID,
+		Type:        o.Type,
+		Source:      source,
+		Timestamp:   t.timestamp,
+		FaultCode:   o.FaultCode,
+		TMCode:      data.Code,
+		MetricValue: x,
+		Unit:        data.Unit,
+		Metadata: withMetadata(t.metadata, "orbit", map[string]interface{}{
+			"period":   res.period,
+			"phase":    res.phase,
+			"bin":      res.bin,
+			"orbits":   res.orbits,
+			"expected": res.expected,
+			"lower":    res.lower,
+			"upper":    res.upper,
+		}),
+	}
+	value := formatNumber(o.Format, x) + data.Unit
+	if !firing {
+		alert.Status = model.AlertStatusResolved
+		alert.Severity = model.SeverityInfo
+		alert.Message = fmt.Sprintf("%s已回到轨道周期包络内: 当前%s", data.Name, value)
+		return alert
+	}
+	dir := "高于"
+	if level == "below" {
+		dir = "低于"
+	}
+	alert.Status = model.AlertStatusFiring
+	alert.Severity = o.Severity
+	alert.Message = fmt.Sprintf("%s%s轨道周期包络: 当前%s，轨道相位%.0f%%处预期[%s,%s]%s", data.Name, dir, value,
+		res.phase*100, formatNumber(o.Format, res.lower), formatNumber(o.Format, res.upper), data.Unit)
+	return alert
+}
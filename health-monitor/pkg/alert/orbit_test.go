@@ -0,0 +1,171 @@
+package alert
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// orbitTemp 随轨道周期变化的温度：15℃ ± 10℃，相位 0.75 处最低（阴影区），叠加 ±0.2℃ 噪声
+func orbitTemp(ts int64, period float64, i int) float64 {
+	v := 15 + 10*math.Sin(2*math.Pi*float64(ts)/period)
+	if i%2 == 0 {
+		return v + 0.2
+	}
+	return v - 0.2
+}
+
+// TestOrbitEnvelope 测试按轨道相位学习包络：正常的周期变化不告警，阴影区加热器失效（仍在固定限值内）时告警
+func TestOrbitEnvelope(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[],"orbit_envelopes":[{"alert_id":"ORBIT","component":6,"field":"ThermalTemps[*]",
+		"period":"90m","min_std":0.3,"format":"%.1f","source":"thermal_orbit{{.N}}"}]}`)
+
+	const period = 5400.0
+	base := int64(period) * 315000 // 相位零点
+	feed := func(i int, failed float64) []*model.AlertEvent {
+		ts := base + int64(i)*30
+		var temps [10]float64
+		for j := range temps {
+			temps[j] = orbitTemp(ts, period, i)
+		}
+		temps[2] -= failed
+		return rs.EvaluateBusiness(&model.BusinessMetrics{ComponentType: 0x06, Timestamp: ts, Data: &model.ThermalMetrics{ThermalTemps: temps}}, sm)
+	}
+
+	// 前两个周期学习，之后一个周期正常变化（首次判定以恢复告警上报）
+	for i := 0; i < 3*180; i++ {
+		for _, a := range feed(i, 0) {
+			if a.IsFiring() {
+				t.Fatalf("正常的周期变化不应告警: %d %+v", i, a)
+			}
+		}
+	}
+
+	// 第 4 个周期进入阴影区后热控温度3 比同相位低 3℃
+	var fired, resolved *model.AlertEvent
+	for i := 3*180 + 126; i < 3*180+144; i++ {
+		for _, a := range feed(i, 3) {
+			if !a.IsFiring() || a.Source != "thermal_orbit3" {
+				t.Fatalf("只有热控温度3 应告警: %+v", a)
+			}
+			if fired == nil {
+				fired = a
+				if i != 3*180+128 {
+					t.Errorf("应在连续 3 个样本越出包络后告警: %d", i)
+				}
+			}
+		}
+	}
+	if fired == nil || fired.Type != OrbitEnvelopeType || !strings.HasPrefix(fired.Message, "cjb热控温度3低于轨道周期包络: 当前") {
+		t.Fatalf("加热器失效应告警: %+v", fired)
+	}
+	orbit := fired.Metadata["orbit"].(map[string]interface{})
+	if p := orbit["phase"].(float64); p < 0.69 || p > 0.72 || orbit["period"] != period || fired.MetricValue < -20 {
+		t.Errorf("包络信息不符: %+v %v", orbit, fired.MetricValue)
+	}
+	if fired.MetricValue > orbit["lower"].(float64) || orbit["orbits"].(float64) < 3 {
+		t.Errorf("告警值应低于包络下限: %+v", orbit)
+	}
+
+	for i := 3*180 + 144; i < 3*180+150 && resolved == nil; i++ {
+		resolved = findAlert(feed(i, 0), "ORBIT")
+	}
+	if resolved == nil || !resolved.IsResolved() || !strings.HasPrefix(resolved.Message, "cjb热控温度3已回到轨道周期包络内") {
+		t.Errorf("恢复后应解除: %+v", resolved)
+	}
+}
+
+// TestOrbitAutoPeriod 测试自动估计轨道周期，基线随快照持久化
+func TestOrbitAutoPeriod(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[],"orbit_envelopes":[{"alert_id":"LOAD","component":3,"field":"LoadCurrent",
+		"period":"auto","history":"5h","min_std":0.05,"format":"%.2f"}]}`)
+
+	// 负载电流 2A ± 1A，周期 97 分钟，每分钟一个样本
+	const period = 97 * 60.0
+	base := int64(1700000000)
+	for i := 0; i < 300; i++ {
+		ts := base + int64(i)*60
+		v := 2 + math.Sin(2*math.Pi*float64(ts)/period) + 0.02*float64(i%2)
+		rs.EvaluateBusiness(&model.BusinessMetrics{ComponentType: 0x03, Timestamp: ts, Data: &model.PowerMetrics{LoadCurrent: v}}, sm)
+		if b := sm.GetOrbitBaselines(); i < 260 && (len(b) != 1 || b[0].Period != 0) {
+			t.Fatalf("序列覆盖两个 max_period 之前不应估计周期: %d %+v", i, b)
+		}
+	}
+	b := sm.GetOrbitBaselines()
+	if len(b) != 1 || math.Abs(b[0].Period-period)/period > 0.01 || b[0].Source != "LoadCurrent" || len(b[0].Series) != 300 {
+		t.Fatalf("周期估计不符: %+v", b)
+	}
+
+	data, err := json.Marshal(sm.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot state.StateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	restored := newTestStateManager(t)
+	restored.RestoreSnapshot(&snapshot)
+	if got := restored.GetOrbitBaselines(); !reflect.DeepEqual(got, b) {
+		t.Errorf("基线恢复不符: %+v", got)
+	}
+
+	// 负载电流突增（加热器卡在接通）在恢复后的基线上直接判定
+	var fired *model.AlertEvent
+	for i := 300; i < 305 && fired == nil; i++ {
+		ts := base + int64(i)*60
+		v := 3.5 + math.Sin(2*math.Pi*float64(ts)/period)
+		for _, a := range rs.EvaluateBusiness(&model.BusinessMetrics{ComponentType: 0x03, Timestamp: ts, Data: &model.PowerMetrics{LoadCurrent: v}}, restored) {
+			if a.IsFiring() {
+				fired = a
+			}
+		}
+	}
+	if fired == nil || !strings.HasPrefix(fired.Message, "负载电流高于轨道周期包络") {
+		t.Errorf("负载电流越出包络应告警: %+v", fired)
+	}
+	if n := restored.ResetOrbitBaselines("", ""); n != 1 || len(restored.GetOrbitBaselines()) != 0 {
+		t.Errorf("应删除全部基线: %d", n)
+	}
+}
+
+// TestParseOrbitEnvelopes 测试轨道周期包络校验与内置配置
+func TestParseOrbitEnvelopes(t *testing.T) {
+	cases := map[string]string{
+		"缺少周期":   `"component":6,"field":"BatteryTemp1"`,
+		"周期非法":   `"component":6,"field":"BatteryTemp1","period":"abc"`,
+		"周期过短":   `"component":6,"field":"BatteryTemp1","period":"30s"`,
+		"固定周期范围": `"component":6,"field":"BatteryTemp1","period":"90m","min_period":"80m"`,
+		"周期范围":   `"component":6,"field":"BatteryTemp1","period":"auto","min_period":"3h"`,
+		"序列过短":   `"component":6,"field":"BatteryTemp1","period":"auto","history":"3h"`,
+		"序列过长":   `"component":6,"field":"BatteryTemp1","period":"auto","resolution":"1s","history":"5h"`,
+		"区间数":    `"component":6,"field":"BatteryTemp1","period":"90m","bins":2`,
+		"平滑系数":   `"component":6,"field":"BatteryTemp1","period":"90m","alpha":1`,
+		"非数值字段":  `"component":6,"field":"BatteryHeaterSwitch","period":"90m"`,
+		"数组来源":   `"component":6,"field":"ThermalTemps[*]","period":"90m","source":"thermal"`,
+		"重复ID":   `"component":6,"field":"BatteryTemp1","period":"90m","alert_id":"R"`,
+	}
+	for name, o := range cases {
+		data := `{"rules":[{"alert_id":"R","layer":"node","field":"cpu_percent","op":">","value":90,"type":"t"}],
+			"orbit_envelopes":[{"alert_id":"O",` + o + `}]}`
+		if _, err := ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+
+	rs := DefaultRules()
+	ids := map[string]bool{}
+	for _, o := range rs.OrbitEnvelopes {
+		ids[o.AlertID] = o.auto && o.maxPeriod == DefaultOrbitMaxPeriod && o.Bins == DefaultOrbitBins
+	}
+	if !ids["ORBIT_THERMAL_ENVELOPE"] || !ids["ORBIT_LOAD_CURRENT_ENVELOPE"] {
+		t.Errorf("内置规则集应包含热控温度与负载电流的轨道周期包络: %+v", ids)
+	}
+}
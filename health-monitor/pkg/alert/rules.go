@@ -0,0 +1,1240 @@
+/*
+告警规则引擎
+
+阈值检查由规则文件描述，不再在代码中逐项写死限值、告警ID、故障码和消息。每条规则包含：
+	指标选择：layer（business / node / container / service）+ component（业务组件编号）+ field
+	判定条件：op 比较（> >= < <= == !=，value 可为数值/字符串/布尔）、
+	          outside（超出 [min,max]）、catalog（超出遥测参数库中的有效范围）
+	告警属性：alert_id、type、severity、fault_code、source、消息模板
+	持续性：  persistence（连续/M-of-N 次数）、deadband（回差），见 debounce.go
+	生效条件：when（模式条件，读取其它组件的当前遥测）、grace（模式切换宽限期），见 condition.go
+	计数器：  rate（按历史计算每分钟增量，处理回绕与复位），见 counter.go
+趋势预测：trend（按历史拟合直线外推，预测越限时间），由 TrendAnalyzer 评估，见 trend.go
+
+field 写法：
+	字段名        BatteryVoltage、DeployStatus
+	嵌套字段      CPUUsage.Total
+	数组元素      ThermalTemps[3]
+	全部元素      ThermalTemps[*]（逐个元素判定，模板中 {{.N}} 为从 1 开始的序号）
+	计算字段      微服务层的 cpu_percent、memory_percent、disk_percent、container_running_percent
+	派生参数      规则集 derived 中定义的表达式参数，如 LoadPower（见 derived.go）
+
+判定条件描述的是"异常"：条件成立即触发。触发/恢复状态通过 StateManager 跟踪，状态变化时才产生告警；
+未提供 StateManager 时只在条件成立时返回触发告警。
+
+黄线/红线：数值条件分两级限值。越过黄线（value、min/max、参数库 min/max）时严重程度为 severity（默认 warning），
+越过红线（hard_value、hard_min/hard_max、参数库 hard_min/hard_max）时为 critical。
+触发中的告警在黄线、红线之间变化时，以同一告警ID发送更新后的触发告警（不重复触发），
+每次越限（含恢复）记录在告警 Metadata["limit_crossing"]：from/to 限值带、越过的 limit、value、timestamp。
+
+消息模板（text/template）可用：{{.ID}} 实体ID、{{.N}} 元素序号、{{.Name}} 参数名、{{.Value}} 当前值、
+{{.Unit}} 单位、{{.Range}} 正常范围、{{.Code}} TM 代号、{{.ETA}} 越限时间描述（趋势预测规则）。
+
+内置默认规则见 rules.json，可通过 LoadRules 加载外部 JSON/YAML 文件后 SetDefaultRules 替换。
+*/
+package alert
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"health-monitor/pkg/config"
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+	"health-monitor/pkg/telemetry"
+)
+
+//go:embed rules.json
+var defaultRulesJSON []byte
+
+// 规则层级
+const (
+	LayerBusiness  = "business"
+	LayerNode      = "node"
+	LayerContainer = "container"
+	LayerService   = "service"
+)
+
+// 判定条件
+const (
+	OpGT      = ">"
+	OpGE      = ">="
+	OpLT      = "<"
+	OpLE      = "<="
+	OpEQ      = "=="
+	OpNE      = "!="
+	OpOutside = "outside" // 超出 [min,max]
+	OpCatalog = "catalog" // 超出遥测参数库有效范围
+)
+
+// 默认消息模板（与原参数检查的消息格式一致）
+const (
+	defaultFiringMessage   = "{{.Name}}异常: {{.Value}}{{.Unit}} (正常{{.Range}})"
+	defaultResolvedMessage = "{{.Name}}已恢复正常: {{.Value}}{{.Unit}}"
+
+	defaultTrendMessage         = "{{.Name}}{{.ETA}}: 预测值{{.Value}}{{.Unit}} (正常{{.Range}})"
+	defaultTrendResolvedMessage = "{{.Name}}越限趋势已解除: 预测值{{.Value}}{{.Unit}}"
+)
+
+// businessTypes 业务组件编号 → 指标结构体（与 business.CompXxx 一致），用于校验 field
+var businessTypes = map[uint8]reflect.Type{
+	0x01: reflect.TypeOf(model.RunMgrMetrics{}),
+	0x02: reflect.TypeOf(model.CommMetrics{}),
+	0x03: reflect.TypeOf(model.PowerMetrics{}),
+	0x04: reflect.TypeOf(model.RailCtrlMetrics{}),
+	0x05: reflect.TypeOf(model.PayloadMetrics{}),
+	0x06: reflect.TypeOf(model.ThermalMetrics{}),
+	0x07: reflect.TypeOf(model.AttCtrlMetrics{}),
+	0x08: reflect.TypeOf(model.MeasureMetrics{}),
+	0x09: reflect.TypeOf(model.OpticalMetrics{}),
+	0x0A: reflect.TypeOf(model.SensorMetrics{}),
+	0x0B: reflect.TypeOf(model.ActuatorMetrics{}),
+	0x0C: reflect.TypeOf(model.TransceiverMetrics{}),
+	0x0D: reflect.TypeOf(model.ThrusterMetrics{}),
+	0x0E: reflect.TypeOf(model.EPSMetrics{}),
+}
+
+// entityTypes 微服务层实体 → 指标结构体
+var entityTypes = map[string]reflect.Type{
+	LayerNode:      reflect.TypeOf(model.NodeMetrics{}),
+	LayerContainer: reflect.TypeOf(model.ContainerMetrics{}),
+	LayerService:   reflect.TypeOf(model.ServiceMetrics{}),
+}
+
+// computedField 计算字段，返回 false 表示当前无法计算（如总量为 0），此时跳过该规则
+type computedField func(data interface{}) (float64, bool)
+
+// computedFields 微服务层计算字段（百分比等不直接存在于结构体中的指标）
+var computedFields = map[string]map[string]computedField{
+	LayerNode: {
+		"cpu_percent": func(d interface{}) (float64, bool) {
+			switch cpu := d.(*model.NodeMetrics).CPUUsage.(type) {
+			case float64:
+				return cpu, true
+			case model.CPUUsage:
+				return cpu.Total, true
+			case *model.CPUUsage:
+				return cpu.Total, cpu != nil
+			case map[string]interface{}:
+				v, ok := cpu["total"].(float64)
+				return v, ok
+			}
+			return 0, false
+		},
+		"memory_percent": func(d interface{}) (float64, bool) {
+			m := d.(*model.NodeMetrics)
+			if m.MemoryTotal <= 0 {
+				return 0, false
+			}
+			return float64(m.MemoryTotal-m.MemoryFree) / float64(m.MemoryTotal) * 100, true
+		},
+		"disk_percent": func(d interface{}) (float64, bool) {
+			m := d.(*model.NodeMetrics)
+			if m.DiskTotal <= 0 {
+				return 0, false
+			}
+			return (m.DiskTotal - m.DiskFree) / m.DiskTotal * 100, true
+		},
+		"container_running_percent": func(d interface{}) (float64, bool) {
+			m := d.(*model.NodeMetrics)
+			if m.ContainerTotal <= 0 {
+				return 0, false
+			}
+			return float64(m.ContainerRunning) / float64(m.ContainerTotal) * 100, true
+		},
+	},
+	LayerContainer: {
+		"cpu_percent": func(d interface{}) (float64, bool) {
+			return d.(*model.ContainerMetrics).CPUUsage.Total, true
+		},
+		"memory_percent": func(d interface{}) (float64, bool) {
+			m := d.(*model.ContainerMetrics)
+			if m.MemoryLimit <= 0 {
+				return 0, false
+			}
+			return float64(m.MemoryUsage) / float64(m.MemoryLimit) * 100, true
+		},
+		"disk_percent": func(d interface{}) (float64, bool) {
+			m := d.(*model.ContainerMetrics)
+			if m.SizeLimit <= 0 {
+				return 0, false
+			}
+			return float64(m.SizeUsage) / float64(m.SizeLimit) * 100, true
+		},
+	},
+	LayerService: {
+		"container_running_percent": func(d interface{}) (float64, bool) {
+			m := d.(*model.ServiceMetrics)
+			if len(m.ContainerStatusGroup) == 0 {
+				return 0, false
+			}
+			running := 0
+			for _, s := range m.ContainerStatusGroup {
+				if s == "running" {
+					running++
+				}
+			}
+			return float64(running) / float64(len(m.ContainerStatusGroup)) * 100, true
+		},
+	},
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                                 规则定义
+////////////////////////////////////////////////////////////////////////////////
+
+// Rule 一条告警规则
+type Rule struct {
+	AlertID         string              `json:"alert_id"`                   // 告警ID（规则唯一标识，状态跟踪键）
+	Disabled        bool                `json:"disabled,omitempty"`         // 停用
+	Layer           string              `json:"layer"`                      // business / node / container / service
+	Component       uint8               `json:"component,omitempty"`        // 业务组件编号（仅 business）
+	Field           string              `json:"field"`                      // 指标字段（写法见文件头）
+	TMCode          string              `json:"tm_code,omitempty"`          // TM 代号，留空时按 component+field 查询参数库
+	Op              string              `json:"op"`                         // 判定条件
+	Value           interface{}         `json:"value,omitempty"`            // 比较值（比较类条件）
+	HardValue       *float64            `json:"hard_value,omitempty"`       // 红线比较值（> >= < <=，可选）
+	Min             *float64            `json:"min,omitempty"`              // 黄线下限（outside）
+	Max             *float64            `json:"max,omitempty"`              // 黄线上限（outside）
+	HardMin         *float64            `json:"hard_min,omitempty"`         // 红线下限（outside，可选）
+	HardMax         *float64            `json:"hard_max,omitempty"`         // 红线上限（outside，可选）
+	Severity        model.AlertSeverity `json:"severity"`                   // 越过黄线/条件成立时的严重程度，默认 warning（越过红线为 critical）
+	Persistence     *Persistence        `json:"persistence,omitempty"`      // 持续性要求（见 debounce.go）
+	Deadband        float64             `json:"deadband,omitempty"`         // 回差（见 debounce.go）
+	When            []Condition         `json:"when,omitempty"`             // 生效条件（见 condition.go）
+	Grace           string              `json:"grace,omitempty"`            // 生效条件成立后的宽限期（如 30s）
+	Rate            string              `json:"rate,omitempty"`             // 计数器增长率统计窗口（如 5m，见 counter.go）
+	Trend           *Trend              `json:"trend,omitempty"`            // 趋势预测（见 trend.go）
+	Type            string              `json:"type"`                       // 告警类型
+	FaultCode       string              `json:"fault_code,omitempty"`       // 故障码
+	Source          string              `json:"source,omitempty"`           // 告警来源模板，微服务层默认 {{.ID}}
+	Name            string              `json:"name,omitempty"`             // 参数名，默认取参数库名称
+	Unit            string              `json:"unit,omitempty"`             // 单位，默认取参数库单位
+	Format          string              `json:"format,omitempty"`           // 数值格式（如 %.2f），默认最短表示
+	Message         string              `json:"message,omitempty"`          // 触发消息模板
+	ResolvedMessage string              `json:"resolved_message,omitempty"` // 恢复消息模板
+
+	path       []pathStep
+	computed   computedField
+	derived    *Derived
+	wildcard   bool
+	grace      time.Duration
+	rate       time.Duration
+	counterMax float64
+	source     *template.Template
+	message    *template.Template
+	resolved   *template.Template
+}
+
+// pathStep field 路径的一段，index 为 -1 表示非数组，wildcardIndex 表示 [*]
+type pathStep struct {
+	name  string
+	index int
+}
+
+const wildcardIndex = -2
+
+// RuleSet 告警规则集
+type RuleSet struct {
+	Version        string            `json:"version"`
+	Derived        []Derived         `json:"derived,omitempty"`         // 派生参数（见 derived.go）
+	Rules          []Rule            `json:"rules"`
+	SensorGroups   []SensorGroup     `json:"sensor_groups,omitempty"`   // 冗余传感器组（见 sensors.go）
+	Anomalies      []AnomalyDetector `json:"anomalies,omitempty"`       // 统计异常检测器（见 anomaly.go）
+	OrbitEnvelopes []OrbitEnvelope   `json:"orbit_envelopes,omitempty"` // 轨道周期包络（见 orbit.go）
+
+	byID           map[string]*Rule
+	byLayer        map[string][]*Rule
+	trendByLayer   map[string][]*Rule
+	derived        map[string]*Derived
+	derivedByLayer map[string][]*Derived
+}
+
+// ParseRules 解析并校验规则集 JSON
+func ParseRules(data []byte) (*RuleSet, error) {
+	var rs RuleSet
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, fmt.Errorf("解析告警规则失败: %w", err)
+	}
+	if err := rs.compile(); err != nil {
+		return nil, fmt.Errorf("告警规则校验失败: %w", err)
+	}
+	return &rs, nil
+}
+
+// ParseRulesYAML 解析并校验规则集 YAML（字段名与 JSON 相同）
+func ParseRulesYAML(data []byte) (*RuleSet, error) {
+	js, err := config.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析告警规则失败: %w", err)
+	}
+	return ParseRules(js)
+}
+
+// LoadRules 从文件加载规则集，.yaml/.yml 按 YAML 解析，其余按 JSON 解析
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取告警规则文件失败: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseRulesYAML(data)
+	}
+	return ParseRules(data)
+}
+
+// Rule 按告警ID查询规则
+func (rs *RuleSet) Rule(alertID string) (*Rule, bool) {
+	if rs == nil {
+		return nil, false
+	}
+	r, ok := rs.byID[alertID]
+	return r, ok
+}
+
+// Enabled 返回某层级启用的规则（不含趋势预测规则）
+func (rs *RuleSet) Enabled(layer string) []*Rule {
+	return rs.byLayer[layer]
+}
+
+// TrendRules 返回某层级启用的趋势预测规则
+func (rs *RuleSet) TrendRules(layer string) []*Rule {
+	if rs == nil {
+		return nil
+	}
+	return rs.trendByLayer[layer]
+}
+
+// compile 校验规则并预编译字段路径与模板
+func (rs *RuleSet) compile() error {
+	if err := rs.compileDerived(); err != nil {
+		return err
+	}
+	rs.byID = make(map[string]*Rule, len(rs.Rules))
+	rs.byLayer = make(map[string][]*Rule)
+	rs.trendByLayer = make(map[string][]*Rule)
+
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.AlertID == "" {
+			return fmt.Errorf("第 %d 条规则缺少 alert_id", i+1)
+		}
+		if _, dup := rs.byID[r.AlertID]; dup {
+			return fmt.Errorf("规则 %s 重复定义", r.AlertID)
+		}
+		if err := r.compile(rs); err != nil {
+			return fmt.Errorf("规则 %s: %w", r.AlertID, err)
+		}
+		rs.byID[r.AlertID] = r
+		switch {
+		case r.Disabled:
+		case r.Trend != nil:
+			// 趋势预测规则按历史评估，不参与逐次采样的判定
+			rs.trendByLayer[r.Layer] = append(rs.trendByLayer[r.Layer], r)
+		default:
+			rs.byLayer[r.Layer] = append(rs.byLayer[r.Layer], r)
+		}
+	}
+	if err := rs.compileSensorGroups(); err != nil {
+		return err
+	}
+	if err := rs.compileAnomalies(); err != nil {
+		return err
+	}
+	return rs.compileOrbitEnvelopes()
+}
+
+func (r *Rule) compile(rs *RuleSet) error {
+	// 指标选择
+	typ, err := metricType(r.Layer, r.Component)
+	if err != nil {
+		return err
+	}
+	if r.Field == "" {
+		return fmt.Errorf("缺少 field")
+	}
+	if fn, ok := computedFields[r.Layer][r.Field]; ok {
+		r.computed = fn
+	} else if d, ok := rs.derivedField(r.Layer, r.Component, r.Field); ok {
+		r.computed = d.value
+		r.derived = d
+	} else {
+		path, err := parseFieldPath(r.Field)
+		if err != nil {
+			return err
+		}
+		if err := checkFieldPath(typ, path); err != nil {
+			return fmt.Errorf("field %s: %w", r.Field, err)
+		}
+		r.path = path
+		for _, s := range path {
+			if s.index == wildcardIndex {
+				r.wildcard = true
+			}
+		}
+	}
+
+	// 判定条件
+	switch r.Op {
+	case OpGT, OpGE, OpLT, OpLE:
+		want, ok := r.Value.(float64)
+		if !ok {
+			return fmt.Errorf("%s 需要数值 value", r.Op)
+		}
+		if r.HardValue != nil {
+			if (r.Op == OpGT || r.Op == OpGE) && *r.HardValue < want {
+				return fmt.Errorf("hard_value 须不小于 value")
+			}
+			if (r.Op == OpLT || r.Op == OpLE) && *r.HardValue > want {
+				return fmt.Errorf("hard_value 须不大于 value")
+			}
+		}
+	case OpEQ, OpNE:
+		switch r.Value.(type) {
+		case float64, string, bool:
+		default:
+			return fmt.Errorf("%s 需要数值、字符串或布尔 value", r.Op)
+		}
+	case OpOutside:
+		if r.Min == nil && r.Max == nil && r.HardMin == nil && r.HardMax == nil {
+			return fmt.Errorf("outside 需要 min 或 max")
+		}
+		if err := r.limits().Validate(); err != nil {
+			return err
+		}
+	case OpCatalog:
+		if r.TMCode == "" && (r.Layer != LayerBusiness || r.derived != nil) {
+			return fmt.Errorf("catalog 需要 tm_code")
+		}
+	default:
+		return fmt.Errorf("未知判定条件 %q", r.Op)
+	}
+	if r.HardValue != nil && r.Op != OpGT && r.Op != OpGE && r.Op != OpLT && r.Op != OpLE {
+		return fmt.Errorf("hard_value 仅适用于 > >= < <=")
+	}
+	if (r.HardMin != nil || r.HardMax != nil) && r.Op != OpOutside {
+		return fmt.Errorf("hard_min/hard_max 仅适用于 outside")
+	}
+	if err := r.compileDebounce(); err != nil {
+		return err
+	}
+	if err := r.compileConditions(); err != nil {
+		return err
+	}
+	if err := r.compileRate(typ); err != nil {
+		return err
+	}
+	if err := r.compileTrend(); err != nil {
+		return err
+	}
+
+	// 告警属性
+	if r.Severity == "" {
+		r.Severity = model.SeverityWarning
+	}
+	if !validSeverity(r.Severity) {
+		return fmt.Errorf("未知严重程度 %q", r.Severity)
+	}
+	if r.Type == "" {
+		return fmt.Errorf("缺少 type")
+	}
+
+	// 模板
+	source := r.Source
+	if source == "" {
+		if r.Layer == LayerBusiness {
+			source = r.Field
+			if r.wildcard {
+				source = strings.Replace(r.Field, "[*]", "{{.N}}", 1)
+			}
+		} else {
+			source = "{{.ID}}"
+		}
+	}
+	if r.wildcard && !strings.Contains(source, "{{.N}}") {
+		// 各元素须有不同来源，否则状态会互相覆盖
+		return fmt.Errorf("field 含 [*] 时 source 须包含 {{.N}}")
+	}
+	message, resolved := r.Message, r.ResolvedMessage
+	if message == "" {
+		message = defaultFiringMessage
+		if r.Trend != nil {
+			message = defaultTrendMessage
+		}
+	}
+	if resolved == "" {
+		resolved = defaultResolvedMessage
+		if r.Trend != nil {
+			resolved = defaultTrendResolvedMessage
+		}
+	}
+	if r.source, err = parseRuleTemplate("source", source); err != nil {
+		return err
+	}
+	if r.message, err = parseRuleTemplate("message", message); err != nil {
+		return err
+	}
+	if r.resolved, err = parseRuleTemplate("resolved_message", resolved); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validSeverity(s model.AlertSeverity) bool {
+	return s == model.SeverityInfo || s == model.SeverityWarning || s == model.SeverityCritical
+}
+
+func parseRuleTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s 模板错误: %w", name, err)
+	}
+	// 用空数据试执行一次，提前发现引用了不存在的字段
+	if err := t.Execute(&bytes.Buffer{}, ruleData{}); err != nil {
+		return nil, fmt.Errorf("%s 模板错误: %w", name, err)
+	}
+	return t, nil
+}
+
+// parseFieldPath 解析 "CPUUsage.Total"、"ThermalTemps[3]"、"ThermalTemps[*]"
+func parseFieldPath(field string) ([]pathStep, error) {
+	var path []pathStep
+	wildcards := 0
+	for _, seg := range strings.Split(field, ".") {
+		step := pathStep{name: seg, index: -1}
+		if i := strings.IndexByte(seg, '['); i >= 0 {
+			if !strings.HasSuffix(seg, "]") {
+				return nil, fmt.Errorf("field %s 格式错误", field)
+			}
+			step.name = seg[:i]
+			idx := seg[i+1 : len(seg)-1]
+			if idx == "*" {
+				step.index = wildcardIndex
+				wildcards++
+			} else {
+				n, err := strconv.Atoi(idx)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("field %s 下标非法", field)
+				}
+				step.index = n
+			}
+		}
+		if step.name == "" {
+			return nil, fmt.Errorf("field %s 格式错误", field)
+		}
+		path = append(path, step)
+	}
+	if wildcards > 1 {
+		return nil, fmt.Errorf("field %s 最多包含一个 [*]", field)
+	}
+	return path, nil
+}
+
+// checkFieldPath 按结构体类型校验字段路径（interface 字段在运行时解析）
+func checkFieldPath(t reflect.Type, path []pathStep) error {
+	for _, s := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Interface {
+			return nil
+		}
+		if t.Kind() != reflect.Struct {
+			return fmt.Errorf("%s 不是结构体字段", s.name)
+		}
+		f, ok := t.FieldByName(s.name)
+		if !ok || !f.IsExported() {
+			return fmt.Errorf("%s 中没有字段 %s", t.Name(), s.name)
+		}
+		t = f.Type
+		if s.index != -1 {
+			if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+				return fmt.Errorf("%s 不是数组", s.name)
+			}
+			if t.Kind() == reflect.Array && s.index >= t.Len() {
+				return fmt.Errorf("%s 下标越界", s.name)
+			}
+			t = t.Elem()
+		}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Interface, reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return nil
+	}
+	return fmt.Errorf("字段类型 %s 不能用于判定", t)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                                   评估
+////////////////////////////////////////////////////////////////////////////////
+
+// ruleTarget 一次评估的对象
+type ruleTarget struct {
+	layer     string
+	component uint8
+	data      interface{}            // 指标结构体指针
+	id        string                 // 实体ID（微服务层）
+	metadata  map[string]interface{} // 附加到告警的元数据
+	timestamp int64
+	suspects  map[string]string // 可疑传感器字段 → 传感器组告警ID（见 sensors.go）
+}
+
+// ruleData 模板数据
+type ruleData struct {
+	ID    string // 实体ID
+	N     int    // 元素序号（从 1 开始）
+	Name  string // 参数名
+	Value string // 当前值
+	Unit  string // 单位
+	Range string // 正常范围
+	Code  string // TM 代号
+	ETA   string // 越限时间描述（趋势预测规则，如 "预计12分钟后越限"）
+}
+
+// fieldValue 取到的一个字段值
+type fieldValue struct {
+	field string      // 具体字段（[*] 已替换为下标）
+	n     int         // 元素序号（从 1 开始，非数组为 0）
+	value interface{} // float64 / string / bool
+
+	forecast *trendForecast // 趋势预测（value 为预测值，见 trend.go）
+}
+
+// EvaluateBusiness 按规则评估业务层指标
+func (rs *RuleSet) EvaluateBusiness(bm *model.BusinessMetrics, sm *state.StateManager) []*model.AlertEvent {
+	ts := bm.Timestamp
+	if ts == 0 {
+		ts = dataTimestamp(bm.Data)
+	}
+	return rs.evaluate(ruleTarget{layer: LayerBusiness, component: bm.ComponentType, data: bm.Data, timestamp: ts}, sm)
+}
+
+// EvaluateNode 按规则评估节点指标
+func (rs *RuleSet) EvaluateNode(m *model.NodeMetrics, sm *state.StateManager) []*model.AlertEvent {
+	return rs.evaluate(ruleTarget{layer: LayerNode, data: m, id: m.ID, timestamp: time.Now().Unix()}, sm)
+}
+
+// EvaluateContainer 按规则评估容器指标，告警附带所属服务、所在节点信息
+func (rs *RuleSet) EvaluateContainer(m *model.ContainerMetrics, sm *state.StateManager) []*model.AlertEvent {
+	return rs.evaluate(ruleTarget{layer: LayerContainer, data: m, id: m.ID, metadata: containerMetadata(m), timestamp: time.Now().Unix()}, sm)
+}
+
+// containerMetadata 容器告警附带的所属服务、所在节点信息
+func containerMetadata(m *model.ContainerMetrics) map[string]interface{} {
+	if m.ServiceName == "" && m.ServiceID == "" && m.NodeID == "" && m.NodeName == "" {
+		return nil
+	}
+	metadata := map[string]interface{}{}
+	if m.ServiceName != "" {
+		metadata["serviceName"] = m.ServiceName
+	}
+	if m.ServiceID != "" {
+		metadata["serviceId"] = m.ServiceID
+	}
+	if m.NodeID != "" {
+		metadata["nodeId"] = m.NodeID
+	}
+	if m.NodeName != "" {
+		metadata["nodeName"] = m.NodeName
+	}
+	return metadata
+}
+
+// EvaluateService 按规则评估服务指标
+func (rs *RuleSet) EvaluateService(m *model.ServiceMetrics, sm *state.StateManager) []*model.AlertEvent {
+	return rs.evaluate(ruleTarget{layer: LayerService, data: m, id: m.ID, timestamp: time.Now().Unix()}, sm)
+}
+
+func (rs *RuleSet) evaluate(t ruleTarget, sm *state.StateManager) []*model.AlertEvent {
+	if t.timestamp == 0 {
+		t.timestamp = time.Now().Unix()
+	}
+	if sm != nil {
+		rs.storeDerived(t, sm)
+	}
+	var alerts []*model.AlertEvent
+	if t.layer == LayerBusiness {
+		alerts, t.suspects = rs.evaluateSensorGroups(t, sm)
+	}
+	for _, r := range rs.byLayer[t.layer] {
+		if r.Layer == LayerBusiness && r.Component != t.component {
+			continue
+		}
+		if len(r.When) > 0 {
+			active, resolved := r.active(t, sm)
+			alerts = append(alerts, resolved...)
+			if !active {
+				continue
+			}
+		}
+		values, rt := r.values(t.data), t
+		if r.rate > 0 {
+			var counter map[string]interface{}
+			values, counter = r.rateValues(t, sm)
+			rt.metadata = withMetadata(t.metadata, "counter", counter)
+		}
+		for _, fv := range values {
+			if alert := r.check(rt, fv, sm); alert != nil {
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+	if sm != nil {
+		alerts = append(alerts, rs.evaluateAnomalies(t, sm)...)
+	}
+	if t.layer == LayerBusiness && sm != nil {
+		alerts = append(alerts, rs.evaluateOrbits(t, sm)...)
+	}
+	if t.layer == LayerBusiness && sm != nil {
+		alerts = append(alerts, rs.reevaluateConditions(t, sm)...)
+	}
+	return alerts
+}
+
+// values 取出规则选择的字段值，字段不存在或无法计算时返回空
+func (r *Rule) values(data interface{}) []fieldValue {
+	if r.computed != nil {
+		if v, ok := r.computed(data); ok {
+			return []fieldValue{{field: r.Field, value: v}}
+		}
+		return nil
+	}
+	return pathValues(r.Field, r.path, data)
+}
+
+// pathValues 按字段路径取值，[*] 展开为各元素
+func pathValues(field string, path []pathStep, data interface{}) []fieldValue {
+	v := reflect.ValueOf(data)
+	for i, s := range path {
+		v = indirect(v)
+		if v.Kind() != reflect.Struct {
+			return nil
+		}
+		v = v.FieldByName(s.name)
+		if !v.IsValid() {
+			return nil
+		}
+
+		switch {
+		case s.index == wildcardIndex:
+			v = indirect(v)
+			if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+				return nil
+			}
+			rest := path[i+1:]
+			var out []fieldValue
+			for j := 0; j < v.Len(); j++ {
+				if val, ok := scalar(resolvePath(v.Index(j), rest)); ok {
+					elem := strings.Replace(field, "[*]", fmt.Sprintf("[%d]", j), 1)
+					out = append(out, fieldValue{field: elem, n: j + 1, value: val})
+				}
+			}
+			return out
+		case s.index >= 0:
+			v = indirect(v)
+			if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || s.index >= v.Len() {
+				return nil
+			}
+			v = v.Index(s.index)
+		}
+	}
+	if val, ok := scalar(v); ok {
+		return []fieldValue{{field: field, value: val}}
+	}
+	return nil
+}
+
+// resolvePath 在 [*] 元素上继续解析剩余路径（不含 [*]）
+func resolvePath(v reflect.Value, path []pathStep) reflect.Value {
+	for _, s := range path {
+		v = indirect(v)
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+		v = v.FieldByName(s.name)
+		if s.index >= 0 {
+			v = indirect(v)
+			if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || s.index >= v.Len() {
+				return reflect.Value{}
+			}
+			v = v.Index(s.index)
+		}
+	}
+	return v
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// scalar 将字段值转为 float64 / string / bool
+func scalar(v reflect.Value) (interface{}, bool) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil, false
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), true
+	case reflect.String:
+		return v.String(), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	}
+	return nil, false
+}
+
+// dataTimestamp 读取组件指标结构体的 Timestamp 字段
+func dataTimestamp(data interface{}) int64 {
+	v := indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+	if f := v.FieldByName("Timestamp"); f.IsValid() && f.Kind() == reflect.Int64 {
+		return f.Int()
+	}
+	return 0
+}
+
+// param 查询规则对应的遥测参数定义
+func (r *Rule) param(t ruleTarget, field string) *telemetry.Parameter {
+	if r.TMCode != "" {
+		return tmParam(r.TMCode)
+	}
+	if t.layer == LayerBusiness {
+		if p, ok := telemetry.Default().LookupField(t.component, field); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// check 判定一个字段值并跟踪状态，状态变化时返回触发/恢复告警
+func (r *Rule) check(t ruleTarget, fv fieldValue, sm *state.StateManager) *model.AlertEvent {
+	p := r.param(t, fv.field)
+	band, num, ok := r.test(fv.value, p)
+	if !ok {
+		return nil
+	}
+
+	data := ruleData{ID: t.id, N: fv.n, Name: r.Name, Value: r.formatValue(fv.value), Unit: r.Unit}
+	if p != nil {
+		data.Code = p.Code
+		if data.Name == "" {
+			data.Name = p.Name
+		}
+		if data.Unit == "" {
+			data.Unit = p.Unit
+		}
+	}
+	if r.derived != nil {
+		if data.Name == "" {
+			data.Name = r.derived.Description
+		}
+		if data.Unit == "" {
+			data.Unit = r.derived.Unit
+		}
+	}
+	if data.Name == "" {
+		data.Name = fv.field
+	}
+	if fv.forecast != nil {
+		data.ETA = fv.forecast.describe()
+	}
+	if r.rate > 0 {
+		// 计数器规则判定的是每分钟增量
+		if r.Name == "" {
+			data.Name += "增长率"
+		}
+		if r.Unit == "" {
+			data.Unit += "/分"
+		}
+	}
+	data.Range = r.rangeString(p, data.Unit)
+	source := execRuleTemplate(r.source, data)
+
+	firing := band != telemetry.BandNominal
+	previous := telemetry.BandNominal
+	if sm != nil {
+		// 微服务层按实体区分状态，业务层 [*] 按元素区分
+		stateSource := t.id
+		if r.wildcard {
+			stateSource = source
+		}
+		band = r.debounce(sm, stateSource, band, num, p, t.timestamp)
+		shouldSend, isFiring, prev := sm.CheckAndUpdateAlertLevel(r.AlertID, stateSource, string(band))
+		if !shouldSend {
+			return nil
+		}
+		firing, previous = isFiring, telemetry.Band(prev)
+	} else if !firing {
+		return nil
+	}
+
+	// 越限记录（首次评估即正常时没有越限）
+	metadata := t.metadata
+	if band != previous {
+		metadata = make(map[string]interface{}, len(t.metadata)+1)
+		for k, v := range t.metadata {
+			metadata[k] = v
+		}
+		metadata["limit_crossing"] = r.crossing(previous, band, num, p, t.timestamp)
+	}
+	if group, ok := t.suspects[fv.field]; ok && firing {
+		// 所在传感器组不一致，超限可能是传感器故障
+		metadata = withMetadata(metadata, "sensor_suspect", group)
+	}
+	if fv.forecast != nil {
+		metadata = withMetadata(metadata, "trend", fv.forecast.info())
+	}
+
+	alert := &model.AlertEvent{
+		AlertID:     r.AlertID,
+		Type:        r.Type,
+		Source:      source,
+		Timestamp:   t.timestamp,
+		FaultCode:   r.FaultCode,
+		MetricValue: num,
+		Unit:        data.Unit,
+		Metadata:    metadata,
+	}
+	if p != nil {
+		alert.TMCode = p.Code
+	}
+	if firing {
+		alert.Status = model.AlertStatusFiring
+		alert.Severity = r.severity(band)
+		alert.Message = execRuleTemplate(r.message, data)
+	} else {
+		alert.Status = model.AlertStatusResolved
+		alert.Severity = model.SeverityInfo
+		alert.Message = execRuleTemplate(r.resolved, data)
+	}
+	return alert
+}
+
+// test 判定条件，返回数值所在的限值带（条件不成立为 BandNominal）与数值形式的指标值；类型不匹配时 ok 为 false
+func (r *Rule) test(v interface{}, p *telemetry.Parameter) (band telemetry.Band, num float64, ok bool) {
+	switch x := v.(type) {
+	case float64:
+		num = x
+	case bool:
+		if x {
+			num = 1
+		}
+	}
+	abnormal := func(cond bool) telemetry.Band {
+		if cond {
+			return telemetry.BandAbnormal
+		}
+		return telemetry.BandNominal
+	}
+
+	switch r.Op {
+	case OpCatalog:
+		if _, isNum := v.(float64); !isNum {
+			return telemetry.BandNominal, 0, false
+		}
+		// 参数库中缺失或未定义范围时不触发
+		if p == nil {
+			return telemetry.BandNominal, num, true
+		}
+		band, _ = p.Check(num)
+		return band, num, true
+	case OpOutside:
+		if _, isNum := v.(float64); !isNum {
+			return telemetry.BandNominal, 0, false
+		}
+		band, _ = r.limits().Check(num)
+		return band, num, true
+	}
+
+	switch want := r.Value.(type) {
+	case string:
+		s, isStr := v.(string)
+		if !isStr {
+			return telemetry.BandNominal, 0, false
+		}
+		return abnormal((s == want) == (r.Op == OpEQ)), 0, true
+	case bool:
+		b, isBool := v.(bool)
+		if !isBool {
+			return telemetry.BandNominal, 0, false
+		}
+		return abnormal((b == want) == (r.Op == OpEQ)), num, true
+	case float64:
+		if _, isStr := v.(string); isStr {
+			return telemetry.BandNominal, 0, false
+		}
+		switch r.Op {
+		case OpEQ:
+			return abnormal(num == want), num, true
+		case OpNE:
+			return abnormal(num != want), num, true
+		}
+		soft, hard := telemetry.BandSoftHigh, telemetry.BandHardHigh
+		if r.Op == OpLT || r.Op == OpLE {
+			soft, hard = telemetry.BandSoftLow, telemetry.BandHardLow
+		}
+		switch {
+		case r.HardValue != nil && compare(r.Op, num, *r.HardValue):
+			return hard, num, true
+		case compare(r.Op, num, want):
+			return soft, num, true
+		}
+		return telemetry.BandNominal, num, true
+	}
+	return telemetry.BandNominal, 0, false
+}
+
+// compare 数值比较（> >= < <=）
+func compare(op string, a, b float64) bool {
+	switch op {
+	case OpGT:
+		return a > b
+	case OpGE:
+		return a >= b
+	case OpLT:
+		return a < b
+	case OpLE:
+		return a <= b
+	}
+	return false
+}
+
+// limits outside 条件的两级限值
+func (r *Rule) limits() telemetry.Limits {
+	return telemetry.Limits{Min: r.Min, Max: r.Max, HardMin: r.HardMin, HardMax: r.HardMax}
+}
+
+// limit 某限值带对应的限值（无方向的异常没有限值）
+func (r *Rule) limit(band telemetry.Band, p *telemetry.Parameter) (float64, bool) {
+	switch r.Op {
+	case OpCatalog:
+		if p == nil {
+			return 0, false
+		}
+		return p.Limits().Limit(band)
+	case OpOutside:
+		return r.limits().Limit(band)
+	case OpGT, OpGE, OpLT, OpLE:
+		if band.Hard() {
+			return *r.HardValue, true
+		}
+		if band == telemetry.BandSoftLow || band == telemetry.BandSoftHigh {
+			return r.Value.(float64), true
+		}
+	}
+	return 0, false
+}
+
+// crossing 一次越限记录：从 from 带进入 to 带，limit 为越过的限值（进入更严重的带时取新带的限值，反之取原带的限值）
+func (r *Rule) crossing(from, to telemetry.Band, value float64, p *telemetry.Parameter, ts int64) map[string]interface{} {
+	c := map[string]interface{}{
+		"from":      from.String(),
+		"to":        to.String(),
+		"value":     value,
+		"timestamp": ts,
+	}
+	crossed := to
+	if bandRank(to) < bandRank(from) {
+		crossed = from
+	}
+	if limit, ok := r.limit(crossed, p); ok {
+		c["limit"] = limit
+	}
+	return c
+}
+
+// bandRank 限值带的严重程度排序：正常 < 黄线/异常 < 红线
+func bandRank(b telemetry.Band) int {
+	switch {
+	case b == telemetry.BandNominal:
+		return 0
+	case b.Hard():
+		return 2
+	}
+	return 1
+}
+
+// severity 触发时的严重程度：越过红线为 critical，其余为规则的 severity
+func (r *Rule) severity(band telemetry.Band) model.AlertSeverity {
+	if band.Hard() {
+		return model.SeverityCritical
+	}
+	return r.Severity
+}
+
+func (r *Rule) formatValue(v interface{}) string {
+	switch x := v.(type) {
+	case float64:
+		if r.Format != "" {
+			return fmt.Sprintf(r.Format, x)
+		}
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	return fmt.Sprint(v)
+}
+
+// negatedOps 比较条件的反面，用于描述正常范围
+var negatedOps = map[string]string{OpGT: "≤", OpGE: "<", OpLT: "≥", OpLE: ">", OpEQ: "≠", OpNE: "="}
+
+// rangeString 正常范围描述
+func (r *Rule) rangeString(p *telemetry.Parameter, unit string) string {
+	switch r.Op {
+	case OpCatalog:
+		if p == nil {
+			return ""
+		}
+		return p.RangeString()
+	case OpOutside:
+		return r.limits().RangeString(unit)
+	}
+	if f, ok := r.Value.(float64); ok {
+		return negatedOps[r.Op] + strconv.FormatFloat(f, 'f', -1, 64) + unit
+	}
+	return negatedOps[r.Op] + fmt.Sprint(r.Value)
+}
+
+func execRuleTemplate(t *template.Template, data ruleData) string {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return fmt.Sprintf("<%s: %v>", t.Name(), err)
+	}
+	return buf.String()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                                 规则变更
+////////////////////////////////////////////////////////////////////////////////
+
+// RemovedRules 返回在 old 中启用、在 next 中已删除或停用的规则
+// alert_id 保留的规则（即使限值、条件已修改）不在其列，其告警状态保留，下一次采样按新规则重新判定
+func RemovedRules(old, next *RuleSet) []*Rule {
+	if old == nil {
+		return nil
+	}
+	var removed []*Rule
+	for i := range old.Rules {
+		r := &old.Rules[i]
+		if r.Disabled {
+			continue
+		}
+		if n, ok := next.Rule(r.AlertID); ok && !n.Disabled {
+			continue
+		}
+		removed = append(removed, r)
+	}
+	return removed
+}
+
+// ResolveRemovedRules 为已删除/停用规则的活跃告警生成恢复告警，并清除这些规则的告警状态
+// layers 限定处理的层级（业务层与微服务层由各自的生成器输出），为空时处理全部层级
+func ResolveRemovedRules(old, next *RuleSet, sm *state.StateManager, layers ...string) []*model.AlertEvent {
+	if sm == nil {
+		return nil
+	}
+	var alerts []*model.AlertEvent
+	for _, r := range RemovedRules(old, next) {
+		if len(layers) > 0 && !containsString(layers, r.Layer) {
+			continue
+		}
+		reason := "已删除"
+		if n, ok := next.Rule(r.AlertID); ok && n.Disabled {
+			reason = "已停用"
+		}
+		alerts = append(alerts, r.resolveActive(sm, fmt.Sprintf("告警规则 %s %s，告警解除", r.AlertID, reason), time.Now().Unix())...)
+	}
+	if len(layers) == 0 || containsString(layers, LayerBusiness) {
+		alerts = append(alerts, resolveRemovedSensorGroups(old, next, sm)...)
+	}
+	return alerts
+}
+
+// resolveActive 清除规则的全部告警状态，为清除前处于触发状态的告警生成恢复告警
+func (r *Rule) resolveActive(sm *state.StateManager, message string, ts int64) []*model.AlertEvent {
+	var alerts []*model.AlertEvent
+	for _, src := range sm.ClearAlertStates(r.AlertID) {
+		// 业务层非数组规则的状态不含来源，使用规则中的来源
+		source := src
+		if source == "" {
+			source = r.Source
+			if source == "" {
+				source = r.Field
+			}
+		}
+		alerts = append(alerts, &model.AlertEvent{
+			AlertID:   r.AlertID,
+			Type:      r.Type,
+			Status:    model.AlertStatusResolved,
+			Severity:  model.SeverityInfo,
+			Source:    source,
+			Message:   message,
+			Timestamp: ts,
+			FaultCode: r.FaultCode,
+			TMCode:    r.TMCode,
+		})
+	}
+	return alerts
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              全局默认规则集
+////////////////////////////////////////////////////////////////////////////////
+
+var defaultRules atomic.Pointer[RuleSet]
+
+func init() {
+	rs, err := ParseRules(defaultRulesJSON)
+	if err != nil {
+		panic(fmt.Sprintf("内置告警规则无效: %v", err))
+	}
+	defaultRules.Store(rs)
+}
+
+// DefaultRules 返回当前全局规则集
+func DefaultRules() *RuleSet {
+	return defaultRules.Load()
+}
+
+// SetDefaultRules 替换全局规则集（rs 需已通过 ParseRules/LoadRules 校验）
+func SetDefaultRules(rs *RuleSet) {
+	defaultRules.Store(rs)
+}
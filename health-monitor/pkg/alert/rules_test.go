@@ -0,0 +1,314 @@
+package alert
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"health-monitor/pkg/models"
+)
+
+// TestDefaultRulesPower 测试内置规则：蓄电池电压分级、触发与恢复
+func TestDefaultRulesPower(t *testing.T) {
+	sm := newTestStateManager(t)
+	normal := &model.PowerMetrics{Timestamp: 1700000000, PowerModule12V: 13, BatteryVoltage: 25, BusVoltage: 26, CPUVoltage: 3.3, LoadCurrent: 2}
+
+	if alerts := CheckPowerThresholdsWithState(normal, sm); len(alerts) != 5 {
+		t.Fatalf("首次评估应为每条规则建立状态: %+v", alerts)
+	}
+
+	low := *normal
+	low.BatteryVoltage = 20.5
+	alerts := CheckPowerThresholdsWithState(&low, sm)
+	if len(alerts) != 1 {
+		t.Fatalf("应只有蓄电池告警: %+v", alerts)
+	}
+	bat := alerts[0]
+	if bat.AlertID != "BATTERY_VOLTAGE_ALERT" || bat.Severity != model.SeverityWarning || bat.FaultCode != "CJB-RG-ZD-3" ||
+		bat.TMCode != "TMEZD01095" || bat.Timestamp != 1700000000 || bat.Message != "蓄电池电压异常: 20.50V (正常[21,29.4]V)" {
+		t.Errorf("蓄电池告警不符: %+v", bat)
+	}
+	if !sm.GetAlertState("BATTERY_VOLTAGE_ALERT") {
+		t.Error("业务层状态键应为告警ID")
+	}
+
+	// 无状态检查：严重程度按区间调整
+	low.BatteryVoltage = 18
+	if alerts := CheckPowerThresholds(&low); len(alerts) != 1 || alerts[0].Severity != model.SeverityCritical {
+		t.Errorf("低于 20V 应为严重: %+v", alerts)
+	}
+
+	alerts = CheckPowerThresholdsWithState(normal, sm)
+	if len(alerts) != 1 || !alerts[0].IsResolved() || alerts[0].Message != "蓄电池电压已恢复正常: 25.00V" {
+		t.Errorf("应产生恢复告警: %+v", alerts)
+	}
+}
+
+// TestRulesWildcard 测试数组字段逐元素判定，各元素独立跟踪状态
+func TestRulesWildcard(t *testing.T) {
+	sm := newTestStateManager(t)
+	m := &model.ThermalMetrics{BatteryTemp1: 20}
+	m.ThermalTemps[2] = 80
+	m.ThermalTemps[7] = -40
+
+	firing := 0
+	for _, a := range CheckThermalThresholds(m) {
+		if a.AlertID == "THERMAL_TEMP_ALERT" {
+			firing++
+		}
+	}
+	if firing != 2 {
+		t.Fatalf("应有 2 个温度点告警，实际 %d", firing)
+	}
+
+	DefaultRules().EvaluateBusiness(&model.BusinessMetrics{ComponentType: 0x06, Data: m}, sm)
+	m.ThermalTemps[2] = 20
+	alerts := DefaultRules().EvaluateBusiness(&model.BusinessMetrics{ComponentType: 0x06, Data: m}, sm)
+	if len(alerts) != 1 || !alerts[0].IsResolved() || alerts[0].Source != "thermal_temp3" || alerts[0].TMCode != "TMEZD01068" {
+		t.Errorf("温度3应单独恢复: %+v", alerts)
+	}
+}
+
+// TestRulesMicroservice 测试微服务层规则：按实体区分状态、字符串与布尔比较、计算字段
+func TestRulesMicroservice(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	c := &model.ContainerMetrics{ID: "c1", DeployStatus: "success", ServiceName: "svc", MemoryLimit: 100, MemoryUsage: 95, CPUUsage: model.CPUUsage{Total: 10}}
+	alerts := CheckContainerThresholdsWithState(c, sm)
+	mem := findAlert(alerts, "CONTAINER_MEMORY_HIGH")
+	if mem == nil || !mem.IsFiring() || mem.Source != "c1" || mem.Metadata["serviceName"] != "svc" || mem.Message != "容器内存使用率过高: 95.00%" {
+		t.Fatalf("容器内存告警不符: %+v", mem)
+	}
+	if findAlert(alerts, "CONTAINER_DISK_HIGH") != nil {
+		t.Error("SizeLimit 为 0 时不应评估磁盘使用率")
+	}
+
+	// 另一个容器的状态相互独立
+	c2 := *c
+	c2.ID = "c2"
+	if mem := findAlert(CheckContainerThresholdsWithState(&c2, sm), "CONTAINER_MEMORY_HIGH"); mem == nil || !mem.IsFiring() {
+		t.Errorf("容器 c2 应单独触发: %+v", mem)
+	}
+
+	n := &model.NodeMetrics{ID: "n1", Status: "offline", CPUUsage: model.CPUUsage{Total: 90}}
+	alerts = CheckNodeThresholds(n)
+	if findAlert(alerts, "NODE_OFFLINE") == nil || findAlert(alerts, "NODE_CPU_HIGH") == nil {
+		t.Errorf("节点离线与 CPU 告警: %+v", alerts)
+	}
+
+	s := &model.ServiceMetrics{ID: "s1", Healthy: false, InstanceOnline: 1}
+	alerts = CheckServiceThresholds(s)
+	if len(alerts) != 1 || alerts[0].AlertID != "SERVICE_UNHEALTHY" || alerts[0].Message != "服务 s1 健康检查失败" {
+		t.Errorf("服务健康告警: %+v", alerts)
+	}
+}
+
+// TestParseRules 测试规则解析、校验与 YAML 加载
+func TestParseRules(t *testing.T) {
+	cases := map[string]string{
+		"缺少alert_id": `{"rules":[{"layer":"node","field":"Status","op":"!=","value":"online","type":"t"}]}`,
+		"重复定义":       `{"rules":[{"alert_id":"A","layer":"node","field":"Status","op":"!=","value":"x","type":"t"},{"alert_id":"A","layer":"node","field":"Status","op":"!=","value":"x","type":"t"}]}`,
+		"未知层级":       `{"rules":[{"alert_id":"A","layer":"x","field":"Status","op":"!=","value":"x","type":"t"}]}`,
+		"未知组件":       `{"rules":[{"alert_id":"A","layer":"business","component":99,"field":"X","op":"catalog","type":"t"}]}`,
+		"字段不存在":      `{"rules":[{"alert_id":"A","layer":"business","component":3,"field":"Nope","op":"catalog","type":"t"}]}`,
+		"下标越界":       `{"rules":[{"alert_id":"A","layer":"business","component":6,"field":"ThermalTemps[10]","op":"catalog","type":"t"}]}`,
+		"比较值类型":      `{"rules":[{"alert_id":"A","layer":"node","field":"cpu_percent","op":">","value":"x","type":"t"}]}`,
+		"outside缺范围":  `{"rules":[{"alert_id":"A","layer":"node","field":"cpu_percent","op":"outside","type":"t"}]}`,
+		"严重程度":       `{"rules":[{"alert_id":"A","layer":"node","field":"cpu_percent","op":">","value":1,"severity":"fatal","type":"t"}]}`,
+		"模板字段":       `{"rules":[{"alert_id":"A","layer":"node","field":"cpu_percent","op":">","value":1,"type":"t","message":"{{.Nope}}"}]}`,
+		"通配来源":       `{"rules":[{"alert_id":"A","layer":"business","component":6,"field":"ThermalTemps[*]","op":"catalog","type":"t","source":"x"}]}`,
+		"红线比较值":      `{"rules":[{"alert_id":"A","layer":"node","field":"cpu_percent","op":">","value":85,"hard_value":80,"type":"t"}]}`,
+		"红线条件":       `{"rules":[{"alert_id":"A","layer":"node","field":"Status","op":"!=","value":"x","hard_value":1,"type":"t"}]}`,
+		"红线范围":       `{"rules":[{"alert_id":"A","layer":"node","field":"cpu_percent","op":"outside","min":10,"max":90,"hard_max":80,"type":"t"}]}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+
+	yamlRules := `
+version: "t"
+rules:
+  - alert_id: CPU_VOLT
+    layer: business
+    component: 3
+    field: CPUVoltage
+    op: outside
+    min: 3.2
+    max: 3.4
+    type: voltage_abnormal
+    source: cpu
+  - alert_id: OFF
+    disabled: true
+    layer: node
+    field: Status
+    op: "!="
+    value: online
+    type: node_offline
+`
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(yamlRules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := LoadRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Rules) != 2 || len(rs.Enabled(LayerNode)) != 0 {
+		t.Fatalf("规则数量不符: %+v", rs.Rules)
+	}
+	alerts := rs.EvaluateBusiness(&model.BusinessMetrics{ComponentType: 0x03, Data: &model.PowerMetrics{CPUVoltage: 3.45}}, nil)
+	if len(alerts) != 1 || alerts[0].Severity != model.SeverityWarning || !strings.Contains(alerts[0].Message, "(正常[3.2,3.4]V)") {
+		t.Errorf("outside 规则告警不符: %+v", alerts)
+	}
+	if alerts := rs.EvaluateNode(&model.NodeMetrics{ID: "n", Status: "offline"}, nil); len(alerts) != 0 {
+		t.Errorf("停用的规则不应评估: %+v", alerts)
+	}
+
+	// 生成器可单独替换规则集
+	g := NewGenerator()
+	g.SetRules(rs)
+	if g.Rules() != rs {
+		t.Error("SetRules 未生效")
+	}
+	g.SetRules(nil)
+	if g.Rules() != DefaultRules() {
+		t.Error("清除后应使用全局默认规则集")
+	}
+}
+
+// TestResolveRemovedRules 测试规则删除/停用后活跃告警解除，保留的规则状态不变
+func TestResolveRemovedRules(t *testing.T) {
+	sm := newTestStateManager(t)
+	c := &model.ContainerMetrics{ID: "c1", DeployStatus: "success", MemoryLimit: 100, MemoryUsage: 95, CPUUsage: model.CPUUsage{Total: 80}}
+	DefaultRules().EvaluateContainer(c, sm)
+	if !sm.GetAlertState("CONTAINER_MEMORY_HIGH:c1") || !sm.GetAlertState("CONTAINER_CPU_HIGH:c1") {
+		t.Fatal("容器告警应已触发")
+	}
+
+	next, err := ParseRules([]byte(`{"rules":[
+		{"alert_id":"CONTAINER_CPU_HIGH","layer":"container","field":"cpu_percent","op":">","value":90,"type":"container_cpu_high"},
+		{"alert_id":"CONTAINER_MEMORY_HIGH","disabled":true,"layer":"container","field":"memory_percent","op":">","value":90,"type":"container_memory_high"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if alerts := ResolveRemovedRules(DefaultRules(), next, sm, LayerBusiness); len(alerts) != 0 {
+		t.Errorf("层级不匹配时不应处理: %+v", alerts)
+	}
+	alerts := ResolveRemovedRules(DefaultRules(), next, sm, LayerContainer)
+	if len(alerts) != 1 || alerts[0].AlertID != "CONTAINER_MEMORY_HIGH" || !alerts[0].IsResolved() || alerts[0].Source != "c1" {
+		t.Fatalf("停用规则的告警应解除: %+v", alerts)
+	}
+	if sm.GetAlertState("CONTAINER_MEMORY_HIGH:c1") {
+		t.Error("停用规则的状态应清除")
+	}
+	if !sm.GetAlertState("CONTAINER_CPU_HIGH:c1") {
+		t.Error("保留的规则状态不应变化")
+	}
+
+	// 限值提高后按新规则重新判定，产生恢复告警
+	alerts = next.EvaluateContainer(c, sm)
+	if len(alerts) != 1 || alerts[0].AlertID != "CONTAINER_CPU_HIGH" || !alerts[0].IsResolved() {
+		t.Errorf("新限值下应恢复: %+v", alerts)
+	}
+}
+
+// TestLimitBands 测试黄线/红线：分级严重程度、限值带变化时更新已有告警并记录越限
+func TestLimitBands(t *testing.T) {
+	sm := newTestStateManager(t)
+	power := func(v float64) *model.BusinessMetrics {
+		return &model.BusinessMetrics{ComponentType: 0x03, Timestamp: 1700000000,
+			Data: &model.PowerMetrics{PowerModule12V: 13, BatteryVoltage: v, BusVoltage: 26, CPUVoltage: 3.3, LoadCurrent: 2}}
+	}
+	battery := func(v float64) *model.AlertEvent {
+		t.Helper()
+		alerts := DefaultRules().EvaluateBusiness(power(v), sm)
+		if len(alerts) > 1 {
+			t.Fatalf("%v: 告警数量不符: %+v", v, alerts)
+		}
+		return findAlert(alerts, "BATTERY_VOLTAGE_ALERT")
+	}
+	crossing := func(a *model.AlertEvent) map[string]interface{} {
+		t.Helper()
+		c, ok := a.Metadata["limit_crossing"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("缺少越限记录: %+v", a)
+		}
+		return c
+	}
+
+	DefaultRules().EvaluateBusiness(power(25), sm)
+
+	a := battery(20.5)
+	if a == nil || !a.IsFiring() || a.Severity != model.SeverityWarning {
+		t.Fatalf("越过黄线应为警告: %+v", a)
+	}
+	if c := crossing(a); c["from"] != "nominal" || c["to"] != "soft_low" || c["limit"] != 21.0 {
+		t.Errorf("黄线越限记录不符: %+v", c)
+	}
+
+	// 黄线 → 红线：同一告警更新为严重
+	a = battery(18)
+	if a == nil || !a.IsFiring() || a.Severity != model.SeverityCritical {
+		t.Fatalf("越过红线应更新为严重: %+v", a)
+	}
+	if c := crossing(a); c["from"] != "soft_low" || c["to"] != "hard_low" || c["limit"] != 20.0 || c["value"] != 18.0 {
+		t.Errorf("红线越限记录不符: %+v", c)
+	}
+	if sm.GetAlertLevel("BATTERY_VOLTAGE_ALERT", "") != "hard_low" {
+		t.Error("状态中应记录当前限值带")
+	}
+	if a := battery(18.5); a != nil {
+		t.Errorf("限值带未变化不应发送: %+v", a)
+	}
+
+	// 红线 → 黄线：降级更新，越过的仍是红线
+	a = battery(20.5)
+	if a == nil || !a.IsFiring() || a.Severity != model.SeverityWarning || crossing(a)["limit"] != 20.0 {
+		t.Fatalf("回到黄线内应更新为警告: %+v", a)
+	}
+
+	a = battery(25)
+	if a == nil || !a.IsResolved() || crossing(a)["to"] != "nominal" || crossing(a)["limit"] != 21.0 {
+		t.Fatalf("应恢复并记录越限: %+v", a)
+	}
+	if sm.GetAlertState("BATTERY_VOLTAGE_ALERT") || sm.GetAlertLevel("BATTERY_VOLTAGE_ALERT", "") != "" {
+		t.Error("恢复后状态应清除")
+	}
+
+	// 规则中的红线比较值
+	rs, err := ParseRules([]byte(`{"rules":[{"alert_id":"CPU","layer":"node","field":"cpu_percent","op":">","value":85,"hard_value":95,"type":"t"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &model.NodeMetrics{ID: "n1", CPUUsage: 90.0}
+	if alerts := rs.EvaluateNode(n, nil); len(alerts) != 1 || alerts[0].Severity != model.SeverityWarning {
+		t.Errorf("90%% 应为警告: %+v", alerts)
+	}
+	n.CPUUsage = 97.0
+	if alerts := rs.EvaluateNode(n, nil); len(alerts) != 1 || alerts[0].Severity != model.SeverityCritical || crossing(alerts[0])["limit"] != 95.0 {
+		t.Errorf("97%% 应为严重: %+v", alerts)
+	}
+}
+
+// TestDeduplicateAlerts 测试共用来源与故障码的同组规则告警不被去重合并
+func TestDeduplicateAlerts(t *testing.T) {
+	g := NewGeneratorWithStateManager(newTestStateManager(t))
+	wheel := func(id string) *model.AlertEvent {
+		return &model.AlertEvent{AlertID: id, Source: "actuator_monitor", Type: "actuator_abnormal", FaultCode: "CJB-O2-CS-16"}
+	}
+	alerts := g.deduplicateAlerts([]*model.AlertEvent{
+		wheel("WHEEL_SPEED_X_ALERT"), wheel("WHEEL_SPEED_Y_ALERT"), wheel("WHEEL_SPEED_Z_ALERT"), wheel("WHEEL_SPEED_X_ALERT"),
+	})
+	var ids []string
+	for _, a := range alerts {
+		ids = append(ids, a.AlertID)
+	}
+	if got := strings.Join(ids, ","); got != "WHEEL_SPEED_X_ALERT,WHEEL_SPEED_Y_ALERT,WHEEL_SPEED_Z_ALERT" {
+		t.Errorf("去重结果不符: %s", got)
+	}
+}
@@ -0,0 +1,342 @@
+/*
+冗余传感器交叉校验与表决
+
+两路蓄电池温度、10 路热控温度等互相冗余的测量值应当一致。
+逐项独立判定时，单个传感器失效与真实的热控/供电故障无法区分。规则集的 sensor_groups 声明传感器组：
+
+	alert_id    不一致告警ID（与规则的 alert_id 不能重复）
+	component   业务组件编号（成员须在同一组件）
+	members     成员字段（写法同规则 field，可用 [*] 展开数组），至少两路
+	tolerance   允许偏差
+	name、unit、format、type（默认 sensor_inconsistency）、severity、fault_code、source
+
+每次评估该组件时：
+
+	两路      差值超过 tolerance 即不一致，无法判定哪一路故障
+	三路以上  以中位数为基准，偏差在 tolerance 内的成员构成多数（超过半数）时，
+	          多数成员的均值为表决值，其余成员判定为故障传感器（如三取二）；无多数时不一致但无法判定
+
+不一致告警的类型为 sensor_inconsistency，区别于参数超限告警，故障树可据此区分传感器故障与设备故障；
+告警 Metadata 带 sensor_group、values（各成员值）、spread（极差）、voted（表决值）、suspects（故障传感器）。
+判定的故障传感器变化时以同一告警ID发送更新。同时，可疑成员自身的超限告警在 Metadata["sensor_suspect"]
+中标注所属传感器组（有表决结果时只标注故障成员，无法判定时标注全部成员）。
+*/
+package alert
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// SensorInconsistencyType 传感器不一致告警类型
+const SensorInconsistencyType = "sensor_inconsistency"
+
+// SensorGroup 冗余传感器组
+type SensorGroup struct {
+	AlertID   string              `json:"alert_id"`             // 不一致告警ID
+	Disabled  bool                `json:"disabled,omitempty"`   // 停用
+	Name      string              `json:"name,omitempty"`       // 名称，默认 alert_id
+	Component uint8               `json:"component"`            // 业务组件编号
+	Members   []string            `json:"members"`              // 成员字段
+	Tolerance float64             `json:"tolerance"`            // 允许偏差
+	Unit      string              `json:"unit,omitempty"`       // 单位
+	Format    string              `json:"format,omitempty"`     // 数值格式，默认最短表示
+	Type      string              `json:"type,omitempty"`       // 告警类型，默认 sensor_inconsistency
+	Severity  model.AlertSeverity `json:"severity,omitempty"`   // 严重程度，默认 warning
+	FaultCode string              `json:"fault_code,omitempty"` // 故障码
+	Source    string              `json:"source,omitempty"`     // 告警来源，默认 sensor_group/<alert_id>
+
+	paths [][]pathStep
+}
+
+// sensorVote 一次表决结果
+type sensorVote struct {
+	fields   []string
+	values   []float64
+	spread   float64  // 极差
+	voted    float64  // 表决值（hasVote 时有效）
+	hasVote  bool     // 是否有一致的多数
+	suspects []string // 故障成员（hasVote 时）或全部成员（无法判定时），一致时为空
+}
+
+// level 状态跟踪用的级别：一致为空，无法判定为 abnormal，否则为故障成员列表
+func (v *sensorVote) level() string {
+	switch {
+	case len(v.suspects) == 0:
+		return ""
+	case !v.hasVote:
+		return "abnormal"
+	}
+	return "suspect:" + strings.Join(v.suspects, ",")
+}
+
+// compileSensorGroups 校验传感器组
+func (rs *RuleSet) compileSensorGroups() error {
+	ids := make(map[string]bool, len(rs.SensorGroups))
+	for i := range rs.SensorGroups {
+		g := &rs.SensorGroups[i]
+		if g.AlertID == "" {
+			return fmt.Errorf("第 %d 个传感器组缺少 alert_id", i+1)
+		}
+		if ids[g.AlertID] {
+			return fmt.Errorf("传感器组 %s 重复定义", g.AlertID)
+		}
+		if _, dup := rs.byID[g.AlertID]; dup {
+			return fmt.Errorf("传感器组 %s 与告警规则 alert_id 重复", g.AlertID)
+		}
+		ids[g.AlertID] = true
+		if err := g.compile(); err != nil {
+			return fmt.Errorf("传感器组 %s: %w", g.AlertID, err)
+		}
+	}
+	return nil
+}
+
+func (g *SensorGroup) compile() error {
+	typ, err := metricType(LayerBusiness, g.Component)
+	if err != nil {
+		return err
+	}
+	expanded := false
+	g.paths = g.paths[:0]
+	for _, m := range g.Members {
+		path, err := parseFieldPath(m)
+		if err != nil {
+			return err
+		}
+		if err := checkFieldPath(typ, path); err != nil {
+			return fmt.Errorf("member %s: %w", m, err)
+		}
+		if fieldKind(typ, path) != kindNum {
+			return fmt.Errorf("member %s 不是数值字段", m)
+		}
+		for _, s := range path {
+			if s.index == wildcardIndex {
+				expanded = true
+			}
+		}
+		g.paths = append(g.paths, path)
+	}
+	if len(g.Members) < 2 && !expanded {
+		return fmt.Errorf("members 至少需要两路")
+	}
+	if g.Tolerance <= 0 {
+		return fmt.Errorf("tolerance 须大于 0")
+	}
+	if g.Name == "" {
+		g.Name = g.AlertID
+	}
+	if g.Type == "" {
+		g.Type = SensorInconsistencyType
+	}
+	if g.Severity == "" {
+		g.Severity = model.SeverityWarning
+	}
+	if !validSeverity(g.Severity) {
+		return fmt.Errorf("未知严重程度 %q", g.Severity)
+	}
+	if g.Source == "" {
+		g.Source = "sensor_group/" + g.AlertID
+	}
+	return nil
+}
+
+// vote 取出成员值并表决，有效成员不足两路时返回 false
+func (g *SensorGroup) vote(data interface{}) (*sensorVote, bool) {
+	v := &sensorVote{}
+	for i, path := range g.paths {
+		for _, fv := range pathValues(g.Members[i], path, data) {
+			if x, ok := fv.value.(float64); ok && !math.IsNaN(x) {
+				v.fields = append(v.fields, fv.field)
+				v.values = append(v.values, x)
+			}
+		}
+	}
+	n := len(v.values)
+	if n < 2 {
+		return nil, false
+	}
+
+	sorted := append([]float64(nil), v.values...)
+	sort.Float64s(sorted)
+	v.spread = sorted[n-1] - sorted[0]
+
+	if n == 2 {
+		if v.spread > g.Tolerance {
+			v.suspects = append(v.suspects, v.fields...)
+		}
+		return v, true
+	}
+
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	var agree []float64
+	var outliers []string
+	for i, x := range v.values {
+		if math.Abs(x-median) <= g.Tolerance {
+			agree = append(agree, x)
+		} else {
+			outliers = append(outliers, v.fields[i])
+		}
+	}
+	if len(agree) >= 2 && len(agree)*2 > n {
+		v.hasVote = true
+		sum := 0.0
+		for _, x := range agree {
+			sum += x
+		}
+		v.voted = sum / float64(len(agree))
+		v.suspects = outliers
+		return v, true
+	}
+	v.suspects = append(v.suspects, v.fields...)
+	return v, true
+}
+
+// evaluateSensorGroups 评估业务组件的传感器组，返回不一致告警与可疑成员（字段 → 传感器组告警ID）
+func (rs *RuleSet) evaluateSensorGroups(t ruleTarget, sm *state.StateManager) ([]*model.AlertEvent, map[string]string) {
+	var alerts []*model.AlertEvent
+	var suspects map[string]string
+	for i := range rs.SensorGroups {
+		g := &rs.SensorGroups[i]
+		if g.Disabled || g.Component != t.component {
+			continue
+		}
+		v, ok := g.vote(t.data)
+		if !ok {
+			continue
+		}
+		level := v.level()
+		if level != "" {
+			if suspects == nil {
+				suspects = make(map[string]string)
+			}
+			for _, f := range v.suspects {
+				suspects[f] = g.AlertID
+			}
+		}
+
+		firing := level != ""
+		if sm != nil {
+			shouldSend, isFiring, _ := sm.CheckAndUpdateAlertLevel(g.AlertID, "", level)
+			if !shouldSend {
+				continue
+			}
+			firing = isFiring
+		} else if !firing {
+			continue
+		}
+		alerts = append(alerts, g.alert(v, firing, t.timestamp))
+	}
+	return alerts, suspects
+}
+
+// alert 生成不一致（或恢复一致）告警
+func (g *SensorGroup) alert(v *sensorVote, firing bool, ts int64) *model.AlertEvent {
+	values := make(map[string]interface{}, len(v.fields))
+	for i, f := range v.fields {
+		values[f] = v.values[i]
+	}
+	metadata := map[string]interface{}{
+		"sensor_group": g.AlertID,
+		"values":       values,
+		"spread":       v.spread,
+	}
+	if v.hasVote {
+		metadata["voted"] = v.voted
+	}
+	if v.hasVote && len(v.suspects) > 0 {
+		metadata["suspects"] = v.suspects
+	}
+
+	alert := &model.AlertEvent{
+		AlertID:     g.AlertID,
+		Type:        g.Type,
+		Source:      g.Source,
+		Timestamp:   ts,
+		FaultCode:   g.FaultCode,
+		MetricValue: v.spread,
+		Unit:        g.Unit,
+		Metadata:    metadata,
+	}
+	if !firing {
+		alert.Status = model.AlertStatusResolved
+		alert.Severity = model.SeverityInfo
+		alert.Message = fmt.Sprintf("%s恢复一致", g.Name)
+		return alert
+	}
+
+	alert.Status = model.AlertStatusFiring
+	alert.Severity = g.Severity
+	if v.hasVote {
+		parts := make([]string, 0, len(v.suspects))
+		for _, f := range v.suspects {
+			for i := range v.fields {
+				if v.fields[i] == f {
+					parts = append(parts, f+"="+g.format(v.values[i])+g.Unit)
+				}
+			}
+		}
+		alert.Message = fmt.Sprintf("%s不一致: %s 偏离表决值 %s%s（容差 %s%s），判定为传感器故障",
+			g.Name, strings.Join(parts, " "), g.format(v.voted), g.Unit, g.format(g.Tolerance), g.Unit)
+		return alert
+	}
+	parts := make([]string, len(v.fields))
+	for i, f := range v.fields {
+		parts[i] = f + "=" + g.format(v.values[i]) + g.Unit
+	}
+	alert.Message = fmt.Sprintf("%s不一致: %s，偏差 %s%s 超过容差 %s%s，无法判定故障传感器",
+		g.Name, strings.Join(parts, " "), g.format(v.spread), g.Unit, g.format(g.Tolerance), g.Unit)
+	return alert
+}
+
+func (g *SensorGroup) format(v float64) string {
+	if g.Format != "" {
+		return fmt.Sprintf(g.Format, v)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// resolveRemovedSensorGroups 为已删除/停用传感器组的不一致告警生成恢复告警，并清除其状态
+func resolveRemovedSensorGroups(old, next *RuleSet, sm *state.StateManager) []*model.AlertEvent {
+	if old == nil {
+		return nil
+	}
+	kept := make(map[string]bool)
+	if next != nil {
+		for _, g := range next.SensorGroups {
+			if !g.Disabled {
+				kept[g.AlertID] = true
+			}
+		}
+	}
+	var alerts []*model.AlertEvent
+	for _, g := range old.SensorGroups {
+		if g.Disabled || kept[g.AlertID] {
+			continue
+		}
+		if len(sm.ClearAlertStates(g.AlertID)) == 0 {
+			continue
+		}
+		alerts = append(alerts, &model.AlertEvent{
+			AlertID:   g.AlertID,
+			Type:      g.Type,
+			Status:    model.AlertStatusResolved,
+			Severity:  model.SeverityInfo,
+			Source:    g.Source,
+			Message:   fmt.Sprintf("传感器组 %s 已删除或停用，告警解除", g.AlertID),
+			Timestamp: time.Now().Unix(),
+			FaultCode: g.FaultCode,
+		})
+	}
+	return alerts
+}
@@ -0,0 +1,111 @@
+package alert
+
+import (
+	"strings"
+	"testing"
+
+	"health-monitor/pkg/models"
+)
+
+const sensorRules = `{"sensor_groups":[
+	{"alert_id":"TEMP3_INCONSISTENT","name":"热控温度1~3","component":6,"members":["ThermalTemps[0]","ThermalTemps[1]","ThermalTemps[2]"],"tolerance":5,"unit":"℃"},
+	{"alert_id":"BAT_INCONSISTENT","component":6,"members":["BatteryTemp1","BatteryTemp2"],"tolerance":3}],
+	"rules":[{"alert_id":"TEMP","layer":"business","component":6,"field":"ThermalTemps[*]","op":">","value":60,"type":"temperature_abnormal","source":"t{{.N}}"}]}`
+
+func thermal(temps ...float64) *model.BusinessMetrics {
+	m := &model.ThermalMetrics{BatteryTemp1: 20, BatteryTemp2: 21}
+	copy(m.ThermalTemps[:], temps)
+	return &model.BusinessMetrics{ComponentType: 0x06, Timestamp: 1700000000, Data: m}
+}
+
+// TestSensorVoting 测试三取二表决：判定故障传感器、标注其超限告警，恢复一致后解除
+func TestSensorVoting(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, sensorRules)
+
+	// 首次评估与规则一致：正常状态以恢复告警上报
+	if g := findAlert(rs.EvaluateBusiness(thermal(20, 21, 22), sm), "TEMP3_INCONSISTENT"); g == nil || g.IsFiring() || g.Metadata["voted"] != 21.0 {
+		t.Fatalf("一致时不应触发: %+v", g)
+	}
+
+	alerts := rs.EvaluateBusiness(thermal(20, 21, 80), sm)
+	g := findAlert(alerts, "TEMP3_INCONSISTENT")
+	if g == nil || !g.IsFiring() || g.Type != SensorInconsistencyType || g.Source != "sensor_group/TEMP3_INCONSISTENT" ||
+		g.Message != "热控温度1~3不一致: ThermalTemps[2]=80℃ 偏离表决值 20.5℃（容差 5℃），判定为传感器故障" {
+		t.Fatalf("不一致告警不符: %+v", g)
+	}
+	if s, _ := g.Metadata["suspects"].([]string); len(s) != 1 || s[0] != "ThermalTemps[2]" || g.Metadata["voted"] != 20.5 || g.MetricValue != 60 {
+		t.Errorf("表决结果不符: %+v", g.Metadata)
+	}
+	temp := findAlert(alerts, "TEMP")
+	if temp == nil || temp.Source != "t3" || temp.Metadata["sensor_suspect"] != "TEMP3_INCONSISTENT" {
+		t.Errorf("故障传感器的超限告警应标注: %+v", temp)
+	}
+
+	// 无多数：全部成员可疑，告警以同一ID更新
+	alerts = rs.EvaluateBusiness(thermal(20, 40, 80), sm)
+	g = findAlert(alerts, "TEMP3_INCONSISTENT")
+	if g == nil || !g.IsFiring() || !strings.Contains(g.Message, "无法判定故障传感器") || g.Metadata["suspects"] != nil {
+		t.Fatalf("无多数时告警不符: %+v", g)
+	}
+
+	alerts = rs.EvaluateBusiness(thermal(20, 21, 22), sm)
+	if g := findAlert(alerts, "TEMP3_INCONSISTENT"); g == nil || !g.IsResolved() || g.Message != "热控温度1~3恢复一致" {
+		t.Errorf("应恢复一致: %+v", alerts)
+	}
+	if temp := findAlert(alerts, "TEMP"); temp == nil || !temp.IsResolved() || temp.Metadata["sensor_suspect"] != nil {
+		t.Errorf("超限告警应恢复且不带标注: %+v", temp)
+	}
+}
+
+// TestSensorPair 测试两路传感器：超过容差即不一致，两路均标注可疑；删除传感器组后告警解除
+func TestSensorPair(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, sensorRules)
+
+	bm := thermal(20, 21, 22)
+	bm.Data.(*model.ThermalMetrics).BatteryTemp2 = 30
+	alerts := rs.EvaluateBusiness(bm, sm)
+	g := findAlert(alerts, "BAT_INCONSISTENT")
+	if g == nil || !g.IsFiring() || g.Message != "BAT_INCONSISTENT不一致: BatteryTemp1=20 BatteryTemp2=30，偏差 10 超过容差 3，无法判定故障传感器" {
+		t.Fatalf("两路不一致告警不符: %+v", g)
+	}
+	if alerts := rs.EvaluateBusiness(bm, sm); len(alerts) != 0 {
+		t.Errorf("状态未变化不应重复告警: %+v", alerts)
+	}
+
+	// 无状态评估只返回触发告警
+	if alerts := rs.EvaluateBusiness(thermal(20, 21, 22), nil); len(alerts) != 0 {
+		t.Errorf("一致时不应告警: %+v", alerts)
+	}
+
+	next := mustParseRules(t, `{"rules":[]}`)
+	alerts = ResolveRemovedRules(rs, next, sm, LayerBusiness)
+	if g := findAlert(alerts, "BAT_INCONSISTENT"); g == nil || !g.IsResolved() || g.Type != SensorInconsistencyType {
+		t.Errorf("删除传感器组应解除告警: %+v", alerts)
+	}
+	if sm.GetAlertState("BAT_INCONSISTENT") {
+		t.Error("传感器组告警状态应清除")
+	}
+}
+
+// TestParseSensorGroups 测试传感器组校验
+func TestParseSensorGroups(t *testing.T) {
+	cases := map[string]string{
+		"缺少alert_id": `{"sensor_groups":[{"component":6,"members":["BatteryTemp1","BatteryTemp2"],"tolerance":1}],"rules":[]}`,
+		"单路":         `{"sensor_groups":[{"alert_id":"G","component":6,"members":["BatteryTemp1"],"tolerance":1}],"rules":[]}`,
+		"容差":         `{"sensor_groups":[{"alert_id":"G","component":6,"members":["BatteryTemp1","BatteryTemp2"]}],"rules":[]}`,
+		"非数值":        `{"sensor_groups":[{"alert_id":"G","component":6,"members":["BatteryTemp1","PlatformHeaterSwitch"],"tolerance":1}],"rules":[]}`,
+		"字段不存在":      `{"sensor_groups":[{"alert_id":"G","component":6,"members":["BatteryTemp1","Nope"],"tolerance":1}],"rules":[]}`,
+		"与规则重复":      `{"sensor_groups":[{"alert_id":"A","component":6,"members":["BatteryTemp1","BatteryTemp2"],"tolerance":1}],"rules":[{"alert_id":"A","layer":"node","field":"Status","op":"!=","value":"x","type":"t"}]}`,
+		"重复定义":       `{"sensor_groups":[{"alert_id":"G","component":6,"members":["BatteryTemp1","BatteryTemp2"],"tolerance":1},{"alert_id":"G","component":6,"members":["BatteryTemp1","BatteryTemp2"],"tolerance":1}],"rules":[]}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+	if _, err := ParseRules([]byte(`{"sensor_groups":[{"alert_id":"G","component":6,"members":["ThermalTemps[*]"],"tolerance":1}],"rules":[]}`)); err != nil {
+		t.Errorf("[*] 成员应可单独构成传感器组: %v", err)
+	}
+}
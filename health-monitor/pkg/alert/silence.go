@@ -0,0 +1,356 @@
+/*
+告警静默（维护窗口）
+
+计划内操作（加热器试验、载荷模式切换、容器重新部署等）期间会产生大量预期内的告警，
+静默规则在指定时间段内拦截匹配的触发告警，不转发到故障诊断（DiagnosisReceiver），避免误诊断与误恢复。
+
+匹配条件（非空项须全部满足，支持 * ? 通配符，如 THERMAL_*）：
+	alert_id    告警ID
+	fault_code  故障码
+	source      告警来源
+	labels      告警 Metadata 中的标签，如 {"serviceName": "payload-*"}
+
+静默只影响转发：告警状态照常在 StateManager 中跟踪，控制台照常输出（标注已静默）；
+触发已转发过的告警，恢复告警照常转发；触发被拦截、从未转发的告警，恢复时也不转发。
+静默结束时仍在触发的被拦截告警会补发给故障诊断。
+
+静默规则到期自动失效，保存在 -silences 指定的文件中，重启后恢复。管理接口见 ServeHTTP：
+	GET    /api/v1/silences       列出静默规则（含已到期 SilenceRetention 内的记录）
+	POST   /api/v1/silences       创建，{"alert_id": "THERMAL_*", "duration": "2h", "reason": "加热器试验"}
+	DELETE /api/v1/silences/{id}  提前结束
+*/
+package alert
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"health-monitor/pkg/admin"
+	"health-monitor/pkg/models"
+)
+
+// SilenceRetention 到期的静默规则保留时长（便于查询），超过后删除
+const SilenceRetention = 24 * time.Hour
+
+// Silence 一条静默规则
+type Silence struct {
+	ID        string            `json:"id"`
+	AlertID   string            `json:"alert_id,omitempty"`   // 告警ID（可含通配符）
+	FaultCode string            `json:"fault_code,omitempty"` // 故障码（可含通配符）
+	Source    string            `json:"source,omitempty"`     // 告警来源（可含通配符）
+	Labels    map[string]string `json:"labels,omitempty"`     // Metadata 标签（值可含通配符）
+	StartsAt  time.Time         `json:"starts_at"`
+	EndsAt    time.Time         `json:"ends_at"`
+	Reason    string            `json:"reason"`
+	CreatedBy string            `json:"created_by,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Silenced  int               `json:"silenced"` // 已拦截的触发告警数
+}
+
+// Active 是否在静默时间段内
+func (s *Silence) Active(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// Matches 告警是否满足全部匹配条件
+func (s *Silence) Matches(a *model.AlertEvent) bool {
+	if !matchPattern(s.AlertID, a.AlertID) || !matchPattern(s.FaultCode, a.FaultCode) || !matchPattern(s.Source, a.Source) {
+		return false
+	}
+	for k, pattern := range s.Labels {
+		v, ok := a.Metadata[k]
+		if !ok || !matchPattern(pattern, fmt.Sprint(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+// validate 校验静默规则
+func (s *Silence) validate() error {
+	if s.AlertID == "" && s.FaultCode == "" && s.Source == "" && len(s.Labels) == 0 {
+		return errors.New("至少需要一个匹配条件（alert_id/fault_code/source/labels）")
+	}
+	patterns := []string{s.AlertID, s.FaultCode, s.Source}
+	for _, v := range s.Labels {
+		patterns = append(patterns, v)
+	}
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("匹配条件 %q 格式错误", p)
+		}
+	}
+	if strings.TrimSpace(s.Reason) == "" {
+		return errors.New("缺少 reason")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return errors.New("ends_at 须晚于 starts_at")
+	}
+	return nil
+}
+
+// matchPattern 空模式匹配任意值
+func matchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// SilenceStore 静默规则存储（并发安全），path 非空时每次变更写入文件
+type SilenceStore struct {
+	mu       sync.Mutex
+	path     string
+	silences map[string]*Silence
+	now      func() time.Time
+}
+
+// silenceFile 静默规则文件格式
+type silenceFile struct {
+	Silences []*Silence `json:"silences"`
+}
+
+// NewSilenceStore 创建静默规则存储，path 非空时从文件加载已有规则（文件不存在时为空）
+func NewSilenceStore(path string) (*SilenceStore, error) {
+	s := &SilenceStore{path: path, silences: make(map[string]*Silence), now: time.Now}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取静默规则文件失败: %w", err)
+	}
+	var f silenceFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("解析静默规则文件失败: %w", err)
+	}
+	for _, sl := range f.Silences {
+		if sl.ID == "" {
+			continue
+		}
+		s.silences[sl.ID] = sl
+	}
+	s.purgeLocked()
+	return s, nil
+}
+
+// Add 创建静默规则：StartsAt 为空时立即开始，ID 为空时自动生成
+func (s *SilenceStore) Add(sl Silence) (*Silence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if sl.StartsAt.IsZero() {
+		sl.StartsAt = now
+	}
+	if err := sl.validate(); err != nil {
+		return nil, err
+	}
+	if !sl.EndsAt.After(now) {
+		return nil, errors.New("ends_at 已过期")
+	}
+	if sl.ID == "" {
+		sl.ID = newSilenceID()
+	}
+	if _, dup := s.silences[sl.ID]; dup {
+		return nil, fmt.Errorf("静默规则 %s 已存在", sl.ID)
+	}
+	sl.CreatedAt = now
+	sl.Silenced = 0
+	s.silences[sl.ID] = &sl
+	if err := s.saveLocked(); err != nil {
+		delete(s.silences, sl.ID)
+		return nil, err
+	}
+	cp := sl
+	return &cp, nil
+}
+
+// Expire 提前结束静默规则：尚未开始的直接删除
+func (s *SilenceStore) Expire(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sl, ok := s.silences[id]
+	if !ok {
+		return fmt.Errorf("静默规则 %s 不存在", id)
+	}
+	now := s.now()
+	switch {
+	case now.Before(sl.StartsAt):
+		delete(s.silences, id)
+	case now.Before(sl.EndsAt):
+		sl.EndsAt = now
+	}
+	return s.saveLocked()
+}
+
+// List 返回全部静默规则（按开始时间排序）
+func (s *SilenceStore) List() []Silence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeLocked()
+	out := make([]Silence, 0, len(s.silences))
+	for _, sl := range s.silences {
+		out = append(out, *sl)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].StartsAt.Equal(out[j].StartsAt) {
+			return out[i].StartsAt.Before(out[j].StartsAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Match 返回当前生效且匹配该告警的静默规则ID，无匹配时返回空
+func (s *SilenceStore) Match(a *model.AlertEvent) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	ids := make([]string, 0, 1)
+	for id, sl := range s.silences {
+		if sl.Active(now) && sl.Matches(a) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	sort.Strings(ids)
+	return ids[0]
+}
+
+// recordSilenced 累计静默规则拦截的告警数，随静默规则一起保存（重启后继续累计）
+func (s *SilenceStore) recordSilenced(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sl, ok := s.silences[id]
+	if !ok {
+		return
+	}
+	sl.Silenced++
+	if err := s.saveLocked(); err != nil {
+		fmt.Printf("[告警静默] %v\n", err)
+	}
+}
+
+// purgeLocked 删除到期超过 SilenceRetention 的规则
+func (s *SilenceStore) purgeLocked() {
+	cutoff := s.now().Add(-SilenceRetention)
+	for id, sl := range s.silences {
+		if sl.EndsAt.Before(cutoff) {
+			delete(s.silences, id)
+		}
+	}
+}
+
+// saveLocked 写入文件（先写临时文件再替换，避免写到一半时重启导致文件损坏）
+func (s *SilenceStore) saveLocked() error {
+	s.purgeLocked()
+	if s.path == "" {
+		return nil
+	}
+	f := silenceFile{Silences: make([]*Silence, 0, len(s.silences))}
+	for _, sl := range s.silences {
+		f.Silences = append(f.Silences, sl)
+	}
+	sort.Slice(f.Silences, func(i, j int) bool { return f.Silences[i].ID < f.Silences[j].ID })
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".silences-*")
+	if err != nil {
+		return fmt.Errorf("保存静默规则失败: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("保存静默规则失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("保存静默规则失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("保存静默规则失败: %w", err)
+	}
+	return nil
+}
+
+func newSilenceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// silenceRequest 创建静默规则的请求，duration（如 "2h"）与 ends_at 二选一
+type silenceRequest struct {
+	Silence
+	Duration string `json:"duration,omitempty"`
+}
+
+// ServeHTTP 静默规则管理接口（挂载在 /api/v1/silences 与 /api/v1/silences/）
+func (s *SilenceStore) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/v1/silences"), "/")
+
+	switch {
+	case req.Method == http.MethodGet && id == "":
+		admin.WriteJSON(w, http.StatusOK, s.List())
+
+	case req.Method == http.MethodPost && id == "":
+		var r silenceRequest
+		if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+			admin.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if r.Duration != "" {
+			d, err := time.ParseDuration(r.Duration)
+			if err != nil || d <= 0 {
+				admin.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "duration 格式错误"})
+				return
+			}
+			if r.StartsAt.IsZero() {
+				r.StartsAt = s.now()
+			}
+			r.EndsAt = r.StartsAt.Add(d)
+		}
+		sl, err := s.Add(r.Silence)
+		if err != nil {
+			admin.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		fmt.Printf("[告警静默] 创建 %s: %s ~ %s，%s\n", sl.ID, sl.StartsAt.Format(time.DateTime), sl.EndsAt.Format(time.DateTime), sl.Reason)
+		admin.WriteJSON(w, http.StatusCreated, sl)
+
+	case req.Method == http.MethodDelete && id != "":
+		if err := s.Expire(id); err != nil {
+			admin.WriteJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		fmt.Printf("[告警静默] 结束 %s\n", id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,212 @@
+package alert
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+)
+
+// recordingReceiver 记录转发到故障诊断的告警
+type recordingReceiver struct {
+	alerts []map[string]interface{}
+}
+
+func (r *recordingReceiver) SendAlert(alert interface{}) error {
+	r.alerts = append(r.alerts, alert.(map[string]interface{}))
+	return nil
+}
+
+// TestSilenceStore 测试静默规则的匹配、到期与持久化
+func TestSilenceStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silences.json")
+	store, err := NewSilenceStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Truncate(time.Second)
+	store.now = func() time.Time { return now }
+
+	for name, sl := range map[string]Silence{
+		"无匹配条件": {Reason: "x", EndsAt: now.Add(time.Hour)},
+		"缺少原因":  {AlertID: "A", EndsAt: now.Add(time.Hour)},
+		"时间倒置":  {AlertID: "A", Reason: "x", StartsAt: now, EndsAt: now.Add(-time.Hour)},
+		"模式错误":  {AlertID: "[", Reason: "x", EndsAt: now.Add(time.Hour)},
+	} {
+		if _, err := store.Add(sl); err == nil {
+			t.Errorf("%s: 期望创建失败", name)
+		}
+	}
+
+	heater, err := store.Add(Silence{AlertID: "THERMAL_*", Reason: "加热器试验", EndsAt: now.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redeploy, err := store.Add(Silence{FaultCode: "MS-CN-FL-*", Labels: map[string]string{"serviceName": "payload-*"},
+		Reason: "载荷服务重新部署", StartsAt: now.Add(time.Hour), EndsAt: now.Add(3 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thermal := &model.AlertEvent{AlertID: "THERMAL_TEMP_ALERT", Source: "thermal_temp3"}
+	container := &model.AlertEvent{AlertID: "CONTAINER_CPU_HIGH", FaultCode: "MS-CN-FL-5", Metadata: map[string]interface{}{"serviceName": "payload-a"}}
+	if store.Match(thermal) != heater.ID {
+		t.Error("告警ID通配应匹配")
+	}
+	if store.Match(container) != "" {
+		t.Error("未开始的静默不应生效")
+	}
+
+	now = now.Add(90 * time.Minute)
+	if store.Match(container) != redeploy.ID {
+		t.Error("故障码+标签应匹配")
+	}
+	if store.Match(&model.AlertEvent{AlertID: "CONTAINER_CPU_HIGH", FaultCode: "MS-CN-FL-5", Metadata: map[string]interface{}{"serviceName": "nav"}}) != "" {
+		t.Error("标签不匹配时不应静默")
+	}
+
+	// 重启后恢复，拦截数一并恢复
+	store.recordSilenced(heater.ID)
+	reloaded, err := NewSilenceStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded.now = store.now
+	if len(reloaded.List()) != 2 || reloaded.Match(thermal) != heater.ID {
+		t.Fatalf("重启后应恢复静默规则: %+v", reloaded.List())
+	}
+	if got := reloaded.List()[0]; got.ID != heater.ID || got.Silenced != 1 {
+		t.Errorf("重启后应恢复拦截数: %+v", got)
+	}
+
+	// 到期自动失效，保留一段时间后删除
+	now = now.Add(time.Hour)
+	if store.Match(thermal) != "" {
+		t.Error("到期后不应生效")
+	}
+	if err := store.Expire(redeploy.ID); err != nil || store.Match(container) != "" {
+		t.Errorf("提前结束后不应生效: %v", err)
+	}
+	if len(store.List()) != 2 {
+		t.Error("到期的静默规则应保留一段时间")
+	}
+	now = now.Add(SilenceRetention + time.Minute)
+	if len(store.List()) != 0 {
+		t.Error("超过保留时长应删除")
+	}
+}
+
+// TestGeneratorSilence 测试静默的告警记录状态但不转发，静默结束后补发
+func TestGeneratorSilence(t *testing.T) {
+	sm := newTestStateManager(t)
+	recv := &recordingReceiver{}
+	g := NewGeneratorWithDiagnosis(sm, recv)
+	store, _ := NewSilenceStore("")
+	g.SetSilences(store)
+
+	sl, err := store.Add(Silence{AlertID: "NODE_CPU_HIGH", Reason: "压力测试", EndsAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms := &model.MicroServiceMetricsSet{NodeMetrics: []model.NodeMetrics{{ID: "n1", Status: "online", CPUUsage: 95.0}}}
+	g.ProcessMicroserviceMetrics(context.Background(), ms)
+	if !sm.GetAlertState("NODE_CPU_HIGH:n1") {
+		t.Fatal("静默的告警仍应记录状态")
+	}
+	for _, a := range recv.alerts {
+		if a["AlertID"] == "NODE_CPU_HIGH" {
+			t.Fatalf("静默的告警不应转发: %+v", a)
+		}
+	}
+	if store.List()[0].Silenced != 1 {
+		t.Errorf("应累计拦截数: %+v", store.List())
+	}
+
+	// 静默结束：仍在触发的告警补发
+	recv.alerts = nil
+	store.Expire(sl.ID)
+	g.ProcessMicroserviceMetrics(context.Background(), ms)
+	if len(recv.alerts) != 1 || recv.alerts[0]["AlertID"] != "NODE_CPU_HIGH" || recv.alerts[0]["Status"] != "firing" {
+		t.Fatalf("静默结束应补发触发告警: %+v", recv.alerts)
+	}
+	if md := recv.alerts[0]["Metadata"].(map[string]interface{}); md["silence_expired"] != sl.ID || md["silenced_by"] != nil {
+		t.Errorf("补发告警元数据不符: %+v", md)
+	}
+
+	// 触发已转发过的告警，静默期间恢复仍转发
+	store.Add(Silence{AlertID: "NODE_CPU_HIGH", Reason: "压力测试", EndsAt: time.Now().Add(time.Hour)})
+	recv.alerts = nil
+	ms.NodeMetrics[0].CPUUsage = 10.0
+	g.ProcessMicroserviceMetrics(context.Background(), ms)
+	if len(recv.alerts) != 1 || recv.alerts[0]["Status"] != "resolved" {
+		t.Errorf("恢复告警应转发: %+v", recv.alerts)
+	}
+}
+
+// TestGeneratorSilenceResolve 测试静默期间触发又恢复的告警，触发与恢复均不转发
+func TestGeneratorSilenceResolve(t *testing.T) {
+	recv := &recordingReceiver{}
+	g := NewGeneratorWithDiagnosis(newTestStateManager(t), recv)
+	store, _ := NewSilenceStore("")
+	g.SetSilences(store)
+	if _, err := store.Add(Silence{AlertID: "NODE_CPU_HIGH", Reason: "压力测试", EndsAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := &model.MicroServiceMetricsSet{NodeMetrics: []model.NodeMetrics{{ID: "n1", Status: "online", CPUUsage: 10.0}}}
+	g.ProcessMicroserviceMetrics(context.Background(), ms)
+	recv.alerts = nil
+
+	for _, cpu := range []float64{95.0, 10.0} {
+		ms.NodeMetrics[0].CPUUsage = cpu
+		g.ProcessMicroserviceMetrics(context.Background(), ms)
+	}
+	for _, a := range recv.alerts {
+		if a["AlertID"] == "NODE_CPU_HIGH" {
+			t.Errorf("触发被静默的告警，恢复也不应转发: %+v", a)
+		}
+	}
+	if len(g.suppressed) != 0 {
+		t.Errorf("恢复后应清除拦截记录: %+v", g.suppressed)
+	}
+}
+
+// TestSilenceHTTP 测试静默管理接口
+func TestSilenceHTTP(t *testing.T) {
+	store, _ := NewSilenceStore("")
+
+	rec := httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/silences",
+		strings.NewReader(`{"alert_id":"PAYLOAD_*","duration":"30m","reason":"载荷模式切换","created_by":"ops"}`)))
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"created_by":"ops"`) {
+		t.Fatalf("创建: %d %s", rec.Code, rec.Body.String())
+	}
+	list := store.List()
+	if len(list) != 1 || list[0].EndsAt.Sub(list[0].StartsAt) != 30*time.Minute {
+		t.Fatalf("duration 未生效: %+v", list)
+	}
+
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/silences", strings.NewReader(`{"alert_id":"X","duration":"30m"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("缺少原因应返回 400: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/silences/"+list[0].ID, nil))
+	if rec.Code != http.StatusNoContent || store.Match(&model.AlertEvent{AlertID: "PAYLOAD_MODE_ALERT"}) != "" {
+		t.Errorf("提前结束: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/silences", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "载荷模式切换") {
+		t.Errorf("查询: %d %s", rec.Code, rec.Body.String())
+	}
+}
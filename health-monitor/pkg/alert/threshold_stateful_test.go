@@ -0,0 +1,135 @@
+package alert
+
+import (
+	"testing"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+	"health-monitor/pkg/telemetry"
+)
+
+func newTestStateManager(t *testing.T) *state.StateManager {
+	t.Helper()
+	sm, err := state.NewStateManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sm.Close() })
+	return sm
+}
+
+// useTestCatalog 在测试期间以 data 替换全局参数库，结束后恢复
+func useTestCatalog(t *testing.T, data string) {
+	t.Helper()
+	c, err := telemetry.Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := telemetry.Default()
+	telemetry.SetDefault(c)
+	t.Cleanup(func() { telemetry.SetDefault(old) })
+}
+
+func findAlert(alerts []*model.AlertEvent, id string) *model.AlertEvent {
+	for _, a := range alerts {
+		if a.AlertID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// TestTransceiverFiringResolved 测试通信机告警的触发与恢复
+func TestTransceiverFiringResolved(t *testing.T) {
+	sm := newTestStateManager(t)
+	normal := &model.TransceiverMetrics{TransmitSwitch: 1, TelemetryEncryptStatus: 1, TelecontrolEncryptStatus: 1, InfoChannelSNR: 12, ReceiveRSSI: -80}
+
+	// 首次正常：仅建立状态，产生恢复事件（与供电检查一致）
+	CheckTransceiverThresholdsWithState(normal, sm)
+
+	bad := *normal
+	bad.InfoChannelSNR = 3
+	bad.ReceiveRSSI = -120
+	alerts := CheckTransceiverThresholdsWithState(&bad, sm)
+	if len(alerts) != 2 {
+		t.Fatalf("应产生 2 条触发告警: %+v", alerts)
+	}
+	rssi := findAlert(alerts, "TRX_RSSI_ALERT")
+	if rssi == nil || !rssi.IsFiring() || rssi.TMCode != "TMEZD01147" || rssi.Unit != "dBm" || rssi.FaultCode != "CJB-O2-CS-4" {
+		t.Errorf("RSSI 告警不符: %+v", rssi)
+	}
+
+	if len(CheckTransceiverThresholdsWithState(&bad, sm)) != 0 {
+		t.Error("状态未变化不应重复告警")
+	}
+
+	alerts = CheckTransceiverThresholdsWithState(normal, sm)
+	if len(alerts) != 2 || !alerts[0].IsResolved() || !alerts[1].IsResolved() {
+		t.Errorf("应产生 2 条恢复告警: %+v", alerts)
+	}
+}
+
+// TestUnsourcedLimits 测试内置参数库中未定义限值的自定义代号不触发告警
+func TestUnsourcedLimits(t *testing.T) {
+	if alerts := CheckAttCtrlThresholdsWithState(&model.AttCtrlMetrics{ControlMode: 9}, nil); len(alerts) != 0 {
+		t.Errorf("模式码未定义取值时不应告警: %+v", alerts)
+	}
+	if alerts := CheckThrusterThresholdsWithState(&model.ThrusterMetrics{PressureSensor: 0, FuelLevel: 0, PipelineSwitch: 2}, nil); len(alerts) != 1 || alerts[0].AlertID != "THRUSTER_PIPELINE_ALERT" {
+		t.Errorf("仅管路开关有取值定义: %+v", alerts)
+	}
+	if alerts := CheckEPSThresholdsWithState(&model.EPSMetrics{Voltage: 0, Current: 100}, nil); len(alerts) != 0 {
+		t.Errorf("电源限值未定义时不应告警: %+v", alerts)
+	}
+}
+
+// TestModeCodeValidity 测试模式码有效性检查
+func TestModeCodeValidity(t *testing.T) {
+	useTestCatalog(t, `{"parameters":[
+		{"code":"HM-ATT-MODE","valid":[0,1,2,3,4,5]},
+		{"code":"HM-PL-MODE","valid":[0,1,2]},
+		{"code":"HM-RAIL-MODE","valid":[0,1,2,3]}]}`)
+	sm := newTestStateManager(t)
+
+	if alerts := CheckAttCtrlThresholdsWithState(&model.AttCtrlMetrics{ControlMode: 9}, sm); len(alerts) != 1 || alerts[0].Severity != model.SeverityCritical {
+		t.Errorf("非法姿态模式应告警: %+v", alerts)
+	}
+	if alerts := CheckAttCtrlThresholdsWithState(&model.AttCtrlMetrics{ControlMode: 3}, sm); len(alerts) != 1 || !alerts[0].IsResolved() {
+		t.Errorf("模式恢复应产生恢复告警: %+v", alerts)
+	}
+
+	// 无状态管理器时只在异常时告警
+	if alerts := CheckPayloadThresholdsWithState(&model.PayloadMetrics{WorkMode: 1}, nil); len(alerts) != 0 {
+		t.Errorf("正常模式不应告警: %+v", alerts)
+	}
+	if alerts := CheckRailCtrlThresholdsWithState(&model.RailCtrlMetrics{OrbitMode: 7}, nil); len(alerts) != 1 || !alerts[0].IsFiring() {
+		t.Errorf("非法轨道模式应告警: %+v", alerts)
+	}
+}
+
+// TestThrusterAndEPS 测试推进器燃料分级与电源、敏感器范围
+func TestThrusterAndEPS(t *testing.T) {
+	useTestCatalog(t, `{"parameters":[
+		{"code":"HM-THR-PRESS","unit":"kPa","hard_min":500,"hard_max":2500},
+		{"code":"HM-THR-FUEL","unit":"g","min":100,"hard_min":50},
+		{"code":"HM-THR-PIPE","valid":[0,1]},
+		{"code":"HM-EPS-VOLT","unit":"V","hard_min":24,"hard_max":32},
+		{"code":"HM-EPS-CURR","unit":"A","min":0,"max":10},
+		{"code":"HM-SEN-ACCY","unit":"mg","min":-2000,"max":2000}]}`)
+
+	alerts := CheckThrusterThresholdsWithState(&model.ThrusterMetrics{PressureSensor: 1500, FuelLevel: 40, PipelineSwitch: 1}, nil)
+	fuel := findAlert(alerts, "THRUSTER_FUEL_ALERT")
+	if len(alerts) != 1 || fuel == nil || fuel.Severity != model.SeverityCritical {
+		t.Errorf("燃料低于红线应为严重: %+v", alerts)
+	}
+	alerts = CheckThrusterThresholdsWithState(&model.ThrusterMetrics{PressureSensor: 3000, FuelLevel: 80, PipelineSwitch: 2}, nil)
+	if len(alerts) != 3 || findAlert(alerts, "THRUSTER_FUEL_ALERT").Severity != model.SeverityWarning {
+		t.Errorf("压力、燃料、管路开关应各有告警: %+v", alerts)
+	}
+
+	if alerts := CheckEPSThresholdsWithState(&model.EPSMetrics{Voltage: 22.5, Current: 3}, nil); len(alerts) != 1 || alerts[0].AlertID != "EPS_VOLTAGE_ALERT" {
+		t.Errorf("电源欠压应告警: %+v", alerts)
+	}
+	if alerts := CheckSensorThresholdsWithState(&model.SensorMetrics{AccX: 10, AccY: -2500, AccZ: 0}, nil); len(alerts) != 1 || alerts[0].AlertID != "SENSOR_ACC_Y_ALERT" {
+		t.Errorf("Y 轴加速度越界应告警: %+v", alerts)
+	}
+}
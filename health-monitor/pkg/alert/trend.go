@@ -1,418 +1,426 @@
-/* 针对微服务层指标根据历史序列判断：
+/* 趋势分析与越限预测
 
-连续上升
+对 StateManager 中的指标历史（QueryHistory）做最小二乘线性拟合，得到：
 
-连续下降
+	斜率      每分钟变化量
+	拟合优度  R²（0~1），作为趋势的置信度：序列越接近直线越高，波动、跳变时降低
+	越限时间  按拟合直线从最新样本外推到限值所需的时间
 
-重启次数增长趋势
+趋势预测规则是规则集中带 trend 的规则（见 rules.go），按 op/value（或 outside、catalog 的限值）预测越限：
 
-业务校验失败率上升 */
+	window      拟合窗口，默认 10m，不能超过历史保留时长（state.HistoryRetention）
+	horizon     预测时长：拟合直线外推 horizon 后的预测值越限即触发，即"预计 horizon 内越限"
+	min_r2      最小拟合优度，默认 0.8；不足时不外推，按最新值判定（已越限时保持告警）
+	min_points  窗口内最少样本数，默认 10；样本不足或未覆盖半个窗口时本次不判定
 
+告警值为预测值，预测越过红线（hard_value、hard_min/hard_max）时为 critical。告警状态与阈值规则一样通过 StateManager 跟踪。
+Metadata["trend"] 带 slope（每分钟）、r2、current（最新值）、fitted（拟合的当前值）、points、window、horizon（秒），
+趋势方向上有限值时另带 eta（预计越限秒数，0 为已越限）；消息模板 {{.ETA}} 为 "预计12分钟后越限" 等描述。
+
+Generator 在每次 ECSM 采集后按节点/容器/服务、每帧业务遥测后按组件调用 Analyze*Trends。
+*/
 package alert
 
 import (
 	"context"
 	"fmt"
+	"math"
+	"time"
+
 	"health-monitor/pkg/models"
 	"health-monitor/pkg/state"
-	"time"
+	"health-monitor/pkg/telemetry"
 )
 
+// 趋势预测默认参数
+const (
+	DefaultTrendWindow    = 10 * time.Minute
+	DefaultTrendMinR2     = 0.8
+	DefaultTrendMinPoints = 10
+)
+
+// Trend 规则的趋势预测配置
+type Trend struct {
+	Window    string  `json:"window,omitempty"`     // 拟合窗口（如 10m）
+	Horizon   string  `json:"horizon"`              // 预测时长（如 30m）
+	MinR2     float64 `json:"min_r2,omitempty"`     // 最小拟合优度
+	MinPoints int     `json:"min_points,omitempty"` // 窗口内最少样本数
+
+	window  time.Duration
+	horizon time.Duration
+}
+
+// compileTrend 校验趋势预测配置并补全默认值
+func (r *Rule) compileTrend() error {
+	tr := r.Trend
+	if tr == nil {
+		return nil
+	}
+	switch r.Op {
+	case OpGT, OpGE, OpLT, OpLE, OpOutside, OpCatalog:
+	default:
+		return fmt.Errorf("trend 需要数值限值（> >= < <= outside catalog）")
+	}
+	if r.Rate != "" {
+		return fmt.Errorf("trend 不能与 rate 同用")
+	}
+	if len(r.When) > 0 {
+		return fmt.Errorf("trend 不支持 when")
+	}
+
+	tr.window = DefaultTrendWindow
+	if tr.Window != "" {
+		d, err := time.ParseDuration(tr.Window)
+		if err != nil || d < time.Second {
+			return fmt.Errorf("trend.window %q 非法（如 10m，至少 1s）", tr.Window)
+		}
+		tr.window = d
+	}
+	if tr.window > state.HistoryRetention {
+		return fmt.Errorf("trend.window 不能超过历史保留时长 %s", state.HistoryRetention)
+	}
+	d, err := time.ParseDuration(tr.Horizon)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("trend.horizon %q 非法（如 30m）", tr.Horizon)
+	}
+	tr.horizon = d
+	if tr.MinR2 == 0 {
+		tr.MinR2 = DefaultTrendMinR2
+	}
+	if tr.MinR2 < 0 || tr.MinR2 > 1 {
+		return fmt.Errorf("trend.min_r2 须在 0~1 之间")
+	}
+	if tr.MinPoints == 0 {
+		tr.MinPoints = DefaultTrendMinPoints
+	}
+	if tr.MinPoints < 3 {
+		return fmt.Errorf("trend.min_points 须不小于 3")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                                   拟合
+////////////////////////////////////////////////////////////////////////////////
+
+// trendFit 最小二乘线性拟合结果（时间以最新样本为原点，单位秒）
+type trendFit struct {
+	points int
+	span   float64 // 样本覆盖的秒数
+	slope  float64 // 每秒变化量
+	fitted float64 // 最新样本时刻的拟合值
+	r2     float64 // 拟合优度
+	last   float64 // 最新样本值
+}
+
+// fitTrend 对按时间排序的序列做最小二乘线性拟合，样本少于 2 个或时间全部相同时返回 false
+func fitTrend(timestamps []int64, values []float64) (trendFit, bool) {
+	n := len(values)
+	if n < 2 || len(timestamps) != n {
+		return trendFit{}, false
+	}
+	end := timestamps[n-1]
+	var mx, my float64
+	for i, y := range values {
+		mx += float64(timestamps[i] - end)
+		my += y
+	}
+	mx /= float64(n)
+	my /= float64(n)
+
+	var sxx, sxy, syy float64
+	for i, y := range values {
+		dx, dy := float64(timestamps[i]-end)-mx, y-my
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return trendFit{}, false
+	}
+	f := trendFit{points: n, span: float64(end - timestamps[0]), slope: sxy / sxx, r2: 1, last: values[n-1]}
+	f.fitted = my - f.slope*mx
+	// 序列恒定时拟合无误差，R² 保持 1
+	if syy > 0 {
+		f.r2 = sxy * sxy / (sxx * syy)
+	}
+	return f, true
+}
+
+// at 拟合直线在最新样本之后 dt 秒的值
+func (f trendFit) at(dt float64) float64 {
+	return f.fitted + f.slope*dt
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                                 越限预测
+////////////////////////////////////////////////////////////////////////////////
+
+// trendForecast 一个字段的越限预测
+type trendForecast struct {
+	fit       trendFit
+	trend     *Trend
+	confident bool    // 拟合优度达到 min_r2，按拟合直线外推
+	eta       float64 // 预计越限秒数（0 为已越限），趋势方向上没有限值时为 -1
+}
+
+// forecast 按拟合结果预测越限：取趋势方向上的限值（上升取上限、下降取下限）计算预计到达时间
+func (r *Rule) forecast(fit trendFit, p *telemetry.Parameter) *trendForecast {
+	f := &trendForecast{fit: fit, trend: r.Trend, confident: fit.r2 >= r.Trend.MinR2, eta: -1}
+	if !f.confident || fit.slope == 0 {
+		return f
+	}
+	if limit, ok := r.trendLimit(fit.slope > 0, p); ok {
+		f.eta = math.Max((limit-fit.fitted)/fit.slope, 0)
+	}
+	return f
+}
+
+// trendLimit 上升/下降方向上的限值（优先黄线），该方向没有限值时返回 false
+func (r *Rule) trendLimit(rising bool, p *telemetry.Parameter) (float64, bool) {
+	soft, hard := telemetry.BandSoftHigh, telemetry.BandHardHigh
+	if !rising {
+		soft, hard = telemetry.BandSoftLow, telemetry.BandHardLow
+	}
+	switch r.Op {
+	case OpGT, OpGE:
+		if !rising {
+			return 0, false
+		}
+	case OpLT, OpLE:
+		if rising {
+			return 0, false
+		}
+	}
+	if limit, ok := r.limit(soft, p); ok {
+		return limit, true
+	}
+	return r.limit(hard, p)
+}
+
+// value 参与判定的值：置信时为外推 horizon 后的预测值，否则为最新值
+func (f *trendForecast) value() float64 {
+	if f.confident {
+		return f.fit.at(f.trend.horizon.Seconds())
+	}
+	return f.fit.last
+}
+
+// describe 越限时间描述（模板 {{.ETA}}）
+func (f *trendForecast) describe() string {
+	switch {
+	case f.eta > 60:
+		return fmt.Sprintf("预计%.0f分钟后越限", math.Ceil(f.eta/60))
+	case f.eta > 0:
+		return "预计1分钟内越限"
+	}
+	return "已越限"
+}
+
+// info 附加到告警的趋势信息
+func (f *trendForecast) info() map[string]interface{} {
+	info := map[string]interface{}{
+		"slope":   f.fit.slope * 60,
+		"r2":      f.fit.r2,
+		"current": f.fit.last,
+		"fitted":  f.fit.fitted,
+		"points":  f.fit.points,
+		"window":  int64(f.trend.window / time.Second),
+		"horizon": int64(f.trend.horizon / time.Second),
+	}
+	if f.eta >= 0 {
+		info["eta"] = f.eta
+	}
+	return info
+}
+
+// trendSample 一次历史采样（业务层为组件指标结构体）
+type trendSample struct {
+	ts   int64
+	data interface{}
+}
+
+// trendSeries 一个字段在窗口内的序列
+type trendSeries struct {
+	n          int
+	timestamps []int64
+	values     []float64
+}
+
+// forecastValues 按窗口内的历史逐字段拟合，返回以预测值判定的字段值（样本不足的字段跳过）
+func (r *Rule) forecastValues(t ruleTarget, samples []trendSample) []fieldValue {
+	start := t.timestamp - int64(r.Trend.window/time.Second)
+	var fields []string
+	series := make(map[string]*trendSeries)
+	for _, s := range samples {
+		if s.ts < start {
+			continue
+		}
+		for _, fv := range r.values(s.data) {
+			v, ok := fv.value.(float64)
+			if !ok {
+				continue
+			}
+			se := series[fv.field]
+			if se == nil {
+				se = &trendSeries{n: fv.n}
+				series[fv.field] = se
+				fields = append(fields, fv.field)
+			}
+			se.timestamps = append(se.timestamps, s.ts)
+			se.values = append(se.values, v)
+		}
+	}
+
+	var values []fieldValue
+	for _, field := range fields {
+		se := series[field]
+		if len(se.values) < r.Trend.MinPoints {
+			continue
+		}
+		fit, ok := fitTrend(se.timestamps, se.values)
+		if !ok || fit.span < r.Trend.window.Seconds()/2 {
+			continue
+		}
+		f := r.forecast(fit, r.param(t, field))
+		values = append(values, fieldValue{field: field, n: se.n, value: f.value(), forecast: f})
+	}
+	return values
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                                 趋势分析器
+////////////////////////////////////////////////////////////////////////////////
+
 // TrendAnalyzer 趋势分析器
 type TrendAnalyzer struct {
 	stateManager *state.StateManager
-	
-	// 趋势判断参数
-	trendWindowSize   int           // 趋势窗口大小（数据点数量）
-	trendThreshold    float64       // 趋势判断阈值（变化率）
-	continuousCount   int           // 连续上升/下降的最小次数
-	lookbackDuration  time.Duration // 回溯时长
+	rules        func() *RuleSet // 趋势预测规则来源（Generator 当前规则集），未设置时使用全局默认规则集
+
+	// 派生参数趋势判断参数
+	trendWindowSize  int           // 最少数据点数量
+	minR2            float64       // 判定存在趋势的最小拟合优度
+	lookbackDuration time.Duration // 回溯时长
 }
 
 // NewTrendAnalyzer 创建趋势分析器
 func NewTrendAnalyzer(sm *state.StateManager) *TrendAnalyzer {
 	return &TrendAnalyzer{
 		stateManager:     sm,
-		trendWindowSize:  10,              // 分析最近10个数据点
-		trendThreshold:   0.1,             // 10%变化率
-		continuousCount:  3,               // 连续3次确认趋势
-		lookbackDuration: 5 * time.Minute, // 回溯5分钟历史
+		trendWindowSize:  10,                // 至少10个数据点
+		minR2:            DefaultTrendMinR2, // 拟合优度0.8以上视为存在趋势
+		lookbackDuration: 5 * time.Minute,   // 回溯5分钟历史
+	}
+}
+
+// ruleSet 趋势预测使用的规则集
+func (ta *TrendAnalyzer) ruleSet() *RuleSet {
+	if ta.rules != nil {
+		if rs := ta.rules(); rs != nil {
+			return rs
+		}
 	}
+	return DefaultRules()
 }
 
 // AnalyzeNodeTrends 分析节点趋势
 func (ta *TrendAnalyzer) AnalyzeNodeTrends(ctx context.Context, nodeID string) []*model.AlertEvent {
-	var alerts []*model.AlertEvent
-	
-	// // 查询历史数据
-	// history := ta.stateManager.QueryHistory(state.MetricTypeNode, nodeID, ta.lookbackDuration)
-	// if len(history) < ta.trendWindowSize {
-	// 	// 数据不足，无法进行趋势分析
-	// 	return alerts
-	// }
-	
-	// // 提取节点指标序列
-	// nodeMetrics := make([]*model.NodeMetrics, 0, len(history))
-	// timestamps := make([]int64, 0, len(history))
-	
-	// for _, entry := range history {
-	// 	if nodeMetric, ok := entry.Data.(*model.NodeMetrics); ok {
-	// 		nodeMetrics = append(nodeMetrics, nodeMetric)
-	// 		timestamps = append(timestamps, entry.Timestamp)
-	// 	}
-	// }
-	
-	// // 分析 CPU 使用率趋势
-	// cpuTrend := ta.analyzeCPUTrend(nodeMetrics)
-	// if cpuTrend != nil {
-	// 	alert := &model.AlertEvent{
-	// 		AlertID:     fmt.Sprintf("TREND-NODE-CPU-%s-%d", nodeID, time.Now().Unix()),
-	// 		Type:        "Node-CPU-Trend",
-	// 		FaultCode:   "TREND_CPU_INCREASE",
-	// 		Severity:    model.SeverityWarning,
-	// 		Source:      fmt.Sprintf("node:%s", nodeID),
-	// 		Message:     cpuTrend.Message,
-	// 		MetricValue: cpuTrend.Value,
-	// 		Timestamp:   time.Now().Unix(),
-	// 		Metadata: map[string]interface{}{
-	// 			"trend_type":  cpuTrend.Type,
-	// 			"change_rate": cpuTrend.ChangeRate,
-	// 			"prediction":  cpuTrend.Prediction,
-	// 		},
-	// 	}
-	// 	alerts = append(alerts, alert)
-	// }
-	
-	// // 分析内存使用率趋势
-	// memoryTrend := ta.analyzeMemoryTrend(nodeMetrics)
-	// if memoryTrend != nil {
-	// 	alert := &model.AlertEvent{
-	// 		AlertID:     fmt.Sprintf("TREND-NODE-MEM-%s-%d", nodeID, time.Now().Unix()),
-	// 		Type:        "Node-Memory-Trend",
-	// 		FaultCode:   "TREND_MEMORY_INCREASE",
-	// 		Severity:    model.SeverityWarning,
-	// 		Source:      fmt.Sprintf("node:%s", nodeID),
-	// 		Message:     memoryTrend.Message,
-	// 		MetricValue: memoryTrend.Value,
-	// 		Timestamp:   time.Now().Unix(),
-	// 		Metadata: map[string]interface{}{
-	// 			"trend_type":  memoryTrend.Type,
-	// 			"change_rate": memoryTrend.ChangeRate,
-	// 			"prediction":  memoryTrend.Prediction,
-	// 		},
-	// 	}
-	// 	alerts = append(alerts, alert)
-	// }
-	
-	return alerts
+	return ta.analyze(ruleTarget{layer: LayerNode, id: nodeID}, state.MetricTypeNode, nodeID)
 }
 
 // AnalyzeContainerTrends 分析容器趋势
 func (ta *TrendAnalyzer) AnalyzeContainerTrends(ctx context.Context, containerID string) []*model.AlertEvent {
-	var alerts []*model.AlertEvent
-	
-	// // 查询历史数据
-	// history := ta.stateManager.QueryHistory(state.MetricTypeContainer, containerID, ta.lookbackDuration)
-	// if len(history) < ta.trendWindowSize {
-	// 	return alerts
-	// }
-	
-	// // 提取容器指标序列
-	// containerMetrics := make([]*model.ContainerMetrics, 0, len(history))
-	
-	// for _, entry := range history {
-	// 	if containerMetric, ok := entry.Data.(*model.ContainerMetrics); ok {
-	// 		containerMetrics = append(containerMetrics, containerMetric)
-	// 	}
-	// }
-	
-	// // 分析重启趋势
-	// restartTrend := ta.analyzeRestartTrend(containerMetrics)
-	// if restartTrend != nil {
-	// 	alert := &model.AlertEvent{
-	// 		AlertID:     fmt.Sprintf("TREND-CONTAINER-RESTART-%s-%d", containerID, time.Now().Unix()),
-	// 		Type:        "Container-Restart-Trend",
-	// 		FaultCode:   "TREND_RESTART_INCREASE",
-	// 		Severity:    model.SeverityWarning,
-	// 		Source:      fmt.Sprintf("container:%s", containerID),
-	// 		Message:     restartTrend.Message,
-	// 		MetricValue: restartTrend.Value,
-	// 		Timestamp:   time.Now().Unix(),
-	// 		Metadata: map[string]interface{}{
-	// 			"trend_type":   restartTrend.Type,
-	// 			"restart_rate": restartTrend.ChangeRate,
-	// 		},
-	// 	}
-	// 	alerts = append(alerts, alert)
-	// }
-	
-	return alerts
+	return ta.analyze(ruleTarget{layer: LayerContainer, id: containerID}, state.MetricTypeContainer, containerID)
 }
 
 // AnalyzeServiceTrends 分析服务趋势
 func (ta *TrendAnalyzer) AnalyzeServiceTrends(ctx context.Context, serviceID string) []*model.AlertEvent {
-	var alerts []*model.AlertEvent
-	
-	// // 查询历史数据
-	// history := ta.stateManager.QueryHistory(state.MetricTypeService, serviceID, ta.lookbackDuration)
-	// if len(history) < ta.trendWindowSize {
-	// 	return alerts
-	// }
-	
-	// // 提取服务指标序列
-	// serviceMetrics := make([]*model.ServiceMetrics, 0, len(history))
-	
-	// for _, entry := range history {
-	// 	if serviceMetric, ok := entry.Data.(*model.ServiceMetrics); ok {
-	// 		serviceMetrics = append(serviceMetrics, serviceMetric)
-	// 	}
-	// }
-	
-	// // 分析业务校验失败率趋势
-	// validationTrend := ta.analyzeValidationTrend(serviceMetrics)
-	// if validationTrend != nil {
-	// 	alert := &model.AlertEvent{
-	// 		AlertID:     fmt.Sprintf("TREND-SERVICE-VALIDATION-%s-%d", serviceID, time.Now().Unix()),
-	// 		Type:        "Service-Validation-Trend",
-	// 		FaultCode:   "TREND_VALIDATION_FAILURE",
-	// 		Severity:    model.SeverityWarning,
-	// 		Source:      fmt.Sprintf("service:%s", serviceID),
-	// 		Message:     validationTrend.Message,
-	// 		MetricValue: validationTrend.Value,
-	// 		Timestamp:   time.Now().Unix(),
-	// 		Metadata: map[string]interface{}{
-	// 			"trend_type":   validationTrend.Type,
-	// 			"failure_rate": validationTrend.ChangeRate,
-	// 		},
-	// 	}
-	// 	alerts = append(alerts, alert)
-	// }
-	
-	return alerts
+	return ta.analyze(ruleTarget{layer: LayerService, id: serviceID}, state.MetricTypeService, serviceID)
 }
 
-// TrendResult 趋势分析结果
-type TrendResult struct {
-	Type       string  // "increasing", "decreasing", "stable"
-	Message    string  // 描述信息
-	Value      float64 // 当前值
-	ChangeRate float64 // 变化率
-	Prediction string  // 预测信息
+// AnalyzeBusinessTrends 分析业务组件遥测趋势
+func (ta *TrendAnalyzer) AnalyzeBusinessTrends(ctx context.Context, componentType uint8) []*model.AlertEvent {
+	return ta.analyze(ruleTarget{layer: LayerBusiness, component: componentType}, state.MetricTypeBusiness, string(rune(componentType)))
 }
 
-// analyzeCPUTrend 分析 CPU 使用率趋势
-func (ta *TrendAnalyzer) analyzeCPUTrend(metrics []*model.NodeMetrics) *TrendResult {
-	if len(metrics) < ta.trendWindowSize {
+// analyze 按趋势预测规则分析一个实体/业务组件的历史，以最新一次采样作为评估时刻
+func (ta *TrendAnalyzer) analyze(t ruleTarget, metricType state.MetricType, id string) []*model.AlertEvent {
+	sm := ta.stateManager
+	if sm == nil {
 		return nil
 	}
-	
-	// 提取 CPU 使用率序列
-	cpuValues := make([]float64, 0, len(metrics))
-	for _, m := range metrics {
-		// CPU 使用率可能是 float64 或 string
-		switch v := m.CPUUsage.(type) {
-		case float64:
-			cpuValues = append(cpuValues, v)
-		case string:
-			// 尝试解析字符串
-			var cpu float64
-			fmt.Sscanf(v, "%f", &cpu)
-			cpuValues = append(cpuValues, cpu)
+	var rules []*Rule
+	for _, r := range ta.ruleSet().TrendRules(t.layer) {
+		if r.Layer != LayerBusiness || r.Component == t.component {
+			rules = append(rules, r)
 		}
 	}
-	
-	if len(cpuValues) < ta.trendWindowSize {
+	if len(rules) == 0 {
 		return nil
 	}
-	
-	// 计算趋势
-	trend := ta.calculateTrend(cpuValues)
-	
-	// 判断是否连续上升
-	if trend.IsIncreasing && trend.ContinuousCount >= ta.continuousCount {
-		currentValue := cpuValues[len(cpuValues)-1]
-		
-		// 预测未来值
-		prediction := "正常"
-		if currentValue > 70 && trend.ChangeRate > 0.1 {
-			prediction = "可能在未来5分钟内达到80%"
-		} else if currentValue > 80 {
-			prediction = "可能在未来3分钟内达到90%"
-		}
-		
-		return &TrendResult{
-			Type:       "increasing",
-			Message:    fmt.Sprintf("CPU使用率持续上升，当前%.1f%%，变化率%.1f%%", currentValue, trend.ChangeRate*100),
-			Value:      currentValue,
-			ChangeRate: trend.ChangeRate,
-			Prediction: prediction,
-		}
-	}
-	
-	return nil
-}
 
-// analyzeMemoryTrend 分析内存使用率趋势
-func (ta *TrendAnalyzer) analyzeMemoryTrend(metrics []*model.NodeMetrics) *TrendResult {
-	if len(metrics) < ta.trendWindowSize {
+	samples := historySamples(sm.QueryHistory(metricType, id, state.HistoryRetention))
+	if len(samples) == 0 {
 		return nil
 	}
-	
-	// 计算内存使用率序列
-	memoryUsageValues := make([]float64, 0, len(metrics))
-	for _, m := range metrics {
-		if m.MemoryTotal > 0 {
-			usageRate := float64(m.MemoryTotal-m.MemoryFree) / float64(m.MemoryTotal) * 100
-			memoryUsageValues = append(memoryUsageValues, usageRate)
-		}
-	}
-	
-	if len(memoryUsageValues) < ta.trendWindowSize {
-		return nil
+	latest := samples[len(samples)-1]
+	t.data, t.timestamp = latest.data, latest.ts
+	if m, ok := latest.data.(*model.ContainerMetrics); ok {
+		t.metadata = containerMetadata(m)
 	}
-	
-	// 计算趋势
-	trend := ta.calculateTrend(memoryUsageValues)
-	
-	// 判断是否连续上升
-	if trend.IsIncreasing && trend.ContinuousCount >= ta.continuousCount {
-		currentValue := memoryUsageValues[len(memoryUsageValues)-1]
-		
-		prediction := "正常"
-		if currentValue > 70 && trend.ChangeRate > 0.1 {
-			prediction = "可能在未来10分钟内达到85%"
-		} else if currentValue > 85 {
-			prediction = "可能在未来5分钟内触发OOM"
-		}
-		
-		return &TrendResult{
-			Type:       "increasing",
-			Message:    fmt.Sprintf("内存使用率持续上升，当前%.1f%%，变化率%.1f%%", currentValue, trend.ChangeRate*100),
-			Value:      currentValue,
-			ChangeRate: trend.ChangeRate,
-			Prediction: prediction,
+
+	var alerts []*model.AlertEvent
+	for _, r := range rules {
+		for _, fv := range r.forecastValues(t, samples) {
+			if alert := r.check(t, fv, sm); alert != nil {
+				alerts = append(alerts, alert)
+			}
 		}
 	}
-	
-	return nil
+	return alerts
 }
 
-// analyzeRestartTrend 分析容器重启趋势
-func (ta *TrendAnalyzer) analyzeRestartTrend(metrics []*model.ContainerMetrics) *TrendResult {
-	if len(metrics) < ta.trendWindowSize {
-		return nil
+// DerivedSeries 查询派生参数（含计算字段）在回溯时长内的历史序列，entity 为实体ID或业务组件编号（如 0x03）
+func (ta *TrendAnalyzer) DerivedSeries(layer, entity, name string) ([]int64, []float64) {
+	if ta.stateManager == nil {
+		return nil, nil
 	}
-	
-	// 统计重启次数变化
-	// 通过比较相邻时间点的 Uptime 来判断是否发生重启
-	restartCount := 0
-	for i := 1; i < len(metrics); i++ {
-		// Uptime 减少表示发生了重启
-		if metrics[i].Uptime < metrics[i-1].Uptime {
-			restartCount++
+	history := ta.stateManager.QueryHistory(state.MetricTypeDerived, state.DerivedID(layer, entity, name), ta.lookbackDuration)
+	timestamps := make([]int64, 0, len(history))
+	values := make([]float64, 0, len(history))
+	for _, entry := range history {
+		if v, ok := entry.Data.(float64); ok {
+			timestamps = append(timestamps, entry.Timestamp)
+			values = append(values, v)
 		}
 	}
-	
-	// 计算重启率（每分钟重启次数）
-	timeSpan := float64(len(metrics)) // 假设每个数据点间隔约为 30 秒
-	restartRate := float64(restartCount) / (timeSpan / 2.0) // 转换为每分钟
-	
-	// 如果重启率异常
-	if restartCount >= 2 {
-		return &TrendResult{
-			Type:       "increasing",
-			Message:    fmt.Sprintf("容器重启次数异常，最近%d分钟内重启%d次", int(ta.lookbackDuration.Minutes()), restartCount),
-			Value:      float64(restartCount),
-			ChangeRate: restartRate,
-		}
-	}
-	
-	return nil
+	return timestamps, values
 }
 
-// analyzeValidationTrend 分析业务校验失败率趋势
-func (ta *TrendAnalyzer) analyzeValidationTrend(metrics []*model.ServiceMetrics) *TrendResult {
-	if len(metrics) < ta.trendWindowSize {
+// AnalyzeDerivedTrend 分析派生参数的趋势，数据不足或拟合优度不足（无明显趋势）时返回 nil
+func (ta *TrendAnalyzer) AnalyzeDerivedTrend(layer, entity, name string) *TrendResult {
+	timestamps, values := ta.DerivedSeries(layer, entity, name)
+	if len(values) < ta.trendWindowSize {
 		return nil
 	}
-	
-	// 计算校验失败率序列
-	failureRates := make([]float64, 0, len(metrics))
-	for _, m := range metrics {
-		totalChecks := m.BusinessCheckSuccess + m.BusinessCheckFail
-		if totalChecks > 0 {
-			failureRate := float64(m.BusinessCheckFail) / float64(totalChecks) * 100
-			failureRates = append(failureRates, failureRate)
-		}
-	}
-	
-	if len(failureRates) < ta.trendWindowSize {
+	fit, ok := fitTrend(timestamps, values)
+	if !ok || fit.slope == 0 || fit.r2 < ta.minR2 {
 		return nil
 	}
-	
-	// 计算趋势
-	trend := ta.calculateTrend(failureRates)
-	
-	// 判断是否连续上升
-	if trend.IsIncreasing && trend.ContinuousCount >= ta.continuousCount {
-		currentValue := failureRates[len(failureRates)-1]
-		
-		// 如果失败率超过阈值且持续上升
-		if currentValue > 5.0 { // 失败率超过5%
-			return &TrendResult{
-				Type:       "increasing",
-				Message:    fmt.Sprintf("业务校验失败率持续上升，当前%.1f%%，变化率%.1f%%", currentValue, trend.ChangeRate*100),
-				Value:      currentValue,
-				ChangeRate: trend.ChangeRate,
-			}
-		}
+	result := &TrendResult{Type: "increasing", Value: fit.last, ChangeRate: fit.slope * 60, R2: fit.r2}
+	direction := "上升"
+	if fit.slope < 0 {
+		result.Type, direction = "decreasing", "下降"
 	}
-	
-	return nil
+	result.Message = fmt.Sprintf("%s持续%s，当前%.2f，每分钟变化%.2f (R²=%.2f)", name, direction, fit.last, result.ChangeRate, fit.r2)
+	return result
 }
 
-// TrendInfo 趋势信息
-type TrendInfo struct {
-	IsIncreasing    bool
-	IsDecreasing    bool
-	ContinuousCount int     // 连续上升/下降的次数
-	ChangeRate      float64 // 平均变化率
+// TrendResult 趋势分析结果
+type TrendResult struct {
+	Type       string  // "increasing", "decreasing"
+	Message    string  // 描述信息
+	Value      float64 // 当前值
+	ChangeRate float64 // 每分钟变化量（拟合斜率）
+	R2         float64 // 拟合优度
 }
-
-// calculateTrend 计算数值序列的趋势
-func (ta *TrendAnalyzer) calculateTrend(values []float64) *TrendInfo {
-	if len(values) < 2 {
-		return &TrendInfo{}
-	}
-	
-	// 计算相邻点的变化方向
-	increases := 0
-	decreases := 0
-	totalChange := 0.0
-	
-	for i := 1; i < len(values); i++ {
-		diff := values[i] - values[i-1]
-		if diff > 0 {
-			increases++
-			totalChange += diff / values[i-1] // 相对变化率
-		} else if diff < 0 {
-			decreases++
-			totalChange += diff / values[i-1]
-		}
-	}
-	
-	// 判断主要趋势
-	info := &TrendInfo{}
-	
-	if increases > len(values)/2 {
-		info.IsIncreasing = true
-		info.ContinuousCount = increases
-		info.ChangeRate = totalChange / float64(increases)
-	} else if decreases > len(values)/2 {
-		info.IsDecreasing = true
-		info.ContinuousCount = decreases
-		info.ChangeRate = totalChange / float64(decreases)
-	}
-	
-	return info
-}
\ No newline at end of file
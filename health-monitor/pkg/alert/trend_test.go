@@ -0,0 +1,165 @@
+package alert
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// TestFitTrend 测试最小二乘拟合的斜率与拟合优度
+func TestFitTrend(t *testing.T) {
+	ts := []int64{0, 10, 20, 30, 40}
+	fit, ok := fitTrend(ts, []float64{1, 3, 5, 7, 9})
+	if !ok || math.Abs(fit.slope-0.2) > 1e-9 || math.Abs(fit.r2-1) > 1e-9 || math.Abs(fit.fitted-9) > 1e-9 || fit.span != 40 {
+		t.Errorf("直线序列拟合不符: %+v", fit)
+	}
+	if v := fit.at(60); math.Abs(v-21) > 1e-9 {
+		t.Errorf("外推 60s 应为 21: %v", v)
+	}
+
+	fit, ok = fitTrend(ts, []float64{10, 30, 10, 30, 12})
+	if !ok || fit.r2 > 0.1 {
+		t.Errorf("来回波动的序列拟合优度应很低: %+v", fit)
+	}
+	fit, ok = fitTrend(ts, []float64{5, 5, 5, 5, 5})
+	if !ok || fit.slope != 0 || fit.r2 != 1 {
+		t.Errorf("恒定序列应为零斜率: %+v", fit)
+	}
+	if _, ok := fitTrend([]int64{5, 5}, []float64{1, 2}); ok {
+		t.Error("时间相同的样本无法拟合")
+	}
+}
+
+// TestContainerMemoryTrend 测试容器内存持续增长时预测越限时间并告警，增长停止后解除
+func TestContainerMemoryTrend(t *testing.T) {
+	sm := newTestStateManager(t)
+	rs := mustParseRules(t, `{"rules":[{"alert_id":"MEM_TREND","layer":"container","field":"memory_percent","op":">","value":90,
+		"trend":{"window":"10m","horizon":"30m"},"type":"memory_trend","unit":"%","format":"%.2f",
+		"message":"容器内存使用率{{.ETA}}: 预测值{{.Value}}% (正常{{.Range}})","resolved_message":"容器内存使用率越限趋势已解除: 预测值{{.Value}}%"}]}`)
+	if len(rs.Enabled(LayerContainer)) != 0 || len(rs.TrendRules(LayerContainer)) != 1 {
+		t.Fatal("趋势预测规则不应参与逐次采样的判定")
+	}
+	ta := NewTrendAnalyzer(sm)
+	ta.rules = func() *RuleSet { return rs }
+
+	// 每 30s 一次采集，内存使用率每分钟增长 1%
+	start := time.Now().Unix() - 300
+	feed := func(i int, usage int64) []*model.AlertEvent {
+		m := &model.ContainerMetrics{ID: "c1", ServiceName: "svc", MemoryUsage: usage, MemoryLimit: 1000}
+		if err := sm.UpdateMetric(&state.ContainerMetric{Data: m, Timestamp: start + int64(i)*30}); err != nil {
+			t.Fatal(err)
+		}
+		return ta.AnalyzeContainerTrends(context.Background(), "c1")
+	}
+	for i := 0; i < 10; i++ {
+		if alerts := feed(i, 603+int64(i)*5); len(alerts) != 0 {
+			t.Fatalf("样本未覆盖半个窗口时不应判定: %d %+v", i, alerts)
+		}
+	}
+	// 当前 65.3%，30 分钟后预测 95.3%，预计 24.7 分钟后超过 90%
+	alerts := feed(10, 653)
+	if len(alerts) != 1 || !alerts[0].IsFiring() || alerts[0].Source != "c1" ||
+		alerts[0].Message != "容器内存使用率预计25分钟后越限: 预测值95.30% (正常≤90%)" {
+		t.Fatalf("内存增长趋势告警不符: %+v", alerts)
+	}
+	trend := alerts[0].Metadata["trend"].(map[string]interface{})
+	if math.Abs(trend["slope"].(float64)-1) > 1e-6 || trend["r2"].(float64) < 0.999 ||
+		math.Abs(trend["eta"].(float64)-1482) > 1e-6 || trend["points"] != 11 || alerts[0].Metadata["serviceName"] != "svc" {
+		t.Errorf("趋势信息不符: %+v %+v", trend, alerts[0].Metadata)
+	}
+	if alerts := feed(11, 658); len(alerts) != 0 {
+		t.Errorf("趋势持续时不应重复告警: %+v", alerts)
+	}
+
+	// 内存回落后趋势解除
+	var resolved *model.AlertEvent
+	for i := 12; i < 17 && resolved == nil; i++ {
+		resolved = findAlert(feed(i, 300), "MEM_TREND")
+	}
+	if resolved == nil || !resolved.IsResolved() || !strings.HasPrefix(resolved.Message, "容器内存使用率越限趋势已解除") {
+		t.Errorf("内存回落后应解除趋势告警: %+v", resolved)
+	}
+}
+
+// TestBusinessTrend 测试内置规则对蓄电池电压衰减与热控温度漂移的预测
+func TestBusinessTrend(t *testing.T) {
+	sm := newTestStateManager(t)
+	ta := NewTrendAnalyzer(sm)
+
+	// 每 10s 一帧，电压每分钟下降 0.12V，热控温度3 每分钟上升 0.6℃
+	start := time.Now().Unix() - 300
+	var power, thermal []*model.AlertEvent
+	for i := 0; i <= 30; i++ {
+		ts := start + int64(i)*10
+		temps := [10]float64{20, 20, 30 + 0.1*float64(i), 20, 20, 20, 20, 20, 20, 20}
+		for _, bm := range []*model.BusinessMetrics{
+			{ComponentType: 0x03, Timestamp: ts, Data: &model.PowerMetrics{BatteryVoltage: 25 - 0.02*float64(i)}},
+			{ComponentType: 0x06, Timestamp: ts, Data: &model.ThermalMetrics{ThermalTemps: temps}},
+		} {
+			if err := sm.UpdateMetric(&state.BusinessMetric{Data: bm, Timestamp: ts}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		power = ta.AnalyzeBusinessTrends(context.Background(), 0x03)
+		thermal = ta.AnalyzeBusinessTrends(context.Background(), 0x06)
+		if i < 30 && len(power)+len(thermal) != 0 {
+			t.Fatalf("样本未覆盖半个窗口时不应判定: %d %+v %+v", i, power, thermal)
+		}
+	}
+
+	// 当前 24.4V，30 分钟后预测 20.8V，低于黄线 21V、高于红线 20V
+	a := findAlert(power, "TREND_BATTERY_VOLTAGE")
+	if a == nil || !a.IsFiring() || a.Severity != model.SeverityWarning || a.Source != "battery_trend_monitor" ||
+		a.Message != "蓄电池电压预计29分钟后越限: 预测值20.80V (正常[21,29.4]V)" {
+		t.Errorf("电压衰减趋势告警不符: %+v", power)
+	}
+
+	var drift *model.AlertEvent
+	for _, a := range thermal {
+		if a.IsFiring() {
+			if drift != nil {
+				t.Errorf("只有热控温度3 应告警: %+v", thermal)
+			}
+			drift = a
+		}
+	}
+	if drift == nil || drift.AlertID != "TREND_THERMAL_DRIFT" || drift.Source != "thermal_trend3" ||
+		drift.Message != "热控温度3预计29分钟后越限: 预测值51.0℃ (正常[-20,50]℃)" {
+		t.Errorf("温度漂移趋势告警不符: %+v", drift)
+	}
+}
+
+// TestParseTrendRules 测试趋势预测规则校验
+func TestParseTrendRules(t *testing.T) {
+	base := `"alert_id":"A","layer":"node","field":"disk_percent","type":"t",`
+	cases := map[string]string{
+		"判定条件": `"op":"==","value":1,"trend":{"horizon":"1h"}`,
+		"缺少预测": `"op":">","value":90,"trend":{}`,
+		"窗口过长": `"op":">","value":90,"trend":{"window":"1h","horizon":"1h"}`,
+		"窗口非法": `"op":">","value":90,"trend":{"window":"abc","horizon":"1h"}`,
+		"拟合优度": `"op":">","value":90,"trend":{"horizon":"1h","min_r2":2}`,
+		"样本数":  `"op":">","value":90,"trend":{"horizon":"1h","min_points":2}`,
+		"生效条件": `"op":">","value":90,"trend":{"horizon":"1h"},"when":[{"component":2,"field":"SerialStatus","op":"==","value":1}]`,
+	}
+	for name, rule := range cases {
+		if _, err := ParseRules([]byte(`{"rules":[{` + base + rule + `}]}`)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+
+	rs := DefaultRules()
+	r, ok := rs.Rule("TREND_NODE_DISK")
+	if !ok || r.Trend.window != DefaultTrendWindow || r.Trend.horizon != time.Hour || r.Trend.MinR2 != DefaultTrendMinR2 {
+		t.Errorf("内置规则集应包含磁盘趋势预测规则: %+v", r)
+	}
+	for _, r := range rs.Enabled(LayerNode) {
+		if r.Trend != nil {
+			t.Errorf("趋势预测规则 %s 不应参与逐次采样的判定", r.AlertID)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+/*
+遥测中断看门狗
+
+Receiver 只在收到报文时工作，数据源停止上报时不会有任何告警。看门狗按业务组件、ECSM 实体配置期望上报周期：
+
+	missed    超过多少个期望周期未收到即判定中断，默认 3
+	business  业务组件编号（"0x03" 或 "3"，"*" 为全部组件）→ 期望上报周期（如 "1s"）
+	entities  "node" / "container" / "service"（该类全部实体）或 "node/<id>" → 期望上报周期
+
+配置换算为 StateManager 的超时时长（见 state/freshness.go），超时的最新状态在 GetLatestState 中带 Stale 标记。
+由 monitor 定期调用 Check：超时时产生 TELEMETRY_LOST 告警（按组件/实体区分来源），数据恢复后下一次检查产生恢复告警。
+只监视收到过数据的组件/实体；从 ECSM 采集结果中消失的实体由 ForgetEntities 删除，其中断告警随之解除。
+与 LINK_QUALITY_ALERT（按序列计数统计丢包）互补：后者需要报文持续到达。
+*/
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// TelemetryLostAlertID 遥测中断告警ID
+const TelemetryLostAlertID = "TELEMETRY_LOST"
+
+// DefaultWatchdogMissed 默认允许缺失的期望周期数
+const DefaultWatchdogMissed = 3
+
+// WatchdogConfig 遥测中断看门狗配置
+type WatchdogConfig struct {
+	Missed   int                 `json:"missed,omitempty"`   // 超过多少个期望周期未收到即判定中断
+	Severity model.AlertSeverity `json:"severity,omitempty"` // 告警严重程度，默认 warning
+	Business map[string]string   `json:"business,omitempty"` // 业务组件编号 → 期望上报周期
+	Entities map[string]string   `json:"entities,omitempty"` // 实体类型或 类型/ID → 期望上报周期
+
+	timeouts []watchdogTimeout
+}
+
+// watchdogTimeout 一项超时设置
+type watchdogTimeout struct {
+	typ     state.MetricType
+	id      string
+	timeout time.Duration
+}
+
+// entityLayers 实体类型 → 指标类型
+var entityLayers = map[string]state.MetricType{
+	LayerNode:      state.MetricTypeNode,
+	LayerContainer: state.MetricTypeContainer,
+	LayerService:   state.MetricTypeService,
+}
+
+// DefaultWatchdogConfig 默认配置：全部业务组件按 businessPeriod，ECSM 实体按采集间隔 ecsmPeriod（<= 0 表示不监视）
+func DefaultWatchdogConfig(businessPeriod, ecsmPeriod time.Duration) *WatchdogConfig {
+	cfg := &WatchdogConfig{Business: map[string]string{}, Entities: map[string]string{}}
+	if businessPeriod > 0 {
+		cfg.Business["*"] = businessPeriod.String()
+	}
+	if ecsmPeriod > 0 {
+		for layer := range entityLayers {
+			cfg.Entities[layer] = ecsmPeriod.String()
+		}
+	}
+	cfg.Validate() // 周期均为正数，不会失败
+	return cfg
+}
+
+// ParseWatchdogConfig 解析并校验看门狗配置 JSON
+func ParseWatchdogConfig(data []byte) (*WatchdogConfig, error) {
+	var cfg WatchdogConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析看门狗配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("看门狗配置校验失败: %w", err)
+	}
+	return &cfg, nil
+}
+
+// LoadWatchdogConfig 从文件加载看门狗配置
+func LoadWatchdogConfig(path string) (*WatchdogConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取看门狗配置文件失败: %w", err)
+	}
+	return ParseWatchdogConfig(data)
+}
+
+// Validate 校验配置并换算超时时长
+func (c *WatchdogConfig) Validate() error {
+	if c.Missed == 0 {
+		c.Missed = DefaultWatchdogMissed
+	}
+	if c.Missed < 1 {
+		return fmt.Errorf("missed 须不小于 1")
+	}
+	if c.Severity == "" {
+		c.Severity = model.SeverityWarning
+	}
+	if !validSeverity(c.Severity) {
+		return fmt.Errorf("未知严重程度 %q", c.Severity)
+	}
+
+	c.timeouts = c.timeouts[:0]
+	for key, period := range c.Business {
+		id := ""
+		if key != "*" {
+			comp, err := strconv.ParseUint(key, 0, 8)
+			if err != nil {
+				return fmt.Errorf("business 组件编号 %q 非法", key)
+			}
+			if _, ok := businessTypes[uint8(comp)]; !ok {
+				return fmt.Errorf("未知业务组件编号 %s", key)
+			}
+			id = string(rune(comp))
+		}
+		if err := c.add(state.MetricTypeBusiness, id, "business "+key, period); err != nil {
+			return err
+		}
+	}
+	for key, period := range c.Entities {
+		layer, id, _ := strings.Cut(key, "/")
+		typ, ok := entityLayers[layer]
+		if !ok {
+			return fmt.Errorf("entities %q 须为 node、container、service 或 类型/ID", key)
+		}
+		if err := c.add(typ, id, "entities "+key, period); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *WatchdogConfig) add(typ state.MetricType, id, name, period string) error {
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("%s 期望上报周期 %q 非法", name, period)
+	}
+	c.timeouts = append(c.timeouts, watchdogTimeout{typ: typ, id: id, timeout: d * time.Duration(c.Missed)})
+	return nil
+}
+
+// Apply 将超时时长设置到 StateManager（替换原有设置）
+func (c *WatchdogConfig) Apply(sm *state.StateManager) {
+	sm.ClearStaleTimeouts()
+	for _, t := range c.timeouts {
+		sm.SetStaleTimeout(t.typ, t.id, t.timeout)
+	}
+}
+
+// Check 检查各组件/实体的数据新鲜度，超时或恢复时返回告警；layers 限定层级（为空时检查全部）
+func (c *WatchdogConfig) Check(sm *state.StateManager, now time.Time, layers ...string) []*model.AlertEvent {
+	var alerts []*model.AlertEvent
+	for _, f := range sm.CheckFreshness(now) {
+		layer := string(f.Type)
+		if len(layers) > 0 && !containsString(layers, layer) {
+			continue
+		}
+		source, name := telemetrySource(f)
+		// 只在中断或已有中断告警时更新状态，避免每个正常实体都产生一次恢复告警
+		if !f.Stale && sm.GetAlertLevel(TelemetryLostAlertID, source) == "" {
+			continue
+		}
+		level := ""
+		if f.Stale {
+			level = "lost"
+		}
+		shouldSend, firing, _ := sm.CheckAndUpdateAlertLevel(TelemetryLostAlertID, source, level)
+		if !shouldSend {
+			continue
+		}
+
+		period := time.Duration(f.Timeout / float64(c.Missed) * float64(time.Second)).Round(time.Millisecond)
+		alert := &model.AlertEvent{
+			AlertID:     TelemetryLostAlertID,
+			Type:        "telemetry_lost",
+			Source:      source,
+			Timestamp:   now.Unix(),
+			MetricValue: f.Age,
+			Unit:        "s",
+			Metadata: map[string]interface{}{
+				"layer":     layer,
+				"last_seen": f.LastSeen,
+				"period":    period.Seconds(),
+				"timeout":   f.Timeout,
+			},
+		}
+		if f.Type == state.MetricTypeBusiness {
+			alert.Metadata["component_type"] = f.Component
+		} else {
+			alert.Metadata["entity_id"] = f.ID
+		}
+		if firing {
+			alert.Status = model.AlertStatusFiring
+			alert.Severity = c.Severity
+			alert.Message = fmt.Sprintf("%s遥测中断: 已 %.0fs 未收到数据（期望周期 %s）", name, f.Age, period)
+		} else {
+			alert.Status = model.AlertStatusResolved
+			alert.Severity = model.SeverityInfo
+			alert.Message = fmt.Sprintf("%s遥测已恢复", name)
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// ForgetEntities 删除已从 ECSM 采集结果中消失的节点、容器、服务（如容器重新部署后ID变化），
+// 对仍处于遥测中断告警的实体返回恢复告警。某类实体在采集结果中为空时不清理，避免 ECSM 接口异常时误删
+func ForgetEntities(sm *state.StateManager, ms *model.MicroServiceMetricsSet, now time.Time) []*model.AlertEvent {
+	ids := make(map[state.MetricType][]string)
+	for _, n := range ms.NodeMetrics {
+		ids[state.MetricTypeNode] = append(ids[state.MetricTypeNode], n.ID)
+	}
+	for _, c := range ms.ContainerMetrics {
+		ids[state.MetricTypeContainer] = append(ids[state.MetricTypeContainer], c.ID)
+	}
+	for _, svc := range ms.ServiceMetrics {
+		ids[state.MetricTypeService] = append(ids[state.MetricTypeService], svc.ID)
+	}
+
+	var alerts []*model.AlertEvent
+	for _, typ := range []state.MetricType{state.MetricTypeNode, state.MetricTypeContainer, state.MetricTypeService} {
+		if len(ids[typ]) == 0 {
+			continue
+		}
+		for _, id := range sm.PruneEntities(typ, ids[typ]) {
+			source, name := telemetrySource(state.FreshnessState{Type: typ, ID: id})
+			if sm.GetAlertLevel(TelemetryLostAlertID, source) == "" {
+				continue
+			}
+			sm.CheckAndUpdateAlertLevel(TelemetryLostAlertID, source, "")
+			alerts = append(alerts, &model.AlertEvent{
+				AlertID:   TelemetryLostAlertID,
+				Type:      "telemetry_lost",
+				Source:    source,
+				Status:    model.AlertStatusResolved,
+				Severity:  model.SeverityInfo,
+				Timestamp: now.Unix(),
+				Message:   fmt.Sprintf("%s已从采集结果中移除，遥测中断告警解除", name),
+				Metadata: map[string]interface{}{
+					"layer":     string(typ),
+					"entity_id": id,
+					"removed":   true,
+				},
+			})
+		}
+	}
+	return alerts
+}
+
+// telemetrySource 遥测中断告警的来源与消息中的名称
+func telemetrySource(f state.FreshnessState) (source, name string) {
+	switch f.Type {
+	case state.MetricTypeBusiness:
+		return fmt.Sprintf("telemetry-0x%02X", f.Component), fmt.Sprintf("组件 0x%02X ", f.Component)
+	case state.MetricTypeNode:
+		name = "节点"
+	case state.MetricTypeContainer:
+		name = "容器"
+	case state.MetricTypeService:
+		name = "服务"
+	}
+	return string(f.Type) + "/" + f.ID, name + " " + f.ID + " "
+}
@@ -0,0 +1,152 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+// TestTelemetryWatchdog 测试数据超时未更新时标记过期、产生遥测中断告警，数据恢复后解除
+func TestTelemetryWatchdog(t *testing.T) {
+	sm := newTestStateManager(t)
+	cfg, err := ParseWatchdogConfig([]byte(`{"missed":2,"business":{"0x03":"10ms"},"entities":{"node":"10ms"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Apply(sm)
+
+	power := &state.BusinessMetric{Data: &model.BusinessMetrics{ComponentType: 0x03, Data: &model.PowerMetrics{}}}
+	sm.UpdateMetric(power)
+	sm.UpdateMetric(&state.NodeMetric{Data: &model.NodeMetrics{ID: "n1"}})
+	// 未配置的组件不监视
+	sm.UpdateMetric(&state.BusinessMetric{Data: &model.BusinessMetrics{ComponentType: 0x06, Data: &model.ThermalMetrics{}}})
+
+	if alerts := cfg.Check(sm, time.Now()); len(alerts) != 0 {
+		t.Fatalf("数据新鲜时不应告警: %+v", alerts)
+	}
+	if m, _ := sm.GetLatestState(state.MetricTypeBusiness, string(rune(0x03))); state.IsStale(m) {
+		t.Fatal("数据新鲜时不应标记过期")
+	}
+
+	later := time.Now().Add(time.Second)
+	alerts := cfg.Check(sm, later)
+	if len(alerts) != 2 {
+		t.Fatalf("应产生业务组件与节点的中断告警: %+v", alerts)
+	}
+	a := alerts[0]
+	if a.AlertID != TelemetryLostAlertID || !a.IsFiring() || a.Source != "telemetry-0x03" ||
+		a.Message != "组件 0x03 遥测中断: 已 1s 未收到数据（期望周期 10ms）" || a.Metadata["component_type"] != uint8(0x03) {
+		t.Errorf("业务组件中断告警不符: %+v", a)
+	}
+	if alerts[1].Source != "node/n1" || alerts[1].Metadata["entity_id"] != "n1" {
+		t.Errorf("节点中断告警不符: %+v", alerts[1])
+	}
+	if alerts := cfg.Check(sm, later); len(alerts) != 0 {
+		t.Errorf("中断持续时不应重复告警: %+v", alerts)
+	}
+
+	// 查询结果带过期标记，仍可按原类型使用
+	time.Sleep(30 * time.Millisecond)
+	m, ok := sm.GetLatestState(state.MetricTypeBusiness, string(rune(0x03)))
+	if bm, isBM := m.(*state.BusinessMetric); !ok || !isBM || !bm.Stale || bm.Data.ComponentType != 0x03 {
+		t.Fatalf("过期的最新状态应带 Stale 标记: %+v", m)
+	}
+	if _, ok := sm.GetLatestBusiness(0x03); !ok {
+		t.Error("过期数据仍应可查询")
+	}
+
+	// 业务组件恢复上报，只检查业务层
+	sm.UpdateMetric(power)
+	alerts = cfg.Check(sm, time.Now(), LayerBusiness)
+	if len(alerts) != 1 || !alerts[0].IsResolved() || alerts[0].Message != "组件 0x03 遥测已恢复" {
+		t.Fatalf("数据恢复后应解除告警: %+v", alerts)
+	}
+	if m, _ := sm.GetLatestState(state.MetricTypeBusiness, string(rune(0x03))); state.IsStale(m) {
+		t.Error("恢复后不应标记过期")
+	}
+	fresh := sm.CheckFreshness(time.Now())
+	if len(fresh) != 2 || fresh[0].Type != state.MetricTypeBusiness || fresh[0].Component != 0x03 || fresh[0].Stale || !fresh[1].Stale {
+		t.Errorf("新鲜度列表不符: %+v", fresh)
+	}
+}
+
+// TestWatchdogConfig 测试看门狗配置校验与默认配置
+func TestWatchdogConfig(t *testing.T) {
+	invalid := map[string]string{
+		"组件编号非法": `{"business":{"abc":"1s"}}`,
+		"未知组件":   `{"business":{"0x20":"1s"}}`,
+		"周期非法":   `{"business":{"3":"soon"}}`,
+		"周期为零":   `{"entities":{"node":"0s"}}`,
+		"实体类型":   `{"entities":{"pod":"1s"}}`,
+		"缺失周期数":  `{"missed":-1}`,
+		"严重程度":   `{"severity":"fatal"}`,
+	}
+	for name, data := range invalid {
+		if _, err := ParseWatchdogConfig([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+
+	cfg := DefaultWatchdogConfig(time.Second, 0)
+	if cfg.Missed != DefaultWatchdogMissed || len(cfg.timeouts) != 1 || cfg.timeouts[0].timeout != 3*time.Second {
+		t.Errorf("默认配置不符: %+v", cfg)
+	}
+	sm := newTestStateManager(t)
+	cfg.Apply(sm)
+	sm.UpdateMetric(&state.BusinessMetric{Data: &model.BusinessMetrics{ComponentType: 0x0E, Data: &model.EPSMetrics{}}})
+	sm.UpdateMetric(&state.NodeMetric{Data: &model.NodeMetrics{ID: "n1"}})
+	if fresh := sm.CheckFreshness(time.Now().Add(5 * time.Second)); len(fresh) != 1 || !fresh[0].Stale || fresh[0].Component != 0x0E {
+		t.Errorf("\"*\" 应作用于全部业务组件、ECSM 周期为 0 时不监视: %+v", fresh)
+	}
+}
+
+// TestWatchdogForgetEntities 测试从采集结果中消失的实体被删除，其遥测中断告警解除且不再产生
+func TestWatchdogForgetEntities(t *testing.T) {
+	sm := newTestStateManager(t)
+	cfg, err := ParseWatchdogConfig([]byte(`{"entities":{"container":"10ms","node":"10ms"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Apply(sm)
+
+	sm.UpdateMetric(&state.NodeMetric{Data: &model.NodeMetrics{ID: "n1"}})
+	sm.UpdateMetric(&state.ContainerMetric{Data: &model.ContainerMetrics{ID: "c-old"}})
+	sm.UpdateMetric(&state.ContainerMetric{Data: &model.ContainerMetrics{ID: "c2"}})
+	later := time.Now().Add(time.Second)
+	if alerts := cfg.Check(sm, later); len(alerts) != 3 {
+		t.Fatalf("应产生三个中断告警: %+v", alerts)
+	}
+
+	// 容器重新部署后ID变化；本轮采集结果中没有服务，服务层不清理
+	sm.UpdateMetric(&state.ContainerMetric{Data: &model.ContainerMetrics{ID: "c-new"}})
+	alerts := ForgetEntities(sm, &model.MicroServiceMetricsSet{
+		NodeMetrics:      []model.NodeMetrics{{ID: "n1"}},
+		ContainerMetrics: []model.ContainerMetrics{{ID: "c2"}, {ID: "c-new"}},
+	}, later)
+	if len(alerts) != 1 || !alerts[0].IsResolved() || alerts[0].Source != "container/c-old" ||
+		alerts[0].Message != "容器 c-old 已从采集结果中移除，遥测中断告警解除" {
+		t.Fatalf("应解除已消失容器的中断告警: %+v", alerts)
+	}
+	if _, ok := sm.GetLatestState(state.MetricTypeContainer, "c-old"); ok {
+		t.Error("已消失容器的最新状态应删除")
+	}
+
+	// 之后的检查不再包含已删除的容器
+	for _, a := range cfg.Check(sm, later.Add(time.Second)) {
+		if a.Source == "container/c-old" {
+			t.Errorf("已删除的容器不应再告警: %+v", a)
+		}
+	}
+	for _, f := range sm.CheckFreshness(later) {
+		if f.ID == "c-old" {
+			t.Errorf("新鲜度列表不应包含已删除的容器: %+v", f)
+		}
+	}
+
+	// 空的采集结果不清理任何实体
+	if alerts := ForgetEntities(sm, &model.MicroServiceMetricsSet{}, later); len(alerts) != 0 || len(sm.CheckFreshness(later)) != 3 {
+		t.Errorf("空采集结果不应删除实体: %+v", alerts)
+	}
+}
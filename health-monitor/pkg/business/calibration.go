@@ -0,0 +1,156 @@
+/*
+原始值 -> 工程值 标定曲线
+
+报文中的遥测可能是 ADC 码值、mV/mA 等原始量，需经标定转换为 V、A、℃ 后再做阈值判断。
+标定曲线挂在布局文件的字段上（内联 "calibration" 或引用 "calibrations" 中的命名曲线），支持：
+
+	linear     : eng = raw * gain + offset
+	polynomial : eng = c0 + c1*raw + c2*raw^2 + ...
+	piecewise  : 按标定点分段线性插值（超出范围默认钳位到端点，可选外推）
+	lookup     : 查表（离散状态量、编码值），表中不存在时取 default 或报错
+
+未配置标定的字段沿用 scale 缩放，二者不能同时配置。
+*/
+package business
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 标定曲线类型
+const (
+	CalLinear     = "linear"
+	CalPolynomial = "polynomial"
+	CalPiecewise  = "piecewise"
+	CalLookup     = "lookup"
+)
+
+// CalPoint 标定点
+type CalPoint struct {
+	Raw float64 `json:"raw"` // 原始值
+	Eng float64 `json:"eng"` // 工程值
+}
+
+// Calibration 标定曲线
+type Calibration struct {
+	Type string `json:"type"` // linear / polynomial / piecewise / lookup
+
+	Gain   float64 `json:"gain,omitempty"`   // linear: 增益
+	Offset float64 `json:"offset,omitempty"` // linear: 偏置
+
+	Coefficients []float64 `json:"coefficients,omitempty"` // polynomial: 系数（c0 在前）
+
+	Points      []CalPoint `json:"points,omitempty"`      // piecewise / lookup: 标定点
+	Extrapolate bool       `json:"extrapolate,omitempty"` // piecewise: 超出范围时线性外推
+	Default     *float64   `json:"default,omitempty"`     // lookup: 未命中时的取值
+
+	table map[float64]float64 // lookup 索引
+}
+
+// Validate 校验标定曲线并建立索引
+func (c *Calibration) Validate() error {
+	c.Type = strings.ToLower(c.Type)
+
+	switch c.Type {
+	case CalLinear:
+		if c.Gain == 0 {
+			return fmt.Errorf("linear 标定的 gain 不能为0")
+		}
+
+	case CalPolynomial:
+		if len(c.Coefficients) == 0 {
+			return fmt.Errorf("polynomial 标定缺少 coefficients")
+		}
+
+	case CalPiecewise:
+		if len(c.Points) < 2 {
+			return fmt.Errorf("piecewise 标定至少需要2个标定点")
+		}
+		pts := make([]CalPoint, len(c.Points))
+		copy(pts, c.Points)
+		sort.Slice(pts, func(i, j int) bool { return pts[i].Raw < pts[j].Raw })
+		for i := 1; i < len(pts); i++ {
+			if pts[i].Raw == pts[i-1].Raw {
+				return fmt.Errorf("piecewise 标定点 raw=%v 重复", pts[i].Raw)
+			}
+		}
+		c.Points = pts
+
+	case CalLookup:
+		if len(c.Points) == 0 {
+			return fmt.Errorf("lookup 标定缺少 points")
+		}
+		c.table = make(map[float64]float64, len(c.Points))
+		for _, p := range c.Points {
+			if _, dup := c.table[p.Raw]; dup {
+				return fmt.Errorf("lookup 标定点 raw=%v 重复", p.Raw)
+			}
+			c.table[p.Raw] = p.Eng
+		}
+
+	default:
+		return fmt.Errorf("不支持的标定类型 %q", c.Type)
+	}
+	return nil
+}
+
+// Apply 将原始值转换为工程值
+func (c *Calibration) Apply(raw float64) (float64, error) {
+	switch c.Type {
+	case CalLinear:
+		return raw*c.Gain + c.Offset, nil
+
+	case CalPolynomial:
+		// Horner 法
+		v := 0.0
+		for i := len(c.Coefficients) - 1; i >= 0; i-- {
+			v = v*raw + c.Coefficients[i]
+		}
+		return v, nil
+
+	case CalPiecewise:
+		return c.interpolate(raw), nil
+
+	case CalLookup:
+		if v, ok := c.table[raw]; ok {
+			return v, nil
+		}
+		if c.Default != nil {
+			return *c.Default, nil
+		}
+		return 0, fmt.Errorf("原始值 %v 不在查找表中", raw)
+	}
+	return 0, fmt.Errorf("标定曲线未校验")
+}
+
+// interpolate 分段线性插值（Points 已按 Raw 升序）
+func (c *Calibration) interpolate(raw float64) float64 {
+	pts := c.Points
+	n := len(pts)
+
+	// 超出标定范围：钳位或使用端点线段外推
+	if raw <= pts[0].Raw {
+		if !c.Extrapolate {
+			return pts[0].Eng
+		}
+		return lerp(pts[0], pts[1], raw)
+	}
+	if raw >= pts[n-1].Raw {
+		if !c.Extrapolate {
+			return pts[n-1].Eng
+		}
+		return lerp(pts[n-2], pts[n-1], raw)
+	}
+
+	i := sort.Search(n, func(i int) bool { return pts[i].Raw >= raw })
+	if pts[i].Raw == raw {
+		return pts[i].Eng
+	}
+	return lerp(pts[i-1], pts[i], raw)
+}
+
+func lerp(a, b CalPoint, raw float64) float64 {
+	return a.Eng + (raw-a.Raw)*(b.Eng-a.Eng)/(b.Raw-a.Raw)
+}
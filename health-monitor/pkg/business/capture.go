@@ -0,0 +1,281 @@
+/*
+业务报文抓包
+
+将经过 Receiver 的每个原始报文连同接收时间写入抓包文件，用于联试故障的事后复现（见 replay.go 与 cmd/replay）。
+抓包在帧校验之前进行，损坏的报文同样会被记录。
+
+文件格式（多字节字段均为 Big Endian）：
+文件头 8B : "HMCP" + 版本(1B, 当前 0x01) + 保留(3B)
+每条记录  : 接收时间(8B, Unix 纳秒) + 类型(1B) + 长度(4B) + 原始字节
+
+记录类型：
+	1 : 帧（Submit 提交的完整帧）
+	2 : CCSDS 空间包（SubmitSpacePacket）
+	3 : 字节流片段（Write 写入的原始数据）
+
+文件按大小轮转：单个文件超过 max_file_size 时新建文件，文件数超过 max_files 时删除最旧的文件，
+总占用约为 max_file_size * max_files。
+*/
+package business
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 抓包记录类型
+const (
+	CaptureFrame       uint8 = 1 // 完整帧
+	CaptureSpacePacket uint8 = 2 // CCSDS 空间包
+	CaptureStream      uint8 = 3 // 字节流片段
+)
+
+const (
+	captureMagic      = "HMCP"
+	captureVersion    = 0x01
+	captureHeaderSize = 8
+	captureRecordHead = 13 // 时间(8) + 类型(1) + 长度(4)
+	captureExt        = ".hmcap"
+
+	// DefaultCaptureFileSize 默认单个抓包文件上限
+	DefaultCaptureFileSize = 64 << 20
+	// DefaultCaptureFiles 默认保留的抓包文件数
+	DefaultCaptureFiles = 8
+
+	// maxCaptureRecord 单条记录上限，超过视为文件损坏
+	maxCaptureRecord = 1 << 20
+)
+
+// ErrCaptureFormat 抓包文件格式错误
+var ErrCaptureFormat = errors.New("invalid capture file")
+
+// CaptureConfig 抓包配置
+type CaptureConfig struct {
+	Dir         string `json:"dir"`           // 抓包目录
+	Prefix      string `json:"prefix"`        // 文件名前缀，默认 business
+	MaxFileSize int64  `json:"max_file_size"` // 单个文件上限（字节）
+	MaxFiles    int    `json:"max_files"`     // 保留文件数
+}
+
+// CaptureRecord 一条抓包记录
+type CaptureRecord struct {
+	Time time.Time // 接收时间
+	Kind uint8     // 记录类型（CaptureFrame / CaptureSpacePacket / CaptureStream）
+	Data []byte    // 原始字节
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              写入（轮转）
+////////////////////////////////////////////////////////////////////////////////
+
+// CaptureWriter 按大小轮转的抓包写入器（并发安全）
+type CaptureWriter struct {
+	cfg CaptureConfig
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	seq     int
+	records uint64
+}
+
+// NewCaptureWriter 创建抓包写入器，并打开第一个文件
+func NewCaptureWriter(cfg CaptureConfig) (*CaptureWriter, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("抓包目录不能为空")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "business"
+	}
+	if strings.ContainsAny(cfg.Prefix, `/\`) {
+		return nil, fmt.Errorf("抓包文件前缀 %q 不能包含路径分隔符", cfg.Prefix)
+	}
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = DefaultCaptureFileSize
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = DefaultCaptureFiles
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建抓包目录失败: %w", err)
+	}
+
+	w := &CaptureWriter{cfg: cfg}
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Config 返回抓包配置（已填充默认值）
+func (w *CaptureWriter) Config() CaptureConfig {
+	return w.cfg
+}
+
+// Write 写入一条记录
+func (w *CaptureWriter) Write(kind uint8, received time.Time, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return fmt.Errorf("capture writer closed")
+	}
+
+	n := int64(captureRecordHead + len(data))
+	if w.size > captureHeaderSize && w.size+n > w.cfg.MaxFileSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	// 每条记录一次写入，进程异常退出时最多丢失末尾一条
+	rec := make([]byte, n)
+	binary.BigEndian.PutUint64(rec[0:8], uint64(received.UnixNano()))
+	rec[8] = kind
+	binary.BigEndian.PutUint32(rec[9:13], uint32(len(data)))
+	copy(rec[captureRecordHead:], data)
+	if _, err := w.file.Write(rec); err != nil {
+		return err
+	}
+	w.size += n
+	w.records++
+	return nil
+}
+
+// Records 返回已写入的记录数
+func (w *CaptureWriter) Records() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.records
+}
+
+// Close 关闭当前文件
+func (w *CaptureWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeFile()
+}
+
+func (w *CaptureWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// rotate 关闭当前文件，新建文件并删除超出数量的旧文件
+func (w *CaptureWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return fmt.Errorf("关闭抓包文件失败: %w", err)
+	}
+
+	w.seq++
+	name := fmt.Sprintf("%s-%s-%04d%s", w.cfg.Prefix, time.Now().UTC().Format("20060102T150405"), w.seq%10000, captureExt)
+	f, err := os.OpenFile(filepath.Join(w.cfg.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("创建抓包文件失败: %w", err)
+	}
+
+	header := make([]byte, captureHeaderSize)
+	copy(header, captureMagic)
+	header[4] = captureVersion
+	if _, err := f.Write(header); err != nil {
+		f.Close()
+		return fmt.Errorf("写入抓包文件头失败: %w", err)
+	}
+
+	w.file = f
+	w.size = captureHeaderSize
+
+	files, err := ListCaptureFiles(w.cfg.Dir, w.cfg.Prefix)
+	if err != nil {
+		return err
+	}
+	for len(files) > w.cfg.MaxFiles {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	return nil
+}
+
+// ListCaptureFiles 列出目录下指定前缀的抓包文件（按时间先后排序），prefix 为空时列出全部
+func ListCaptureFiles(dir, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取抓包目录失败: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, captureExt) {
+			continue
+		}
+		if prefix != "" && !strings.HasPrefix(name, prefix+"-") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                                  读取
+////////////////////////////////////////////////////////////////////////////////
+
+// CaptureReader 顺序读取抓包文件
+type CaptureReader struct {
+	r io.Reader
+}
+
+// NewCaptureReader 校验文件头并创建读取器
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	header := make([]byte, captureHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCaptureFormat, err)
+	}
+	if string(header[:4]) != captureMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrCaptureFormat)
+	}
+	if header[4] != captureVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrCaptureFormat, header[4])
+	}
+	return &CaptureReader{r: bufio.NewReader(r)}, nil
+}
+
+// Next 读取下一条记录，文件结束返回 io.EOF，末尾记录不完整（写入时中断）返回 io.ErrUnexpectedEOF
+func (c *CaptureReader) Next() (*CaptureRecord, error) {
+	var head [captureRecordHead]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(head[9:13])
+	if n > maxCaptureRecord {
+		return nil, fmt.Errorf("%w: record length %d", ErrCaptureFormat, n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return &CaptureRecord{
+		Time: time.Unix(0, int64(binary.BigEndian.Uint64(head[0:8]))),
+		Kind: head[8],
+		Data: data,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package business
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCaptureRotation 测试抓包文件轮转与数量上限
+func TestCaptureRotation(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewCaptureWriter(CaptureConfig{Dir: dir, MaxFileSize: 200, MaxFiles: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := EncodeFrame(CompPower, buildPowerPayload()) // 22B，每条记录 35B
+	for i := 0; i < 30; i++ {
+		if err := w.Write(CaptureFrame, time.Now(), frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	files, err := ListCaptureFiles(dir, "business")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("应保留 3 个文件，实际 %d", len(files))
+	}
+	for _, f := range files {
+		info, _ := os.Stat(f)
+		if info.Size() > 200 {
+			t.Errorf("%s 超过上限: %d", f, info.Size())
+		}
+	}
+	if w.Records() != 30 {
+		t.Errorf("记录数 = %d", w.Records())
+	}
+	if err := w.Write(CaptureFrame, time.Now(), frame); err == nil {
+		t.Error("关闭后写入应返回错误")
+	}
+}
+
+// TestCaptureReadBack 测试记录读回及末尾不完整记录
+func TestCaptureReadBack(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewCaptureWriter(CaptureConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Unix(1700000000, 123456789)
+	w.Write(CaptureFrame, t0, []byte{1, 2, 3})
+	w.Write(CaptureStream, t0.Add(time.Second), []byte{4})
+	w.Close()
+
+	files, _ := ListCaptureFiles(dir, "")
+	data, _ := os.ReadFile(files[0])
+	os.WriteFile(files[0], data[:len(data)-1], 0644) // 模拟写入中断
+
+	f, _ := os.Open(files[0])
+	defer f.Close()
+	cr, err := NewCaptureReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := cr.Next()
+	if err != nil || rec.Kind != CaptureFrame || !rec.Time.Equal(t0) || string(rec.Data) != "\x01\x02\x03" {
+		t.Fatalf("记录不符: %+v %v", rec, err)
+	}
+	if _, err := cr.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("不完整记录应返回 ErrUnexpectedEOF: %v", err)
+	}
+
+	if _, err := NewCaptureReader(strings.NewReader("not a capture")); err == nil {
+		t.Error("非抓包文件应返回错误")
+	}
+}
+
+// TestReplay 测试抓包回放经过 Receiver 解析，以及加速回放的节奏
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	src := NewReceiver(NewDispatcher(nil))
+	w, err := NewCaptureWriter(CaptureConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.SetCapture(w)
+
+	frame := EncodeFrame(CompPower, buildPowerPayload())
+	src.Submit(frame)
+	src.Submit([]byte{0xEB}) // 过短的报文同样记录
+	<-src.inputChan
+	src.Write(frame[:5])
+	src.Write(frame[5:])
+	w.Close()
+
+	files, _ := ListCaptureFiles(dir, "")
+	dst := NewReceiver(NewDispatcher(nil))
+	stats, err := NewReplayer(dst, 0).Run(context.Background(), files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Records != 4 || stats.Frames != 2 || stats.StreamChunks != 2 {
+		t.Errorf("回放统计不符: %+v", stats)
+	}
+	if fs := dst.FrameStats()[CompPower]; fs.Accepted != 2 {
+		t.Errorf("回放后应解析 2 帧: %+v", fs)
+	}
+
+	// 10 倍速：记录间隔 300ms，回放约 30ms
+	p := NewReplayer(NewReceiver(NewDispatcher(nil)), 10)
+	var rs ReplayStats
+	t0 := time.Now()
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		p.Play(context.Background(), &CaptureRecord{Time: t0.Add(time.Duration(i) * 300 * time.Millisecond), Kind: CaptureFrame, Data: frame}, &rs)
+	}
+	if d := time.Since(start); d < 25*time.Millisecond || d > 250*time.Millisecond {
+		t.Errorf("加速回放耗时 %v", d)
+	}
+
+	// 取消
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Play(ctx, &CaptureRecord{Time: t0.Add(time.Hour)}, &rs); err != context.Canceled {
+		t.Errorf("取消后应返回 context.Canceled: %v", err)
+	}
+}
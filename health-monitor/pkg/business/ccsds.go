@@ -0,0 +1,345 @@
+/*
+CCSDS Space Packet 接入（CCSDS 133.0-B）
+
+星上软件直接发出的空间包不带本模块的帧头，按以下方式处理：
+1. 解析主导头（6 字节，Big Endian）
+     Bit 0-2   : 版本号（必须为 0）
+     Bit 3     : 包类型（0=TM，1=TC）
+     Bit 4     : 副导头标志
+     Bit 5-15  : APID
+     Bit 16-17 : 分段标志（01 首段，00 中间段，10 末段，11 未分段）
+     Bit 18-31 : 包序列计数（14 位，循环）
+     Bit 32-47 : 数据域长度 - 1
+2. 副导头（可选）为时间码，长度由 APID 配置决定，原样保留在 SpacePacket.TimeCode
+3. 按配置的 APID -> 组件编号映射，交给现有的组件布局解析
+4. 分段包按 APID 重组，分段标志或序列计数不连续时丢弃并报 ErrSequenceFlags
+
+APID 映射配置见 LoadCCSDSConfig（monitor -ccsds 参数指定）。
+*/
+package business
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	CCSDSPrimaryHeaderSize = 6      // 主导头长度
+	CCSDSIdleAPID          = 0x7FF  // 空闲包 APID，直接丢弃
+	CCSDSMaxSeqCount       = 0x3FFF // 14 位序列计数
+
+	// DefaultMaxReassembled 重组后负载的默认上限
+	DefaultMaxReassembled = 64 * 1024
+)
+
+// 分段标志
+const (
+	SeqContinuation = 0x0 // 中间段
+	SeqFirst        = 0x1 // 首段
+	SeqLast         = 0x2 // 末段
+	SeqUnsegmented  = 0x3 // 未分段
+)
+
+// ErrSequenceFlags 分段标志或序列计数非法（分段缺失、乱序、未以首段开始等）
+var ErrSequenceFlags = errors.New("invalid ccsds sequence flags")
+
+// APIDMapping 单个 APID 的映射配置
+type APIDMapping struct {
+	APID               uint16 `json:"apid"`                              // 应用过程标识
+	Component          uint8  `json:"component"`                         // 组件编号（CompRunMgr ~ CompEPS）
+	Name               string `json:"name,omitempty"`                    // 备注名称
+	SecondaryHeaderLen *int   `json:"secondary_header_length,omitempty"` // 副导头长度，未设置时使用全局默认值
+}
+
+// CCSDSConfig 空间包接入配置
+type CCSDSConfig struct {
+	SecondaryHeaderLen int           `json:"secondary_header_length"` // 默认副导头（时间码）长度，字节
+	MaxReassembled     int           `json:"max_reassembled,omitempty"`
+	APIDs              []APIDMapping `json:"apids"`
+
+	byAPID map[uint16]*APIDMapping
+}
+
+// ParseCCSDSConfig 解析并校验空间包配置 JSON
+func ParseCCSDSConfig(data []byte) (*CCSDSConfig, error) {
+	var cfg CCSDSConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析CCSDS配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("CCSDS配置校验失败: %w", err)
+	}
+	return &cfg, nil
+}
+
+// LoadCCSDSConfig 从文件加载空间包配置
+func LoadCCSDSConfig(path string) (*CCSDSConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取CCSDS配置文件失败: %w", err)
+	}
+	return ParseCCSDSConfig(data)
+}
+
+// Validate 校验配置并建立索引
+func (c *CCSDSConfig) Validate() error {
+	if c.SecondaryHeaderLen < 0 {
+		return fmt.Errorf("secondary_header_length 不能为负")
+	}
+	if c.MaxReassembled <= 0 {
+		c.MaxReassembled = DefaultMaxReassembled
+	}
+
+	c.byAPID = make(map[uint16]*APIDMapping, len(c.APIDs))
+	for i := range c.APIDs {
+		m := &c.APIDs[i]
+		if m.APID >= CCSDSIdleAPID {
+			return fmt.Errorf("APID 0x%03X 非法（需小于 0x7FF）", m.APID)
+		}
+		if _, ok := componentFactories[m.Component]; !ok {
+			return fmt.Errorf("APID 0x%03X: 未知组件编号 0x%02X", m.APID, m.Component)
+		}
+		if _, dup := c.byAPID[m.APID]; dup {
+			return fmt.Errorf("APID 0x%03X 重复定义", m.APID)
+		}
+		if m.SecondaryHeaderLen != nil && *m.SecondaryHeaderLen < 0 {
+			return fmt.Errorf("APID 0x%03X: secondary_header_length 不能为负", m.APID)
+		}
+		c.byAPID[m.APID] = m
+	}
+	return nil
+}
+
+// Mapping 查询 APID 映射
+func (c *CCSDSConfig) Mapping(apid uint16) (*APIDMapping, bool) {
+	m, ok := c.byAPID[apid]
+	return m, ok
+}
+
+func (c *CCSDSConfig) secondaryHeaderLen(m *APIDMapping) int {
+	if m.SecondaryHeaderLen != nil {
+		return *m.SecondaryHeaderLen
+	}
+	return c.SecondaryHeaderLen
+}
+
+// SpacePacket 解析后的空间包（分段包为重组后的结果）
+type SpacePacket struct {
+	Version   uint8
+	Type      uint8 // 0=TM, 1=TC
+	SecHeader bool  // 是否带副导头
+	APID      uint16
+	SeqFlags  uint8
+	SeqCount  uint16
+	Component uint8  // 映射后的组件编号
+	TimeCode  []byte // 副导头中的时间码（原始字节）
+	Data      []byte // 用户数据（即组件负载）
+}
+
+// EncodeSpacePacket 封装空间包（供发送端/测试使用）
+func EncodeSpacePacket(apid uint16, seqFlags uint8, seqCount uint16, secondary, data []byte) []byte {
+	n := len(secondary) + len(data)
+	pkt := make([]byte, CCSDSPrimaryHeaderSize+n)
+
+	word := apid & 0x7FF
+	if len(secondary) > 0 {
+		word |= 1 << 11
+	}
+	binary.BigEndian.PutUint16(pkt[0:2], word)
+	binary.BigEndian.PutUint16(pkt[2:4], uint16(seqFlags&0x3)<<14|seqCount&CCSDSMaxSeqCount)
+	binary.BigEndian.PutUint16(pkt[4:6], uint16(n-1))
+	copy(pkt[CCSDSPrimaryHeaderSize:], secondary)
+	copy(pkt[CCSDSPrimaryHeaderSize+len(secondary):], data)
+	return pkt
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              解码与重组
+////////////////////////////////////////////////////////////////////////////////
+
+// CCSDSDecoder 空间包解码器（按 APID 重组分段包，并发安全）
+type CCSDSDecoder struct {
+	cfg *CCSDSConfig
+
+	mu      sync.Mutex
+	pending map[uint16]*segmentBuffer
+
+	// 每个成功解析的包（含中间段）到达时回调，用于序列计数统计
+	onPacket func(pkt *SpacePacket)
+}
+
+// segmentBuffer 正在重组的分段包
+type segmentBuffer struct {
+	first   *SpacePacket
+	lastSeq uint16
+	data    []byte
+}
+
+// NewCCSDSDecoder 创建空间包解码器（cfg 需已通过校验）
+func NewCCSDSDecoder(cfg *CCSDSConfig) *CCSDSDecoder {
+	return &CCSDSDecoder{
+		cfg:     cfg,
+		pending: make(map[uint16]*segmentBuffer),
+	}
+}
+
+// Config 返回解码器配置
+func (d *CCSDSDecoder) Config() *CCSDSConfig {
+	return d.cfg
+}
+
+// SetPacketObserver 设置单包回调（每个分段都会回调一次，在重组之前）
+func (d *CCSDSDecoder) SetPacketObserver(fn func(pkt *SpacePacket)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onPacket = fn
+}
+
+// Decode 解码一个空间包
+// 返回 (packet, err)：
+//   - packet 非 nil：完整的包（未分段或重组完成）
+//   - packet 为 nil 且 err 为 nil：分段尚未收齐，或为空闲包
+//   - err 非 nil：*FrameError，Kind 为 ErrFrameVersion / ErrFrameLength / ErrFrameTruncated / ErrUnknownComponent / ErrSequenceFlags
+func (d *CCSDSDecoder) Decode(data []byte) (*SpacePacket, error) {
+	pkt, err := d.parse(data)
+	if err != nil || pkt == nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.onPacket != nil {
+		d.onPacket(pkt)
+	}
+
+	pending := d.pending[pkt.APID]
+
+	switch pkt.SeqFlags {
+	case SeqUnsegmented:
+		// 未收齐的分段包被新包打断，直接丢弃
+		delete(d.pending, pkt.APID)
+		return pkt, nil
+
+	case SeqFirst:
+		d.pending[pkt.APID] = &segmentBuffer{
+			first:   pkt,
+			lastSeq: pkt.SeqCount,
+			data:    append([]byte(nil), pkt.Data...),
+		}
+		if pending != nil {
+			return nil, d.seqError(pkt, "new first segment before previous packet completed")
+		}
+		return nil, nil
+
+	default: // 中间段 / 末段
+		if pending == nil {
+			return nil, d.seqError(pkt, "segment without first segment")
+		}
+		if pkt.SeqCount != (pending.lastSeq+1)&CCSDSMaxSeqCount {
+			delete(d.pending, pkt.APID)
+			return nil, d.seqError(pkt, fmt.Sprintf("seq count %d, want %d", pkt.SeqCount, (pending.lastSeq+1)&CCSDSMaxSeqCount))
+		}
+		if len(pending.data)+len(pkt.Data) > d.cfg.MaxReassembled {
+			delete(d.pending, pkt.APID)
+			return nil, &FrameError{
+				Kind:      ErrFrameLength,
+				Component: pkt.Component,
+				Detail:    fmt.Sprintf("apid=0x%03X reassembled packet exceeds %d bytes", pkt.APID, d.cfg.MaxReassembled),
+			}
+		}
+
+		pending.data = append(pending.data, pkt.Data...)
+		pending.lastSeq = pkt.SeqCount
+		if pkt.SeqFlags == SeqContinuation {
+			return nil, nil
+		}
+
+		delete(d.pending, pkt.APID)
+		out := *pending.first
+		out.SeqFlags = SeqUnsegmented
+		out.SeqCount = pkt.SeqCount
+		out.Data = pending.data
+		return &out, nil
+	}
+}
+
+// Pending 返回正在重组的 APID 数量
+func (d *CCSDSDecoder) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending)
+}
+
+func (d *CCSDSDecoder) seqError(pkt *SpacePacket, detail string) error {
+	return &FrameError{
+		Kind:      ErrSequenceFlags,
+		Component: pkt.Component,
+		Detail:    fmt.Sprintf("apid=0x%03X flags=%02b: %s", pkt.APID, pkt.SeqFlags, detail),
+	}
+}
+
+// parse 解析单个空间包（不做重组），空闲包返回 nil
+func (d *CCSDSDecoder) parse(data []byte) (*SpacePacket, error) {
+	if len(data) < CCSDSPrimaryHeaderSize {
+		return nil, &FrameError{Kind: ErrFrameTruncated, NoComponent: true, Detail: "incomplete ccsds primary header"}
+	}
+
+	w0 := binary.BigEndian.Uint16(data[0:2])
+	w1 := binary.BigEndian.Uint16(data[2:4])
+	length := int(binary.BigEndian.Uint16(data[4:6])) + 1
+
+	pkt := &SpacePacket{
+		Version:   uint8(w0 >> 13),
+		Type:      uint8(w0>>12) & 0x1,
+		SecHeader: w0&(1<<11) != 0,
+		APID:      w0 & 0x7FF,
+		SeqFlags:  uint8(w1 >> 14),
+		SeqCount:  w1 & CCSDSMaxSeqCount,
+	}
+
+	if pkt.Version != 0 {
+		return nil, &FrameError{Kind: ErrFrameVersion, NoComponent: true, Detail: fmt.Sprintf("ccsds version=%d", pkt.Version)}
+	}
+
+	total := CCSDSPrimaryHeaderSize + length
+	if len(data) < total {
+		return nil, &FrameError{Kind: ErrFrameTruncated, NoComponent: true, Detail: fmt.Sprintf("apid=0x%03X need %d bytes, have %d", pkt.APID, total, len(data))}
+	}
+	if len(data) > total {
+		return nil, &FrameError{Kind: ErrFrameLength, NoComponent: true, Detail: fmt.Sprintf("apid=0x%03X %d trailing bytes", pkt.APID, len(data)-total)}
+	}
+
+	if pkt.APID == CCSDSIdleAPID {
+		return nil, nil
+	}
+
+	m, ok := d.cfg.Mapping(pkt.APID)
+	if !ok {
+		return nil, &FrameError{Kind: ErrUnknownComponent, NoComponent: true, Detail: fmt.Sprintf("unmapped apid=0x%03X", pkt.APID)}
+	}
+	pkt.Component = m.Component
+
+	body := data[CCSDSPrimaryHeaderSize:total]
+
+	// 副导头只出现在首段或未分段包中
+	if pkt.SecHeader && (pkt.SeqFlags == SeqFirst || pkt.SeqFlags == SeqUnsegmented) {
+		n := d.cfg.secondaryHeaderLen(m)
+		if len(body) < n {
+			return nil, &FrameError{
+				Kind:      ErrFrameTruncated,
+				Component: pkt.Component,
+				Detail:    fmt.Sprintf("apid=0x%03X secondary header needs %d bytes", pkt.APID, n),
+			}
+		}
+		pkt.TimeCode = append([]byte(nil), body[:n]...)
+		body = body[n:]
+	}
+
+	pkt.Data = append([]byte(nil), body...)
+	return pkt, nil
+}
@@ -0,0 +1,144 @@
+package business
+
+import (
+	"errors"
+	"testing"
+
+	"health-monitor/pkg/models"
+)
+
+func testCCSDSConfig(t *testing.T) *CCSDSConfig {
+	t.Helper()
+	cfg, err := ParseCCSDSConfig([]byte(`{
+		"secondary_header_length": 6,
+		"apids": [
+			{"apid": 100, "component": 3, "name": "power"},
+			{"apid": 101, "component": 6, "name": "thermal", "secondary_header_length": 0}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("解析CCSDS配置失败: %v", err)
+	}
+	return cfg
+}
+
+// TestSpacePacketUnsegmented 测试未分段空间包解析与 APID 映射
+func TestSpacePacketUnsegmented(t *testing.T) {
+	r := NewReceiver(NewDispatcher(nil))
+	r.SetCCSDS(NewCCSDSDecoder(testCCSDSConfig(t)))
+
+	timeCode := []byte{0, 0, 0, 10, 0x80, 0}
+	pkt := EncodeSpacePacket(100, SeqUnsegmented, 5, timeCode, buildPowerPayload())
+
+	sp, err := r.CCSDS().Decode(pkt)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if sp.APID != 100 || sp.Component != CompPower || !sp.SecHeader || sp.SeqCount != 5 || string(sp.TimeCode) != string(timeCode) {
+		t.Errorf("主导头解析不符: %+v", sp)
+	}
+
+	metrics, err := r.ParseSpacePacket(pkt)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	power := metrics.Data.(*model.PowerMetrics)
+	if metrics.ComponentType != CompPower || power.BatteryVoltage < 24.999 || power.BatteryVoltage > 25.001 {
+		t.Errorf("负载解析不符: %+v", power)
+	}
+
+	// 空闲包丢弃
+	if m, err := r.ParseSpacePacket(EncodeSpacePacket(CCSDSIdleAPID, SeqUnsegmented, 0, nil, []byte{0})); m != nil || err != nil {
+		t.Errorf("空闲包应被丢弃: %v %v", m, err)
+	}
+
+	// 未映射 APID
+	if _, err := r.ParseSpacePacket(EncodeSpacePacket(200, SeqUnsegmented, 0, nil, []byte{0})); !errors.Is(err, ErrUnknownComponent) {
+		t.Errorf("未映射APID: got %v", err)
+	}
+
+	// 版本号非0
+	bad := append([]byte(nil), pkt...)
+	bad[0] |= 0x20
+	if _, err := r.ParseSpacePacket(bad); !errors.Is(err, ErrFrameVersion) {
+		t.Errorf("版本错误: got %v", err)
+	}
+
+	// 长度字段与实际不符
+	if _, err := r.ParseSpacePacket(pkt[:len(pkt)-1]); !errors.Is(err, ErrFrameTruncated) {
+		t.Errorf("截断包: got %v", err)
+	}
+}
+
+// TestSpacePacketReassembly 测试分段包重组与非法分段标志
+func TestSpacePacketReassembly(t *testing.T) {
+	dec := NewCCSDSDecoder(testCCSDSConfig(t))
+
+	thermal := make([]byte, 31)
+	for i := range thermal {
+		thermal[i] = byte(i)
+	}
+
+	// 首段 -> 中间段 -> 末段，序列计数跨越 14 位回绕
+	segs := [][]byte{
+		EncodeSpacePacket(101, SeqFirst, CCSDSMaxSeqCount, nil, thermal[:10]),
+		EncodeSpacePacket(101, SeqContinuation, 0, nil, thermal[10:20]),
+		EncodeSpacePacket(101, SeqLast, 1, nil, thermal[20:]),
+	}
+	for i, seg := range segs {
+		sp, err := dec.Decode(seg)
+		if err != nil {
+			t.Fatalf("第 %d 段解码失败: %v", i+1, err)
+		}
+		if i < 2 && sp != nil {
+			t.Fatalf("第 %d 段不应输出完整包", i+1)
+		}
+		if i == 2 {
+			if sp == nil || string(sp.Data) != string(thermal) || sp.Component != CompThermal {
+				t.Fatalf("重组结果不符: %+v", sp)
+			}
+		}
+	}
+	if dec.Pending() != 0 {
+		t.Error("重组完成后不应残留")
+	}
+
+	// 没有首段的中间段/末段
+	if _, err := dec.Decode(EncodeSpacePacket(101, SeqLast, 7, nil, []byte{1})); !errors.Is(err, ErrSequenceFlags) {
+		t.Errorf("缺少首段: got %v", err)
+	}
+
+	// 序列计数跳变
+	dec.Decode(EncodeSpacePacket(101, SeqFirst, 10, nil, []byte{1}))
+	if _, err := dec.Decode(EncodeSpacePacket(101, SeqLast, 12, nil, []byte{2})); !errors.Is(err, ErrSequenceFlags) {
+		t.Errorf("序列计数跳变: got %v", err)
+	}
+
+	// 前一包未收齐又收到首段
+	dec.Decode(EncodeSpacePacket(101, SeqFirst, 20, nil, []byte{1}))
+	if _, err := dec.Decode(EncodeSpacePacket(101, SeqFirst, 21, nil, []byte{1})); !errors.Is(err, ErrSequenceFlags) {
+		t.Errorf("重复首段: got %v", err)
+	}
+
+	// 统计计入 SeqErrors
+	r := NewReceiver(NewDispatcher(nil))
+	r.SetCCSDS(dec)
+	r.ParseSpacePacket(EncodeSpacePacket(101, SeqContinuation, 0, nil, []byte{1}))
+	if r.FrameStats()[CompThermal].SeqErrors == 0 {
+		t.Error("分段错误未计入统计")
+	}
+}
+
+// TestInvalidCCSDSConfig 测试非法 APID 配置
+func TestInvalidCCSDSConfig(t *testing.T) {
+	for _, bad := range []string{
+		`{"apids": [{"apid": 2047, "component": 3}]}`,
+		`{"apids": [{"apid": 1, "component": 99}]}`,
+		`{"apids": [{"apid": 1, "component": 3}, {"apid": 1, "component": 6}]}`,
+		`{"secondary_header_length": -1, "apids": []}`,
+	} {
+		if _, err := ParseCCSDSConfig([]byte(bad)); err == nil {
+			t.Errorf("%s 应校验失败", bad)
+		}
+	}
+}
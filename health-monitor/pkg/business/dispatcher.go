@@ -16,6 +16,7 @@ import (
 type Dispatcher struct {
 	generator    *alert.Generator
 	stateManager *state.StateManager
+	health       *alert.HealthScorer // 健康分计算（可选）
 }
 
 // NewDispatcher 创建新的分发器
@@ -31,6 +32,31 @@ func (d *Dispatcher) SetDiagnosisReceiver(receiver alert.DiagnosisReceiver) {
 	d.generator.SetDiagnosisReceiver(receiver)
 }
 
+// SetSilences 设置告警静默规则
+func (d *Dispatcher) SetSilences(store *alert.SilenceStore) {
+	d.generator.SetSilences(store)
+}
+
+// SetCorrelator 设置告警关联器（业务层与微服务层共用）
+func (d *Dispatcher) SetCorrelator(c *alert.Correlator) {
+	d.generator.SetCorrelator(c)
+}
+
+// SetHealthScorer 设置健康分计算器，每次收到指标后计算该组件的健康分
+func (d *Dispatcher) SetHealthScorer(h *alert.HealthScorer) {
+	d.health = h
+}
+
+// RulesChanged 告警规则集切换后调用，解除已删除/停用的业务层规则的活跃告警
+func (d *Dispatcher) RulesChanged(old, next *alert.RuleSet) {
+	d.generator.ApplyRuleChange(old, next, alert.LayerBusiness)
+}
+
+// CheckTelemetryLoss 检查各业务组件的遥测是否中断（由 monitor 定期调用）
+func (d *Dispatcher) CheckTelemetryLoss(ctx context.Context, cfg *alert.WatchdogConfig) {
+	d.generator.ProcessTelemetryLoss(ctx, cfg, alert.LayerBusiness)
+}
+
 // HandleBusinessMetrics 处理业务层解析后的指标
 func (d *Dispatcher) HandleBusinessMetrics(ctx context.Context, bm *model.BusinessMetrics) {
 	fmt.Printf("[业务层Dispatcher] 收到解析指标：Comp=0x%02X Timestamp=%d\n", 
@@ -38,9 +64,10 @@ func (d *Dispatcher) HandleBusinessMetrics(ctx context.Context, bm *model.Busine
 	
 	// 1. 推送到 StateManager
 	if d.stateManager != nil {
+		// 以测量时间入库（星上时间码或接收时间），偏差过大时由 StateManager.AlignTimestamp 修正
 		businessMetric := &state.BusinessMetric{
 			Data:      bm,
-			Timestamp: time.Now().Unix(),
+			Timestamp: bm.Timestamp,
 		}
 		if err := d.stateManager.UpdateMetric(businessMetric); err != nil {
 			fmt.Printf("[业务层Dispatcher] 保存到StateManager失败: %v\n", err)
@@ -53,8 +80,12 @@ func (d *Dispatcher) HandleBusinessMetrics(ctx context.Context, bm *model.Busine
 	// Generator会调用threshold检查，生成告警事件并直接输出
 	d.generator.ProcessBusinessMetrics(ctx, bm)
 	
-	// 3. 健康分计算
-	// TODO: 实现健康分计算逻辑
+	// 3. 健康分计算（系统健康分由 monitor 定期汇总）
+	if d.health != nil && d.stateManager != nil {
+		if s, ok := d.health.ScoreBusiness(bm.ComponentType, time.Now()); ok && s.Level != alert.HealthHealthy {
+			fmt.Printf("[业务层Dispatcher] Comp=0x%02X 健康分 %.1f（%s）: %v\n", bm.ComponentType, s.Score, s.Level, s.Reasons)
+		}
+	}
 	
 	// 4. 写入 DB / MQ
 	// TODO: 持久化指标数据
@@ -65,3 +96,11 @@ func (d *Dispatcher) HandleBusinessMetrics(ctx context.Context, bm *model.Busine
 	// 6. 与微服务层指标融合
 	// TODO: 实现指标融合逻辑
 }
+
+// HandleLinkQuality 处理链路质量评估结果（由 Receiver 在评估窗口结束时调用）
+func (d *Dispatcher) HandleLinkQuality(ctx context.Context, lq *model.LinkQuality) {
+	fmt.Printf("[业务层Dispatcher] 链路质量：Comp=0x%02X 丢包率=%.2f%% 丢失=%d 重复=%d 乱序=%d\n",
+		lq.ComponentType, lq.LossRate*100, lq.Lost, lq.Duplicates, lq.Reordered)
+	
+	d.generator.ProcessLinkQuality(ctx, lq)
+}
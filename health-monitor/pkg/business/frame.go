@@ -0,0 +1,491 @@
+/*
+业务报文帧格式（成帧层）
+
+报文在负载外增加同步字、版本号、长度与 CRC 校验，用于：
+1. 在字节流中重新同步（总线噪声、半包、粘包）
+2. 拒绝损坏报文，避免解析出错误的 PowerMetrics 等指标
+3. 按组件统计 CRC 错误、截断、未知类型，便于发现总线质量问题
+
+帧结构（多字节字段均为 Big Endian）：
+Byte 0-1 : 同步字 0xEB 0x90
+Byte 2   : 版本号（当前为 0x01）
+Byte 3   : 组件类型（CompRunMgr ~ CompEPS）
+Byte 4-5 : 负载长度 N
+Byte 6.. : 负载数据（N 字节）
+最后 2B  : CRC-16/CCITT-FALSE，覆盖 Byte 2 至负载末尾
+
+v2 帧（版本号 0x02）在长度之后增加 1 字节标志，长度 N 为标志之后、CRC 之前的字节数，
+标志置位的扩展字段按位序依次位于负载之前：
+Byte 6   : 标志（bit0 = 携带星上时间码，bit1 = 携带序列计数）
+[bit0]   : 1 字节时间码长度 L + L 字节时间码（格式见 timecode.go）
+[bit1]   : 2 字节序列计数（每组件独立递增，65535 后回绕为 0，见 sequence.go）
+之后     : 负载数据
+*/
+package business
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	FrameSync0 = 0xEB // 同步字第1字节
+	FrameSync1 = 0x90 // 同步字第2字节
+
+	FrameVersion   = 0x01 // 基本帧版本
+	FrameVersionV2 = 0x02 // 带扩展字段的帧版本
+
+	FrameHeaderSize   = 6 // 同步字(2) + 版本(1) + 组件类型(1) + 长度(2)
+	FrameHeaderSizeV2 = 7 // v1 帧头 + 标志(1)
+	FrameTrailerSize  = 2 // CRC-16
+
+	FlagTimeCode = 0x01 // v2 标志：携带星上时间码
+	FlagSequence = 0x02 // v2 标志：携带序列计数
+
+	// FrameSeqModulus v2 帧序列计数的模（16 位）
+	FrameSeqModulus = 1 << 16
+
+	// MaxFramePayload 单帧负载上限，超过视为长度字段损坏
+	MaxFramePayload = 4096
+)
+
+// 帧错误类型
+var (
+	ErrFrameSync        = errors.New("frame sync marker not found")
+	ErrFrameTruncated   = errors.New("frame truncated")
+	ErrFrameVersion     = errors.New("unsupported frame version")
+	ErrFrameLength      = errors.New("invalid frame length")
+	ErrFrameCRC         = errors.New("frame crc mismatch")
+	ErrUnknownComponent = errors.New("unknown component type")
+)
+
+// FrameError 帧解析错误，携带出错的组件类型和具体原因
+// 可通过 errors.Is(err, ErrFrameCRC) 等方式判断错误类别
+type FrameError struct {
+	Kind        error  // 错误类别（ErrFrameXxx）
+	Component   uint8  // 帧头中的组件类型
+	NoComponent bool   // 无法确定组件类型（头部不完整等），统计时计入未归属
+	Detail      string // 补充说明
+}
+
+func (e *FrameError) Error() string {
+	if e.NoComponent {
+		if e.Detail == "" {
+			return fmt.Sprintf("business frame: %v", e.Kind)
+		}
+		return fmt.Sprintf("business frame: %v: %s", e.Kind, e.Detail)
+	}
+	if e.Detail == "" {
+		return fmt.Sprintf("business frame (comp=0x%02X): %v", e.Component, e.Kind)
+	}
+	return fmt.Sprintf("business frame (comp=0x%02X): %v: %s", e.Component, e.Kind, e.Detail)
+}
+
+func (e *FrameError) Unwrap() error { return e.Kind }
+
+// Frame 解析后的业务报文帧
+type Frame struct {
+	Version   uint8
+	Component uint8
+	Flags     uint8  // v2 标志位（v1 帧为0）
+	TimeCode  []byte // 星上时间码（未携带时为 nil）
+	HasSeq    bool   // 是否携带序列计数
+	Seq       uint16 // 序列计数
+	Payload   []byte
+	Raw       []byte // 完整帧的原始字节
+}
+
+// FrameOptions v2 帧的可选扩展字段
+type FrameOptions struct {
+	TimeCode []byte // 星上时间码，非空时置 FlagTimeCode
+	HasSeq   bool   // 为 true 时置 FlagSequence 并写入 Seq
+	Seq      uint16 // 序列计数
+}
+
+// CRC16CCITT 计算 CRC-16/CCITT-FALSE（多项式0x1021，初值0xFFFF）
+func CRC16CCITT(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// EncodeFrame 按帧格式封装负载（供发送端/测试使用）
+func EncodeFrame(component uint8, payload []byte) []byte {
+	frame := make([]byte, FrameHeaderSize+len(payload)+FrameTrailerSize)
+	frame[0] = FrameSync0
+	frame[1] = FrameSync1
+	frame[2] = FrameVersion
+	frame[3] = component
+	binary.BigEndian.PutUint16(frame[4:6], uint16(len(payload)))
+	copy(frame[FrameHeaderSize:], payload)
+
+	end := FrameHeaderSize + len(payload)
+	binary.BigEndian.PutUint16(frame[end:], CRC16CCITT(frame[2:end]))
+	return frame
+}
+
+// EncodeFrameV2 按 v2 帧格式封装负载及扩展字段（供发送端/测试使用）
+func EncodeFrameV2(component uint8, opts FrameOptions, payload []byte) []byte {
+	var flags uint8
+	var ext []byte
+	if len(opts.TimeCode) > 0 {
+		flags |= FlagTimeCode
+		ext = append(ext, byte(len(opts.TimeCode)))
+		ext = append(ext, opts.TimeCode...)
+	}
+	if opts.HasSeq {
+		flags |= FlagSequence
+		ext = append(ext, byte(opts.Seq>>8), byte(opts.Seq))
+	}
+
+	n := len(ext) + len(payload)
+	frame := make([]byte, FrameHeaderSizeV2+n+FrameTrailerSize)
+	frame[0] = FrameSync0
+	frame[1] = FrameSync1
+	frame[2] = FrameVersionV2
+	frame[3] = component
+	binary.BigEndian.PutUint16(frame[4:6], uint16(n))
+	frame[6] = flags
+	copy(frame[FrameHeaderSizeV2:], ext)
+	copy(frame[FrameHeaderSizeV2+len(ext):], payload)
+
+	end := FrameHeaderSizeV2 + n
+	binary.BigEndian.PutUint16(frame[end:], CRC16CCITT(frame[2:end]))
+	return frame
+}
+
+// DecodeFrame 解析一个完整的帧（数据报场景，data 应恰好包含一帧）
+func DecodeFrame(data []byte) (*Frame, error) {
+	frame, n, err := decodeFrameAt(data)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(data) {
+		return nil, &FrameError{
+			Kind:      ErrFrameLength,
+			Component: frame.Component,
+			Detail:    fmt.Sprintf("%d trailing bytes", len(data)-n),
+		}
+	}
+	return frame, nil
+}
+
+// headerTruncated 构造帧头不完整的错误：已收到组件类型字节时归属该组件，否则计入未归属统计
+func headerTruncated(data []byte, detail string) *FrameError {
+	if len(data) > 3 && data[0] == FrameSync0 && data[1] == FrameSync1 {
+		return &FrameError{Kind: ErrFrameTruncated, Component: data[3], Detail: detail}
+	}
+	return &FrameError{Kind: ErrFrameTruncated, NoComponent: true, Detail: detail}
+}
+
+// decodeFrameAt 从 data 起始位置解析一帧，返回帧和消耗的字节数
+func decodeFrameAt(data []byte) (*Frame, int, error) {
+	if len(data) < 2 || data[0] != FrameSync0 || data[1] != FrameSync1 {
+		return nil, 0, &FrameError{Kind: ErrFrameSync}
+	}
+	if len(data) < FrameHeaderSize {
+		return nil, 0, headerTruncated(data, "incomplete header")
+	}
+
+	version := data[2]
+	component := data[3]
+	length := int(binary.BigEndian.Uint16(data[4:6]))
+
+	headerSize := FrameHeaderSize
+	switch version {
+	case FrameVersion:
+	case FrameVersionV2:
+		headerSize = FrameHeaderSizeV2
+	default:
+		return nil, 0, &FrameError{
+			Kind:      ErrFrameVersion,
+			Component: component,
+			Detail:    fmt.Sprintf("version=0x%02X", version),
+		}
+	}
+	if length > MaxFramePayload {
+		return nil, 0, &FrameError{
+			Kind:      ErrFrameLength,
+			Component: component,
+			Detail:    fmt.Sprintf("payload length %d exceeds %d", length, MaxFramePayload),
+		}
+	}
+
+	total := headerSize + length + FrameTrailerSize
+	if len(data) < total {
+		return nil, 0, &FrameError{
+			Kind:      ErrFrameTruncated,
+			Component: component,
+			Detail:    fmt.Sprintf("need %d bytes, have %d", total, len(data)),
+		}
+	}
+
+	end := headerSize + length
+	want := binary.BigEndian.Uint16(data[end:total])
+	if got := CRC16CCITT(data[2:end]); got != want {
+		return nil, 0, &FrameError{
+			Kind:      ErrFrameCRC,
+			Component: component,
+			Detail:    fmt.Sprintf("crc=0x%04X want=0x%04X", got, want),
+		}
+	}
+
+	raw := make([]byte, total)
+	copy(raw, data[:total])
+
+	frame := &Frame{
+		Version:   version,
+		Component: component,
+		Payload:   raw[headerSize:end],
+		Raw:       raw,
+	}
+	if version == FrameVersionV2 {
+		frame.Flags = raw[6]
+		if err := frame.parseExtensions(); err != nil {
+			return nil, 0, err
+		}
+	}
+	return frame, total, nil
+}
+
+// parseExtensions 按标志位拆出 v2 扩展字段，剩余部分为负载
+func (f *Frame) parseExtensions() error {
+	body := f.Payload
+
+	if f.Flags&FlagTimeCode != 0 {
+		if len(body) < 1 || len(body) < 1+int(body[0]) {
+			return &FrameError{Kind: ErrFrameLength, Component: f.Component, Detail: "time code extension exceeds frame"}
+		}
+		n := int(body[0])
+		f.TimeCode = body[1 : 1+n]
+		body = body[1+n:]
+	}
+	if f.Flags&FlagSequence != 0 {
+		if len(body) < 2 {
+			return &FrameError{Kind: ErrFrameLength, Component: f.Component, Detail: "sequence extension exceeds frame"}
+		}
+		f.HasSeq = true
+		f.Seq = binary.BigEndian.Uint16(body[:2])
+		body = body[2:]
+	}
+
+	f.Payload = body
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                           字节流解帧（重同步）
+////////////////////////////////////////////////////////////////////////////////
+
+// StreamDecoder 从连续字节流中切分帧
+// 遇到同步字错误或CRC错误时丢弃1字节后重新搜索同步字
+type StreamDecoder struct {
+	buf []byte
+}
+
+// NewStreamDecoder 创建字节流解帧器
+func NewStreamDecoder() *StreamDecoder {
+	return &StreamDecoder{}
+}
+
+// Feed 追加收到的字节
+func (d *StreamDecoder) Feed(data []byte) {
+	d.buf = append(d.buf, data...)
+}
+
+// Buffered 返回尚未消费的字节数
+func (d *StreamDecoder) Buffered() int {
+	return len(d.buf)
+}
+
+// Next 取出下一帧
+// 返回 (frame, skipped, err)：
+//   - frame 非 nil：成功解出一帧，skipped 为此前丢弃的字节数
+//   - frame 为 nil 且 err 为 nil：数据不足，等待更多字节
+//   - err 非 nil：遇到坏帧（CRC/版本/长度），已跳过其同步字，调用方可继续调用 Next
+func (d *StreamDecoder) Next() (*Frame, int, error) {
+	skipped := d.seekSync()
+
+	for len(d.buf) >= 2 {
+		frame, n, err := decodeFrameAt(d.buf)
+		if err == nil {
+			d.buf = d.buf[n:]
+			return frame, skipped, nil
+		}
+
+		if errors.Is(err, ErrFrameTruncated) {
+			// 等待后续数据
+			return nil, skipped, nil
+		}
+
+		// 坏帧：跳过当前同步字，重新搜索
+		d.buf = d.buf[1:]
+		skipped += 1 + d.seekSync()
+		return nil, skipped, err
+	}
+
+	return nil, skipped, nil
+}
+
+// seekSync 丢弃同步字之前的字节，返回丢弃数量
+func (d *StreamDecoder) seekSync() int {
+	for i := 0; i+1 < len(d.buf); i++ {
+		if d.buf[i] == FrameSync0 && d.buf[i+1] == FrameSync1 {
+			d.buf = d.buf[i:]
+			return i
+		}
+	}
+
+	// 未找到完整同步字，保留可能是同步字前半部分的最后1字节
+	if n := len(d.buf); n > 0 {
+		if d.buf[n-1] == FrameSync0 {
+			d.buf = d.buf[n-1:]
+			return n - 1
+		}
+		d.buf = d.buf[:0]
+		return n
+	}
+	return 0
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                           帧质量统计
+////////////////////////////////////////////////////////////////////////////////
+
+// FrameStats 单个组件的帧统计
+type FrameStats struct {
+	Accepted     uint64 `json:"accepted"`      // 成功解析的帧
+	CRCErrors    uint64 `json:"crc_errors"`    // CRC 校验失败
+	Truncated    uint64 `json:"truncated"`     // 帧或负载截断
+	UnknownType  uint64 `json:"unknown_type"`  // 未知组件类型
+	LengthErrors uint64 `json:"length_errors"` // 长度字段非法
+	VersionError uint64 `json:"version_error"` // 版本不支持
+	SeqErrors    uint64 `json:"seq_errors"`    // 分段标志/序列计数非法（CCSDS）
+	TimeErrors   uint64 `json:"time_errors"`   // 星上时间码无法解码（已回退为接收时间）
+}
+
+// FrameCounters 按组件统计帧质量（并发安全）
+type FrameCounters struct {
+	mu           sync.Mutex
+	components   map[uint8]*FrameStats
+	unattributed FrameStats // 无法确定组件类型的错误
+	syncErrors   uint64     // 缺少同步字的报文
+	skippedBytes uint64     // 重同步时丢弃的字节
+}
+
+// NewFrameCounters 创建帧统计
+func NewFrameCounters() *FrameCounters {
+	return &FrameCounters{
+		components: make(map[uint8]*FrameStats),
+	}
+}
+
+func (c *FrameCounters) stats(component uint8) *FrameStats {
+	s, ok := c.components[component]
+	if !ok {
+		s = &FrameStats{}
+		c.components[component] = s
+	}
+	return s
+}
+
+// RecordAccepted 记录成功帧
+func (c *FrameCounters) RecordAccepted(component uint8) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats(component).Accepted++
+}
+
+// RecordTimeError 记录时间码解码失败
+func (c *FrameCounters) RecordTimeError(component uint8) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats(component).TimeErrors++
+}
+
+// RecordSkipped 记录重同步丢弃的字节
+func (c *FrameCounters) RecordSkipped(n int) {
+	if n <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skippedBytes += uint64(n)
+}
+
+// RecordError 根据错误类别累加对应计数
+func (c *FrameCounters) RecordError(err error) {
+	var fe *FrameError
+	if !errors.As(err, &fe) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	if fe.NoComponent {
+		stats = func(uint8) *FrameStats { return &c.unattributed }
+	}
+
+	switch {
+	case errors.Is(fe.Kind, ErrFrameSync):
+		c.syncErrors++
+	case errors.Is(fe.Kind, ErrFrameCRC):
+		stats(fe.Component).CRCErrors++
+	case errors.Is(fe.Kind, ErrFrameTruncated):
+		stats(fe.Component).Truncated++
+	case errors.Is(fe.Kind, ErrUnknownComponent):
+		stats(fe.Component).UnknownType++
+	case errors.Is(fe.Kind, ErrFrameLength):
+		stats(fe.Component).LengthErrors++
+	case errors.Is(fe.Kind, ErrFrameVersion):
+		stats(fe.Component).VersionError++
+	case errors.Is(fe.Kind, ErrSequenceFlags):
+		stats(fe.Component).SeqErrors++
+	}
+}
+
+// Snapshot 返回当前统计的副本
+func (c *FrameCounters) Snapshot() map[uint8]FrameStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make(map[uint8]FrameStats, len(c.components))
+	for comp, s := range c.components {
+		out[comp] = *s
+	}
+	return out
+}
+
+// Unattributed 返回无法确定组件类型的错误统计
+func (c *FrameCounters) Unattributed() FrameStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.unattributed
+}
+
+// SyncErrors 返回缺少同步字的报文数量
+func (c *FrameCounters) SyncErrors() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.syncErrors
+}
+
+// SkippedBytes 返回重同步时丢弃的总字节数
+func (c *FrameCounters) SkippedBytes() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skippedBytes
+}
@@ -0,0 +1,143 @@
+package business
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func buildPowerPayload() []byte {
+	payload := make([]byte, 14)
+	binary.BigEndian.PutUint16(payload[0:2], 13000)
+	binary.BigEndian.PutUint16(payload[2:4], 25000)
+	binary.BigEndian.PutUint16(payload[4:6], 24500)
+	binary.BigEndian.PutUint16(payload[6:8], 3300)
+	binary.BigEndian.PutUint16(payload[8:10], 5000)
+	binary.BigEndian.PutUint16(payload[10:12], 1200)
+	binary.BigEndian.PutUint16(payload[12:14], 2000)
+	return payload
+}
+
+// TestCRC16CCITT 使用标准校验值 "123456789" -> 0x29B1
+func TestCRC16CCITT(t *testing.T) {
+	if got := CRC16CCITT([]byte("123456789")); got != 0x29B1 {
+		t.Fatalf("CRC16CCITT = 0x%04X, want 0x29B1", got)
+	}
+}
+
+// TestParsePacketRejectsBadFrames 测试坏帧被拒绝并按组件计数
+func TestParsePacketRejectsBadFrames(t *testing.T) {
+	receiver := NewReceiver(NewDispatcher(nil))
+
+	good := EncodeFrame(CompPower, buildPowerPayload())
+	metrics, err := receiver.ParsePacket(good)
+	if err != nil {
+		t.Fatalf("解析正常帧失败: %v", err)
+	}
+	if metrics.Raw["BatteryVoltage"] != 25000 {
+		t.Errorf("未保留原始值: %v", metrics.Raw)
+	}
+
+	corrupted := append([]byte(nil), good...)
+	corrupted[FrameHeaderSize] ^= 0xFF
+	if _, err := receiver.ParsePacket(corrupted); !errors.Is(err, ErrFrameCRC) {
+		t.Errorf("CRC错误帧: got %v, want ErrFrameCRC", err)
+	}
+
+	if _, err := receiver.ParsePacket(good[:len(good)-3]); !errors.Is(err, ErrFrameTruncated) {
+		t.Errorf("截断帧: got %v, want ErrFrameTruncated", err)
+	}
+
+	if _, err := receiver.ParsePacket(EncodeFrame(CompPower, buildPowerPayload()[:10])); !errors.Is(err, ErrFrameTruncated) {
+		t.Errorf("负载不足: got %v, want ErrFrameTruncated", err)
+	}
+
+	if _, err := receiver.ParsePacket(EncodeFrame(0x7F, []byte{1, 2, 3})); !errors.Is(err, ErrUnknownComponent) {
+		t.Errorf("未知组件: got %v, want ErrUnknownComponent", err)
+	}
+
+	stats := receiver.FrameStats()
+	power := stats[CompPower]
+	if power.Accepted != 1 || power.CRCErrors != 1 || power.Truncated != 2 {
+		t.Errorf("供电服务统计不符: %+v", power)
+	}
+	if stats[0x7F].UnknownType != 1 {
+		t.Errorf("未知类型统计不符: %+v", stats[0x7F])
+	}
+}
+
+// TestSubmitTruncatedHeader 测试帧头不完整时按组件类型字节归属，无法确定时计入未归属
+func TestSubmitTruncatedHeader(t *testing.T) {
+	receiver := NewReceiver(NewDispatcher(nil))
+	good := EncodeFrame(CompPower, buildPowerPayload())
+
+	if err := receiver.Submit(good[:5]); !errors.Is(err, ErrFrameTruncated) {
+		t.Errorf("截断帧头: got %v, want ErrFrameTruncated", err)
+	}
+	if err := receiver.Submit(good[:3]); !errors.Is(err, ErrFrameTruncated) {
+		t.Errorf("缺少组件类型: got %v, want ErrFrameTruncated", err)
+	}
+
+	stats := receiver.FrameStats()
+	if stats[CompPower].Truncated != 1 {
+		t.Errorf("应归属供电服务: %+v", stats)
+	}
+	if _, ok := stats[0]; ok {
+		t.Errorf("不应计入组件 0x00: %+v", stats[0])
+	}
+	if un := receiver.Counters().Unattributed(); un.Truncated != 1 {
+		t.Errorf("未归属统计不符: %+v", un)
+	}
+}
+
+// TestStreamDecoderResync 测试字节流中的噪声与坏帧能被跳过
+func TestStreamDecoderResync(t *testing.T) {
+	good := EncodeFrame(CompPower, buildPowerPayload())
+	bad := append([]byte(nil), good...)
+	bad[len(bad)-1] ^= 0x01
+
+	var stream []byte
+	stream = append(stream, 0x00, 0xEB, 0x13, 0x37) // 噪声（含伪同步字节）
+	stream = append(stream, bad...)
+	stream = append(stream, good...)
+	stream = append(stream, good[:5]...) // 尾部半帧
+
+	d := NewStreamDecoder()
+	var frames []*Frame
+	var crcErrors int
+
+	// 分多次喂入，模拟任意切分
+	for i := 0; i < len(stream); i += 7 {
+		end := i + 7
+		if end > len(stream) {
+			end = len(stream)
+		}
+		d.Feed(stream[i:end])
+		for {
+			frame, _, err := d.Next()
+			if err != nil {
+				if errors.Is(err, ErrFrameCRC) {
+					crcErrors++
+				}
+				continue
+			}
+			if frame == nil {
+				break
+			}
+			frames = append(frames, frame)
+		}
+	}
+
+	if len(frames) != 1 {
+		t.Fatalf("解出 %d 帧, want 1", len(frames))
+	}
+	if frames[0].Component != CompPower || len(frames[0].Payload) != 14 {
+		t.Errorf("帧内容不符: comp=0x%02X len=%d", frames[0].Component, len(frames[0].Payload))
+	}
+	if crcErrors != 1 {
+		t.Errorf("CRC错误 %d 次, want 1", crcErrors)
+	}
+	if d.Buffered() != 5 {
+		t.Errorf("剩余 %d 字节, want 5", d.Buffered())
+	}
+}
@@ -10,7 +10,7 @@ import (
 // TestDispatcherToGeneratorFlow 测试从Dispatcher到Generator的完整流程
 func TestDispatcherToGeneratorFlow(t *testing.T) {
 	// 1. 创建组件
-	dispatcher := NewDispatcher()
+	dispatcher := NewDispatcher(nil)
 	receiver := NewReceiver(dispatcher)
 	
 	ctx := context.Background()
@@ -66,27 +66,25 @@ func TestDispatcherToGeneratorFlow(t *testing.T) {
 
 // TestNormalMetrics 测试正常数据（无告警）
 func TestNormalMetrics(t *testing.T) {
-	dispatcher := NewDispatcher()
+	dispatcher := NewDispatcher(nil)
 	receiver := NewReceiver(dispatcher)
 	ctx := context.Background()
 	
 	t.Log("=== 测试正常数据（预期无告警） ===")
 	
 	// 构造正常的供电服务报文
-	packet := make([]byte, 3+14)
-	packet[0] = 0x03 // CompPower
-	binary.BigEndian.PutUint16(packet[1:3], 14)
+	payload := make([]byte, 14)
 	
 	// 所有数据都在正常范围内
-	binary.BigEndian.PutUint16(packet[3:5], 13000)   // 13.0V - 正常
-	binary.BigEndian.PutUint16(packet[5:7], 25000)   // 25.0V - 正常
-	binary.BigEndian.PutUint16(packet[7:9], 24500)   // 24.5V - 正常
-	binary.BigEndian.PutUint16(packet[9:11], 3300)   // 3.3V - 正常
-	binary.BigEndian.PutUint16(packet[11:13], 5000)  // 5.0V - 正常
-	binary.BigEndian.PutUint16(packet[13:15], 1200)  // 1.2A - 正常
-	binary.BigEndian.PutUint16(packet[15:17], 2000)  // 2.0A - 正常
-	
-	metrics, err := receiver.ParsePacket(packet)
+	binary.BigEndian.PutUint16(payload[0:2], 13000)   // 13.0V - 正常
+	binary.BigEndian.PutUint16(payload[2:4], 25000)   // 25.0V - 正常
+	binary.BigEndian.PutUint16(payload[4:6], 24500)   // 24.5V - 正常
+	binary.BigEndian.PutUint16(payload[6:8], 3300)   // 3.3V - 正常
+	binary.BigEndian.PutUint16(payload[8:10], 5000)  // 5.0V - 正常
+	binary.BigEndian.PutUint16(payload[10:12], 1200)  // 1.2A - 正常
+	binary.BigEndian.PutUint16(payload[12:14], 2000)  // 2.0A - 正常
+	
+	metrics, err := receiver.ParsePacket(EncodeFrame(CompPower, payload))
 	if err != nil {
 		t.Fatalf("解析报文失败: %v", err)
 	}
@@ -97,27 +95,23 @@ func TestNormalMetrics(t *testing.T) {
 
 // 辅助函数：构造异常的供电服务报文
 func buildAbnormalPowerPacket() []byte {
-	packet := make([]byte, 3+14)
-	packet[0] = 0x03 // CompPower
-	binary.BigEndian.PutUint16(packet[1:3], 14)
+	payload := make([]byte, 14)
 	
 	// 填充异常数据
-	binary.BigEndian.PutUint16(packet[3:5], 11000)   // 11.0V - 异常！(正常约13V)
-	binary.BigEndian.PutUint16(packet[5:7], 19000)   // 19.0V - 异常！(正常[21,29.4]V)
-	binary.BigEndian.PutUint16(packet[7:9], 24500)   // 24.5V - 正常
-	binary.BigEndian.PutUint16(packet[9:11], 2800)   // 2.8V - 异常！(正常[3.1,3.5]V)
-	binary.BigEndian.PutUint16(packet[11:13], 5000)  // 5.0V - 正常
-	binary.BigEndian.PutUint16(packet[13:15], 1200)  // 1.2A - 正常
-	binary.BigEndian.PutUint16(packet[15:17], 6000)  // 6.0A - 异常！(正常[0.5,5]A)
-	
-	return packet
+	binary.BigEndian.PutUint16(payload[0:2], 11000)   // 11.0V - 异常！(正常约13V)
+	binary.BigEndian.PutUint16(payload[2:4], 19000)   // 19.0V - 异常！(正常[21,29.4]V)
+	binary.BigEndian.PutUint16(payload[4:6], 24500)   // 24.5V - 正常
+	binary.BigEndian.PutUint16(payload[6:8], 2800)   // 2.8V - 异常！(正常[3.1,3.5]V)
+	binary.BigEndian.PutUint16(payload[8:10], 5000)  // 5.0V - 正常
+	binary.BigEndian.PutUint16(payload[10:12], 1200)  // 1.2A - 正常
+	binary.BigEndian.PutUint16(payload[12:14], 6000)  // 6.0A - 异常！(正常[0.5,5]A)
+	
+	return EncodeFrame(0x03, payload)
 }
 
 // 辅助函数：构造异常的热控服务报文
 func buildAbnormalThermalPacket() []byte {
-	packet := make([]byte, 3+31)
-	packet[0] = 0x06 // CompThermal
-	binary.BigEndian.PutUint16(packet[1:3], 31)
+	payload := make([]byte, 31)
 	
 	// 填充10个热控温度点 - 部分异常
 	for i := 0; i < 10; i++ {
@@ -128,48 +122,44 @@ func buildAbnormalThermalPacket() []byte {
 		if i == 7 {
 			temp = int16(-250) // -25.0℃ - 异常！
 		}
-		binary.BigEndian.PutUint16(packet[3+i*2:5+i*2], uint16(temp))
+		binary.BigEndian.PutUint16(payload[i*2:i*2+2], uint16(temp))
 	}
 	
 	// 蓄电池温度
-	binary.BigEndian.PutUint16(packet[23:25], uint16(int16(280)))  // 28.0℃ - 正常
-	binary.BigEndian.PutUint16(packet[25:27], uint16(int16(500))) // 50.0℃ - 异常！
+	binary.BigEndian.PutUint16(payload[20:22], uint16(int16(280)))  // 28.0℃ - 正常
+	binary.BigEndian.PutUint16(payload[22:24], uint16(int16(500))) // 50.0℃ - 异常！
 	
 	// 其他温度
-	binary.BigEndian.PutUint16(packet[27:29], uint16(int16(240)))
-	binary.BigEndian.PutUint16(packet[29:31], uint16(int16(220)))
-	binary.BigEndian.PutUint16(packet[31:33], uint16(int16(220)))
+	binary.BigEndian.PutUint16(payload[24:26], uint16(int16(240)))
+	binary.BigEndian.PutUint16(payload[26:28], uint16(int16(220)))
+	binary.BigEndian.PutUint16(payload[28:30], uint16(int16(220)))
 	
 	// 开关状态
-	packet[33] = 0x07
+	payload[30] = 0x07
 	
-	return packet
+	return EncodeFrame(0x06, payload)
 }
 
 // 辅助函数：构造异常的通信服务报文
 func buildAbnormalCommPacket() []byte {
-	packet := make([]byte, 3+6)
-	packet[0] = 0x02 // CompComm
-	binary.BigEndian.PutUint16(packet[1:3], 6)
+	payload := make([]byte, 18)
 	
-	packet[3] = 15 // SNR
-	binary.BigEndian.PutUint16(packet[4:6], 9600) // rate
-	packet[6] = 0  // CAN状态: 无应答 - 异常！
-	packet[7] = 0  // 串口状态: 无遥测 - 异常！
-	packet[8] = 1  // 空空通信状态: 正常
+	payload[0] = 15 // SNR
+	binary.BigEndian.PutUint16(payload[1:3], 9600) // rate
+	payload[3] = 0  // CAN状态: 无应答 - 异常！
+	payload[4] = 0  // 串口状态: 无遥测 - 异常！
+	payload[5] = 1  // 空空通信状态: 正常
 	
-	return packet
+	return EncodeFrame(0x02, payload)
 }
 
 // 辅助函数：构造异常的姿态控制机构报文
 func buildAbnormalActuatorPacket() []byte {
-	packet := make([]byte, 3+6)
-	packet[0] = 0x0B // CompActuator
-	binary.BigEndian.PutUint16(packet[1:3], 6)
+	payload := make([]byte, 6)
 	
-	binary.BigEndian.PutUint16(packet[3:5], 98)   // X轴: 98转 - 正常
-	binary.BigEndian.PutUint16(packet[5:7], 150)  // Y轴: 150转 - 异常！
-	binary.BigEndian.PutUint16(packet[7:9], 70)   // Z轴: 70转 - 异常！
+	binary.BigEndian.PutUint16(payload[0:2], 98)   // X轴: 98转 - 正常
+	binary.BigEndian.PutUint16(payload[2:4], 150)  // Y轴: 150转 - 异常！
+	binary.BigEndian.PutUint16(payload[4:6], 70)   // Z轴: 70转 - 异常！
 	
-	return packet
+	return EncodeFrame(0x0B, payload)
 }
@@ -0,0 +1,468 @@
+/*
+声明式报文布局
+
+各组件负载的字段偏移、宽度、符号、端序、位域掩码和缩放系数由 JSON 或 YAML 描述，
+通用解码器按布局把负载填充到 models 中对应的指标结构体（PowerMetrics 等）。
+卫星 ICD 变更时只需修改布局文件（-layouts 参数指定），无需重新编译监测程序。
+字段可挂接标定曲线（见 calibration.go），解码时同时保留标定前的原始值。
+
+内置默认布局见 layouts/default.json。
+*/
+package business
+
+import (
+	"embed"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"health-monitor/pkg/config"
+	"health-monitor/pkg/models"
+)
+
+//go:embed layouts/default.json
+var defaultLayoutFS embed.FS
+
+// FieldLayout 单个字段的二进制布局
+type FieldLayout struct {
+	Name   string  `json:"name"`             // 目标结构体字段名，数组元素可写作 ThermalTemps[3]
+	Offset int     `json:"offset"`           // 相对负载起始的字节偏移
+	Width  int     `json:"width"`            // 字节宽度：1/2/4/8
+	Signed bool    `json:"signed,omitempty"` // 是否为有符号数（补码）
+	Endian string  `json:"endian,omitempty"` // big（默认）/ little
+	Mask   uint64  `json:"mask,omitempty"`   // 位域掩码，取值后按最低置位右移
+	Scale  float64 `json:"scale,omitempty"`  // 缩放系数，工程值 = 原始值 * scale（默认1）
+	Count  int     `json:"count,omitempty"`  // 数组字段的元素个数（默认1）
+	Stride int     `json:"stride,omitempty"` // 数组元素间隔字节数（默认等于 width）
+
+	Calibration    *Calibration `json:"calibration,omitempty"`     // 内联标定曲线（与 scale 互斥）
+	CalibrationRef string       `json:"calibration_ref,omitempty"` // 引用 LayoutSet.Calibrations 中的命名曲线
+
+	// 校验后缓存的反射信息
+	fieldIndex int
+	elemIndex  int    // 数组下标，-1 表示非数组元素
+	rawKey     string // 原始值在 BusinessMetrics.Raw 中的键，如 ThermalTemps[3]
+}
+
+// PacketLayout 单个组件的负载布局
+type PacketLayout struct {
+	Component uint8         `json:"component"` // 组件编号（CompRunMgr ~ CompEPS）
+	Name      string        `json:"name"`      // 组件名称
+	Fields    []FieldLayout `json:"fields"`
+
+	minSize int
+}
+
+// LayoutSet 全部组件的布局集合
+type LayoutSet struct {
+	Version      string                  `json:"version"`
+	Calibrations map[string]*Calibration `json:"calibrations,omitempty"` // 命名标定曲线，可被多个字段引用
+	Layouts      []PacketLayout          `json:"layouts"`
+
+	byComponent map[uint8]*PacketLayout
+}
+
+// componentFactories 组件编号 -> 指标结构体构造函数
+var componentFactories = map[uint8]func() interface{}{
+	CompRunMgr:      func() interface{} { return &model.RunMgrMetrics{Payload: make(map[string]interface{})} },
+	CompComm:        func() interface{} { return &model.CommMetrics{} },
+	CompPower:       func() interface{} { return &model.PowerMetrics{} },
+	CompRailCtrl:    func() interface{} { return &model.RailCtrlMetrics{} },
+	CompPayload:     func() interface{} { return &model.PayloadMetrics{} },
+	CompThermal:     func() interface{} { return &model.ThermalMetrics{} },
+	CompAttCtrl:     func() interface{} { return &model.AttCtrlMetrics{} },
+	CompMeasure:     func() interface{} { return &model.MeasureMetrics{} },
+	CompOptical:     func() interface{} { return &model.OpticalMetrics{} },
+	CompSensor:      func() interface{} { return &model.SensorMetrics{} },
+	CompActuator:    func() interface{} { return &model.ActuatorMetrics{} },
+	CompTransceiver: func() interface{} { return &model.TransceiverMetrics{} },
+	CompThruster:    func() interface{} { return &model.ThrusterMetrics{} },
+	CompEPS:         func() interface{} { return &model.EPSMetrics{} },
+}
+
+// DefaultLayouts 返回内置默认布局
+func DefaultLayouts() *LayoutSet {
+	data, err := defaultLayoutFS.ReadFile("layouts/default.json")
+	if err != nil {
+		panic(fmt.Sprintf("读取内置布局失败: %v", err))
+	}
+	ls, err := ParseLayouts(data)
+	if err != nil {
+		panic(fmt.Sprintf("内置布局无效: %v", err))
+	}
+	return ls
+}
+
+// LoadLayouts 从文件加载布局，.yaml/.yml 按 YAML 解析，其余按 JSON 解析
+func LoadLayouts(path string) (*LayoutSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取布局文件失败: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseLayoutsYAML(data)
+	}
+	return ParseLayouts(data)
+}
+
+// ParseLayoutsYAML 解析并校验布局 YAML（字段名与 JSON 相同）
+func ParseLayoutsYAML(data []byte) (*LayoutSet, error) {
+	js, err := config.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析布局文件失败: %w", err)
+	}
+	return ParseLayouts(js)
+}
+
+// ParseLayouts 解析并校验布局 JSON
+func ParseLayouts(data []byte) (*LayoutSet, error) {
+	var ls LayoutSet
+	if err := json.Unmarshal(data, &ls); err != nil {
+		return nil, fmt.Errorf("解析布局文件失败: %w", err)
+	}
+	if err := ls.Validate(); err != nil {
+		return nil, fmt.Errorf("布局校验失败: %w", err)
+	}
+	return &ls, nil
+}
+
+// Validate 校验布局并建立索引
+func (ls *LayoutSet) Validate() error {
+	byComponent := make(map[uint8]*PacketLayout, len(ls.Layouts))
+
+	for name, cal := range ls.Calibrations {
+		if cal == nil {
+			return fmt.Errorf("标定曲线 %s 为空", name)
+		}
+		if err := cal.Validate(); err != nil {
+			return fmt.Errorf("标定曲线 %s: %w", name, err)
+		}
+	}
+
+	for i := range ls.Layouts {
+		pl := &ls.Layouts[i]
+
+		factory, ok := componentFactories[pl.Component]
+		if !ok {
+			return fmt.Errorf("未知组件编号 0x%02X", pl.Component)
+		}
+		if _, dup := byComponent[pl.Component]; dup {
+			return fmt.Errorf("组件 0x%02X 布局重复定义", pl.Component)
+		}
+
+		structType := reflect.TypeOf(factory()).Elem()
+		if err := pl.resolve(structType, ls.Calibrations); err != nil {
+			return fmt.Errorf("组件 0x%02X(%s): %w", pl.Component, pl.Name, err)
+		}
+		byComponent[pl.Component] = pl
+	}
+
+	ls.byComponent = byComponent
+	return nil
+}
+
+// Layout 查询组件布局
+func (ls *LayoutSet) Layout(component uint8) (*PacketLayout, bool) {
+	pl, ok := ls.byComponent[component]
+	return pl, ok
+}
+
+// MinSize 负载的最小字节数（由最远字段决定）
+func (pl *PacketLayout) MinSize() int {
+	return pl.minSize
+}
+
+// resolve 校验字段并缓存反射索引
+func (pl *PacketLayout) resolve(structType reflect.Type, calibrations map[string]*Calibration) error {
+	var expanded []FieldLayout
+
+	for _, f := range pl.Fields {
+		switch f.Width {
+		case 1, 2, 4, 8:
+		default:
+			return fmt.Errorf("字段 %s: 不支持的宽度 %d", f.Name, f.Width)
+		}
+		switch strings.ToLower(f.Endian) {
+		case "", "big", "little":
+		default:
+			return fmt.Errorf("字段 %s: 不支持的端序 %q", f.Name, f.Endian)
+		}
+		if f.Offset < 0 {
+			return fmt.Errorf("字段 %s: 偏移不能为负", f.Name)
+		}
+		if f.Mask != 0 && f.Width < 8 && f.Mask>>(uint(f.Width)*8) != 0 {
+			return fmt.Errorf("字段 %s: 掩码 0x%X 超出宽度", f.Name, f.Mask)
+		}
+
+		if err := f.resolveCalibration(calibrations); err != nil {
+			return err
+		}
+
+		name, index, err := splitFieldName(f.Name)
+		if err != nil {
+			return err
+		}
+		sf, ok := structType.FieldByName(name)
+		if !ok || len(sf.Index) != 1 {
+			return fmt.Errorf("字段 %s: 结构体 %s 中不存在", f.Name, structType.Name())
+		}
+
+		count := f.Count
+		if count <= 0 {
+			count = 1
+		}
+		stride := f.Stride
+		if stride <= 0 {
+			stride = f.Width
+		}
+
+		if sf.Type.Kind() == reflect.Array {
+			if index < 0 && f.Count <= 0 {
+				return fmt.Errorf("字段 %s: 数组字段需指定 count 或下标", f.Name)
+			}
+			start := index
+			if start < 0 {
+				start = 0
+			}
+			if start+count > sf.Type.Len() {
+				return fmt.Errorf("字段 %s: 下标越界（数组长度 %d）", f.Name, sf.Type.Len())
+			}
+			if !assignable(sf.Type.Elem().Kind()) {
+				return fmt.Errorf("字段 %s: 不支持的元素类型 %s", f.Name, sf.Type.Elem())
+			}
+			for i := 0; i < count; i++ {
+				e := f
+				e.Offset = f.Offset + i*stride
+				e.Count = 1
+				e.fieldIndex = sf.Index[0]
+				e.elemIndex = start + i
+				e.rawKey = fmt.Sprintf("%s[%d]", name, start+i)
+				expanded = append(expanded, e)
+			}
+			continue
+		}
+
+		if index >= 0 || count > 1 {
+			return fmt.Errorf("字段 %s: 非数组字段不能指定下标或 count", f.Name)
+		}
+		if !assignable(sf.Type.Kind()) {
+			return fmt.Errorf("字段 %s: 不支持的类型 %s", f.Name, sf.Type)
+		}
+		f.fieldIndex = sf.Index[0]
+		f.elemIndex = -1
+		f.rawKey = name
+		expanded = append(expanded, f)
+	}
+
+	pl.minSize = 0
+	for _, f := range expanded {
+		if end := f.Offset + f.Width; end > pl.minSize {
+			pl.minSize = end
+		}
+	}
+	pl.Fields = expanded
+	return nil
+}
+
+// resolveCalibration 校验字段的标定配置，命名引用解析为共享的曲线
+func (f *FieldLayout) resolveCalibration(calibrations map[string]*Calibration) error {
+	if f.Calibration != nil && f.CalibrationRef != "" && f.Calibration != calibrations[f.CalibrationRef] {
+		return fmt.Errorf("字段 %s: calibration 与 calibration_ref 不能同时配置", f.Name)
+	}
+	if f.CalibrationRef != "" {
+		cal, ok := calibrations[f.CalibrationRef]
+		if !ok {
+			return fmt.Errorf("字段 %s: 标定曲线 %s 未定义", f.Name, f.CalibrationRef)
+		}
+		f.Calibration = cal
+	} else if f.Calibration != nil {
+		if err := f.Calibration.Validate(); err != nil {
+			return fmt.Errorf("字段 %s: %w", f.Name, err)
+		}
+	}
+	if f.Calibration != nil && f.Scale != 0 {
+		return fmt.Errorf("字段 %s: scale 与标定曲线不能同时配置", f.Name)
+	}
+	return nil
+}
+
+// splitFieldName 拆分 "Name[3]" 形式的字段名，无下标时 index 为 -1
+func splitFieldName(s string) (string, int, error) {
+	open := strings.IndexByte(s, '[')
+	if open < 0 {
+		return s, -1, nil
+	}
+	if !strings.HasSuffix(s, "]") {
+		return "", 0, fmt.Errorf("字段名 %q 格式错误", s)
+	}
+	idx, err := strconv.Atoi(s[open+1 : len(s)-1])
+	if err != nil || idx < 0 {
+		return "", 0, fmt.Errorf("字段名 %q 下标错误", s)
+	}
+	return s[:open], idx, nil
+}
+
+func assignable(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              通用解码
+////////////////////////////////////////////////////////////////////////////////
+
+// Decode 按布局解码负载，返回对应的指标结构体指针（如 *model.PowerMetrics）
+// 负载长度不足时返回 ErrFrameTruncated，未定义布局时返回 ErrUnknownComponent
+func (ls *LayoutSet) Decode(component uint8, payload []byte, timestamp int64) (interface{}, error) {
+	out, _, err := ls.DecodeWithRaw(component, payload, timestamp)
+	return out, err
+}
+
+// DecodeWithRaw 同 Decode，并返回各字段标定前的原始值（字段名 -> 原始值）
+func (ls *LayoutSet) DecodeWithRaw(component uint8, payload []byte, timestamp int64) (interface{}, map[string]float64, error) {
+	pl, ok := ls.Layout(component)
+	if !ok {
+		return nil, nil, &FrameError{Kind: ErrUnknownComponent, Component: component}
+	}
+	if len(payload) < pl.minSize {
+		return nil, nil, &FrameError{
+			Kind:      ErrFrameTruncated,
+			Component: component,
+			Detail:    fmt.Sprintf("payload %d bytes, layout %s needs %d", len(payload), pl.Name, pl.minSize),
+		}
+	}
+
+	out := componentFactories[component]()
+	v := reflect.ValueOf(out).Elem()
+	raw := make(map[string]float64, len(pl.Fields))
+
+	if ts := v.FieldByName("Timestamp"); ts.IsValid() && ts.Kind() == reflect.Int64 {
+		ts.SetInt(timestamp)
+	}
+
+	for i := range pl.Fields {
+		f := &pl.Fields[i]
+		target := v.Field(f.fieldIndex)
+		if f.elemIndex >= 0 {
+			target = target.Index(f.elemIndex)
+		}
+
+		r := f.rawValue(payload)
+		raw[f.rawKey] = r
+
+		eng, err := f.engineering(r)
+		if err == nil {
+			err = setValue(target, eng)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("组件 0x%02X 字段 %s: %w", component, f.rawKey, err)
+		}
+	}
+
+	return out, raw, nil
+}
+
+// raw 读取字段原始整数值（已应用掩码和符号扩展）
+func (f *FieldLayout) raw(payload []byte) (uint64, int64) {
+	b := payload[f.Offset : f.Offset+f.Width]
+
+	var u uint64
+	if strings.EqualFold(f.Endian, "little") {
+		switch f.Width {
+		case 1:
+			u = uint64(b[0])
+		case 2:
+			u = uint64(binary.LittleEndian.Uint16(b))
+		case 4:
+			u = uint64(binary.LittleEndian.Uint32(b))
+		case 8:
+			u = binary.LittleEndian.Uint64(b)
+		}
+	} else {
+		switch f.Width {
+		case 1:
+			u = uint64(b[0])
+		case 2:
+			u = uint64(binary.BigEndian.Uint16(b))
+		case 4:
+			u = uint64(binary.BigEndian.Uint32(b))
+		case 8:
+			u = binary.BigEndian.Uint64(b)
+		}
+	}
+
+	nbits := uint(f.Width) * 8
+	if f.Mask != 0 {
+		shift := uint(bits.TrailingZeros64(f.Mask))
+		u = (u & f.Mask) >> shift
+		nbits = uint(bits.Len64(f.Mask >> shift))
+	}
+
+	s := int64(u)
+	if f.Signed && nbits < 64 && u&(1<<(nbits-1)) != 0 {
+		s = int64(u | ^uint64(0)<<nbits)
+	}
+	return u, s
+}
+
+// rawValue 字段的原始值（掩码、符号扩展后，标定前）
+func (f *FieldLayout) rawValue(payload []byte) float64 {
+	u, s := f.raw(payload)
+	if f.Signed {
+		return float64(s)
+	}
+	return float64(u)
+}
+
+// engineering 原始值转换为工程值：优先使用标定曲线，否则按 scale 缩放
+func (f *FieldLayout) engineering(raw float64) (float64, error) {
+	if f.Calibration != nil {
+		return f.Calibration.Apply(raw)
+	}
+	if f.Scale != 0 {
+		return raw * f.Scale, nil
+	}
+	return raw, nil
+}
+
+// setValue 按目标字段类型写入数值
+func setValue(target reflect.Value, v float64) error {
+	switch target.Kind() {
+	case reflect.Bool:
+		target.SetBool(v != 0)
+	case reflect.Float32, reflect.Float64:
+		target.SetFloat(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := int64(math.Round(v))
+		if target.OverflowInt(n) {
+			return fmt.Errorf("值 %v 超出 %s 范围", v, target.Type())
+		}
+		target.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v < 0 {
+			return fmt.Errorf("值 %v 不能写入无符号字段", v)
+		}
+		n := uint64(math.Round(v))
+		if target.OverflowUint(n) {
+			return fmt.Errorf("值 %v 超出 %s 范围", v, target.Type())
+		}
+		target.SetUint(n)
+	default:
+		return fmt.Errorf("不支持的字段类型 %s", target.Type())
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package business
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"health-monitor/pkg/models"
+)
+
+// TestDefaultLayoutsDecode 测试内置布局解码结果与ICD一致
+func TestDefaultLayoutsDecode(t *testing.T) {
+	ls := DefaultLayouts()
+
+	for comp := range componentFactories {
+		if _, ok := ls.Layout(comp); !ok {
+			t.Errorf("组件 0x%02X 缺少默认布局", comp)
+		}
+	}
+
+	data, err := ls.Decode(CompPower, buildPowerPayload(), 100)
+	if err != nil {
+		t.Fatalf("解码供电服务失败: %v", err)
+	}
+	power := data.(*model.PowerMetrics)
+	if power.Timestamp != 100 || power.BatteryVoltage < 24.999 || power.BatteryVoltage > 25.001 {
+		t.Errorf("供电服务解码不符: %+v", power)
+	}
+
+	thermal := make([]byte, 31)
+	binary.BigEndian.PutUint16(thermal[4:6], uint16(0xFFFF-249)) // ThermalTemps[2] = -25.0
+	binary.BigEndian.PutUint16(thermal[22:24], 500)               // BatteryTemp2 = 50.0
+	thermal[30] = 0x05
+	data, err = ls.Decode(CompThermal, thermal, 0)
+	if err != nil {
+		t.Fatalf("解码热控服务失败: %v", err)
+	}
+	tm := data.(*model.ThermalMetrics)
+	if tm.ThermalTemps[2] != -25.0 || tm.BatteryTemp2 != 50.0 {
+		t.Errorf("温度解码不符: temps=%v bat2=%v", tm.ThermalTemps, tm.BatteryTemp2)
+	}
+	if !tm.PlatformHeaterSwitch || tm.BatteryHeaterSwitch || !tm.TankHeaterSwitch {
+		t.Errorf("开关位域解码不符: %+v", tm)
+	}
+
+	if _, err := ls.Decode(CompComm, make([]byte, 16), 0); !errors.Is(err, ErrFrameTruncated) {
+		t.Errorf("通信服务负载不足: got %v, want ErrFrameTruncated", err)
+	}
+}
+
+// TestCustomLayout 测试自定义布局（小端、位域、下标）
+func TestCustomLayout(t *testing.T) {
+	ls, err := ParseLayouts([]byte(`{
+		"version": "test",
+		"layouts": [{
+			"component": 11,
+			"name": "Actuator",
+			"fields": [
+				{"name": "WheelSpeedX", "offset": 0, "width": 2, "signed": true, "endian": "little"},
+				{"name": "WheelSpeedY", "offset": 2, "width": 1, "signed": true, "mask": 240},
+				{"name": "WheelSpeedZ", "offset": 2, "width": 1, "mask": 15, "scale": 10}
+			]
+		}]
+	}`))
+	if err != nil {
+		t.Fatalf("解析布局失败: %v", err)
+	}
+
+	data, err := ls.Decode(CompActuator, []byte{0x9C, 0xFF, 0xE3}, 0)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	act := data.(*model.ActuatorMetrics)
+	if act.WheelSpeedX != -100 || act.WheelSpeedY != -2 || act.WheelSpeedZ != 30 {
+		t.Errorf("解码不符: %+v", act)
+	}
+}
+
+// TestLoadLayoutsYAML 测试按扩展名加载 YAML 布局，解码结果与等价 JSON 布局一致
+func TestLoadLayoutsYAML(t *testing.T) {
+	ls, err := LoadLayouts("testdata/actuator.yaml")
+	if err != nil {
+		t.Fatalf("加载 YAML 布局失败: %v", err)
+	}
+	if ls.Version != "test-yaml" {
+		t.Errorf("版本不符: %s", ls.Version)
+	}
+
+	data, err := ls.Decode(CompActuator, []byte{0x9C, 0xFF, 0xE3}, 0)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	act := data.(*model.ActuatorMetrics)
+	if act.WheelSpeedX != -100 || act.WheelSpeedY != -2 || act.WheelSpeedZ != 30 {
+		t.Errorf("解码不符: %+v", act)
+	}
+
+	if _, err := ParseLayoutsYAML([]byte("layouts:\n  - component: 99\n")); err == nil {
+		t.Error("未知组件: 期望校验失败")
+	}
+}
+
+// TestInvalidLayouts 测试非法布局在加载时被拒绝
+func TestInvalidLayouts(t *testing.T) {
+	cases := map[string]string{
+		"未知字段": `{"layouts":[{"component":3,"fields":[{"name":"NoSuchField","offset":0,"width":2}]}]}`,
+		"非法宽度": `{"layouts":[{"component":3,"fields":[{"name":"BusVoltage","offset":0,"width":3}]}]}`,
+		"未知组件": `{"layouts":[{"component":99,"fields":[]}]}`,
+		"数组越界": `{"layouts":[{"component":6,"fields":[{"name":"ThermalTemps","offset":0,"width":2,"count":11}]}]}`,
+		"掩码越界": `{"layouts":[{"component":6,"fields":[{"name":"TankHeaterSwitch","offset":0,"width":1,"mask":256}]}]}`,
+	}
+	for name, js := range cases {
+		if _, err := ParseLayouts([]byte(js)); err == nil {
+			t.Errorf("%s: 期望校验失败", name)
+		}
+	}
+}
+
+// TestCalibrationCurves 测试四种标定曲线
+func TestCalibrationCurves(t *testing.T) {
+	def := -1.0
+	cases := []struct {
+		name string
+		cal  Calibration
+		raw  float64
+		want float64
+	}{
+		{"linear", Calibration{Type: "linear", Gain: 0.001, Offset: 0.5}, 25000, 25.5},
+		{"polynomial", Calibration{Type: "polynomial", Coefficients: []float64{1, 2, 3}}, 2, 17},
+		{"piecewise-mid", Calibration{Type: "piecewise", Points: []CalPoint{{100, 50}, {0, -50}, {200, 150}}}, 150, 100},
+		{"piecewise-clamp", Calibration{Type: "piecewise", Points: []CalPoint{{0, -50}, {100, 50}}}, 300, 50},
+		{"piecewise-extrapolate", Calibration{Type: "piecewise", Points: []CalPoint{{0, -50}, {100, 50}}, Extrapolate: true}, -20, -70},
+		{"lookup-hit", Calibration{Type: "lookup", Points: []CalPoint{{0x55, 1}, {0xAA, 0}}}, 0xAA, 0},
+		{"lookup-default", Calibration{Type: "lookup", Points: []CalPoint{{0x55, 1}}, Default: &def}, 3, -1},
+	}
+	for _, tc := range cases {
+		if err := tc.cal.Validate(); err != nil {
+			t.Fatalf("%s: 校验失败: %v", tc.name, err)
+		}
+		got, err := tc.cal.Apply(tc.raw)
+		if err != nil || got < tc.want-1e-9 || got > tc.want+1e-9 {
+			t.Errorf("%s: Apply(%v) = %v, %v; want %v", tc.name, tc.raw, got, err, tc.want)
+		}
+	}
+
+	miss := Calibration{Type: "lookup", Points: []CalPoint{{1, 1}}}
+	_ = miss.Validate()
+	if _, err := miss.Apply(2); err == nil {
+		t.Error("lookup 未命中且无 default 时应返回错误")
+	}
+
+	for _, bad := range []Calibration{
+		{Type: "linear"},
+		{Type: "polynomial"},
+		{Type: "piecewise", Points: []CalPoint{{1, 1}}},
+		{Type: "piecewise", Points: []CalPoint{{1, 1}, {1, 2}}},
+		{Type: "lookup", Points: []CalPoint{{1, 1}, {1, 2}}},
+		{Type: "spline"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("标定 %+v 应校验失败", bad)
+		}
+	}
+}
+
+// TestLayoutCalibrationKeepsRaw 测试布局挂接标定曲线并保留原始值
+func TestLayoutCalibrationKeepsRaw(t *testing.T) {
+	ls, err := ParseLayouts([]byte(`{
+		"version": "test",
+		"calibrations": {
+			"thermistor": {"type": "piecewise", "points": [{"raw": 0, "eng": -40}, {"raw": 4095, "eng": 85}]}
+		},
+		"layouts": [{
+			"component": 6,
+			"name": "Thermal",
+			"fields": [
+				{"name": "ThermalTemps", "offset": 0, "width": 2, "count": 2, "calibration_ref": "thermistor"},
+				{"name": "BatteryTemp1", "offset": 4, "width": 2, "calibration": {"type": "linear", "gain": 0.1, "offset": -273.15}}
+			]
+		}]
+	}`))
+	if err != nil {
+		t.Fatalf("解析布局失败: %v", err)
+	}
+
+	payload := make([]byte, 6)
+	binary.BigEndian.PutUint16(payload[0:2], 0)
+	binary.BigEndian.PutUint16(payload[2:4], 4095)
+	binary.BigEndian.PutUint16(payload[4:6], 2982)
+
+	data, raw, err := ls.DecodeWithRaw(CompThermal, payload, 0)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	tm := data.(*model.ThermalMetrics)
+	if tm.ThermalTemps[0] != -40 || tm.ThermalTemps[1] != 85 {
+		t.Errorf("插值标定不符: %v", tm.ThermalTemps)
+	}
+	if tm.BatteryTemp1 < 25.04 || tm.BatteryTemp1 > 25.06 {
+		t.Errorf("线性标定不符: %v", tm.BatteryTemp1)
+	}
+	if raw["ThermalTemps[1]"] != 4095 || raw["BatteryTemp1"] != 2982 {
+		t.Errorf("原始值未保留: %v", raw)
+	}
+
+	if _, err := ParseLayouts([]byte(`{"layouts": [{"component": 6, "fields": [
+		{"name": "BatteryTemp1", "offset": 0, "width": 2, "scale": 0.1, "calibration": {"type": "linear", "gain": 1}}
+	]}]}`)); err == nil {
+		t.Error("scale 与标定曲线同时配置应校验失败")
+	}
+	if _, err := ParseLayouts([]byte(`{"layouts": [{"component": 6, "fields": [
+		{"name": "BatteryTemp1", "offset": 0, "width": 2, "calibration_ref": "missing"}
+	]}]}`)); err == nil {
+		t.Error("引用未定义的标定曲线应校验失败")
+	}
+}
@@ -0,0 +1,483 @@
+/*
+业务报文网络监听
+
+共性服务进程无需链接本模块的 Go 代码，直接通过 socket 推送报文：
+1. UDP       : 每个数据报一帧
+2. TCP       : 长度前缀流，每帧前加 4 字节 Big Endian 长度
+3. unixgram  : Unix 数据报套接字，每个数据报一帧
+
+每个监听器可配置：
+- 对端白名单（IP/CIDR；unixgram 为对端套接字路径），为空表示不限制
+- 单帧最大字节数，超过的报文直接丢弃（TCP 连接因无法重新对齐而断开）
+
+监听地址写法（monitor -listen 参数，多个用逗号分隔，可带查询参数单独配置）：
+	udp://0.0.0.0:9000
+	tcp://:9001?allow=10.0.0.0/8|127.0.0.1&max=2048
+	udp://:9002?format=ccsds（CCSDS 空间包，需配置 APID 映射）
+	unixgram:///var/run/health-monitor/business.sock
+*/
+package business
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultMaxPacketSize 默认单帧上限：帧头 + 最大负载 + CRC
+const DefaultMaxPacketSize = FrameHeaderSize + MaxFramePayload + FrameTrailerSize
+
+// 报文格式
+const (
+	FormatFrame = "frame"
+	FormatCCSDS = "ccsds"
+)
+
+// 支持的网络类型
+const (
+	NetworkUDP      = "udp"
+	NetworkTCP      = "tcp"
+	NetworkUnixgram = "unixgram"
+)
+
+// ListenerConfig 监听器配置
+type ListenerConfig struct {
+	Network       string   // udp / tcp / unixgram
+	Address       string   // host:port 或 socket 路径
+	AllowPeers    []string // 对端白名单：IP、CIDR 或 unix 套接字路径，为空不限制
+	MaxPacketSize int      // 单帧最大字节数，<=0 使用 DefaultMaxPacketSize
+	Format        string   // 报文格式：frame（默认，见 frame.go）/ ccsds（空间包）
+}
+
+// ListenerStats 监听器统计
+type ListenerStats struct {
+	Packets  uint64 `json:"packets"`  // 提交到 Receiver 的报文数
+	Bytes    uint64 `json:"bytes"`    // 提交的字节数
+	Denied   uint64 `json:"denied"`   // 白名单拒绝的报文/连接数
+	Oversize uint64 `json:"oversize"` // 超过最大长度被丢弃的报文数
+	Errors   uint64 `json:"errors"`   // 读取或提交错误
+}
+
+// Listener 业务报文网络监听器
+type Listener struct {
+	cfg      ListenerConfig
+	receiver *Receiver
+	allow    *peerFilter
+
+	packetConn net.PacketConn // udp / unixgram
+	streamLn   net.Listener   // tcp
+
+	conns   map[net.Conn]struct{}
+	connsMu sync.Mutex
+
+	packets  atomic.Uint64
+	bytes    atomic.Uint64
+	denied   atomic.Uint64
+	oversize atomic.Uint64
+	errs     atomic.Uint64
+
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewListener 创建监听器（校验配置，不绑定端口）
+func NewListener(cfg ListenerConfig, receiver *Receiver) (*Listener, error) {
+	switch cfg.Network {
+	case NetworkUDP, NetworkTCP, NetworkUnixgram:
+	default:
+		return nil, fmt.Errorf("不支持的网络类型 %q", cfg.Network)
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("%s 监听地址为空", cfg.Network)
+	}
+	if cfg.MaxPacketSize <= 0 {
+		cfg.MaxPacketSize = DefaultMaxPacketSize
+	}
+	switch cfg.Format {
+	case "":
+		cfg.Format = FormatFrame
+	case FormatFrame, FormatCCSDS:
+	default:
+		return nil, fmt.Errorf("不支持的报文格式 %q", cfg.Format)
+	}
+	if cfg.MaxPacketSize < FrameHeaderSize+FrameTrailerSize {
+		return nil, fmt.Errorf("最大报文长度 %d 小于帧头+CRC", cfg.MaxPacketSize)
+	}
+
+	allow, err := newPeerFilter(cfg.Network, cfg.AllowPeers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Listener{
+		cfg:      cfg,
+		receiver: receiver,
+		allow:    allow,
+		conns:    make(map[net.Conn]struct{}),
+		closed:   make(chan struct{}),
+	}, nil
+}
+
+// Start 绑定地址并开始接收，ctx 取消时自动关闭
+func (l *Listener) Start(ctx context.Context) error {
+	switch l.cfg.Network {
+	case NetworkUDP:
+		conn, err := net.ListenPacket("udp", l.cfg.Address)
+		if err != nil {
+			return fmt.Errorf("监听 udp %s 失败: %w", l.cfg.Address, err)
+		}
+		l.packetConn = conn
+
+	case NetworkUnixgram:
+		removeStaleSocket(l.cfg.Address)
+		conn, err := net.ListenPacket("unixgram", l.cfg.Address)
+		if err != nil {
+			return fmt.Errorf("监听 unixgram %s 失败: %w", l.cfg.Address, err)
+		}
+		l.packetConn = conn
+
+	case NetworkTCP:
+		ln, err := net.Listen("tcp", l.cfg.Address)
+		if err != nil {
+			return fmt.Errorf("监听 tcp %s 失败: %w", l.cfg.Address, err)
+		}
+		l.streamLn = ln
+	}
+
+	if l.packetConn != nil {
+		l.wg.Add(1)
+		go l.servePackets()
+	} else {
+		l.wg.Add(1)
+		go l.serveStream()
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.Close()
+		case <-l.closed:
+		}
+	}()
+
+	fmt.Printf("[业务层] %s 监听已启动: %s\n", l.cfg.Network, l.Addr())
+	return nil
+}
+
+// Addr 返回实际监听地址（端口为0时可获取系统分配的端口）
+func (l *Listener) Addr() net.Addr {
+	if l.packetConn != nil {
+		return l.packetConn.LocalAddr()
+	}
+	if l.streamLn != nil {
+		return l.streamLn.Addr()
+	}
+	return nil
+}
+
+// Close 停止监听并断开所有连接
+func (l *Listener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		if l.packetConn != nil {
+			err = l.packetConn.Close()
+		}
+		if l.streamLn != nil {
+			err = l.streamLn.Close()
+		}
+
+		l.connsMu.Lock()
+		for c := range l.conns {
+			c.Close()
+		}
+		l.connsMu.Unlock()
+
+		l.wg.Wait()
+		if l.cfg.Network == NetworkUnixgram {
+			os.Remove(l.cfg.Address)
+		}
+	})
+	return err
+}
+
+// Stats 返回监听器统计
+func (l *Listener) Stats() ListenerStats {
+	return ListenerStats{
+		Packets:  l.packets.Load(),
+		Bytes:    l.bytes.Load(),
+		Denied:   l.denied.Load(),
+		Oversize: l.oversize.Load(),
+		Errors:   l.errs.Load(),
+	}
+}
+
+// Config 返回监听器配置
+func (l *Listener) Config() ListenerConfig {
+	return l.cfg
+}
+
+// servePackets 数据报接收循环（udp / unixgram）
+func (l *Listener) servePackets() {
+	defer l.wg.Done()
+
+	// 多读1字节用于识别超长报文（数据报超出缓冲区的部分会被内核截断）
+	buf := make([]byte, l.cfg.MaxPacketSize+1)
+	for {
+		n, peer, err := l.packetConn.ReadFrom(buf)
+		if err != nil {
+			if l.isClosed() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			l.errs.Add(1)
+			continue
+		}
+
+		if !l.allow.allowed(peer) {
+			l.denied.Add(1)
+			continue
+		}
+		if n > l.cfg.MaxPacketSize {
+			l.oversize.Add(1)
+			continue
+		}
+
+		packet := make([]byte, n)
+		copy(packet, buf[:n])
+		l.submit(packet)
+	}
+}
+
+// serveStream TCP 连接接收循环
+func (l *Listener) serveStream() {
+	defer l.wg.Done()
+
+	for {
+		conn, err := l.streamLn.Accept()
+		if err != nil {
+			if l.isClosed() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			l.errs.Add(1)
+			continue
+		}
+
+		if !l.allow.allowed(conn.RemoteAddr()) {
+			l.denied.Add(1)
+			fmt.Printf("[业务层] 拒绝未授权连接: %s\n", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+
+		l.connsMu.Lock()
+		if l.isClosed() {
+			l.connsMu.Unlock()
+			conn.Close()
+			return
+		}
+		l.conns[conn] = struct{}{}
+		l.connsMu.Unlock()
+
+		l.wg.Add(1)
+		go l.serveConn(conn)
+	}
+}
+
+// serveConn 读取长度前缀帧：4 字节 Big Endian 长度 + 帧数据
+func (l *Listener) serveConn(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.connsMu.Lock()
+		delete(l.conns, conn)
+		l.connsMu.Unlock()
+		conn.Close()
+	}()
+
+	var lenBuf [4]byte
+	for {
+		if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+			if err != io.EOF && !l.isClosed() {
+				l.errs.Add(1)
+			}
+			return
+		}
+
+		size := binary.BigEndian.Uint32(lenBuf[:])
+		if size > uint32(l.cfg.MaxPacketSize) {
+			// 长度字段不可信，无法在流中重新对齐，断开连接
+			l.oversize.Add(1)
+			fmt.Printf("[业务层] %s 报文长度 %d 超过上限 %d，断开连接\n", conn.RemoteAddr(), size, l.cfg.MaxPacketSize)
+			return
+		}
+
+		packet := make([]byte, size)
+		if _, err := io.ReadFull(conn, packet); err != nil {
+			if !l.isClosed() {
+				l.errs.Add(1)
+			}
+			return
+		}
+		l.submit(packet)
+	}
+}
+
+func (l *Listener) submit(packet []byte) {
+	submit := l.receiver.Submit
+	if l.cfg.Format == FormatCCSDS {
+		submit = l.receiver.SubmitSpacePacket
+	}
+	if err := submit(packet); err != nil {
+		l.errs.Add(1)
+		return
+	}
+	l.packets.Add(1)
+	l.bytes.Add(uint64(len(packet)))
+}
+
+func (l *Listener) isClosed() bool {
+	select {
+	case <-l.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// removeStaleSocket 删除上次异常退出残留的 socket 文件（仅删除 socket 类型）
+func removeStaleSocket(path string) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              对端白名单
+////////////////////////////////////////////////////////////////////////////////
+
+// peerFilter 对端白名单，nil 表示不限制
+type peerFilter struct {
+	nets  []*net.IPNet
+	paths map[string]bool
+}
+
+func newPeerFilter(network string, peers []string) (*peerFilter, error) {
+	if len(peers) == 0 {
+		return nil, nil
+	}
+
+	f := &peerFilter{paths: make(map[string]bool)}
+	for _, p := range peers {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		if network == NetworkUnixgram {
+			f.paths[p] = true
+			continue
+		}
+
+		if _, ipnet, err := net.ParseCIDR(p); err == nil {
+			f.nets = append(f.nets, ipnet)
+			continue
+		}
+		ip := net.ParseIP(p)
+		if ip == nil {
+			return nil, fmt.Errorf("白名单项 %q 不是合法的 IP 或 CIDR", p)
+		}
+		bits := 128
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		f.nets = append(f.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return f, nil
+}
+
+func (f *peerFilter) allowed(addr net.Addr) bool {
+	if f == nil {
+		return true
+	}
+
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UnixAddr:
+		// 未绑定路径的发送端地址为空，白名单模式下一律拒绝
+		return a != nil && f.paths[a.Name]
+	default:
+		return false
+	}
+
+	for _, n := range f.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              监听地址解析
+////////////////////////////////////////////////////////////////////////////////
+
+// ParseListenerSpec 解析监听地址，如 udp://0.0.0.0:9000?allow=10.0.0.1|10.1.0.0/16&max=2048
+// 查询参数中的 allow / max 覆盖 defaults 中的对应项；
+// defaults.AllowPeers 中以 "/" 开头的项只用于 unixgram，其余只用于 udp/tcp
+func ParseListenerSpec(spec string, defaults ListenerConfig) (ListenerConfig, error) {
+	cfg := defaults
+	cfg.AllowPeers = nil
+
+	u, err := url.Parse(strings.TrimSpace(spec))
+	if err != nil {
+		return cfg, fmt.Errorf("监听地址 %q 格式错误: %w", spec, err)
+	}
+
+	cfg.Network = u.Scheme
+	switch u.Scheme {
+	case NetworkUDP, NetworkTCP:
+		cfg.Address = u.Host
+	case NetworkUnixgram:
+		cfg.Address = u.Path
+	default:
+		return cfg, fmt.Errorf("监听地址 %q: 不支持的协议 %q（支持 udp/tcp/unixgram）", spec, u.Scheme)
+	}
+	if cfg.Address == "" {
+		return cfg, fmt.Errorf("监听地址 %q 缺少地址", spec)
+	}
+
+	for _, p := range defaults.AllowPeers {
+		if strings.HasPrefix(p, "/") == (cfg.Network == NetworkUnixgram) {
+			cfg.AllowPeers = append(cfg.AllowPeers, p)
+		}
+	}
+
+	q := u.Query()
+	if v := q.Get("allow"); v != "" {
+		cfg.AllowPeers = strings.Split(v, "|")
+	}
+	if v := q.Get("max"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("监听地址 %q: max=%q 非法", spec, v)
+		}
+		cfg.MaxPacketSize = n
+	}
+	if v := q.Get("format"); v != "" {
+		cfg.Format = v
+	}
+	return cfg, nil
+}
@@ -0,0 +1,190 @@
+package business
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitPacket 从 Receiver 输入队列取出一个报文
+func waitPacket(t *testing.T, r *Receiver) []byte {
+	t.Helper()
+	select {
+	case p := <-r.inputChan:
+		return p
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待报文超时")
+		return nil
+	}
+}
+
+// waitStats 等待监听器统计满足条件
+func waitStats(t *testing.T, l *Listener, ok func(ListenerStats) bool) ListenerStats {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if s := l.Stats(); ok(s) {
+			return s
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("监听器统计不符: %+v", l.Stats())
+	return ListenerStats{}
+}
+
+func startListener(t *testing.T, cfg ListenerConfig, r *Receiver) *Listener {
+	t.Helper()
+	l, err := NewListener(cfg, r)
+	if err != nil {
+		t.Fatalf("创建监听器失败: %v", err)
+	}
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatalf("启动监听器失败: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// TestUDPListener 测试 UDP 接收、白名单与最大长度
+func TestUDPListener(t *testing.T) {
+	r := NewReceiver(NewDispatcher(nil))
+	frame := EncodeFrame(CompPower, buildPowerPayload())
+
+	l := startListener(t, ListenerConfig{
+		Network:       NetworkUDP,
+		Address:       "127.0.0.1:0",
+		AllowPeers:    []string{"127.0.0.0/8"},
+		MaxPacketSize: len(frame),
+	}, r)
+
+	conn, err := net.Dial("udp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write(frame)
+	if got := waitPacket(t, r); string(got) != string(frame) {
+		t.Errorf("收到的报文不符")
+	}
+
+	conn.Write(append(frame, 0x00))
+	waitStats(t, l, func(s ListenerStats) bool { return s.Oversize == 1 })
+
+	denied := startListener(t, ListenerConfig{
+		Network:    NetworkUDP,
+		Address:    "127.0.0.1:0",
+		AllowPeers: []string{"10.0.0.1"},
+	}, r)
+	conn2, err := net.Dial("udp", denied.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+	conn2.Write(frame)
+	waitStats(t, denied, func(s ListenerStats) bool { return s.Denied == 1 && s.Packets == 0 })
+}
+
+// TestTCPListener 测试长度前缀 TCP 流
+func TestTCPListener(t *testing.T) {
+	r := NewReceiver(NewDispatcher(nil))
+	frame := EncodeFrame(CompPower, buildPowerPayload())
+
+	l := startListener(t, ListenerConfig{
+		Network:       NetworkTCP,
+		Address:       "127.0.0.1:0",
+		MaxPacketSize: 64,
+	}, r)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var stream []byte
+	for i := 0; i < 2; i++ {
+		stream = binary.BigEndian.AppendUint32(stream, uint32(len(frame)))
+		stream = append(stream, frame...)
+	}
+	// 分两次写入，验证跨段组帧
+	conn.Write(stream[:7])
+	time.Sleep(10 * time.Millisecond)
+	conn.Write(stream[7:])
+
+	for i := 0; i < 2; i++ {
+		if got := waitPacket(t, r); string(got) != string(frame) {
+			t.Errorf("第 %d 帧不符", i+1)
+		}
+	}
+
+	// 超长长度字段：断开连接
+	conn.Write(binary.BigEndian.AppendUint32(nil, 1000))
+	waitStats(t, l, func(s ListenerStats) bool { return s.Oversize == 1 })
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("超长报文后连接应被断开")
+	}
+}
+
+// TestUnixgramListener 测试 Unix 数据报套接字
+func TestUnixgramListener(t *testing.T) {
+	r := NewReceiver(NewDispatcher(nil))
+	frame := EncodeFrame(CompPower, buildPowerPayload())
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "business.sock")
+	client := filepath.Join(dir, "client.sock")
+
+	startListener(t, ListenerConfig{
+		Network:    NetworkUnixgram,
+		Address:    path,
+		AllowPeers: []string{client},
+	}, r)
+
+	conn, err := net.DialUnix("unixgram",
+		&net.UnixAddr{Name: client, Net: "unixgram"},
+		&net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write(frame)
+	if got := waitPacket(t, r); string(got) != string(frame) {
+		t.Errorf("收到的报文不符")
+	}
+}
+
+// TestParseListenerSpec 测试监听地址解析
+func TestParseListenerSpec(t *testing.T) {
+	defaults := ListenerConfig{AllowPeers: []string{"127.0.0.1", "/run/sender.sock"}, MaxPacketSize: 512}
+
+	cfg, err := ParseListenerSpec("tcp://:9001?allow=10.0.0.0/8|192.168.1.5&max=2048", defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Network != NetworkTCP || cfg.Address != ":9001" || len(cfg.AllowPeers) != 2 || cfg.MaxPacketSize != 2048 {
+		t.Errorf("解析结果不符: %+v", cfg)
+	}
+
+	cfg, err = ParseListenerSpec("unixgram:///tmp/hm.sock", defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Address != "/tmp/hm.sock" || cfg.MaxPacketSize != 512 || len(cfg.AllowPeers) != 1 || cfg.AllowPeers[0] != "/run/sender.sock" {
+		t.Errorf("默认值未生效: %+v", cfg)
+	}
+
+	for _, bad := range []string{"http://:80", "udp://", "udp://:9000?max=abc"} {
+		if _, err := ParseListenerSpec(bad, defaults); err == nil {
+			t.Errorf("%q 应解析失败", bad)
+		}
+	}
+	if _, err := NewListener(ListenerConfig{Network: NetworkUDP, Address: ":0", AllowPeers: []string{"not-an-ip"}}, nil); err == nil {
+		t.Error("非法白名单应创建失败")
+	}
+}
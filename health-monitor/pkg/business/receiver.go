@@ -1,10 +1,12 @@
 /*
 提供共性服务提交指标的接口
 当有输入时才启动
-报文格式
+报文格式见 frame.go（同步字 + 版本 + 组件类型 + 长度 + 负载 + CRC）
 报文格式存在几个问题
 1.有些指标给我们的文件中没有明确是属于那个服务的，我们先按照自己的理解分
 2.给我们的是原始数据还是处理过的数据，如果是原始数据，我们还需要知道处理方法，暂时按照处理过的数据设计
+  （各字段的偏移、宽度、缩放等由 layout.go 中的声明式布局描述，默认布局见 layouts/default.json；
+   原始量可在布局中挂接标定曲线转换为工程值，见 calibration.go，原始值保留在 BusinessMetrics.Raw）
 3.有些指标没有范围暂时未编入
 解析完数据后，交由alert/threshold判断
 */
@@ -12,9 +14,8 @@ package business
 
 import (
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"health-monitor/pkg/models"
@@ -25,14 +26,135 @@ type Receiver struct {
 	dispatcher *Dispatcher
 	inputChan  chan []byte
 	stopChan   chan struct{}
+
+	// 帧质量统计
+	counters *FrameCounters
+
+	// 字节流解帧（Write 接口使用）
+	stream   *StreamDecoder
+	streamMu sync.Mutex
+
+	// 报文布局（可运行时替换）
+	layouts   *LayoutSet
+	layoutsMu sync.RWMutex
+
+	// CCSDS 空间包接入（未配置时为 nil）
+	spaceChan chan []byte
+	ccsds     *CCSDSDecoder
+	ccsdsMu   sync.RWMutex
+
+	// 星上时间码解码（未配置时使用接收时间）
+	timeCode   *TimeCodeConfig
+	timeCodeMu sync.RWMutex
+
+	// 序列计数跟踪（链路质量）
+	sequences *SequenceTracker
+
+	// 原始报文抓包（未启用时为 nil）
+	capture   *CaptureWriter
+	captureMu sync.RWMutex
 }
 
 func NewReceiver(dispatcher *Dispatcher) *Receiver {
-	return &Receiver{
+	r := &Receiver{
 		dispatcher: dispatcher,
 		inputChan:  make(chan []byte, 100),
+		spaceChan:  make(chan []byte, 100),
 		stopChan:   make(chan struct{}),
+		counters:   NewFrameCounters(),
+		stream:     NewStreamDecoder(),
+		layouts:    DefaultLayouts(),
+		sequences:  NewSequenceTracker(SequenceConfig{}),
+	}
+	if dispatcher != nil && dispatcher.stateManager != nil {
+		dispatcher.stateManager.RegisterStatsProvider("link_quality", func() interface{} {
+			return r.LinkQuality()
+		})
 	}
+	return r
+}
+
+// SetLayouts 替换报文布局（布局需已通过校验，如 LoadLayouts 的返回值）
+func (r *Receiver) SetLayouts(ls *LayoutSet) {
+	r.layoutsMu.Lock()
+	defer r.layoutsMu.Unlock()
+	r.layouts = ls
+}
+
+// Layouts 返回当前使用的报文布局
+func (r *Receiver) Layouts() *LayoutSet {
+	r.layoutsMu.RLock()
+	defer r.layoutsMu.RUnlock()
+	return r.layouts
+}
+
+// SetTimeCode 设置星上时间码解码配置（cfg 需已通过校验）
+func (r *Receiver) SetTimeCode(cfg *TimeCodeConfig) {
+	r.timeCodeMu.Lock()
+	defer r.timeCodeMu.Unlock()
+	r.timeCode = cfg
+}
+
+// TimeCode 返回当前的时间码配置，未配置时为 nil
+func (r *Receiver) TimeCode() *TimeCodeConfig {
+	r.timeCodeMu.RLock()
+	defer r.timeCodeMu.RUnlock()
+	return r.timeCode
+}
+
+// SetCCSDS 启用 CCSDS 空间包接入（APID 映射见 CCSDSConfig）
+func (r *Receiver) SetCCSDS(dec *CCSDSDecoder) {
+	if dec != nil {
+		dec.SetPacketObserver(func(pkt *SpacePacket) {
+			r.sequences.Observe(pkt.Component, uint32(pkt.SeqCount), CCSDSMaxSeqCount+1)
+		})
+	}
+
+	r.ccsdsMu.Lock()
+	defer r.ccsdsMu.Unlock()
+	r.ccsds = dec
+}
+
+// CCSDS 返回当前的空间包解码器，未启用时为 nil
+func (r *Receiver) CCSDS() *CCSDSDecoder {
+	r.ccsdsMu.RLock()
+	defer r.ccsdsMu.RUnlock()
+	return r.ccsds
+}
+
+// SetCapture 启用原始报文抓包，传入 nil 关闭（不会关闭原写入器）
+func (r *Receiver) SetCapture(w *CaptureWriter) {
+	r.captureMu.Lock()
+	defer r.captureMu.Unlock()
+	r.capture = w
+}
+
+// Capture 返回当前的抓包写入器，未启用时为 nil
+func (r *Receiver) Capture() *CaptureWriter {
+	r.captureMu.RLock()
+	defer r.captureMu.RUnlock()
+	return r.capture
+}
+
+// record 记录原始报文（在任何校验之前），写入失败只打印日志，不影响接收
+func (r *Receiver) record(kind uint8, data []byte) {
+	w := r.Capture()
+	if w == nil {
+		return
+	}
+	if err := w.Write(kind, time.Now(), data); err != nil {
+		fmt.Println("[业务层] 抓包写入失败:", err)
+	}
+}
+
+// SetSequenceConfig 设置序列计数评估窗口与丢包率告警阈值
+func (r *Receiver) SetSequenceConfig(cfg SequenceConfig) {
+	r.sequences.SetConfig(cfg)
+}
+
+// LinkQuality 返回按组件统计的链路质量（序列计数）
+func (r *Receiver) LinkQuality() []model.LinkQuality {
+	return r.sequences.Stats()
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -40,14 +162,76 @@ func NewReceiver(dispatcher *Dispatcher) *Receiver {
 ////////////////////////////////////////////////////////////////////////////////
 
 // 只有收到了业务层输入才会触发解析与分发
+// data 应为一个完整的帧（数据报方式）；字节流请使用 Write
 func (r *Receiver) Submit(data []byte) error {
-	if len(data) < 3 { // 至少需要：类型 + 长度字段
-		return errors.New("invalid business packet")
+	r.record(CaptureFrame, data)
+	if len(data) < FrameHeaderSize+FrameTrailerSize { // 至少需要：帧头 + CRC
+		err := headerTruncated(data, "packet shorter than frame header")
+		r.counters.RecordError(err)
+		return err
 	}
 	r.inputChan <- data
 	return nil
 }
 
+// SubmitSpacePacket 提交一个 CCSDS 空间包（分段包逐段提交，由接收端重组）
+func (r *Receiver) SubmitSpacePacket(data []byte) error {
+	if r.CCSDS() == nil {
+		return fmt.Errorf("ccsds ingestion not configured")
+	}
+	r.record(CaptureSpacePacket, data)
+	if len(data) < CCSDSPrimaryHeaderSize {
+		err := &FrameError{Kind: ErrFrameTruncated, NoComponent: true, Detail: "packet shorter than ccsds primary header"}
+		r.counters.RecordError(err)
+		return err
+	}
+	r.spaceChan <- data
+	return nil
+}
+
+// Write 以字节流方式提交数据（实现 io.Writer）
+// 数据可以是任意切分的片段，内部按同步字重新组帧，损坏的字节会被丢弃
+func (r *Receiver) Write(p []byte) (int, error) {
+	r.record(CaptureStream, p)
+	for _, raw := range r.deframe(p) {
+		r.inputChan <- raw
+	}
+	return len(p), nil
+}
+
+// deframe 将字节流片段送入解帧器，返回已切分出的完整帧
+func (r *Receiver) deframe(p []byte) [][]byte {
+	r.streamMu.Lock()
+	defer r.streamMu.Unlock()
+
+	var frames [][]byte
+	r.stream.Feed(p)
+	for {
+		frame, skipped, err := r.stream.Next()
+		r.counters.RecordSkipped(skipped)
+		if err != nil {
+			r.counters.RecordError(err)
+			fmt.Println("[业务层] 丢弃损坏帧:", err)
+			continue
+		}
+		if frame == nil {
+			break
+		}
+		frames = append(frames, frame.Raw)
+	}
+	return frames
+}
+
+// FrameStats 返回按组件统计的帧质量计数
+func (r *Receiver) FrameStats() map[uint8]FrameStats {
+	return r.counters.Snapshot()
+}
+
+// Counters 返回帧质量统计对象
+func (r *Receiver) Counters() *FrameCounters {
+	return r.counters
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 //                           监听器启动/停止
 ////////////////////////////////////////////////////////////////////////////////
@@ -63,19 +247,49 @@ func (r *Receiver) Start(ctx context.Context) {
 				return
 
 			case packet := <-r.inputChan:
-				metrics, err := r.ParsePacket(packet)
-				if err != nil {
-					fmt.Println("[业务层] 报文解析失败:", err)
-					continue
-				}
-
-				// 分发到业务层的 dispatcher
-				r.dispatcher.HandleBusinessMetrics(ctx, metrics)
+				r.handlePacket(ctx, packet)
+
+			case packet := <-r.spaceChan:
+				r.handleSpacePacket(ctx, packet)
 			}
 		}
 	}()
 }
 
+// handlePacket 解析一帧并分发到业务层的 dispatcher
+func (r *Receiver) handlePacket(ctx context.Context, packet []byte) {
+	metrics, err := r.ParsePacket(packet)
+	if err != nil {
+		fmt.Println("[业务层] 报文解析失败:", err)
+		return
+	}
+
+	r.dispatcher.HandleBusinessMetrics(ctx, metrics)
+	r.checkLinkQuality(ctx, metrics.ComponentType)
+}
+
+// handleSpacePacket 解析一个空间包（或分段）并分发
+func (r *Receiver) handleSpacePacket(ctx context.Context, packet []byte) {
+	metrics, err := r.ParseSpacePacket(packet)
+	if err != nil {
+		fmt.Println("[业务层] 空间包解析失败:", err)
+		return
+	}
+	if metrics == nil {
+		// 分段未收齐或空闲包
+		return
+	}
+	r.dispatcher.HandleBusinessMetrics(ctx, metrics)
+	r.checkLinkQuality(ctx, metrics.ComponentType)
+}
+
+// checkLinkQuality 组件完成一个评估窗口时，将链路质量交给 dispatcher 判断告警
+func (r *Receiver) checkLinkQuality(ctx context.Context, component uint8) {
+	if lq, ok := r.sequences.TakeEvaluation(component); ok {
+		r.dispatcher.HandleLinkQuality(ctx, lq)
+	}
+}
+
 func (r *Receiver) Stop() {
 	close(r.stopChan)
 }
@@ -102,310 +316,94 @@ const (
 	CompEPS          = 0x0E // 电源
 )
 
-// 解析业务层报文
+// 解析业务层报文（完整帧）
+// 失败时返回 *FrameError，可用 errors.Is 判断 ErrFrameCRC / ErrFrameTruncated / ErrUnknownComponent 等
 func (r *Receiver) ParsePacket(packet []byte) (*model.BusinessMetrics, error) {
-
-	component := packet[0]
-	length := binary.BigEndian.Uint16(packet[1:3])
-
-	if int(length) > len(packet)-3 {
-		return nil, errors.New("length mismatch in business packet")
+	frame, err := DecodeFrame(packet)
+	if err != nil {
+		r.counters.RecordError(err)
+		return nil, err
 	}
 
-	payload := packet[3 : 3+length]
-
-	out := &model.BusinessMetrics{
-		ComponentType: component,
-		Timestamp:     time.Now().Unix(),
-		Data:          make(map[string]interface{}),
+	// 序列计数在负载解析之前统计，负载解析失败的帧也说明链路上收到了该报文
+	if frame.HasSeq {
+		r.sequences.Observe(frame.Component, uint32(frame.Seq), FrameSeqModulus)
 	}
 
-	switch component {
-
-	case CompRunMgr:
-		r.parseRunMgr(payload, out)
-
-	case CompComm:
-		r.parseComm(payload, out)
-
-	case CompPower:
-		r.parsePower(payload, out)
-
-	case CompRailCtrl:
-		r.parseRailCtrl(payload, out)
-
-	case CompPayload:
-		r.parsePayload(payload, out)
-
-	case CompThermal:
-		r.parseThermal(payload, out)
-
-	case CompAttCtrl:
-		r.parseAttCtrl(payload, out)
-
-	case CompMeasure:
-		r.parseMeasure(payload, out)
-
-	case CompOptical:
-		r.parseOptical(payload, out)
-
-	case CompSensor:
-		r.parseSensor(payload, out)
-
-	case CompActuator:
-		r.parseActuator(payload, out)
-
-	case CompTransceiver:
-		r.parseTransceiver(payload, out)
-
-	case CompThruster:
-		r.parseThruster(payload, out)
-
-	case CompEPS:
-		r.parseEPS(payload, out)
-
-	default:
-		return nil, fmt.Errorf("unknown business packet type: 0x%02X", component)
+	out, err := r.parseFrame(frame)
+	if err != nil {
+		r.counters.RecordError(err)
+		return nil, err
 	}
 
+	r.counters.RecordAccepted(frame.Component)
 	return out, nil
 }
 
-////////////////////////////////////////////////////////////////////////////////
-//                     3. 各组件的解析函数（可继续扩展）
-////////////////////////////////////////////////////////////////////////////////
-//运行管理
-func (r *Receiver) parseRunMgr(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 5 {
-		return
-	}
-	
-	metrics := &model.RunMgrMetrics{
-		Timestamp: time.Now().Unix(),
-		Temperature: float64(binary.BigEndian.Uint16(payload[0:2])) / 10.0,
-		Voltage: float64(binary.BigEndian.Uint16(payload[2:4])) / 1000.0,
-		StatusCode: int(payload[4]),
-		Payload: make(map[string]interface{}),
-	}
-	
-	out.Data = metrics
-}
-//通信服务:包含CAN、串口、空空通信状态,以及串口错误计数、命令接收计数
-func (r *Receiver) parseComm(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 16 {
-		return
-	}
-	
-	metrics := &model.CommMetrics{
-		Timestamp: time.Now().Unix(),
-		SNR: payload[0],
-		Rate: binary.BigEndian.Uint16(payload[1:3]),
-		CANStatus: payload[3],
-		SerialStatus: payload[4],
-		AirToAirStatus: payload[5],
-		// 串口错误计数
-		ParityErrorCount: binary.BigEndian.Uint16(payload[6:8]),
-		FrameHeaderErrorCount: binary.BigEndian.Uint16(payload[8:10]),
-		FrameLengthErrorCount: binary.BigEndian.Uint16(payload[10:12]),
-		SerialResetCount: binary.BigEndian.Uint16(payload[12:14]),
-		// 命令接收计数
-		ReceiveCmdCount: binary.BigEndian.Uint32(payload[14:18]),
-	}
-	
-	out.Data = metrics
-}
-//供电服务：包含电压类和电流类指标
-func (r *Receiver) parsePower(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 14 {
-		return
-	}
-	
-	metrics := &model.PowerMetrics{
-		Timestamp: time.Now().Unix(),
-	}
-	
-	// 解析所有电压和电流指标
-	metrics.PowerModule12V = float64(binary.BigEndian.Uint16(payload[0:2])) / 1000.0     // TMAN01046
-	metrics.BatteryVoltage = float64(binary.BigEndian.Uint16(payload[2:4])) / 1000.0     // TMEZD01095
-	metrics.BusVoltage = float64(binary.BigEndian.Uint16(payload[4:6])) / 1000.0         // TMEZD01096
-	metrics.CPUVoltage = float64(binary.BigEndian.Uint16(payload[6:8])) / 1000.0         // TMEZD01011
-	metrics.ThermalRefVoltage = float64(binary.BigEndian.Uint16(payload[8:10])) / 1000.0 // TMEZD01100
-	metrics.Bracket12VCurrent = float64(binary.BigEndian.Uint16(payload[10:12])) / 1000.0 // TMAN01050
-	metrics.LoadCurrent = float64(binary.BigEndian.Uint16(payload[12:14])) / 1000.0      // TMEZD01247
-	
-	out.Data = metrics
-}
-//轨道控制
-func (r *Receiver) parseRailCtrl(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 1 {
-		return
-	}
-	
-	metrics := &model.RailCtrlMetrics{
-		Timestamp: time.Now().Unix(),
-		OrbitMode: payload[0],
-	}
-	
-	out.Data = metrics
-}
-//载荷
-func (r *Receiver) parsePayload(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 1 {
-		return
-	}
-	
-	metrics := &model.PayloadMetrics{
-		Timestamp: time.Now().Unix(),
-		WorkMode: payload[0],
-	}
-	
-	out.Data = metrics
-}
-//热控服务：包含温度类和开关状态类指标
-func (r *Receiver) parseThermal(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 31 {
-		return
-	}
-	
-	metrics := &model.ThermalMetrics{
-		Timestamp: time.Now().Unix(),
-	}
-	
-	// 解析10个热控温度点（TMEZD01066-01075）
-	for i := 0; i < 10; i++ {
-		metrics.ThermalTemps[i] = float64(int16(binary.BigEndian.Uint16(payload[i*2:i*2+2]))) / 10.0
+// ParseSpacePacket 解析 CCSDS 空间包，按 APID 映射到组件布局
+// 分段包未收齐或为空闲包时返回 (nil, nil)
+func (r *Receiver) ParseSpacePacket(packet []byte) (*model.BusinessMetrics, error) {
+	dec := r.CCSDS()
+	if dec == nil {
+		return nil, fmt.Errorf("ccsds ingestion not configured")
 	}
-	
-	// 蓄电池温度（TMEZD01084、TMEZD01085）
-	metrics.BatteryTemp1 = float64(int16(binary.BigEndian.Uint16(payload[20:22]))) / 10.0
-	metrics.BatteryTemp2 = float64(int16(binary.BigEndian.Uint16(payload[22:24]))) / 10.0
-	
-	// 其他热控温度
-	metrics.PlatformThermalTemp = float64(int16(binary.BigEndian.Uint16(payload[24:26]))) / 10.0
-	metrics.BatteryThermalTemp = float64(int16(binary.BigEndian.Uint16(payload[26:28]))) / 10.0
-	metrics.TankThermalTemp = float64(int16(binary.BigEndian.Uint16(payload[28:30]))) / 10.0
-	
-	// 开关状态（TMEZD01121, TMEZD01254, TMEZD01115）
-	switchState := payload[30]
-	metrics.PlatformHeaterSwitch = (switchState & 0x01) != 0
-	metrics.BatteryHeaterSwitch = (switchState & 0x02) != 0
-	metrics.TankHeaterSwitch = (switchState & 0x04) != 0
-	
-	out.Data = metrics
-}
-//姿态控制
-func (r *Receiver) parseAttCtrl(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 1 {
-		return
-	}
-	
-	metrics := &model.AttCtrlMetrics{
-		Timestamp: time.Now().Unix(),
-		ControlMode: payload[0],
-	}
-	
-	out.Data = metrics
-}
-//测量
-func (r *Receiver) parseMeasure(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 4 {
-		return
-	}
-	
-	metrics := &model.MeasureMetrics{
-		Timestamp: time.Now().Unix(),
-		SensorValue: binary.BigEndian.Uint32(payload[0:4]),
-	}
-	
-	out.Data = metrics
-}
-//光电设备
-func (r *Receiver) parseOptical(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 2 {
-		return
-	}
-	
-	metrics := &model.OpticalMetrics{
-		Timestamp: time.Now().Unix(),
-		PhotoCurrent: binary.BigEndian.Uint16(payload[0:2]),
-	}
-	
-	out.Data = metrics
-}
-//敏感器
-func (r *Receiver) parseSensor(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 6 {
-		return
-	}
-	
-	metrics := &model.SensorMetrics{
-		Timestamp: time.Now().Unix(),
-		AccX: int16(binary.BigEndian.Uint16(payload[0:2])),
-		AccY: int16(binary.BigEndian.Uint16(payload[2:4])),
-		AccZ: int16(binary.BigEndian.Uint16(payload[4:6])),
-	}
-	
-	out.Data = metrics
-}
-//姿态控制机构：动量轮三轴转速
-func (r *Receiver) parseActuator(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 6 {
-		return
+
+	pkt, err := dec.Decode(packet)
+	if err != nil {
+		r.counters.RecordError(err)
+		return nil, err
 	}
-	
-	metrics := &model.ActuatorMetrics{
-		Timestamp: time.Now().Unix(),
-		WheelSpeedX: int16(binary.BigEndian.Uint16(payload[0:2])), // TMEGNC2029
-		WheelSpeedY: int16(binary.BigEndian.Uint16(payload[2:4])), // TMEGNC2030
-		WheelSpeedZ: int16(binary.BigEndian.Uint16(payload[4:6])), // TMEGNC2031
+	if pkt == nil {
+		return nil, nil
 	}
-	
-	out.Data = metrics
-}
-//通信机:包含发射功率、信噪比、RSSI、开关状态等
-func (r *Receiver) parseTransceiver(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 7 {
-		return
-	}
-	
-	metrics := &model.TransceiverMetrics{
-		Timestamp: time.Now().Unix(),
-		TransmitPower: payload[0],
-		TelemetryEncryptStatus: payload[1],     // TMEZD01167
-		TelecontrolEncryptStatus: payload[2],   // TMEZD01168
-		TransmitSwitch: payload[3],             // TMEZD01155
-		InfoChannelSNR: payload[4],             // TMEZD01145
-		ReceiveRSSI: int8(payload[6]),          // TMEZD01147
+
+	out, err := r.parsePayload(pkt.Component, pkt.Data, pkt.TimeCode)
+	if err != nil {
+		r.counters.RecordError(err)
+		return nil, err
 	}
-	
-	out.Data = metrics
+
+	r.counters.RecordAccepted(pkt.Component)
+	return out, nil
 }
-//推进器：燃料、管路开关、压力传感器
-func (r *Receiver) parseThruster(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 5 {
-		return
-	}
-	
-	metrics := &model.ThrusterMetrics{
-		Timestamp: time.Now().Unix(),
-		FuelLevel: binary.BigEndian.Uint16(payload[0:2]),
-		PipelineSwitch: payload[2],
-		PressureSensor: binary.BigEndian.Uint16(payload[3:5]),
-	}
-	
-	out.Data = metrics
+
+// parseFrame 按组件布局解析帧负载
+func (r *Receiver) parseFrame(frame *Frame) (*model.BusinessMetrics, error) {
+	return r.parsePayload(frame.Component, frame.Payload, frame.TimeCode)
 }
-//电源
-func (r *Receiver) parseEPS(payload []byte, out *model.BusinessMetrics) {
-	if len(payload) < 4 {
-		return
+
+// parsePayload 按组件布局解析负载
+// 携带星上时间码且已配置解码方式时以星上时间为测量时间，否则使用接收时间
+func (r *Receiver) parsePayload(component uint8, payload []byte, timeCode []byte) (*model.BusinessMetrics, error) {
+	received := time.Now()
+	measured := received
+	source := model.TimeSourceReceive
+
+	if len(timeCode) > 0 {
+		if tc := r.TimeCode(); tc != nil {
+			t, err := tc.Decode(timeCode)
+			if err != nil {
+				r.counters.RecordTimeError(component)
+				fmt.Printf("[业务层] 组件 0x%02X 时间码解码失败，使用接收时间: %v\n", component, err)
+			} else {
+				measured = t
+				source = model.TimeSourceOnboard
+			}
+		}
 	}
-	
-	metrics := &model.EPSMetrics{
-		Timestamp: time.Now().Unix(),
-		Voltage: float64(binary.BigEndian.Uint16(payload[0:2])) / 1000.0,
-		Current: float64(binary.BigEndian.Uint16(payload[2:4])) / 1000.0,
+
+	data, raw, err := r.Layouts().DecodeWithRaw(component, payload, measured.Unix())
+	if err != nil {
+		return nil, err
 	}
-	
-	out.Data = metrics
+
+	return &model.BusinessMetrics{
+		ComponentType: component,
+		Timestamp:     measured.Unix(),
+		TimestampNano: measured.UnixNano(),
+		ReceiveTime:   received.UnixNano(),
+		TimeSource:    source,
+		Data:          data,
+		Raw:           raw,
+	}, nil
 }
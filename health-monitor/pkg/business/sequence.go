@@ -0,0 +1,242 @@
+/*
+按组件跟踪报文序列计数，区分"遥测丢失"和"组件无数据"
+
+序列计数来源：
+- v2 帧的序列扩展（FlagSequence，16 位）
+- CCSDS 空间包主导头的包序列计数（14 位）
+
+检测内容：
+- 丢包（计数跳变）：中间缺失的计数记为丢失
+- 重复：已收到过的计数
+- 乱序：落在最近窗口内、此前记为丢失的计数（迟到补回，丢失数相应减少）
+- 回绕：计数从最大值回到 0
+- 复位：计数大幅回退（组件重启），重新开始跟踪
+
+丢包率按评估窗口（期望收到的报文数）计算，每个窗口结束时交给告警生成器判断链路质量。
+*/
+package business
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"health-monitor/pkg/models"
+)
+
+const (
+	// DefaultSeqWindow 默认评估窗口（期望报文数）
+	DefaultSeqWindow = 100
+
+	// DefaultLossRateThreshold 默认丢包率告警阈值
+	DefaultLossRateThreshold = 0.05
+
+	// seqHistory 用于判断重复/乱序的最近计数个数（需整除 16 位和 14 位计数的模）
+	seqHistory = 1024
+)
+
+// SequenceConfig 序列计数跟踪配置
+type SequenceConfig struct {
+	Window            int     `json:"window"`              // 评估窗口（期望报文数）
+	LossRateThreshold float64 `json:"loss_rate_threshold"` // 丢包率告警阈值（0-1）
+}
+
+// SequenceTracker 按组件跟踪序列计数（并发安全）
+type SequenceTracker struct {
+	mu      sync.Mutex
+	cfg     SequenceConfig
+	streams map[uint8]*seqStream
+}
+
+// seqStream 单个组件的计数状态
+type seqStream struct {
+	initialized bool
+	modulus     uint64
+	highest     uint64 // 已收到的最大扩展计数（含回绕次数）
+	slots       [seqHistory]uint64
+	slotValid   [seqHistory]bool
+
+	total model.LinkQuality // 累计统计
+
+	// 当前评估窗口
+	winExpected uint64
+	winLost     int64
+	lastRate    float64
+	evaluated   bool // 至少完成过一个窗口
+	pending     bool // 有新完成的窗口待评估
+}
+
+// NewSequenceTracker 创建序列计数跟踪器
+func NewSequenceTracker(cfg SequenceConfig) *SequenceTracker {
+	return &SequenceTracker{
+		cfg:     normalizeSeqConfig(cfg),
+		streams: make(map[uint8]*seqStream),
+	}
+}
+
+func normalizeSeqConfig(cfg SequenceConfig) SequenceConfig {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultSeqWindow
+	}
+	if cfg.LossRateThreshold <= 0 {
+		cfg.LossRateThreshold = DefaultLossRateThreshold
+	}
+	return cfg
+}
+
+// SetConfig 更新配置（对后续窗口生效）
+func (t *SequenceTracker) SetConfig(cfg SequenceConfig) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cfg = normalizeSeqConfig(cfg)
+}
+
+// Config 返回当前配置
+func (t *SequenceTracker) Config() SequenceConfig {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cfg
+}
+
+// Observe 记录组件收到的序列计数，modulus 为计数的模（16 位为 65536，CCSDS 为 16384）
+func (t *SequenceTracker) Observe(component uint8, seq uint32, modulus uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.streams[component]
+	if !ok || s.modulus != uint64(modulus) {
+		s = &seqStream{modulus: uint64(modulus)}
+		s.total.ComponentType = component
+		t.streams[component] = s
+	}
+	s.observe(uint64(seq)%s.modulus, t.cfg.Window)
+}
+
+func (s *seqStream) observe(seq uint64, window int) {
+	s.total.LastSeq = uint32(seq)
+	s.total.LastUpdate = time.Now().Unix()
+
+	if !s.initialized {
+		s.initialized = true
+		s.highest = seq
+		s.mark(seq)
+		s.total.Received++
+		s.count(1, 0, window)
+		return
+	}
+
+	m := s.modulus
+	cur := s.highest % m
+	ahead := (seq + m - cur) % m
+
+	switch {
+	case ahead == 0:
+		s.total.Duplicates++
+
+	case ahead < m/2:
+		// 正常前进，中间缺失的计为丢失
+		if seq < cur {
+			s.total.Wraps++
+		}
+		gap := ahead - 1
+		s.total.Lost += gap
+		s.total.Received++
+		s.highest += ahead
+		s.mark(s.highest)
+		s.count(ahead, int64(gap), window)
+
+	default:
+		back := m - ahead
+		ext := s.highest - back
+		if back >= seqHistory || back > s.highest {
+			// 大幅回退：视为组件复位，重新跟踪
+			s.total.Resets++
+			s.total.Received++
+			s.highest = seq
+			s.slotValid = [seqHistory]bool{}
+			s.mark(seq)
+			s.count(1, 0, window)
+			return
+		}
+		if s.seen(ext) {
+			s.total.Duplicates++
+			return
+		}
+		// 迟到的报文：此前已计为丢失，现补回
+		s.total.Reordered++
+		s.total.Received++
+		if s.total.Lost > 0 {
+			s.total.Lost--
+		}
+		s.mark(ext)
+		s.count(0, -1, window)
+	}
+}
+
+func (s *seqStream) mark(ext uint64) {
+	i := ext % seqHistory
+	s.slots[i] = ext
+	s.slotValid[i] = true
+}
+
+func (s *seqStream) seen(ext uint64) bool {
+	i := ext % seqHistory
+	return s.slotValid[i] && s.slots[i] == ext
+}
+
+// count 累加当前窗口，窗口满时结算丢包率
+func (s *seqStream) count(expected uint64, lost int64, window int) {
+	s.winExpected += expected
+	s.winLost += lost
+	if s.winLost < 0 {
+		s.winLost = 0
+	}
+
+	if s.winExpected >= uint64(window) {
+		s.lastRate = float64(s.winLost) / float64(s.winExpected)
+		s.evaluated = true
+		s.pending = true
+		s.winExpected = 0
+		s.winLost = 0
+	}
+}
+
+func (s *seqStream) snapshot() model.LinkQuality {
+	lq := s.total
+	lq.LossRate = s.lastRate
+	if !s.evaluated && s.winExpected > 0 {
+		// 尚未完成首个窗口，用当前窗口估算
+		lq.LossRate = float64(s.winLost) / float64(s.winExpected)
+	}
+	return lq
+}
+
+// TakeEvaluation 取出组件新完成窗口的链路质量（每个窗口只返回一次）
+func (t *SequenceTracker) TakeEvaluation(component uint8) (*model.LinkQuality, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.streams[component]
+	if !ok || !s.pending {
+		return nil, false
+	}
+	s.pending = false
+	lq := s.snapshot()
+	lq.Threshold = t.cfg.LossRateThreshold
+	return &lq, true
+}
+
+// Stats 返回全部组件的链路质量统计（按组件编号排序）
+func (t *SequenceTracker) Stats() []model.LinkQuality {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]model.LinkQuality, 0, len(t.streams))
+	for _, s := range t.streams {
+		lq := s.snapshot()
+		lq.Threshold = t.cfg.LossRateThreshold
+		out = append(out, lq)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ComponentType < out[j].ComponentType })
+	return out
+}
@@ -0,0 +1,164 @@
+package business
+
+import (
+	"testing"
+	"time"
+
+	"health-monitor/pkg/alert"
+	"health-monitor/pkg/models"
+	"health-monitor/pkg/state"
+)
+
+func linkStats(t *testing.T, st *SequenceTracker, comp uint8) model.LinkQuality {
+	t.Helper()
+	for _, lq := range st.Stats() {
+		if lq.ComponentType == comp {
+			return lq
+		}
+	}
+	t.Fatalf("组件 0x%02X 无统计", comp)
+	return model.LinkQuality{}
+}
+
+// TestSequenceTracker 测试丢包、重复、乱序、回绕与复位
+func TestSequenceTracker(t *testing.T) {
+	st := NewSequenceTracker(SequenceConfig{Window: 1000})
+
+	// 0 1 2 5（丢 3、4） 4（迟到） 4（重复） 6
+	for _, seq := range []uint32{0, 1, 2, 5, 4, 4, 6} {
+		st.Observe(CompPower, seq, FrameSeqModulus)
+	}
+	lq := linkStats(t, st, CompPower)
+	if lq.Received != 6 || lq.Lost != 1 || lq.Reordered != 1 || lq.Duplicates != 1 {
+		t.Errorf("统计不符: %+v", lq)
+	}
+
+	// 16 位回绕：65534 65535 0 1
+	st2 := NewSequenceTracker(SequenceConfig{})
+	for _, seq := range []uint32{65534, 65535, 0, 1} {
+		st2.Observe(CompThermal, seq, FrameSeqModulus)
+	}
+	lq = linkStats(t, st2, CompThermal)
+	if lq.Wraps != 1 || lq.Lost != 0 || lq.Received != 4 {
+		t.Errorf("回绕统计不符: %+v", lq)
+	}
+
+	// 大幅回退视为复位，不计丢失
+	st2.Observe(CompThermal, 30000, FrameSeqModulus)
+	st2.Observe(CompThermal, 10, FrameSeqModulus)
+	st2.Observe(CompThermal, 11, FrameSeqModulus)
+	lq = linkStats(t, st2, CompThermal)
+	if lq.Resets != 1 || lq.LastSeq != 11 {
+		t.Errorf("复位统计不符: %+v", lq)
+	}
+
+	// 不同组件独立计数
+	st.Observe(CompComm, 100, FrameSeqModulus)
+	if linkStats(t, st, CompComm).Received != 1 || linkStats(t, st, CompPower).Received != 6 {
+		t.Error("组件计数应相互独立")
+	}
+}
+
+// TestSequenceWindow 测试评估窗口的丢包率结算
+func TestSequenceWindow(t *testing.T) {
+	st := NewSequenceTracker(SequenceConfig{Window: 10, LossRateThreshold: 0.1})
+
+	// 期望 10 个，收到 8 个（丢 2 个）
+	for _, seq := range []uint32{0, 1, 2, 3, 5, 6, 8, 9} {
+		st.Observe(CompPower, seq, FrameSeqModulus)
+	}
+	lq, ok := st.TakeEvaluation(CompPower)
+	if !ok {
+		t.Fatal("窗口满后应产生评估结果")
+	}
+	if lq.LossRate < 0.199 || lq.LossRate > 0.201 || lq.Threshold != 0.1 {
+		t.Errorf("丢包率不符: %+v", lq)
+	}
+	if _, ok := st.TakeEvaluation(CompPower); ok {
+		t.Error("同一窗口只应评估一次")
+	}
+}
+
+// TestFrameSequenceExtension 测试 v2 帧序列计数扩展及接收端统计
+func TestFrameSequenceExtension(t *testing.T) {
+	cfg := &TimeCodeConfig{Format: "cuc"}
+	cfg.Validate()
+	raw := EncodeFrameV2(CompPower, FrameOptions{TimeCode: cfg.EncodeCUC(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)), HasSeq: true, Seq: 0xBEEF}, buildPowerPayload())
+
+	f, err := DecodeFrame(raw)
+	if err != nil {
+		t.Fatalf("解帧失败: %v", err)
+	}
+	if !f.HasSeq || f.Seq != 0xBEEF || f.Flags != FlagTimeCode|FlagSequence || len(f.TimeCode) != 6 {
+		t.Errorf("扩展字段不符: %+v", f)
+	}
+
+	// 标志置位但缺少序列计数
+	bad := EncodeFrameV2(CompPower, FrameOptions{}, nil)
+	bad[6] = FlagSequence
+	end := len(bad) - FrameTrailerSize
+	crc := CRC16CCITT(bad[2:end])
+	bad[end], bad[end+1] = byte(crc>>8), byte(crc)
+	if _, err := DecodeFrame(bad); err == nil {
+		t.Error("序列扩展越界应返回错误")
+	}
+
+	r := NewReceiver(NewDispatcher(nil))
+	for _, seq := range []uint16{1, 2, 4} {
+		if _, err := r.ParsePacket(EncodeFrameV2(CompPower, FrameOptions{HasSeq: true, Seq: seq}, buildPowerPayload())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lq := r.LinkQuality()
+	if len(lq) != 1 || lq[0].Lost != 1 || lq[0].LastSeq != 4 {
+		t.Errorf("接收端统计不符: %+v", lq)
+	}
+}
+
+// TestCCSDSSequenceObserved 测试空间包逐段统计序列计数
+func TestCCSDSSequenceObserved(t *testing.T) {
+	r := NewReceiver(NewDispatcher(nil))
+	r.SetCCSDS(NewCCSDSDecoder(testCCSDSConfig(t)))
+
+	// 14 位回绕，分段包每段都计入
+	r.ParseSpacePacket(EncodeSpacePacket(101, SeqFirst, CCSDSMaxSeqCount, nil, []byte{1}))
+	r.ParseSpacePacket(EncodeSpacePacket(101, SeqContinuation, 0, nil, []byte{2}))
+	r.ParseSpacePacket(EncodeSpacePacket(101, SeqLast, 3, nil, []byte{3})) // 丢 1、2
+
+	lq := r.LinkQuality()
+	if len(lq) != 1 || lq[0].ComponentType != CompThermal || lq[0].Wraps != 1 || lq[0].Lost != 2 || lq[0].Received != 3 {
+		t.Errorf("空间包统计不符: %+v", lq)
+	}
+}
+
+// TestLinkQualityAlert 测试丢包率告警/恢复及 GetStats 输出
+func TestLinkQualityAlert(t *testing.T) {
+	sm, err := state.NewStateManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+
+	r := NewReceiver(NewDispatcher(sm))
+	r.SetSequenceConfig(SequenceConfig{Window: 10, LossRateThreshold: 0.05})
+
+	bad := &model.LinkQuality{ComponentType: CompPower, LossRate: 0.3, Threshold: 0.05}
+	alerts := alert.CheckLinkQualityWithState(bad, sm)
+	if len(alerts) != 1 || !alerts[0].IsFiring() || alerts[0].Severity != model.SeverityCritical || alerts[0].Source != "link-0x03" {
+		t.Fatalf("应产生严重告警: %+v", alerts)
+	}
+	if len(alert.CheckLinkQualityWithState(bad, sm)) != 0 {
+		t.Error("持续超限不应重复告警")
+	}
+	good := &model.LinkQuality{ComponentType: CompPower, LossRate: 0, Threshold: 0.05}
+	if alerts := alert.CheckLinkQualityWithState(good, sm); len(alerts) != 1 || !alerts[0].IsResolved() {
+		t.Errorf("应产生恢复告警: %+v", alerts)
+	}
+
+	r.ParsePacket(EncodeFrameV2(CompPower, FrameOptions{HasSeq: true, Seq: 7}, buildPowerPayload()))
+	stats, ok := sm.GetStats()["link_quality"].([]model.LinkQuality)
+	if !ok || len(stats) != 1 || stats[0].LastSeq != 7 {
+		t.Errorf("GetStats 未包含链路质量: %+v", sm.GetStats()["link_quality"])
+	}
+
+}
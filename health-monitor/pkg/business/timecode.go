@@ -0,0 +1,241 @@
+/*
+星上时间码（CCSDS 301.0-B）
+
+报文可携带星上测量时间（CCSDS 空间包副导头，或 v2 帧的时间码扩展），
+解码后写入 BusinessMetrics.Timestamp / TimestampNano，地面接收时间单独保存在 ReceiveTime，
+避免总线延迟和乱序导致测量时间错误。
+
+支持两种时间码：
+	cuc : 非分段时间码，coarse 为自历元起的秒数，fine 为秒的二进制小数
+	cds : 日分段时间码，自历元起的天数 + 当日毫秒数 + 可选亚毫秒（微秒/皮秒）
+
+可配置项：历元、coarse/fine 字节数（或由 P 场给出）、星上钟差修正、闰秒。
+CCSDS 推荐历元 1958-01-01 为 TAI 时间，转换为 UTC 需减去闰秒（leap_seconds，当前为 37）。
+*/
+package business
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// 时间码格式
+const (
+	TimeCodeCUC = "cuc"
+	TimeCodeCDS = "cds"
+)
+
+// CCSDSEpoch CCSDS 推荐历元 1958-01-01T00:00:00 (TAI)
+var CCSDSEpoch = time.Date(1958, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TimeCodeConfig 时间码解码配置
+type TimeCodeConfig struct {
+	Format string `json:"format"`          // cuc / cds
+	Epoch  string `json:"epoch,omitempty"` // 历元（RFC3339），默认 1958-01-01T00:00:00Z
+
+	// CUC
+	PField      bool `json:"p_field,omitempty"`      // 时间码前带 1 字节 P 场（由 P 场给出 coarse/fine 字节数）
+	CoarseBytes int  `json:"coarse_bytes,omitempty"` // coarse 字节数 1-4，默认 4
+	FineBytes   int  `json:"fine_bytes,omitempty"`   // fine 字节数 0-3，默认 2
+
+	// CDS
+	DayBytes   int `json:"day_bytes,omitempty"`    // 天数字节数 2/3，默认 2
+	SubMsBytes int `json:"sub_ms_bytes,omitempty"` // 亚毫秒字节数 0/2(微秒)/4(皮秒)，默认 0
+
+	ClockOffsetMs int64 `json:"clock_offset_ms,omitempty"` // 星上钟差修正（毫秒，加到解码结果上）
+	LeapSeconds   int   `json:"leap_seconds,omitempty"`    // 历元为 TAI 时的闰秒数（从解码结果中减去）
+
+	epoch time.Time
+}
+
+// LoadTimeCodeConfig 从文件加载时间码配置
+func LoadTimeCodeConfig(path string) (*TimeCodeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取时间码配置文件失败: %w", err)
+	}
+	var cfg TimeCodeConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析时间码配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("时间码配置校验失败: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Validate 校验配置并填充默认值
+func (c *TimeCodeConfig) Validate() error {
+	c.Format = strings.ToLower(c.Format)
+
+	c.epoch = CCSDSEpoch
+	if c.Epoch != "" {
+		t, err := time.Parse(time.RFC3339Nano, c.Epoch)
+		if err != nil {
+			return fmt.Errorf("历元 %q 格式错误: %w", c.Epoch, err)
+		}
+		c.epoch = t.UTC()
+	}
+
+	switch c.Format {
+	case TimeCodeCUC:
+		if c.CoarseBytes == 0 {
+			c.CoarseBytes = 4
+		}
+		if c.FineBytes == 0 && !c.PField {
+			c.FineBytes = 2
+		}
+		if c.CoarseBytes < 1 || c.CoarseBytes > 4 {
+			return fmt.Errorf("coarse_bytes 需在 1-4 之间")
+		}
+		if c.FineBytes < 0 || c.FineBytes > 3 {
+			return fmt.Errorf("fine_bytes 需在 0-3 之间")
+		}
+	case TimeCodeCDS:
+		if c.DayBytes == 0 {
+			c.DayBytes = 2
+		}
+		if c.DayBytes != 2 && c.DayBytes != 3 {
+			return fmt.Errorf("day_bytes 只能为 2 或 3")
+		}
+		switch c.SubMsBytes {
+		case 0, 2, 4:
+		default:
+			return fmt.Errorf("sub_ms_bytes 只能为 0、2 或 4")
+		}
+	default:
+		return fmt.Errorf("不支持的时间码格式 %q", c.Format)
+	}
+	return nil
+}
+
+// Size 时间码字节数（CUC 带 P 场时长度由 P 场决定，返回 0）
+func (c *TimeCodeConfig) Size() int {
+	switch c.Format {
+	case TimeCodeCUC:
+		if c.PField {
+			return 0
+		}
+		return c.CoarseBytes + c.FineBytes
+	case TimeCodeCDS:
+		return c.DayBytes + 4 + c.SubMsBytes
+	}
+	return 0
+}
+
+// Decode 解码时间码，返回 UTC 时间（已应用钟差修正与闰秒）
+func (c *TimeCodeConfig) Decode(b []byte) (time.Time, error) {
+	var t time.Time
+	var err error
+
+	switch c.Format {
+	case TimeCodeCUC:
+		t, err = c.decodeCUC(b)
+	case TimeCodeCDS:
+		t, err = c.decodeCDS(b)
+	default:
+		return time.Time{}, fmt.Errorf("time code config not validated")
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t = t.Add(time.Duration(c.ClockOffsetMs) * time.Millisecond)
+	t = t.Add(-time.Duration(c.LeapSeconds) * time.Second)
+	return t, nil
+}
+
+// decodeCUC 解码 CUC：coarse（秒）+ fine（2^-8n 秒）
+func (c *TimeCodeConfig) decodeCUC(b []byte) (time.Time, error) {
+	coarseN, fineN := c.CoarseBytes, c.FineBytes
+	if c.PField {
+		if len(b) < 1 {
+			return time.Time{}, fmt.Errorf("cuc time code: missing p-field")
+		}
+		p := b[0]
+		if p&0x80 != 0 {
+			return time.Time{}, fmt.Errorf("cuc time code: extended p-field not supported")
+		}
+		coarseN = int(p>>2&0x3) + 1
+		fineN = int(p & 0x3)
+		b = b[1:]
+	}
+
+	if len(b) != coarseN+fineN {
+		return time.Time{}, fmt.Errorf("cuc time code: %d bytes, want %d", len(b), coarseN+fineN)
+	}
+
+	var coarse uint64
+	for _, v := range b[:coarseN] {
+		coarse = coarse<<8 | uint64(v)
+	}
+	var fine uint64
+	for _, v := range b[coarseN:] {
+		fine = fine<<8 | uint64(v)
+	}
+
+	nanos := int64(0)
+	if fineN > 0 {
+		nanos = int64(fine * uint64(time.Second) >> (8 * uint(fineN)))
+	}
+	return c.epoch.Add(time.Duration(coarse)*time.Second + time.Duration(nanos)), nil
+}
+
+// decodeCDS 解码 CDS：天数 + 当日毫秒 + 可选亚毫秒
+func (c *TimeCodeConfig) decodeCDS(b []byte) (time.Time, error) {
+	if len(b) != c.Size() {
+		return time.Time{}, fmt.Errorf("cds time code: %d bytes, want %d", len(b), c.Size())
+	}
+
+	var days uint64
+	for _, v := range b[:c.DayBytes] {
+		days = days<<8 | uint64(v)
+	}
+	b = b[c.DayBytes:]
+
+	ms := uint64(b[0])<<24 | uint64(b[1])<<16 | uint64(b[2])<<8 | uint64(b[3])
+	if ms >= 86401000 { // 允许闰秒当天多出的1秒
+		return time.Time{}, fmt.Errorf("cds time code: milliseconds of day %d out of range", ms)
+	}
+	b = b[4:]
+
+	var sub time.Duration
+	switch c.SubMsBytes {
+	case 2:
+		us := uint64(b[0])<<8 | uint64(b[1])
+		if us >= 1000 {
+			return time.Time{}, fmt.Errorf("cds time code: microseconds %d out of range", us)
+		}
+		sub = time.Duration(us) * time.Microsecond
+	case 4:
+		ps := uint64(b[0])<<24 | uint64(b[1])<<16 | uint64(b[2])<<8 | uint64(b[3])
+		if ps >= 1000000000 {
+			return time.Time{}, fmt.Errorf("cds time code: picoseconds %d out of range", ps)
+		}
+		sub = time.Duration(ps / 1000)
+	}
+
+	return c.epoch.Add(time.Duration(days)*24*time.Hour + time.Duration(ms)*time.Millisecond + sub), nil
+}
+
+// EncodeCUC 按配置编码 CUC 时间码（供发送端/测试使用，不含 P 场）
+func (c *TimeCodeConfig) EncodeCUC(t time.Time) []byte {
+	t = t.Add(time.Duration(c.LeapSeconds) * time.Second).Add(-time.Duration(c.ClockOffsetMs) * time.Millisecond)
+	d := t.Sub(c.epoch)
+	coarse := uint64(d / time.Second)
+	fine := uint64(d%time.Second) << (8 * uint(c.FineBytes)) / uint64(time.Second)
+
+	out := make([]byte, c.CoarseBytes+c.FineBytes)
+	for i := c.CoarseBytes - 1; i >= 0; i-- {
+		out[i] = byte(coarse)
+		coarse >>= 8
+	}
+	for i := c.CoarseBytes + c.FineBytes - 1; i >= c.CoarseBytes; i-- {
+		out[i] = byte(fine)
+		fine >>= 8
+	}
+	return out
+}
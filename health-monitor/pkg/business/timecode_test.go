@@ -0,0 +1,127 @@
+package business
+
+import (
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+)
+
+// TestDecodeCUC 测试 CUC 时间码（含 P 场、钟差与闰秒）
+func TestDecodeCUC(t *testing.T) {
+	cfg := &TimeCodeConfig{Format: "cuc", Epoch: "2000-01-01T00:00:00Z"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// coarse = 100s，fine = 0x8000/65536 = 0.5s
+	got, err := cfg.Decode([]byte{0, 0, 0, 100, 0x80, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2000, 1, 1, 0, 1, 40, 500000000, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("CUC = %v, want %v", got, want)
+	}
+
+	// P 场：0x2E = 时间码ID 010、coarse 4 字节、fine 2 字节
+	pcfg := &TimeCodeConfig{Format: "cuc", Epoch: "2000-01-01T00:00:00Z", PField: true}
+	pcfg.Validate()
+	if got, err := pcfg.Decode([]byte{0x2E, 0, 0, 0, 100, 0x80, 0x00}); err != nil || !got.Equal(want) {
+		t.Errorf("CUC(P场) = %v, %v", got, err)
+	}
+
+	// 钟差 +250ms，闰秒 37
+	tai := &TimeCodeConfig{Format: "cuc", ClockOffsetMs: 250, LeapSeconds: 37}
+	tai.Validate()
+	now := time.Date(2026, 10, 16, 8, 30, 0, 125000000, time.UTC)
+	back, err := tai.Decode(tai.EncodeCUC(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := back.Sub(now); d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("编解码往返误差 %v", d)
+	}
+
+	if _, err := cfg.Decode([]byte{0, 0, 1}); err == nil {
+		t.Error("长度不符应返回错误")
+	}
+}
+
+// TestDecodeCDS 测试 CDS 时间码
+func TestDecodeCDS(t *testing.T) {
+	cfg := &TimeCodeConfig{Format: "cds", SubMsBytes: 2}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1958-01-01 + 1 天 + 3723004ms (01:02:03.004) + 5us
+	got, err := cfg.Decode([]byte{0x00, 0x01, 0x00, 0x38, 0xCE, 0xFC, 0x00, 0x05})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(1958, 1, 2, 1, 2, 3, 4005000, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("CDS = %v, want %v", got, want)
+	}
+
+	if _, err := cfg.Decode([]byte{0, 1, 0xFF, 0xFF, 0xFF, 0xFF, 0, 0}); err == nil {
+		t.Error("当日毫秒越界应返回错误")
+	}
+
+	for _, bad := range []TimeCodeConfig{
+		{Format: "gps"},
+		{Format: "cds", DayBytes: 4},
+		{Format: "cds", SubMsBytes: 3},
+		{Format: "cuc", CoarseBytes: 5},
+		{Format: "cuc", Epoch: "yesterday"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v 应校验失败", bad)
+		}
+	}
+}
+
+// TestOnboardTimestamp 测试 v2 帧携带时间码时使用星上时间
+func TestOnboardTimestamp(t *testing.T) {
+	r := NewReceiver(NewDispatcher(nil))
+	cfg := &TimeCodeConfig{Format: "cuc"}
+	cfg.Validate()
+	r.SetTimeCode(cfg)
+
+	onboard := time.Date(2026, 1, 2, 3, 4, 5, 250000000, time.UTC)
+	frame := EncodeFrameV2(CompPower, FrameOptions{TimeCode: cfg.EncodeCUC(onboard)}, buildPowerPayload())
+
+	m, err := r.ParsePacket(frame)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if m.TimeSource != model.TimeSourceOnboard || m.Timestamp != onboard.Unix() {
+		t.Errorf("未使用星上时间: source=%s ts=%d", m.TimeSource, m.Timestamp)
+	}
+	if d := time.Duration(m.TimestampNano - onboard.UnixNano()); d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("亚秒精度丢失: %v", d)
+	}
+	if m.ReceiveTime <= onboard.UnixNano() {
+		t.Errorf("接收时间未单独保存: %d", m.ReceiveTime)
+	}
+	if m.Data.(*model.PowerMetrics).Timestamp != onboard.Unix() {
+		t.Error("组件指标时间戳应与星上时间一致")
+	}
+
+	// 时间码损坏：回退为接收时间并计数
+	bad := EncodeFrameV2(CompPower, FrameOptions{TimeCode: []byte{1, 2, 3}}, buildPowerPayload())
+	m, err = r.ParsePacket(bad)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if m.TimeSource != model.TimeSourceReceive || r.FrameStats()[CompPower].TimeErrors != 1 {
+		t.Errorf("时间码损坏未回退: %+v", m)
+	}
+
+	// v1 帧不带时间码
+	m, _ = r.ParsePacket(EncodeFrame(CompPower, buildPowerPayload()))
+	if m.TimeSource != model.TimeSourceReceive || m.TimestampNano != m.ReceiveTime {
+		t.Errorf("v1 帧应使用接收时间: %+v", m)
+	}
+}
@@ -1,4 +1,3 @@
-// UNUSED: 当前未被 health-monitor 模块的可执行程序依赖（截至 2026-01-22），仅作为配置设计草稿/占位说明。
 /* 包括：
 
 阈值配置（CPU、内存、业务报文 delay 等）
@@ -16,7 +15,8 @@ ECSM API 地址
 提供函数：
 
 LoadConfig()
-ReloadConfig()（热更新可选） */
+ReloadConfig()（热更新，已实现为 Reloader，见 reload.go） */
+package config
 
 //报文设计
 /*Byte 0   : 组件类型（下面 14 类之一）
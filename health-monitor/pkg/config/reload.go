@@ -0,0 +1,246 @@
+/*
+配置热加载
+
+告警规则、遥测参数库、报文布局等配置文件可在运行时重新加载，修改限值无需重新编译、部署 monitor。
+触发方式：
+
+	文件变化：按固定间隔检查文件的修改时间与大小（不依赖 inotify，SylixOS 目标机上同样可用）
+	SIGHUP  ：kill -HUP <pid>
+	API     ：POST /api/v1/reload，GET 同一路径查询最近一次加载结果（见 ServeHTTP）
+
+一次重新加载先解析、校验全部配置文件，全部成功后才依次切换（每个切换函数只做指针替换）；
+任一文件失败时保留原有配置并记录错误，不会出现部分文件已切换的状态。
+*/
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval 默认文件检查间隔
+const DefaultWatchInterval = 5 * time.Second
+
+// LoadFunc 加载并校验配置文件，成功时返回切换函数（在全部文件校验通过后调用）
+type LoadFunc func(path string) (apply func(), err error)
+
+// source 一个可热加载的配置文件
+type source struct {
+	name   string
+	path   string
+	load   LoadFunc
+	stamp  fileStamp // 最近一次成功加载时的文件状态
+	failed fileStamp // 最近一次加载失败时的文件状态，文件再次修改前不重复尝试
+}
+
+// fileStamp 文件变化判据（修改时间 + 大小）
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// SourceStatus 配置文件状态
+type SourceStatus struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Status 热加载状态
+type Status struct {
+	Reloads     int            `json:"reloads"`                // 成功加载次数（含启动时的首次加载）
+	Failures    int            `json:"failures"`               // 失败次数
+	LastReload  time.Time      `json:"last_reload"`            // 最近一次成功加载时间
+	LastError   string         `json:"last_error,omitempty"`   // 最近一次加载的错误（成功后清空）
+	LastTrigger string         `json:"last_trigger,omitempty"` // 最近一次加载的触发方式
+	Sources     []SourceStatus `json:"sources"`
+}
+
+// Reloader 配置热加载器（并发安全，同一时间只进行一次加载）
+type Reloader struct {
+	mu      sync.Mutex
+	sources []*source
+	status  Status
+}
+
+// NewReloader 创建热加载器
+func NewReloader() *Reloader {
+	return &Reloader{}
+}
+
+// Add 登记配置文件，path 为空时忽略
+func (r *Reloader) Add(name, path string, load LoadFunc) {
+	if path == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = append(r.sources, &source{name: name, path: path, load: load})
+}
+
+// Len 已登记的配置文件数
+func (r *Reloader) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sources)
+}
+
+// Reload 重新加载全部配置文件：全部校验通过后才切换
+func (r *Reloader) Reload(trigger string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked(trigger)
+}
+
+func (r *Reloader) reloadLocked(trigger string) error {
+	r.status.LastTrigger = trigger
+
+	applies := make([]func(), 0, len(r.sources))
+	stamps := make([]fileStamp, len(r.sources))
+	for i, s := range r.sources {
+		// 先取文件状态再读取，读取期间文件再次变化时下一轮检查仍会触发
+		stamp, err := statFile(s.path)
+		var apply func()
+		if err == nil {
+			apply, err = s.load(s.path)
+		}
+		if err != nil {
+			r.status.Failures++
+			r.status.LastError = fmt.Sprintf("%s: %v", s.name, err)
+			r.markFailedLocked()
+			return fmt.Errorf("加载%s失败，保留原配置: %w", s.name, err)
+		}
+		applies = append(applies, apply)
+		stamps[i] = stamp
+	}
+
+	for i, apply := range applies {
+		if apply != nil {
+			apply()
+		}
+		r.sources[i].stamp = stamps[i]
+		r.sources[i].failed = fileStamp{}
+	}
+	r.status.Reloads++
+	r.status.LastReload = time.Now()
+	r.status.LastError = ""
+	return nil
+}
+
+// markFailedLocked 加载失败时记录全部文件的当前状态（含本轮已校验通过的文件），
+// 任一文件再次修改前 Watch 不重复加载
+func (r *Reloader) markFailedLocked() {
+	for _, s := range r.sources {
+		if stamp, err := statFile(s.path); err == nil {
+			s.failed = stamp
+		}
+	}
+}
+
+// Status 返回热加载状态
+func (r *Reloader) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.status
+	st.Sources = make([]SourceStatus, len(r.sources))
+	for i, s := range r.sources {
+		st.Sources[i] = SourceStatus{Name: s.name, Path: s.path, ModTime: s.stamp.modTime}
+	}
+	return st
+}
+
+// changed 是否有配置文件在上次加载（成功或失败）后发生变化
+func (r *Reloader) changed() bool {
+	for _, s := range r.sources {
+		stamp, err := statFile(s.path)
+		if err != nil {
+			// 文件暂时不存在（编辑器先删后写等），等待下一轮
+			continue
+		}
+		if stamp != s.stamp && stamp != s.failed {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch 按间隔检查配置文件，变化时重新加载，直到 ctx 结束
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			if r.changed() {
+				r.report("file", r.reloadLocked("file"))
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// WatchSignal 收到指定信号（通常为 SIGHUP）时重新加载，直到 ctx 结束
+func (r *Reloader) WatchSignal(ctx context.Context, sigs ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-ch:
+			r.report(sig.String(), r.Reload("signal"))
+		}
+	}
+}
+
+func (r *Reloader) report(trigger string, err error) {
+	if err != nil {
+		fmt.Printf("[配置] 热加载失败(%s): %v\n", trigger, err)
+		return
+	}
+	fmt.Printf("[配置] 热加载完成(%s)\n", trigger)
+}
+
+// ServeHTTP POST 重新加载，GET 查询状态；均返回 Status JSON，加载失败时状态码为 422
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	code := http.StatusOK
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		err := r.Reload("api")
+		r.report("api", err)
+		if err != nil {
+			code = http.StatusUnprocessableEntity
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(r.Status())
+}
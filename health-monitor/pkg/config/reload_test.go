@@ -0,0 +1,200 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReloadAtomic 测试任一文件校验失败时不切换任何配置
+func TestReloadAtomic(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	b := filepath.Join(dir, "b.json")
+	writeFile(t, a, "a1")
+	writeFile(t, b, "b1")
+
+	var applied []string
+	load := func(path string) (func(), error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(string(data), "bad") {
+			return nil, errors.New("invalid")
+		}
+		return func() { applied = append(applied, string(data)) }, nil
+	}
+
+	r := NewReloader()
+	r.Add("a", a, load)
+	r.Add("b", b, load)
+	r.Add("unused", "", load)
+	if r.Len() != 2 {
+		t.Fatalf("空路径应忽略: %d", r.Len())
+	}
+	if err := r.Reload("startup"); err != nil || strings.Join(applied, ",") != "a1,b1" {
+		t.Fatalf("首次加载: %v %v", err, applied)
+	}
+
+	applied = nil
+	writeFile(t, a, "a2")
+	writeFile(t, b, "bad")
+	if err := r.Reload("test"); err == nil || len(applied) != 0 {
+		t.Fatalf("b 失败时 a 不应切换: %v %v", err, applied)
+	}
+	st := r.Status()
+	if st.Reloads != 1 || st.Failures != 1 || !strings.Contains(st.LastError, "b:") {
+		t.Errorf("状态不符: %+v", st)
+	}
+
+	writeFile(t, b, "b2")
+	if err := r.Reload("test"); err != nil || strings.Join(applied, ",") != "a2,b2" {
+		t.Fatalf("修复后应全部切换: %v %v", err, applied)
+	}
+	if st := r.Status(); st.LastError != "" || st.Reloads != 2 || len(st.Sources) != 2 {
+		t.Errorf("状态不符: %+v", st)
+	}
+}
+
+// TestReloadWatch 测试文件变化检测
+func TestReloadWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	writeFile(t, path, "v1")
+
+	loaded := make(chan string, 4)
+	r := NewReloader()
+	r.Add("rules", path, func(path string) (func(), error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return func() { loaded <- string(data) }, nil
+	})
+	if err := r.Reload("startup"); err != nil {
+		t.Fatal(err)
+	}
+	<-loaded
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	// 内容长度变化，修改时间精度不足时同样可检测
+	writeFile(t, path, "v2-longer")
+	select {
+	case v := <-loaded:
+		if v != "v2-longer" {
+			t.Errorf("加载内容不符: %s", v)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("未检测到文件变化")
+	}
+	select {
+	case v := <-loaded:
+		t.Errorf("文件未变化不应重复加载: %s", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestReloadWatchFailure 测试加载失败后文件未再修改时不重复加载，修复后全部切换
+func TestReloadWatchFailure(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	b := filepath.Join(dir, "b.json")
+	writeFile(t, a, "a1")
+	writeFile(t, b, "b1")
+
+	loaded := make(chan string, 8)
+	load := func(path string) (func(), error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(string(data), "bad") {
+			return nil, errors.New("invalid")
+		}
+		return func() { loaded <- string(data) }, nil
+	}
+	r := NewReloader()
+	r.Add("a", a, load)
+	r.Add("b", b, load)
+	if err := r.Reload("startup"); err != nil {
+		t.Fatal(err)
+	}
+	<-loaded
+	<-loaded
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	// a 校验通过、b 失败：a 的新状态同样记录，之后的检查不再重复加载
+	writeFile(t, a, "a2")
+	writeFile(t, b, "bad")
+	time.Sleep(200 * time.Millisecond)
+	if st := r.Status(); st.Failures != 1 || st.Reloads != 1 {
+		t.Fatalf("文件未再修改时不应重复加载: %+v", st)
+	}
+
+	writeFile(t, b, "b2-fixed")
+	deadline := time.After(2 * time.Second)
+	var got []string
+	for len(got) < 2 {
+		select {
+		case v := <-loaded:
+			got = append(got, v)
+		case <-deadline:
+			t.Fatalf("修复后应重新加载: %v", got)
+		}
+	}
+	if strings.Join(got, ",") != "a2,b2-fixed" {
+		t.Errorf("加载内容不符: %v", got)
+	}
+}
+
+// TestReloadHTTP 测试管理接口
+func TestReloadHTTP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.json")
+	writeFile(t, path, "ok")
+	fail := false
+	r := NewReloader()
+	r.Add("x", path, func(string) (func(), error) {
+		if fail {
+			return nil, errors.New("invalid")
+		}
+		return nil, nil
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/reload", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"reloads":1`) {
+		t.Errorf("POST: %d %s", rec.Code, rec.Body.String())
+	}
+
+	fail = true
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/reload", nil))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "invalid") {
+		t.Errorf("失败时应返回 422: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/reload", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: %d", rec.Code)
+	}
+}
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLToJSON 将 YAML 文档转为 JSON
+// 告警规则、报文布局等配置的 YAML 格式经此转换后按 JSON 统一解析，保证两种格式的字段与校验一致
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
@@ -15,6 +15,7 @@ type Dispatcher struct {
 	extractor    *Extractor
 	generator    *alert.Generator
 	stateManager *state.StateManager
+	health       *alert.HealthScorer // 健康分计算（可选）
 }
 
 func NewDispatcher(fetcher *Fetcher, stateManager *state.StateManager) *Dispatcher {
@@ -31,6 +32,31 @@ func (d *Dispatcher) SetDiagnosisReceiver(receiver alert.DiagnosisReceiver) {
 	d.generator.SetDiagnosisReceiver(receiver)
 }
 
+// SetSilences 设置告警静默规则
+func (d *Dispatcher) SetSilences(store *alert.SilenceStore) {
+	d.generator.SetSilences(store)
+}
+
+// SetCorrelator 设置告警关联器（业务层与微服务层共用）
+func (d *Dispatcher) SetCorrelator(c *alert.Correlator) {
+	d.generator.SetCorrelator(c)
+}
+
+// SetHealthScorer 设置健康分计算器，每轮采集后计算各节点、容器、服务的健康分
+func (d *Dispatcher) SetHealthScorer(h *alert.HealthScorer) {
+	d.health = h
+}
+
+// RulesChanged 告警规则集切换后调用，解除已删除/停用的节点、容器、服务规则的活跃告警
+func (d *Dispatcher) RulesChanged(old, next *alert.RuleSet) {
+	d.generator.ApplyRuleChange(old, next, alert.LayerNode, alert.LayerContainer, alert.LayerService)
+}
+
+// CheckTelemetryLoss 检查各节点、容器、服务的 ECSM 指标是否中断（由 monitor 定期调用）
+func (d *Dispatcher) CheckTelemetryLoss(ctx context.Context, cfg *alert.WatchdogConfig) {
+	d.generator.ProcessTelemetryLoss(ctx, cfg, alert.LayerNode, alert.LayerContainer, alert.LayerService)
+}
+
 func (d *Dispatcher) RunOnce(ctx context.Context) (*model.MicroServiceMetricsSet, error) {
 	raw, err := d.fetcher.GatherRawMetrics(ctx)
 	if err != nil {
@@ -45,6 +71,8 @@ func (d *Dispatcher) RunOnce(ctx context.Context) (*model.MicroServiceMetricsSet
 		if err := d.saveToStateManager(metrics); err != nil {
 			fmt.Printf("[Dispatcher] 保存到StateManager失败: %v\n", err)
 		}
+		// 已从采集结果中消失的实体不再判定遥测中断
+		d.generator.ProcessVanishedEntities(ctx, metrics)
 	}
 	
 	// 2. 发送到告警生成器进行阈值检查
@@ -52,9 +80,14 @@ func (d *Dispatcher) RunOnce(ctx context.Context) (*model.MicroServiceMetricsSet
 		d.generator.ProcessMicroserviceMetrics(ctx, metrics)
 	}
 	
+	// 3. 健康分计算（系统健康分由 monitor 定期汇总）
+	if d.health != nil && d.stateManager != nil {
+		d.health.Score(time.Now(), alert.LayerNode, alert.LayerContainer, alert.LayerService)
+	}
+	
 	// TODO: 其他处理
-	// 3. 发送到数据库
-	// 4. 推送到可视化平台
+	// 4. 发送到数据库
+	// 5. 推送到可视化平台
 	
 	return metrics, nil
 }
@@ -91,6 +91,8 @@ func (e *Extractor) ExtractContainerMetrics(containers []ContainerInfo) []model.
 			SizeLimit:      c.SizeLimit,
 			ServiceID:      c.ServiceID,
 			ServiceName:    c.ServiceName,
+			NodeID:         c.NodeID,
+			NodeName:       c.NodeName,
 		})
 	}
 
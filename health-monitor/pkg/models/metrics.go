@@ -72,6 +72,8 @@ type ContainerMetrics struct {
 	SizeLimit       int64
 	ServiceID     string
 	ServiceName   string
+	NodeID        string // 所在节点
+	NodeName      string
 }
 
 // ---------------- Service ----------------
@@ -89,11 +91,36 @@ type ServiceMetrics struct {
 }
 
 // ---------------- Business ----------------
+// 业务指标时间来源
+const (
+	TimeSourceOnboard = "onboard" // 星上时间码
+	TimeSourceReceive = "receive" // 地面接收时间
+)
+
 // BusinessMetrics 业务层健康监测指标基础结构
 type BusinessMetrics struct {
 	ComponentType uint8                  // 组件类型编号
-	Timestamp     int64                  // 时间戳
+	Timestamp     int64                  // 测量时间（Unix 秒），携带星上时间码时为星上时间
+	TimestampNano int64                  // 测量时间（Unix 纳秒，亚秒精度）
+	ReceiveTime   int64                  // 地面接收时间（Unix 纳秒）
+	TimeSource    string                 // 测量时间来源：onboard / receive
 	Data          interface{}            // 具体组件的指标数据
+	Raw           map[string]float64     // 各字段标定前的原始值（字段名 -> 原始值），用于事后分析
+}
+
+// LinkQuality 业务报文链路质量（按组件统计序列计数）
+type LinkQuality struct {
+	ComponentType uint8   `json:"component_type"` // 组件类型编号
+	Received      uint64  `json:"received"`       // 收到的不重复报文数
+	Lost          uint64  `json:"lost"`           // 序列计数缺失（扣除迟到补回）
+	Duplicates    uint64  `json:"duplicates"`     // 重复报文数
+	Reordered     uint64  `json:"reordered"`      // 乱序（迟到）报文数
+	Wraps         uint64  `json:"wraps"`          // 计数回绕次数
+	Resets        uint64  `json:"resets"`         // 计数复位次数（大幅回退）
+	LastSeq       uint32  `json:"last_seq"`       // 最近收到的序列计数
+	LossRate      float64 `json:"loss_rate"`      // 最近一个评估窗口的丢包率
+	Threshold     float64 `json:"threshold"`      // 丢包率告警阈值
+	LastUpdate    int64   `json:"last_update"`    // 最近更新时间（Unix 秒）
 }
 
 // ========== 供电服务检测指标 ==========
@@ -0,0 +1,141 @@
+/* 统计异常检测基线
+
+alert/anomaly.go 为每个检测器、每个来源维护一份在线基线（EWMA 均值/方差、CUSUM 累积和），
+alert/orbit.go 按轨道相位学习期望包络。基线需要数小时到数天的数据才能学到，
+进程重启后重新学习会丢失对缓慢漂移的检测能力，因此由 StateManager 保存：
+
+	UpdateAnomalyBaseline / UpdateOrbitBaseline   在锁内读取并更新一份基线（不存在时以空基线调用）
+	GetAnomalyBaselines / GetOrbitBaselines       列出全部基线（供查询接口与快照）
+	ResetAnomalyBaselines / ResetOrbitBaselines   删除基线，下次评估时重新学习
+
+基线随 SaveSnapshot 写入快照，LoadSnapshot 时恢复。
+*/
+package state
+
+import "sort"
+
+func baselineKey(alertID, source string) string {
+	return alertID + "|" + source
+}
+
+// UpdateAnomalyBaseline 在锁内更新告警ID+来源的基线，返回更新后的副本
+func (sm *StateManager) UpdateAnomalyBaseline(alertID, source string, update func(b *AnomalyBaseline)) AnomalyBaseline {
+	sm.baselineMutex.Lock()
+	defer sm.baselineMutex.Unlock()
+
+	key := baselineKey(alertID, source)
+	b, ok := sm.baselines[key]
+	if !ok {
+		b = &AnomalyBaseline{AlertID: alertID, Source: source}
+	}
+	update(b)
+	if b.Count > 0 {
+		sm.baselines[key] = b
+	}
+	return *b
+}
+
+// GetAnomalyBaselines 获取全部基线，按告警ID、来源排序
+func (sm *StateManager) GetAnomalyBaselines() []AnomalyBaseline {
+	sm.baselineMutex.Lock()
+	defer sm.baselineMutex.Unlock()
+
+	baselines := make([]AnomalyBaseline, 0, len(sm.baselines))
+	for _, b := range sm.baselines {
+		baselines = append(baselines, *b)
+	}
+	sort.Slice(baselines, func(i, j int) bool {
+		if baselines[i].AlertID != baselines[j].AlertID {
+			return baselines[i].AlertID < baselines[j].AlertID
+		}
+		return baselines[i].Source < baselines[j].Source
+	})
+	return baselines
+}
+
+// ResetAnomalyBaselines 删除基线：alertID 为空时删除全部，source 为空时删除该告警ID的全部来源；返回删除的数量
+func (sm *StateManager) ResetAnomalyBaselines(alertID, source string) int {
+	sm.baselineMutex.Lock()
+	defer sm.baselineMutex.Unlock()
+
+	n := 0
+	for key, b := range sm.baselines {
+		if (alertID == "" || b.AlertID == alertID) && (source == "" || b.Source == source) {
+			delete(sm.baselines, key)
+			n++
+		}
+	}
+	return n
+}
+
+// restoreAnomalyBaselines 按快照恢复基线（已有的同名基线被覆盖）
+func (sm *StateManager) restoreAnomalyBaselines(baselines []AnomalyBaseline) {
+	sm.baselineMutex.Lock()
+	defer sm.baselineMutex.Unlock()
+
+	for i := range baselines {
+		b := baselines[i]
+		sm.baselines[baselineKey(b.AlertID, b.Source)] = &b
+	}
+}
+
+// UpdateOrbitBaseline 在锁内更新告警ID+来源的轨道周期基线，返回更新后的副本；更新后 Field 为空时不保存
+func (sm *StateManager) UpdateOrbitBaseline(alertID, source string, update func(b *OrbitBaseline)) OrbitBaseline {
+	sm.baselineMutex.Lock()
+	defer sm.baselineMutex.Unlock()
+
+	key := baselineKey(alertID, source)
+	b, ok := sm.orbitBaselines[key]
+	if !ok {
+		b = &OrbitBaseline{AlertID: alertID, Source: source}
+	}
+	update(b)
+	if b.Field != "" {
+		sm.orbitBaselines[key] = b
+	}
+	return b.clone()
+}
+
+// GetOrbitBaselines 获取全部轨道周期基线，按告警ID、来源排序
+func (sm *StateManager) GetOrbitBaselines() []OrbitBaseline {
+	sm.baselineMutex.Lock()
+	defer sm.baselineMutex.Unlock()
+
+	baselines := make([]OrbitBaseline, 0, len(sm.orbitBaselines))
+	for _, b := range sm.orbitBaselines {
+		baselines = append(baselines, b.clone())
+	}
+	sort.Slice(baselines, func(i, j int) bool {
+		if baselines[i].AlertID != baselines[j].AlertID {
+			return baselines[i].AlertID < baselines[j].AlertID
+		}
+		return baselines[i].Source < baselines[j].Source
+	})
+	return baselines
+}
+
+// ResetOrbitBaselines 删除轨道周期基线，参数含义同 ResetAnomalyBaselines；返回删除的数量
+func (sm *StateManager) ResetOrbitBaselines(alertID, source string) int {
+	sm.baselineMutex.Lock()
+	defer sm.baselineMutex.Unlock()
+
+	n := 0
+	for key, b := range sm.orbitBaselines {
+		if (alertID == "" || b.AlertID == alertID) && (source == "" || b.Source == source) {
+			delete(sm.orbitBaselines, key)
+			n++
+		}
+	}
+	return n
+}
+
+// restoreOrbitBaselines 按快照恢复轨道周期基线
+func (sm *StateManager) restoreOrbitBaselines(baselines []OrbitBaseline) {
+	sm.baselineMutex.Lock()
+	defer sm.baselineMutex.Unlock()
+
+	for i := range baselines {
+		b := baselines[i].clone()
+		sm.orbitBaselines[baselineKey(b.AlertID, b.Source)] = &b
+	}
+}
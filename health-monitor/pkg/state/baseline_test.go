@@ -0,0 +1,87 @@
+package state
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestAnomalyBaselines 测试基线的更新、排序、按条件删除与快照恢复
+func TestAnomalyBaselines(t *testing.T) {
+	sm := newTestManager(t)
+	learn := func(alertID, source string, v float64) AnomalyBaseline {
+		return sm.UpdateAnomalyBaseline(alertID, source, func(b *AnomalyBaseline) {
+			b.Count++
+			b.Mean += (v - b.Mean) / float64(b.Count)
+		})
+	}
+
+	learn("B", "s1", 10)
+	if b := learn("B", "s1", 20); b.Count != 2 || b.Mean != 15 || b.AlertID != "B" || b.Source != "s1" {
+		t.Errorf("基线更新不符: %+v", b)
+	}
+	learn("A", "s2", 1)
+	learn("A", "s1", 1)
+	// 未学到样本的基线不保存
+	sm.UpdateAnomalyBaseline("C", "", func(b *AnomalyBaseline) {})
+
+	var keys []string
+	for _, b := range sm.GetAnomalyBaselines() {
+		keys = append(keys, b.AlertID+"/"+b.Source)
+	}
+	if !reflect.DeepEqual(keys, []string{"A/s1", "A/s2", "B/s1"}) {
+		t.Errorf("基线列表不符: %v", keys)
+	}
+
+	data, err := json.Marshal(sm.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot StateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	restored := newTestManager(t)
+	restored.RestoreSnapshot(&snapshot)
+	if !reflect.DeepEqual(restored.GetAnomalyBaselines(), sm.GetAnomalyBaselines()) {
+		t.Errorf("基线恢复不符: %+v", restored.GetAnomalyBaselines())
+	}
+
+	if n := sm.ResetAnomalyBaselines("A", "s1"); n != 1 {
+		t.Errorf("按告警ID与来源删除: 删除 %d 个", n)
+	}
+	if n := sm.ResetAnomalyBaselines("A", ""); n != 1 {
+		t.Errorf("按告警ID删除: 删除 %d 个", n)
+	}
+	if n := sm.ResetAnomalyBaselines("", ""); n != 1 || len(sm.GetAnomalyBaselines()) != 0 {
+		t.Errorf("删除全部: 删除 %d 个", n)
+	}
+}
+
+// TestOrbitBaselines 测试轨道周期基线返回深拷贝，Field 为空时不保存
+func TestOrbitBaselines(t *testing.T) {
+	sm := newTestManager(t)
+	sm.UpdateOrbitBaseline("ORBIT", "", func(b *OrbitBaseline) {})
+	if len(sm.GetOrbitBaselines()) != 0 {
+		t.Fatal("Field 为空时不应保存")
+	}
+
+	b := sm.UpdateOrbitBaseline("ORBIT", "0x06", func(b *OrbitBaseline) {
+		b.Field = "ThermalTemps[0]"
+		b.Period = 5400
+		b.Bins = append(b.Bins, PhaseBin{Count: 1, Mean: 20})
+	})
+	b.Bins[0].Mean = 99
+	got := sm.GetOrbitBaselines()
+	if len(got) != 1 || got[0].Period != 5400 || got[0].Bins[0].Mean != 20 {
+		t.Fatalf("返回的基线应为深拷贝: %+v", got)
+	}
+	got[0].Bins[0].Mean = 99
+	if sm.GetOrbitBaselines()[0].Bins[0].Mean != 20 {
+		t.Error("修改查询结果不应影响保存的基线")
+	}
+
+	if n := sm.ResetOrbitBaselines("", "0x06"); n != 1 || len(sm.GetOrbitBaselines()) != 0 {
+		t.Errorf("按来源删除: 删除 %d 个", n)
+	}
+}
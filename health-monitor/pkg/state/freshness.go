@@ -0,0 +1,189 @@
+/* 数据新鲜度
+
+latestStates 只保存最后一次收到的值，数据源停止上报后仍会一直返回该值。
+为各类指标设置超时时长（期望上报周期 × 允许缺失的周期数，由 alert.WatchdogConfig 换算）后：
+
+	UpdateMetric     记录收到指标的本地时间（不受测量时间戳、时钟偏差影响）
+	GetLatestState   超时未更新的指标返回 Stale=true 的副本（已保存的最新状态不变）
+	CheckFreshness   列出全部受监视的指标及其是否超时，供看门狗产生遥测中断告警
+
+超时时长按 指标类型+ID 设置，ID 为空时作用于该类型的全部指标（业务层 ID 为组件编号，同 GetLatestState）。
+只监视收到过数据的指标：从未上报的组件/实体不判定。
+ECSM 实体从采集结果中消失（如容器重新部署后ID变化）时由 PruneEntities 删除，不再判定。
+*/
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FreshnessState 一个受监视指标的新鲜度
+type FreshnessState struct {
+	Type      MetricType `json:"type"`
+	ID        string     `json:"id,omitempty"`        // 实体ID（微服务层）
+	Component uint8      `json:"component,omitempty"` // 业务组件编号（业务层）
+	LastSeen  int64      `json:"last_seen"`           // 最近一次收到的本地时间
+	Age       float64    `json:"age"`                 // 距最近一次收到的秒数
+	Timeout   float64    `json:"timeout"`             // 超时时长（秒）
+	Stale     bool       `json:"stale"`               // 是否已超时
+}
+
+// staleMarker 可标记为超时的指标包装
+type staleMarker interface {
+	markStale() Metric
+	IsStale() bool
+}
+
+func (m NodeMetric) markStale() Metric      { m.Stale = true; return &m }
+func (m ContainerMetric) markStale() Metric { m.Stale = true; return &m }
+func (m ServiceMetric) markStale() Metric   { m.Stale = true; return &m }
+func (m BusinessMetric) markStale() Metric  { m.Stale = true; return &m }
+func (m DerivedMetric) markStale() Metric   { m.Stale = true; return &m }
+
+func (m *NodeMetric) IsStale() bool      { return m.Stale }
+func (m *ContainerMetric) IsStale() bool { return m.Stale }
+func (m *ServiceMetric) IsStale() bool   { return m.Stale }
+func (m *BusinessMetric) IsStale() bool  { return m.Stale }
+func (m *DerivedMetric) IsStale() bool   { return m.Stale }
+
+// IsStale 查询结果是否已超时未更新
+func IsStale(m Metric) bool {
+	s, ok := m.(staleMarker)
+	return ok && s.IsStale()
+}
+
+// markStale 返回带超时标记的副本
+func markStale(m Metric) Metric {
+	if s, ok := m.(staleMarker); ok {
+		return s.markStale()
+	}
+	return m
+}
+
+// SetStaleTimeout 设置指标的超时时长，id 为空时作用于该类型的全部指标；timeout <= 0 取消
+func (sm *StateManager) SetStaleTimeout(metricType MetricType, id string, timeout time.Duration) {
+	key := fmt.Sprintf("%s:%s", metricType, id)
+	sm.freshMutex.Lock()
+	defer sm.freshMutex.Unlock()
+	if timeout <= 0 {
+		delete(sm.staleTimeouts, key)
+		return
+	}
+	sm.staleTimeouts[key] = timeout
+}
+
+// ClearStaleTimeouts 取消全部超时设置（重新加载看门狗配置前调用）
+func (sm *StateManager) ClearStaleTimeouts() {
+	sm.freshMutex.Lock()
+	defer sm.freshMutex.Unlock()
+	sm.staleTimeouts = make(map[string]time.Duration)
+}
+
+// touch 记录收到指标的本地时间
+func (sm *StateManager) touch(key string) {
+	sm.freshMutex.Lock()
+	sm.lastSeen[key] = time.Now()
+	sm.freshMutex.Unlock()
+}
+
+// staleTimeout 指标的超时时长（先按 类型+ID，再按类型），未设置时返回 false；调用方持有 freshMutex
+func (sm *StateManager) staleTimeout(key string) (time.Duration, bool) {
+	if t, ok := sm.staleTimeouts[key]; ok {
+		return t, true
+	}
+	if i := strings.IndexByte(key, ':'); i >= 0 {
+		t, ok := sm.staleTimeouts[key[:i+1]]
+		return t, ok
+	}
+	return 0, false
+}
+
+// isStale 指标是否超时未更新
+func (sm *StateManager) isStale(key string, now time.Time) bool {
+	sm.freshMutex.RLock()
+	defer sm.freshMutex.RUnlock()
+	timeout, ok := sm.staleTimeout(key)
+	if !ok {
+		return false
+	}
+	seen, ok := sm.lastSeen[key]
+	return ok && now.Sub(seen) > timeout
+}
+
+// PruneEntities 删除 metricType 类型中不在 ids 内的实体的最新状态与新鲜度记录，返回被删除的ID（已排序）
+// 用于 ECSM 实体从最新采集结果中消失的情况，避免已不存在的实体一直被判定为遥测中断
+func (sm *StateManager) PruneEntities(metricType MetricType, ids []string) []string {
+	keep := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		keep[fmt.Sprintf("%s:%s", metricType, id)] = true
+	}
+	prefix := string(metricType) + ":"
+
+	// 与 GetLatestState 相同的加锁顺序：statesMutex → freshMutex
+	sm.statesMutex.Lock()
+	defer sm.statesMutex.Unlock()
+	sm.freshMutex.Lock()
+	defer sm.freshMutex.Unlock()
+
+	removed := make(map[string]bool)
+	for key := range sm.latestStates {
+		if strings.HasPrefix(key, prefix) && !keep[key] {
+			delete(sm.latestStates, key)
+			removed[key] = true
+		}
+	}
+	for key := range sm.lastSeen {
+		if strings.HasPrefix(key, prefix) && !keep[key] {
+			delete(sm.lastSeen, key)
+			removed[key] = true
+		}
+	}
+
+	var result []string
+	for key := range removed {
+		result = append(result, key[len(prefix):])
+	}
+	sort.Strings(result)
+	return result
+}
+
+// CheckFreshness 列出设置了超时时长、且收到过数据的指标的新鲜度（按类型、ID 排序）
+func (sm *StateManager) CheckFreshness(now time.Time) []FreshnessState {
+	sm.freshMutex.RLock()
+	defer sm.freshMutex.RUnlock()
+
+	var states []FreshnessState
+	for key, seen := range sm.lastSeen {
+		timeout, ok := sm.staleTimeout(key)
+		if !ok {
+			continue
+		}
+		i := strings.IndexByte(key, ':')
+		f := FreshnessState{
+			Type:     MetricType(key[:i]),
+			LastSeen: seen.Unix(),
+			Age:      now.Sub(seen).Seconds(),
+			Timeout:  timeout.Seconds(),
+			Stale:    now.Sub(seen) > timeout,
+		}
+		if id := key[i+1:]; f.Type == MetricTypeBusiness && len([]rune(id)) == 1 {
+			f.Component = uint8([]rune(id)[0])
+		} else {
+			f.ID = id
+		}
+		states = append(states, f)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Type != states[j].Type {
+			return states[i].Type < states[j].Type
+		}
+		if states[i].Component != states[j].Component {
+			return states[i].Component < states[j].Component
+		}
+		return states[i].ID < states[j].ID
+	})
+	return states
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"health-monitor/pkg/models"
+)
+
+// TestFreshness 测试按类型与按ID的超时设置、过期标记和新鲜度列表
+func TestFreshness(t *testing.T) {
+	sm := newTestManager(t)
+	sm.SetStaleTimeout(MetricTypeNode, "", time.Minute)
+	sm.SetStaleTimeout(MetricTypeNode, "n2", time.Hour)
+
+	sm.UpdateMetric(&NodeMetric{Data: &model.NodeMetrics{ID: "n1"}})
+	sm.UpdateMetric(&NodeMetric{Data: &model.NodeMetrics{ID: "n2"}})
+	// 未设置超时的类型不监视
+	sm.UpdateMetric(&ServiceMetric{Data: &model.ServiceMetrics{ID: "s1"}})
+
+	later := time.Now().Add(2 * time.Minute)
+	fresh := sm.CheckFreshness(later)
+	if len(fresh) != 2 || fresh[0].ID != "n1" || !fresh[0].Stale || fresh[0].Timeout != 60 || fresh[1].ID != "n2" || fresh[1].Stale {
+		t.Fatalf("新鲜度列表不符: %+v", fresh)
+	}
+
+	if m, _ := sm.GetLatestState(MetricTypeNode, "n1"); IsStale(m) {
+		t.Error("未超时的指标不应标记过期")
+	}
+	sm.SetStaleTimeout(MetricTypeNode, "", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	m, ok := sm.GetLatestState(MetricTypeNode, "n1")
+	if nm, isNM := m.(*NodeMetric); !ok || !isNM || !nm.Stale || nm.Data.ID != "n1" {
+		t.Fatalf("超时的指标应返回带 Stale 标记的副本: %+v", m)
+	}
+	if m, _ := sm.GetLatestState(MetricTypeNode, "n2"); IsStale(m) {
+		t.Error("按ID设置的超时优先于按类型的设置")
+	}
+
+	sm.ClearStaleTimeouts()
+	if fresh := sm.CheckFreshness(later); len(fresh) != 0 {
+		t.Errorf("取消超时设置后不应监视: %+v", fresh)
+	}
+}
+
+// TestPruneEntities 测试删除已从采集结果中消失的实体
+func TestPruneEntities(t *testing.T) {
+	sm := newTestManager(t)
+	sm.SetStaleTimeout(MetricTypeContainer, "", time.Minute)
+	for _, id := range []string{"c1", "c2", "c3"} {
+		sm.UpdateMetric(&ContainerMetric{Data: &model.ContainerMetrics{ID: id}})
+	}
+	sm.UpdateMetric(&NodeMetric{Data: &model.NodeMetrics{ID: "c1"}})
+
+	if removed := sm.PruneEntities(MetricTypeContainer, []string{"c2"}); !reflect.DeepEqual(removed, []string{"c1", "c3"}) {
+		t.Fatalf("删除的实体不符: %v", removed)
+	}
+	if _, ok := sm.GetLatestState(MetricTypeContainer, "c1"); ok {
+		t.Error("已删除实体的最新状态应删除")
+	}
+	if _, ok := sm.GetLatestState(MetricTypeNode, "c1"); !ok {
+		t.Error("其他类型的同名实体不应删除")
+	}
+	if fresh := sm.CheckFreshness(time.Now()); len(fresh) != 1 || fresh[0].ID != "c2" {
+		t.Errorf("新鲜度列表不符: %+v", fresh)
+	}
+	if removed := sm.PruneEntities(MetricTypeContainer, []string{"c2"}); len(removed) != 0 {
+		t.Errorf("重复清理不应再删除: %v", removed)
+	}
+}
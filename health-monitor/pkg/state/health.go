@@ -0,0 +1,214 @@
+/* 健康分存储
+
+alert/health.go 按触发中告警、限值裕度、趋势预测、数据新鲜度计算各业务组件、ECSM 节点/容器/服务的健康分，
+并按权重汇总为系统健康分。计算所需的告警归属与计算结果由 StateManager 保存：
+
+	RecordAlert / GetFiringAlerts            触发中的告警及其所属实体（alert.Generator 在输出告警时记录，恢复时删除）
+	UpdateHealthScore                        保存最新健康分并追加历史（每个记录间隔保留最低分，超过保留时长的删除）
+	GetHealthScores / GetHealthScore         查询最新健康分（报表、修复节点选择）
+	GetHealthHistory                         查询健康分历史
+
+健康分及其历史随 SaveSnapshot 写入快照，LoadSnapshot 时恢复。
+*/
+package state
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// 健康分历史默认保留时长
+	DefaultHealthRetention = 24 * time.Hour
+
+	// 健康分历史默认记录间隔
+	DefaultHealthInterval = time.Minute
+
+	// 系统健康分的类别
+	HealthKindSystem = "system"
+)
+
+// healthEntry 一个实体的最新健康分与历史
+type healthEntry struct {
+	score   HealthScore
+	history []HealthPoint
+}
+
+func healthKey(kind, id string) string {
+	return kind + "/" + id
+}
+
+// RecordAlert 记录告警的触发/恢复：触发时保存（保留开始触发的时间），恢复时删除
+func (sm *StateManager) RecordAlert(a ActiveAlert, firing bool) {
+	key := sm.alertKey(a.AlertID, a.Source)
+
+	sm.alertMutex.Lock()
+	defer sm.alertMutex.Unlock()
+
+	if !firing {
+		delete(sm.activeAlerts, key)
+		return
+	}
+	if prev, ok := sm.activeAlerts[key]; ok {
+		a.Since = prev.Since
+	} else if a.Since == 0 {
+		a.Since = a.Updated
+	}
+	sm.activeAlerts[key] = &a
+}
+
+// GetFiringAlerts 获取触发中的告警，layer 为空时返回全部；按层级、实体、告警ID、来源排序
+func (sm *StateManager) GetFiringAlerts(layer string) []ActiveAlert {
+	sm.alertMutex.RLock()
+	defer sm.alertMutex.RUnlock()
+
+	var alerts []ActiveAlert
+	for _, a := range sm.activeAlerts {
+		if layer == "" || a.Layer == layer {
+			alerts = append(alerts, *a)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		a, b := alerts[i], alerts[j]
+		if a.Layer != b.Layer {
+			return a.Layer < b.Layer
+		}
+		if a.Entity != b.Entity {
+			return a.Entity < b.Entity
+		}
+		if a.AlertID != b.AlertID {
+			return a.AlertID < b.AlertID
+		}
+		return a.Source < b.Source
+	})
+	return alerts
+}
+
+// SetHealthHistory 设置健康分历史的保留时长与记录间隔（<= 0 时使用默认值）
+func (sm *StateManager) SetHealthHistory(retention, interval time.Duration) {
+	if retention <= 0 {
+		retention = DefaultHealthRetention
+	}
+	if interval <= 0 {
+		interval = DefaultHealthInterval
+	}
+	sm.healthMutex.Lock()
+	defer sm.healthMutex.Unlock()
+	sm.healthRetention, sm.healthInterval = retention, interval
+}
+
+// UpdateHealthScore 保存最新健康分并追加历史
+func (sm *StateManager) UpdateHealthScore(score HealthScore) {
+	sm.healthMutex.Lock()
+	defer sm.healthMutex.Unlock()
+
+	key := healthKey(score.Kind, score.ID)
+	e, ok := sm.health[key]
+	if !ok {
+		e = &healthEntry{}
+		sm.health[key] = e
+	}
+	e.score = score
+
+	interval := int64(sm.healthInterval / time.Second)
+	if interval < 1 {
+		interval = 1
+	}
+	bucket := score.Timestamp - score.Timestamp%interval
+	if n := len(e.history); n > 0 && e.history[n-1].T >= bucket {
+		// 同一记录间隔内保留最低分（乱序到达的早期评估也计入最后一个间隔）
+		if score.Score < e.history[n-1].Score {
+			e.history[n-1].Score = score.Score
+		}
+	} else {
+		e.history = append(e.history, HealthPoint{T: bucket, Score: score.Score})
+	}
+
+	cutoff := score.Timestamp - int64(sm.healthRetention/time.Second)
+	k := 0
+	for k < len(e.history) && e.history[k].T < cutoff {
+		k++
+	}
+	if k > 0 {
+		e.history = append(e.history[:0], e.history[k:]...)
+	}
+}
+
+// GetHealthScores 获取最新健康分，kind 为空时返回全部；按类别、ID 排序
+func (sm *StateManager) GetHealthScores(kind string) []HealthScore {
+	sm.healthMutex.RLock()
+	defer sm.healthMutex.RUnlock()
+
+	var scores []HealthScore
+	for _, e := range sm.health {
+		if kind == "" || e.score.Kind == kind {
+			scores = append(scores, e.score)
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Kind != scores[j].Kind {
+			return scores[i].Kind < scores[j].Kind
+		}
+		return scores[i].ID < scores[j].ID
+	})
+	return scores
+}
+
+// GetHealthScore 获取一个实体的最新健康分（系统健康分 kind 为 system、id 为空）
+func (sm *StateManager) GetHealthScore(kind, id string) (HealthScore, bool) {
+	sm.healthMutex.RLock()
+	defer sm.healthMutex.RUnlock()
+
+	e, ok := sm.health[healthKey(kind, id)]
+	if !ok {
+		return HealthScore{}, false
+	}
+	return e.score, true
+}
+
+// GetHealthHistory 获取一个实体最近 duration 内的健康分历史（以最新一次评估时间为准）
+func (sm *StateManager) GetHealthHistory(kind, id string, duration time.Duration) []HealthPoint {
+	sm.healthMutex.RLock()
+	defer sm.healthMutex.RUnlock()
+
+	e, ok := sm.health[healthKey(kind, id)]
+	if !ok {
+		return nil
+	}
+	cutoff := e.score.Timestamp - int64(duration/time.Second)
+	var points []HealthPoint
+	for _, p := range e.history {
+		if p.T >= cutoff {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+// healthSeries 导出全部健康分及其历史（快照用）
+func (sm *StateManager) healthSeries() []HealthSeries {
+	sm.healthMutex.RLock()
+	defer sm.healthMutex.RUnlock()
+
+	series := make([]HealthSeries, 0, len(sm.health))
+	for _, e := range sm.health {
+		series = append(series, HealthSeries{HealthScore: e.score, History: append([]HealthPoint(nil), e.history...)})
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].Kind != series[j].Kind {
+			return series[i].Kind < series[j].Kind
+		}
+		return series[i].ID < series[j].ID
+	})
+	return series
+}
+
+// restoreHealth 按快照恢复健康分及其历史（已有的同名实体被覆盖）
+func (sm *StateManager) restoreHealth(series []HealthSeries) {
+	sm.healthMutex.Lock()
+	defer sm.healthMutex.Unlock()
+
+	for _, s := range series {
+		sm.health[healthKey(s.Kind, s.ID)] = &healthEntry{score: s.HealthScore, history: append([]HealthPoint(nil), s.History...)}
+	}
+}
@@ -0,0 +1,63 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestRecordAlert 测试触发中告警的记录、开始时间保留与按层级查询
+func TestRecordAlert(t *testing.T) {
+	sm := newTestManager(t)
+	sm.RecordAlert(ActiveAlert{AlertID: "CPU", Source: "n1", Layer: "node", Entity: "n1", Severity: "warning", Updated: 100}, true)
+	sm.RecordAlert(ActiveAlert{AlertID: "CPU", Source: "n1", Layer: "node", Entity: "n1", Severity: "critical", Updated: 160}, true)
+	sm.RecordAlert(ActiveAlert{AlertID: "BUS_V", Layer: "business", Entity: "0x03", Updated: 120}, true)
+
+	node := sm.GetFiringAlerts("node")
+	if len(node) != 1 || node[0].Since != 100 || node[0].Updated != 160 || node[0].Severity != "critical" {
+		t.Fatalf("再次触发应保留开始时间并更新严重程度: %+v", node)
+	}
+	if all := sm.GetFiringAlerts(""); len(all) != 2 || all[0].AlertID != "BUS_V" {
+		t.Errorf("全部触发中告警应按层级排序: %+v", all)
+	}
+
+	sm.RecordAlert(ActiveAlert{AlertID: "CPU", Source: "n1"}, false)
+	if node := sm.GetFiringAlerts("node"); len(node) != 0 {
+		t.Errorf("恢复后应删除: %+v", node)
+	}
+}
+
+// TestHealthHistory 测试健康分按记录间隔保留最低分、超过保留时长删除与按时长查询
+func TestHealthHistory(t *testing.T) {
+	sm := newTestManager(t)
+	sm.SetHealthHistory(10*time.Minute, time.Minute)
+
+	const t0 = 1700000040 // 整分钟
+	for _, p := range []HealthPoint{{t0, 90}, {t0 + 20, 70}, {t0 + 50, 80}, {t0 + 60, 100}, {t0 + 300, 95}} {
+		sm.UpdateHealthScore(HealthScore{Kind: "node", ID: "n1", Score: p.Score, Timestamp: p.T})
+	}
+	want := []HealthPoint{{t0, 70}, {t0 + 60, 100}, {t0 + 300, 95}}
+	if got := sm.GetHealthHistory("node", "n1", time.Hour); !reflect.DeepEqual(got, want) {
+		t.Fatalf("历史不符: %+v", got)
+	}
+	if got := sm.GetHealthHistory("node", "n1", 4*time.Minute); !reflect.DeepEqual(got, want[1:]) {
+		t.Errorf("按时长查询不符: %+v", got)
+	}
+
+	// 最新一次评估 11 分钟后，早于保留时长的记录删除
+	sm.UpdateHealthScore(HealthScore{Kind: "node", ID: "n1", Score: 60, Timestamp: t0 + 660})
+	if got := sm.GetHealthHistory("node", "n1", time.Hour); len(got) != 3 || got[0].T != t0+60 {
+		t.Errorf("超过保留时长的历史应删除: %+v", got)
+	}
+	if s, ok := sm.GetHealthScore("node", "n1"); !ok || s.Score != 60 {
+		t.Errorf("最新健康分不符: %+v", s)
+	}
+
+	sm.UpdateHealthScore(HealthScore{Kind: HealthKindSystem, Score: 88, Timestamp: t0})
+	if scores := sm.GetHealthScores(""); len(scores) != 2 || scores[0].Kind != "node" || scores[1].Kind != HealthKindSystem {
+		t.Errorf("健康分列表应按类别排序: %+v", scores)
+	}
+	if _, ok := sm.GetHealthScore("node", "n2"); ok || sm.GetHealthHistory("node", "n2", time.Hour) != nil {
+		t.Error("未评估的实体不应有健康分")
+	}
+}
@@ -4,7 +4,9 @@
 2. 统一查询接口 - GetLatestState()
 3. 历史窗口缓存 - AppendHistory() / QueryHistory()
 4. 时间戳对齐 - AlignTimestamp()
-5. 持久化快照 - SaveSnapshot() / LoadSnapshot()
+5. 持久化快照 - SaveSnapshot() / LoadSnapshot()（含统计异常检测基线、轨道周期基线，见 baseline.go）
+6. 数据新鲜度 - SetStaleTimeout() / CheckFreshness()（见 freshness.go）
+7. 健康分 - RecordAlert() / UpdateHealthScore() / GetHealthScores()（见 health.go）
 */
 package state
 
@@ -12,9 +14,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"health-monitor/pkg/models"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
@@ -28,6 +33,9 @@ const (
 	// 快照持久化间隔
 	SnapshotInterval = 1 * time.Minute
 	
+	// 默认允许的时钟偏差（测量时间与本地时间相差超过此值时改用接收时间）
+	DefaultMaxClockSkew = 1 * time.Hour
+	
 	// etcd key前缀
 	EtcdPrefixSnapshot = "/health-monitor/snapshots/"
 	EtcdPrefixHistory  = "/health-monitor/history/"
@@ -45,14 +53,40 @@ type StateManager struct {
 	
 	// 告警状态跟踪 (alertID -> 是否激活)
 	alertStates map[string]bool
+	alertLevels map[string]string // 触发中告警的级别（限值带），仅 CheckAndUpdateAlertLevel 维护
+	alertPending map[string]*PendingAlert // 持续性判定中的告警
+	ruleConditions map[string]*RuleCondition // 规则生效条件的判定状态（alertID -> 状态）
+	activeAlerts map[string]*ActiveAlert // 触发中的告警及其所属实体（见 health.go）
 	alertMutex  sync.RWMutex
 	
+	// 统计异常检测基线与轨道周期基线（告警ID|来源 -> 基线，见 baseline.go）
+	baselines      map[string]*AnomalyBaseline
+	orbitBaselines map[string]*OrbitBaseline
+	baselineMutex sync.Mutex
+	
+	// 健康分及其历史（类别/ID -> 健康分，见 health.go）
+	health          map[string]*healthEntry
+	healthRetention time.Duration
+	healthInterval  time.Duration
+	healthMutex     sync.RWMutex
+	
+	// 数据新鲜度：最近一次收到指标的本地时间与各类指标的超时时长（见 freshness.go）
+	lastSeen      map[string]time.Time
+	staleTimeouts map[string]time.Duration
+	freshMutex    sync.RWMutex
+	
 	// etcd客户端
 	etcdClient *clientv3.Client
 	etcdConfig clientv3.Config
 	
 	// 时间基准（用于时间戳对齐）
-	timeBase int64
+	timeBase     int64
+	maxClockSkew time.Duration
+	skewMutex    sync.RWMutex
+	
+	// 外部统计项（名称 -> 统计函数），由 GetStats 一并输出
+	statsProviders map[string]func() interface{}
+	statsMutex     sync.RWMutex
 	
 	// 停止信号
 	stopChan chan struct{}
@@ -121,11 +155,23 @@ func (rb *RingBuffer) Query(since time.Duration) []HistoryEntry {
 // 如果 endpoints 为空，则不使用持久化（纯内存模式）
 func NewStateManager(endpoints ...string) (*StateManager, error) {
 	sm := &StateManager{
-		latestStates:   make(map[string]Metric),
-		historyBuffers: make(map[string]*RingBuffer),
-		alertStates:    make(map[string]bool),
-		timeBase:       time.Now().Unix(),
-		stopChan:       make(chan struct{}),
+		latestStates:    make(map[string]Metric),
+		historyBuffers:  make(map[string]*RingBuffer),
+		alertStates:     make(map[string]bool),
+		alertLevels:     make(map[string]string),
+		alertPending:    make(map[string]*PendingAlert),
+		ruleConditions:  make(map[string]*RuleCondition),
+		activeAlerts:    make(map[string]*ActiveAlert),
+		baselines:       make(map[string]*AnomalyBaseline),
+		orbitBaselines:  make(map[string]*OrbitBaseline),
+		health:          make(map[string]*healthEntry),
+		healthRetention: DefaultHealthRetention,
+		healthInterval:  DefaultHealthInterval,
+		lastSeen:        make(map[string]time.Time),
+		staleTimeouts:   make(map[string]time.Duration),
+		timeBase:        time.Now().Unix(),
+		maxClockSkew:    DefaultMaxClockSkew,
+		stopChan:        make(chan struct{}),
 	}
 	
 	// 如果提供了 etcd 地址，则初始化 etcd 客户端
@@ -174,6 +220,7 @@ func (sm *StateManager) UpdateMetric(metric Metric) error {
 	sm.statesMutex.Lock()
 	sm.latestStates[key] = alignedMetric
 	sm.statesMutex.Unlock()
+	sm.touch(key)
 	
 	// 追加到历史缓冲区
 	sm.AppendHistory(alignedMetric)
@@ -189,9 +236,22 @@ func (sm *StateManager) GetLatestState(metricType MetricType, id string) (Metric
 	defer sm.statesMutex.RUnlock()
 	
 	metric, exists := sm.latestStates[key]
+	if exists && sm.isStale(key, time.Now()) {
+		metric = markStale(metric)
+	}
 	return metric, exists
 }
 
+// GetLatestBusiness 获取业务组件的最新指标
+func (sm *StateManager) GetLatestBusiness(componentType uint8) (*model.BusinessMetrics, bool) {
+	metric, ok := sm.GetLatestState(MetricTypeBusiness, string(rune(componentType)))
+	if !ok {
+		return nil, false
+	}
+	bm, ok := metric.GetData().(*model.BusinessMetrics)
+	return bm, ok && bm != nil
+}
+
 // GetAllLatestStates 获取指定类型的所有最新状态
 func (sm *StateManager) GetAllLatestStates(metricType MetricType) []Metric {
 	sm.statesMutex.RLock()
@@ -200,8 +260,12 @@ func (sm *StateManager) GetAllLatestStates(metricType MetricType) []Metric {
 	var results []Metric
 	prefix := string(metricType) + ":"
 	
+	now := time.Now()
 	for key, metric := range sm.latestStates {
 		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
+			if sm.isStale(key, now) {
+				metric = markStale(metric)
+			}
 			results = append(results, metric)
 		}
 	}
@@ -248,24 +312,75 @@ func (sm *StateManager) QueryHistory(metricType MetricType, id string, duration
 	return buffer.Query(duration)
 }
 
+// SetMaxClockSkew 设置允许的时钟偏差，<=0 表示不检查偏差（只补齐缺失的时间戳）
+func (sm *StateManager) SetMaxClockSkew(d time.Duration) {
+	sm.skewMutex.Lock()
+	defer sm.skewMutex.Unlock()
+	sm.maxClockSkew = d
+}
+
+// MaxClockSkew 返回允许的时钟偏差
+func (sm *StateManager) MaxClockSkew() time.Duration {
+	sm.skewMutex.RLock()
+	defer sm.skewMutex.RUnlock()
+	return sm.maxClockSkew
+}
+
 // AlignTimestamp 时间戳对齐（用于处理不同来源的时间偏差）
+// 时间戳缺失（0）或与本地时间相差超过 MaxClockSkew 时，改用接收时间；
+// 业务指标优先使用 BusinessMetrics.ReceiveTime，并将 TimeSource 标记为 receive。
+// 不修改传入的指标，需要调整时返回副本。
 func (sm *StateManager) AlignTimestamp(metric Metric) Metric {
-	// 简单实现：如果时间戳过旧或过新，调整为当前时间
-	now := time.Now().Unix()
+	now := time.Now()
 	ts := metric.GetTimestamp()
+	skew := sm.MaxClockSkew()
 	
-	// 如果时间戳为0或相差超过1小时，使用当前时间
-	if ts == 0 || ts < now-3600 || ts > now+3600 {
-		// 注意：这里需要根据具体的Metric类型来更新时间戳
-		// 为了简化，我们假设时间戳已经在创建时正确设置
+	if ts != 0 {
+		diff := now.Unix() - ts
+		if diff < 0 {
+			diff = -diff
+		}
+		if skew <= 0 || diff <= int64(skew/time.Second) {
+			return metric
+		}
+		fmt.Printf("[StateManager] %s:%s 时间戳偏差 %ds 超过 %v，改用接收时间\n",
+			metric.GetType(), metric.GetID(), now.Unix()-ts, skew)
+	}
+	
+	switch m := metric.(type) {
+	case *BusinessMetric:
+		received := now
+		if m.Data.ReceiveTime != 0 {
+			received = time.Unix(0, m.Data.ReceiveTime)
+		}
+		data := *m.Data
+		data.Timestamp = received.Unix()
+		data.TimestampNano = received.UnixNano()
+		data.TimeSource = model.TimeSourceReceive
+		return &BusinessMetric{Data: &data, Timestamp: data.Timestamp}
+	case *NodeMetric:
+		aligned := *m
+		aligned.Timestamp = now.Unix()
+		return &aligned
+	case *ContainerMetric:
+		aligned := *m
+		aligned.Timestamp = now.Unix()
+		return &aligned
+	case *ServiceMetric:
+		aligned := *m
+		aligned.Timestamp = now.Unix()
+		return &aligned
+	case *DerivedMetric:
+		aligned := *m
+		aligned.Timestamp = now.Unix()
+		return &aligned
 	}
 	
 	return metric
 }
 
-// SaveSnapshot 保存状态快照到BoltDB
-func (sm *StateManager) SaveSnapshot() error {
-	// 收集当前所有状态
+// Snapshot 收集当前状态（各实体最新值、统计异常检测基线、轨道周期基线、健康分）
+func (sm *StateManager) Snapshot() *StateSnapshot {
 	sm.statesMutex.RLock()
 	snapshot := &StateSnapshot{
 		Timestamp: time.Now().Unix(),
@@ -293,6 +408,16 @@ func (sm *StateManager) SaveSnapshot() error {
 	}
 	sm.statesMutex.RUnlock()
 	
+	snapshot.Baselines = sm.GetAnomalyBaselines()
+	snapshot.Orbits = sm.GetOrbitBaselines()
+	snapshot.Health = sm.healthSeries()
+	return snapshot
+}
+
+// SaveSnapshot 保存状态快照到BoltDB
+func (sm *StateManager) SaveSnapshot() error {
+	snapshot := sm.Snapshot()
+	
 	// 序列化
 	data, err := json.Marshal(snapshot)
 	if err != nil {
@@ -310,8 +435,8 @@ func (sm *StateManager) SaveSnapshot() error {
 			return fmt.Errorf("保存快照到etcd失败: %w", err)
 		}
 		
-		fmt.Printf("[StateManager] 快照已保存到etcd: %d nodes, %d containers, %d services, %d business\n",
-			len(snapshot.Nodes), len(snapshot.Containers), len(snapshot.Services), len(snapshot.Business))
+		fmt.Printf("[StateManager] 快照已保存到etcd: %d nodes, %d containers, %d services, %d business, %d baselines\n",
+			len(snapshot.Nodes), len(snapshot.Containers), len(snapshot.Services), len(snapshot.Business), len(snapshot.Baselines)+len(snapshot.Orbits))
 	}
 	
 	return nil
@@ -342,59 +467,68 @@ func (sm *StateManager) LoadSnapshot() error {
 		return fmt.Errorf("解析快照失败: %w", err)
 	}
 	
-	// 恢复状态
+	sm.RestoreSnapshot(&latestSnapshot)
+	
+	return nil
+}
+
+// RestoreSnapshot 按快照恢复状态（已有的同名实体被覆盖）
+func (sm *StateManager) RestoreSnapshot(snapshot *StateSnapshot) {
 	sm.statesMutex.Lock()
-	defer sm.statesMutex.Unlock()
 	
 	// 恢复节点状态
-	for _, node := range latestSnapshot.Nodes {
+	for _, node := range snapshot.Nodes {
 		nodeCopy := node
 		metric := &NodeMetric{
 			Data:      &nodeCopy,
-			Timestamp: latestSnapshot.Timestamp,
+			Timestamp: snapshot.Timestamp,
 		}
 		key := fmt.Sprintf("%s:%s", MetricTypeNode, metric.GetID())
 		sm.latestStates[key] = metric
 	}
 	
 	// 恢复容器状态
-	for _, container := range latestSnapshot.Containers {
+	for _, container := range snapshot.Containers {
 		containerCopy := container
 		metric := &ContainerMetric{
 			Data:      &containerCopy,
-			Timestamp: latestSnapshot.Timestamp,
+			Timestamp: snapshot.Timestamp,
 		}
 		key := fmt.Sprintf("%s:%s", MetricTypeContainer, metric.GetID())
 		sm.latestStates[key] = metric
 	}
 	
 	// 恢复服务状态
-	for _, service := range latestSnapshot.Services {
+	for _, service := range snapshot.Services {
 		serviceCopy := service
 		metric := &ServiceMetric{
 			Data:      &serviceCopy,
-			Timestamp: latestSnapshot.Timestamp,
+			Timestamp: snapshot.Timestamp,
 		}
 		key := fmt.Sprintf("%s:%s", MetricTypeService, metric.GetID())
 		sm.latestStates[key] = metric
 	}
 	
 	// 恢复业务层状态
-	for _, business := range latestSnapshot.Business {
+	for _, business := range snapshot.Business {
 		businessCopy := business
 		metric := &BusinessMetric{
 			Data:      &businessCopy,
-			Timestamp: latestSnapshot.Timestamp,
+			Timestamp: snapshot.Timestamp,
 		}
 		key := fmt.Sprintf("%s:%s", MetricTypeBusiness, metric.GetID())
 		sm.latestStates[key] = metric
 	}
+	sm.statesMutex.Unlock()
 	
-	fmt.Printf("[StateManager] 快照已加载: timestamp=%d, %d nodes, %d containers, %d services, %d business\n",
-		latestSnapshot.Timestamp, len(latestSnapshot.Nodes), len(latestSnapshot.Containers),
-		len(latestSnapshot.Services), len(latestSnapshot.Business))
+	// 恢复统计异常检测基线与轨道周期基线
+	sm.restoreAnomalyBaselines(snapshot.Baselines)
+	sm.restoreOrbitBaselines(snapshot.Orbits)
+	sm.restoreHealth(snapshot.Health)
 	
-	return nil
+	fmt.Printf("[StateManager] 快照已加载: timestamp=%d, %d nodes, %d containers, %d services, %d business, %d baselines\n",
+		snapshot.Timestamp, len(snapshot.Nodes), len(snapshot.Containers),
+		len(snapshot.Services), len(snapshot.Business), len(snapshot.Baselines)+len(snapshot.Orbits))
 }
 
 // backgroundPersist 后台持久化任务
@@ -477,15 +611,46 @@ func (sm *StateManager) GetStats() map[string]interface{} {
 	
 	sm.alertMutex.RLock()
 	alertCount := len(sm.alertStates)
+	pendingCount := 0
+	for _, p := range sm.alertPending {
+		if p.Pending {
+			pendingCount++
+		}
+	}
 	sm.alertMutex.RUnlock()
 	
-	return map[string]interface{}{
+	stats := map[string]interface{}{
 		"latest_states":   stateCount,
 		"history_buffers": historyCount,
 		"active_alerts":   alertCount,
+		"pending_alerts":  pendingCount,
 		"ring_buffer_size": RingBufferSize,
 		"retention":       HistoryRetention.String(),
 	}
+	
+	sm.statsMutex.RLock()
+	for name, fn := range sm.statsProviders {
+		stats[name] = fn()
+	}
+	sm.statsMutex.RUnlock()
+	
+	return stats
+}
+
+// RegisterStatsProvider 注册外部统计项（如业务链路质量），GetStats 时调用 fn 获取当前值
+// 同名统计项会被覆盖，fn 为 nil 时移除
+func (sm *StateManager) RegisterStatsProvider(name string, fn func() interface{}) {
+	sm.statsMutex.Lock()
+	defer sm.statsMutex.Unlock()
+	
+	if fn == nil {
+		delete(sm.statsProviders, name)
+		return
+	}
+	if sm.statsProviders == nil {
+		sm.statsProviders = make(map[string]func() interface{})
+	}
+	sm.statsProviders[name] = fn
 }
 
 // SetAlertState 设置告警状态
@@ -493,6 +658,9 @@ func (sm *StateManager) SetAlertState(alertID string, active bool) {
 	sm.alertMutex.Lock()
 	defer sm.alertMutex.Unlock()
 	sm.alertStates[alertID] = active
+	if !active {
+		delete(sm.alertLevels, alertID)
+	}
 }
 
 // GetAlertState 获取告警状态
@@ -533,6 +701,9 @@ func (sm *StateManager) checkAndUpdateAlertStateByKey(key string, isFiring bool)
 	// 状态发生变化
 	if !exists || wasActive != isFiring {
 		sm.alertStates[key] = isFiring
+		if !isFiring {
+			delete(sm.alertLevels, key)
+		}
 		return true, isFiring // 需要发送告警
 	}
 	
@@ -540,6 +711,119 @@ func (sm *StateManager) checkAndUpdateAlertStateByKey(key string, isFiring bool)
 	return false, isFiring
 }
 
+// CheckAndUpdateAlertLevel 按告警ID+来源检查并更新告警级别（如黄线/红线限值带），level 为空表示正常
+// 返回: (shouldSendAlert, isFiring, previous)
+// 首次出现或级别变化时 shouldSendAlert 为 true；触发中的告警级别变化（如黄线→红线）isFiring 仍为 true，
+// 由调用方作为已有告警的更新发送；previous 为变化前的级别
+func (sm *StateManager) CheckAndUpdateAlertLevel(alertID, source, level string) (bool, bool, string) {
+	key := sm.alertKey(alertID, source)
+	isFiring := level != ""
+	
+	sm.alertMutex.Lock()
+	defer sm.alertMutex.Unlock()
+	
+	wasActive, exists := sm.alertStates[key]
+	previous, hasLevel := sm.alertLevels[key]
+	if wasActive && !hasLevel {
+		// 由 CheckAndUpdateAlertState 置为触发的状态，首次记录级别不视为变化
+		previous = level
+	}
+	
+	if isFiring {
+		sm.alertLevels[key] = level
+	} else {
+		delete(sm.alertLevels, key)
+	}
+	sm.alertStates[key] = isFiring
+	
+	if !exists || wasActive != isFiring || previous != level {
+		return true, isFiring, previous
+	}
+	return false, isFiring, previous
+}
+
+// GetAlertLevel 获取触发中告警的级别，未触发或未记录级别时返回空
+func (sm *StateManager) GetAlertLevel(alertID, source string) string {
+	sm.alertMutex.RLock()
+	defer sm.alertMutex.RUnlock()
+	return sm.alertLevels[sm.alertKey(alertID, source)]
+}
+
+// UpdatePendingAlert 在告警状态锁内读取并修改告警的待定状态，level 为当前已确认的级别
+// fn 返回 false 时删除该记录
+func (sm *StateManager) UpdatePendingAlert(alertID, source string, fn func(p *PendingAlert, level string) bool) {
+	key := sm.alertKey(alertID, source)
+	
+	sm.alertMutex.Lock()
+	defer sm.alertMutex.Unlock()
+	
+	p, ok := sm.alertPending[key]
+	if !ok {
+		p = &PendingAlert{AlertID: alertID, Source: source}
+	}
+	if fn(p, sm.alertLevels[key]) {
+		sm.alertPending[key] = p
+	} else {
+		delete(sm.alertPending, key)
+	}
+}
+
+// GetPendingAlerts 获取持续性判定中（待触发、待恢复、待变更级别）的告警，按告警ID、来源排序
+func (sm *StateManager) GetPendingAlerts() []PendingAlert {
+	sm.alertMutex.RLock()
+	defer sm.alertMutex.RUnlock()
+	
+	var pending []PendingAlert
+	for key, p := range sm.alertPending {
+		if !p.Pending {
+			continue
+		}
+		cp := *p
+		cp.Level = sm.alertLevels[key]
+		cp.Recent = append([]bool(nil), p.Recent...)
+		pending = append(pending, cp)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].AlertID != pending[j].AlertID {
+			return pending[i].AlertID < pending[j].AlertID
+		}
+		return pending[i].Source < pending[j].Source
+	})
+	return pending
+}
+
+// UpdateRuleCondition 记录规则生效条件的判定结果
+// 返回当前结果的开始时间（首次判定为 0）以及本次判定结果是否发生变化（首次判定不视为变化）
+func (sm *StateManager) UpdateRuleCondition(alertID string, active bool, ts int64) (int64, bool) {
+	sm.alertMutex.Lock()
+	defer sm.alertMutex.Unlock()
+	
+	c, ok := sm.ruleConditions[alertID]
+	if !ok {
+		sm.ruleConditions[alertID] = &RuleCondition{AlertID: alertID, Active: active}
+		return 0, false
+	}
+	if c.Active == active {
+		return c.Since, false
+	}
+	c.Active = active
+	c.Since = ts
+	return ts, true
+}
+
+// GetRuleConditions 获取规则生效条件的判定状态，按告警ID排序
+func (sm *StateManager) GetRuleConditions() []RuleCondition {
+	sm.alertMutex.RLock()
+	defer sm.alertMutex.RUnlock()
+	
+	conditions := make([]RuleCondition, 0, len(sm.ruleConditions))
+	for _, c := range sm.ruleConditions {
+		conditions = append(conditions, *c)
+	}
+	sort.Slice(conditions, func(i, j int) bool { return conditions[i].AlertID < conditions[j].AlertID })
+	return conditions
+}
+
 // GetActiveAlertCount 获取活跃告警数量
 func (sm *StateManager) GetActiveAlertCount() int {
 	sm.alertMutex.RLock()
@@ -573,6 +857,42 @@ func (sm *StateManager) ClearAlertState(alertID string) {
 	sm.alertMutex.Lock()
 	defer sm.alertMutex.Unlock()
 	delete(sm.alertStates, alertID)
+	delete(sm.alertLevels, alertID)
+	delete(sm.alertPending, alertID)
+	delete(sm.activeAlerts, alertID)
+}
+
+// ClearAlertStates 清除某告警ID在所有来源下的状态（规则删除时使用），
+// 返回清除前处于触发状态的来源，无来源维度的状态返回空字符串
+func (sm *StateManager) ClearAlertStates(alertID string) []string {
+	sm.alertMutex.Lock()
+	defer sm.alertMutex.Unlock()
+	
+	var active []string
+	prefix := alertID + ":"
+	for key, firing := range sm.alertStates {
+		var source string
+		switch {
+		case key == alertID:
+		case strings.HasPrefix(key, prefix):
+			source = key[len(prefix):]
+		default:
+			continue
+		}
+		if firing {
+			active = append(active, source)
+		}
+		delete(sm.alertStates, key)
+		delete(sm.alertLevels, key)
+		delete(sm.alertPending, key)
+	}
+	for key, a := range sm.activeAlerts {
+		if a.AlertID == alertID {
+			delete(sm.activeAlerts, key)
+		}
+	}
+	sort.Strings(active)
+	return active
 }
 
 // ResetAllAlerts 重置所有告警状态
@@ -580,4 +900,8 @@ func (sm *StateManager) ResetAllAlerts() {
 	sm.alertMutex.Lock()
 	defer sm.alertMutex.Unlock()
 	sm.alertStates = make(map[string]bool)
+	sm.alertLevels = make(map[string]string)
+	sm.alertPending = make(map[string]*PendingAlert)
+	sm.ruleConditions = make(map[string]*RuleCondition)
+	sm.activeAlerts = make(map[string]*ActiveAlert)
 }
@@ -1,19 +1,27 @@
 package state
 
 import (
-	"model"
-	"os"
+	"encoding/json"
 	"testing"
 	"time"
+
+	"health-monitor/pkg/models"
 )
 
+// newTestManager 纯内存模式的状态管理器，测试结束时关闭
+func newTestManager(t *testing.T) *StateManager {
+	t.Helper()
+	sm, err := NewStateManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sm.Close() })
+	return sm
+}
+
 func TestStateManager(t *testing.T) {
-	// 创建临时数据库
-	dbPath := "/tmp/test_state.db"
-	defer os.Remove(dbPath)
-	
-	// 创建状态管理器
-	sm, err := NewStateManager(dbPath)
+	// 创建状态管理器（纯内存模式，不连接 etcd）
+	sm, err := NewStateManager()
 	if err != nil {
 		t.Fatalf("创建状态管理器失败: %v", err)
 	}
@@ -49,7 +57,7 @@ func TestStateManager(t *testing.T) {
 		
 		nm, ok := metric.(*NodeMetric)
 		if !ok {
-			t.Error("类型断言失败")
+			t.Fatal("类型断言失败")
 		}
 		
 		if nm.Data.ID != "node-001" {
@@ -83,7 +91,7 @@ func TestStateManager(t *testing.T) {
 		
 		cm, ok := metric.(*ContainerMetric)
 		if !ok {
-			t.Error("类型断言失败")
+			t.Fatal("类型断言失败")
 		}
 		
 		if cm.Data.Status != "running" {
@@ -119,17 +127,23 @@ func TestStateManager(t *testing.T) {
 	
 	// 测试快照保存和加载
 	t.Run("SnapshotSaveAndLoad", func(t *testing.T) {
-		// 保存快照
-		if err := sm.SaveSnapshot(); err != nil {
-			t.Errorf("保存快照失败: %v", err)
+		// 生成快照（与保存到 etcd 的内容相同）
+		data, err := json.Marshal(sm.Snapshot())
+		if err != nil {
+			t.Fatalf("序列化快照失败: %v", err)
+		}
+		var snapshot StateSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			t.Fatalf("解析快照失败: %v", err)
 		}
 		
 		// 创建新的管理器并加载快照
-		sm2, err := NewStateManager(dbPath)
+		sm2, err := NewStateManager()
 		if err != nil {
 			t.Fatalf("创建第二个管理器失败: %v", err)
 		}
 		defer sm2.Close()
+		sm2.RestoreSnapshot(&snapshot)
 		
 		// 验证数据已恢复
 		metric, exists := sm2.GetLatestState(MetricTypeNode, "node-001")
@@ -139,7 +153,7 @@ func TestStateManager(t *testing.T) {
 		
 		nm, ok := metric.(*NodeMetric)
 		if !ok {
-			t.Error("类型断言失败")
+			t.Fatal("类型断言失败")
 		}
 		
 		if nm.Data.ID != "node-001" {
@@ -161,10 +175,7 @@ func TestStateManager(t *testing.T) {
 
 // BenchmarkUpdateMetric 性能测试
 func BenchmarkUpdateMetric(b *testing.B) {
-	dbPath := "/tmp/bench_state.db"
-	defer os.Remove(dbPath)
-	
-	sm, _ := NewStateManager(dbPath)
+	sm, _ := NewStateManager()
 	defer sm.Close()
 	
 	nodeMetric := &NodeMetric{
@@ -184,10 +195,7 @@ func BenchmarkUpdateMetric(b *testing.B) {
 
 // BenchmarkGetLatestState 查询性能测试
 func BenchmarkGetLatestState(b *testing.B) {
-	dbPath := "/tmp/bench_state2.db"
-	defer os.Remove(dbPath)
-	
-	sm, _ := NewStateManager(dbPath)
+	sm, _ := NewStateManager()
 	defer sm.Close()
 	
 	// 预先插入数据
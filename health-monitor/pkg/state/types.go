@@ -14,6 +14,7 @@ const (
 	MetricTypeContainer MetricType = "container"
 	MetricTypeService   MetricType = "service"
 	MetricTypeBusiness  MetricType = "business"
+	MetricTypeDerived   MetricType = "derived"
 )
 
 // Metric 统一的指标接口
@@ -28,6 +29,7 @@ type Metric interface {
 type NodeMetric struct {
 	Data      *model.NodeMetrics
 	Timestamp int64
+	Stale     bool // 超时未更新（仅出现在查询结果中，见 freshness.go）
 }
 
 func (m *NodeMetric) GetID() string        { return m.Data.ID }
@@ -39,6 +41,7 @@ func (m *NodeMetric) GetData() interface{} { return m.Data }
 type ContainerMetric struct {
 	Data      *model.ContainerMetrics
 	Timestamp int64
+	Stale     bool // 超时未更新（仅出现在查询结果中，见 freshness.go）
 }
 
 func (m *ContainerMetric) GetID() string        { return m.Data.ID }
@@ -50,6 +53,7 @@ func (m *ContainerMetric) GetData() interface{} { return m.Data }
 type ServiceMetric struct {
 	Data      *model.ServiceMetrics
 	Timestamp int64
+	Stale     bool // 超时未更新（仅出现在查询结果中，见 freshness.go）
 }
 
 func (m *ServiceMetric) GetID() string        { return m.Data.ID }
@@ -61,6 +65,7 @@ func (m *ServiceMetric) GetData() interface{} { return m.Data }
 type BusinessMetric struct {
 	Data      *model.BusinessMetrics
 	Timestamp int64
+	Stale     bool // 超时未更新（仅出现在查询结果中，见 freshness.go）
 }
 
 func (m *BusinessMetric) GetID() string {
@@ -71,6 +76,127 @@ func (m *BusinessMetric) GetType() MetricType  { return MetricTypeBusiness }
 func (m *BusinessMetric) GetTimestamp() int64  { return m.Timestamp }
 func (m *BusinessMetric) GetData() interface{} { return m.Data }
 
+// DerivedMetric 派生参数（由指标按表达式计算，见 alert/derived.go），与原生指标一样保存最新值与历史
+type DerivedMetric struct {
+	Layer     string  // 所属层级（business / node / container / service）
+	Entity    string  // 实体ID，业务层为组件编号（如 0x03）
+	Name      string  // 参数名
+	Value     float64
+	Unit      string
+	Timestamp int64
+	Stale     bool // 超时未更新（仅出现在查询结果中，见 freshness.go）
+}
+
+// DerivedID 派生参数的指标ID：<layer>/<entity>/<name>
+func DerivedID(layer, entity, name string) string {
+	return layer + "/" + entity + "/" + name
+}
+
+func (m *DerivedMetric) GetID() string        { return DerivedID(m.Layer, m.Entity, m.Name) }
+func (m *DerivedMetric) GetType() MetricType  { return MetricTypeDerived }
+func (m *DerivedMetric) GetTimestamp() int64  { return m.Timestamp }
+func (m *DerivedMetric) GetData() interface{} { return m.Value }
+
+// RuleCondition 告警规则生效条件（模式条件）的判定状态（见 alert/condition.go）
+type RuleCondition struct {
+	AlertID string `json:"alert_id"`
+	Active  bool   `json:"active"`          // 条件是否成立（规则是否生效）
+	Since   int64  `json:"since,omitempty"` // 当前判定结果的开始时间（首次判定为 0，不计宽限期）
+}
+
+// AnomalyBaseline 统计异常检测的在线基线（见 alert/anomaly.go），随快照持久化
+type AnomalyBaseline struct {
+	AlertID   string  `json:"alert_id"`
+	Source    string  `json:"source,omitempty"`
+	Field     string  `json:"field"`
+	Count     int64   `json:"count"`      // 已学习的样本数
+	Mean      float64 `json:"mean"`       // 均值（学习期为算术均值，之后为 EWMA）
+	Variance  float64 `json:"variance"`   // 方差（同上）
+	RefMean   float64 `json:"ref_mean"`   // CUSUM 参考均值（学习期结束时冻结）
+	RefStd    float64 `json:"ref_std"`    // CUSUM 参考标准差
+	CusumHigh float64 `json:"cusum_high"` // 向上累积和（以参考标准差为单位）
+	CusumLow  float64 `json:"cusum_low"`  // 向下累积和
+	Updated   int64   `json:"updated"`    // 最近一次学习的采样时间
+}
+
+// PhaseBin 一个轨道相位区间的统计
+type PhaseBin struct {
+	Count    int64   `json:"count"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+}
+
+// OrbitPoint 降采样序列的一个点
+type OrbitPoint struct {
+	T int64   `json:"t"`
+	V float64 `json:"v"`
+}
+
+// OrbitBaseline 轨道周期基线：按轨道相位折叠学习的期望包络（见 alert/orbit.go），随快照持久化
+type OrbitBaseline struct {
+	AlertID   string       `json:"alert_id"`
+	Source    string       `json:"source,omitempty"`
+	Field     string       `json:"field"`
+	Period    float64      `json:"period"`              // 当前使用的轨道周期（秒），自动估计出之前为 0
+	Epoch     int64        `json:"epoch"`               // 相位零点
+	Since     int64        `json:"since"`               // 按当前周期开始学习的时间
+	Bins      []PhaseBin   `json:"bins"`                // 各相位区间的统计
+	Series    []OrbitPoint `json:"series,omitempty"`    // 降采样序列（自动估计周期用）
+	Level     string       `json:"level,omitempty"`     // 已确认的状态：空为包络内，above / below
+	Candidate string       `json:"candidate,omitempty"` // 待确认的状态
+	Streak    int          `json:"streak,omitempty"`    // 待确认状态已连续的样本数
+	Updated   int64        `json:"updated"`             // 最近一次更新的采样时间
+}
+
+// clone 深拷贝（Bins、Series 在锁内原地更新）
+func (b *OrbitBaseline) clone() OrbitBaseline {
+	c := *b
+	c.Bins = append([]PhaseBin(nil), b.Bins...)
+	c.Series = append([]OrbitPoint(nil), b.Series...)
+	return c
+}
+
+// ActiveAlert 触发中的告警及其所属实体（见 health.go），供健康分统计
+type ActiveAlert struct {
+	AlertID   string  `json:"alert_id"`
+	Source    string  `json:"source,omitempty"`
+	Layer     string  `json:"layer"`               // business / node / container / service
+	Entity    string  `json:"entity"`              // 实体ID，业务组件为编号（如 0x03）
+	Severity  string  `json:"severity"`            // 当前严重程度
+	Type      string  `json:"type,omitempty"`      // 告警类型
+	Message   string  `json:"message,omitempty"`   // 最近一次触发消息
+	Predicted bool    `json:"predicted,omitempty"` // 趋势预测告警（尚未越限）
+	ETA       float64 `json:"eta,omitempty"`       // 趋势预测的预计越限秒数
+	Horizon   float64 `json:"horizon,omitempty"`   // 趋势预测的外推时长（秒）
+	Since     int64   `json:"since"`               // 开始触发的时间
+	Updated   int64   `json:"updated"`             // 最近一次触发/更新的时间
+}
+
+// HealthScore 一个实体或整个系统的健康分（0~100，见 alert/health.go）
+type HealthScore struct {
+	Kind      string             `json:"kind"`              // business / node / container / service / system
+	ID        string             `json:"id,omitempty"`      // 实体ID，业务组件为编号（如 0x03），系统为空
+	Score     float64            `json:"score"`             // 健康分
+	Level     string             `json:"level"`             // healthy / degraded / unhealthy
+	Factors   map[string]float64 `json:"factors,omitempty"` // 各项扣分（alerts / trend / margin / stale），系统为各类实体的平均分
+	Reasons   []string           `json:"reasons,omitempty"` // 扣分原因
+	Alerts    int                `json:"alerts"`            // 触发中的告警数（含趋势预测）
+	Stale     bool               `json:"stale,omitempty"`   // 遥测数据超时
+	Timestamp int64              `json:"timestamp"`         // 评估时间
+}
+
+// HealthPoint 健康分历史的一个点（每个记录间隔保留最低分）
+type HealthPoint struct {
+	T     int64   `json:"t"`
+	Score float64 `json:"score"`
+}
+
+// HealthSeries 最新健康分及其历史（快照用）
+type HealthSeries struct {
+	HealthScore
+	History []HealthPoint `json:"history,omitempty"`
+}
+
 // StateSnapshot 状态快照
 type StateSnapshot struct {
 	Timestamp int64                  `json:"timestamp"`
@@ -78,4 +204,20 @@ type StateSnapshot struct {
 	Containers []model.ContainerMetrics `json:"containers"`
 	Services  []model.ServiceMetrics `json:"services"`
 	Business  []model.BusinessMetrics `json:"business"`
-}
\ No newline at end of file
+	Baselines []AnomalyBaseline       `json:"baselines,omitempty"` // 统计异常检测基线
+	Orbits    []OrbitBaseline         `json:"orbits,omitempty"`    // 轨道周期基线
+	Health    []HealthSeries          `json:"health,omitempty"`    // 健康分及其历史
+}
+
+// PendingAlert 持续性判定中的告警：越限或恢复尚未达到规则要求的次数，告警状态暂不改变（见 alert/debounce.go）
+type PendingAlert struct {
+	AlertID  string `json:"alert_id"`
+	Source   string `json:"source,omitempty"`
+	Level    string `json:"level"`            // 当前已确认的级别（空为正常）
+	Target   string `json:"target,omitempty"` // 待切换到的级别（空为待恢复）
+	Pending  bool   `json:"pending"`          // 是否有待切换的级别（否则仅保留最近样本）
+	Count    int    `json:"count"`            // 已满足的次数
+	Required int    `json:"required"`         // 切换需要的次数
+	Recent   []bool `json:"recent,omitempty"` // 最近样本是否越限（最新在后，M/N 判定用）
+	Since    int64  `json:"since,omitempty"`  // 开始待定的时间
+}
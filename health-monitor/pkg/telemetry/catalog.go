@@ -0,0 +1,316 @@
+/*
+遥测参数库（按 TM 代号索引）
+
+每个 TM 代号（如 TMEZD01095、TMAN01046）一条记录：
+描述、所属组件、对应指标字段、单位、有效物理范围、标称值。
+
+范围分两级：min/max 为黄线（soft，越过为警告），hard_min/hard_max 为红线（hard，越过为严重），
+红线须在黄线之外（可与黄线相同，表示越过即为严重）；只定义红线的一侧以红线为正常范围边界。
+
+告警、界面、报表统一从这里查询参数的单位与范围，
+避免在 alert/threshold 等处重复写死 "[21, 29.4]V" 之类的字面量。
+
+内置默认参数库见 catalog.json，可通过 Load 加载外部文件后 SetDefault 替换。
+*/
+package telemetry
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+//go:embed catalog.json
+var defaultCatalogJSON []byte
+
+// Parameter 遥测参数定义
+type Parameter struct {
+	Code        string    `json:"code"`               // TM 代号
+	Name        string    `json:"name"`               // 参数名称
+	Description string    `json:"description"`        // 参数描述
+	Component   uint8     `json:"component"`          // 所属组件编号（与 business.CompXxx 一致）
+	Field       string    `json:"field"`              // 对应指标结构体字段，数组元素写作 ThermalTemps[0]
+	Unit        string    `json:"unit"`               // 工程单位
+	Min         *float64  `json:"min,omitempty"`      // 有效范围下限，黄线（可选）
+	Max         *float64  `json:"max,omitempty"`      // 有效范围上限，黄线（可选）
+	HardMin     *float64  `json:"hard_min,omitempty"` // 红线下限（可选）
+	HardMax     *float64  `json:"hard_max,omitempty"` // 红线上限（可选）
+	Nominal     *float64  `json:"nominal,omitempty"`  // 标称值（可选）
+	Valid       []float64 `json:"valid,omitempty"`    // 有效取值（模式码/状态码/开关量，可选），非空时数值须为其中之一
+}
+
+// HasRange 是否定义了有效范围（上下限、红线或有效取值任一即可）
+func (p *Parameter) HasRange() bool {
+	return p.Min != nil || p.Max != nil || p.HardMin != nil || p.HardMax != nil || len(p.Valid) > 0
+}
+
+// Limits 参数的两级限值
+func (p *Parameter) Limits() Limits {
+	return Limits{Min: p.Min, Max: p.Max, HardMin: p.HardMin, HardMax: p.HardMax}
+}
+
+// Check 判定数值所在的限值带，返回越过的限值；不在有效取值内时为 BandAbnormal
+func (p *Parameter) Check(v float64) (Band, float64) {
+	if len(p.Valid) > 0 {
+		for _, ok := range p.Valid {
+			if v == ok {
+				return BandNominal, 0
+			}
+		}
+		return BandAbnormal, 0
+	}
+	return p.Limits().Check(v)
+}
+
+// InRange 判断数值是否在有效范围内（未越过黄线、红线），未定义范围时总是返回 true
+func (p *Parameter) InRange(v float64) bool {
+	band, _ := p.Check(v)
+	return band == BandNominal
+}
+
+// RangeString 范围的文字描述，如 "[21,29.4]V"，有效取值写作 "{0,1,2}"
+func (p *Parameter) RangeString() string {
+	if len(p.Valid) > 0 {
+		vals := make([]string, len(p.Valid))
+		for i, v := range p.Valid {
+			vals[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return fmt.Sprintf("{%s}%s", strings.Join(vals, ","), p.Unit)
+	}
+	return p.Limits().RangeString(p.Unit)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              黄线 / 红线限值
+////////////////////////////////////////////////////////////////////////////////
+
+// Band 数值所在的限值带
+type Band string
+
+const (
+	BandNominal  Band = ""          // 正常
+	BandSoftLow  Band = "soft_low"  // 低于黄线下限
+	BandSoftHigh Band = "soft_high" // 高于黄线上限
+	BandHardLow  Band = "hard_low"  // 低于红线下限
+	BandHardHigh Band = "hard_high" // 高于红线上限
+	BandAbnormal Band = "abnormal"  // 无方向的异常（不在有效取值内、等值条件成立等）
+)
+
+// Hard 是否越过红线
+func (b Band) Hard() bool {
+	return b == BandHardLow || b == BandHardHigh
+}
+
+// String 限值带名称，正常为 "nominal"
+func (b Band) String() string {
+	if b == BandNominal {
+		return "nominal"
+	}
+	return string(b)
+}
+
+// Limits 两级限值：黄线 Min/Max 与红线 HardMin/HardMax，均可缺省
+type Limits struct {
+	Min     *float64
+	Max     *float64
+	HardMin *float64
+	HardMax *float64
+}
+
+// Validate 校验限值的大小关系：红线在黄线之外
+func (l Limits) Validate() error {
+	le := func(a, b *float64) bool { return a == nil || b == nil || *a <= *b }
+	switch {
+	case !le(l.Min, l.Max):
+		return fmt.Errorf("范围非法: min > max")
+	case !le(l.HardMin, l.HardMax):
+		return fmt.Errorf("范围非法: hard_min > hard_max")
+	case !le(l.HardMin, l.Min) || !le(l.HardMin, l.Max):
+		return fmt.Errorf("范围非法: hard_min 须不大于 min/max")
+	case !le(l.Max, l.HardMax) || !le(l.Min, l.HardMax):
+		return fmt.Errorf("范围非法: hard_max 须不小于 min/max")
+	}
+	return nil
+}
+
+// Check 判定数值所在的限值带，返回越过的限值（红线优先）
+func (l Limits) Check(v float64) (Band, float64) {
+	switch {
+	case l.HardMin != nil && v < *l.HardMin:
+		return BandHardLow, *l.HardMin
+	case l.HardMax != nil && v > *l.HardMax:
+		return BandHardHigh, *l.HardMax
+	case l.Min != nil && v < *l.Min:
+		return BandSoftLow, *l.Min
+	case l.Max != nil && v > *l.Max:
+		return BandSoftHigh, *l.Max
+	}
+	return BandNominal, 0
+}
+
+// Limit 某限值带对应的限值
+func (l Limits) Limit(b Band) (float64, bool) {
+	var p *float64
+	switch b {
+	case BandSoftLow:
+		p = l.Min
+	case BandSoftHigh:
+		p = l.Max
+	case BandHardLow:
+		p = l.HardMin
+	case BandHardHigh:
+		p = l.HardMax
+	}
+	if p == nil {
+		return 0, false
+	}
+	return *p, true
+}
+
+// RangeString 正常范围描述（黄线范围，未定义黄线的一侧取红线）
+func (l Limits) RangeString(unit string) string {
+	lo, hi := "-∞", "+∞"
+	if min := firstNonNil(l.Min, l.HardMin); min != nil {
+		lo = strconv.FormatFloat(*min, 'f', -1, 64)
+	}
+	if max := firstNonNil(l.Max, l.HardMax); max != nil {
+		hi = strconv.FormatFloat(*max, 'f', -1, 64)
+	}
+	return fmt.Sprintf("[%s,%s]%s", lo, hi, unit)
+}
+
+func firstNonNil(a, b *float64) *float64 {
+	if a != nil {
+		return a
+	}
+	return b
+}
+
+// Catalog 遥测参数库
+type Catalog struct {
+	Version    string      `json:"version"`
+	Parameters []Parameter `json:"parameters"`
+
+	byCode  map[string]*Parameter
+	byField map[fieldKey]*Parameter
+}
+
+type fieldKey struct {
+	component uint8
+	field     string
+}
+
+// Parse 解析并校验参数库 JSON
+func Parse(data []byte) (*Catalog, error) {
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("解析参数库失败: %w", err)
+	}
+	if err := c.index(); err != nil {
+		return nil, fmt.Errorf("参数库校验失败: %w", err)
+	}
+	return &c, nil
+}
+
+// Load 从文件加载参数库
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取参数库文件失败: %w", err)
+	}
+	return Parse(data)
+}
+
+// index 校验并建立索引
+func (c *Catalog) index() error {
+	c.byCode = make(map[string]*Parameter, len(c.Parameters))
+	c.byField = make(map[fieldKey]*Parameter, len(c.Parameters))
+
+	for i := range c.Parameters {
+		p := &c.Parameters[i]
+		if p.Code == "" {
+			return fmt.Errorf("第 %d 个参数缺少 code", i+1)
+		}
+		if _, dup := c.byCode[p.Code]; dup {
+			return fmt.Errorf("参数 %s 重复定义", p.Code)
+		}
+		if err := p.Limits().Validate(); err != nil {
+			return fmt.Errorf("参数 %s %w", p.Code, err)
+		}
+		if len(p.Valid) > 0 && (p.Min != nil || p.Max != nil || p.HardMin != nil || p.HardMax != nil) {
+			return fmt.Errorf("参数 %s 不能同时定义 valid 与 min/max", p.Code)
+		}
+		c.byCode[p.Code] = p
+		if p.Field != "" {
+			c.byField[fieldKey{p.Component, p.Field}] = p
+		}
+	}
+	return nil
+}
+
+// Lookup 按 TM 代号查询
+func (c *Catalog) Lookup(code string) (*Parameter, bool) {
+	p, ok := c.byCode[code]
+	return p, ok
+}
+
+// LookupField 按组件编号 + 指标字段名查询
+func (c *Catalog) LookupField(component uint8, field string) (*Parameter, bool) {
+	p, ok := c.byField[fieldKey{component, field}]
+	return p, ok
+}
+
+// ByComponent 返回某组件的全部参数（按 TM 代号排序）
+func (c *Catalog) ByComponent(component uint8) []*Parameter {
+	var out []*Parameter
+	for i := range c.Parameters {
+		if c.Parameters[i].Component == component {
+			out = append(out, &c.Parameters[i])
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// All 返回全部参数
+func (c *Catalog) All() []*Parameter {
+	out := make([]*Parameter, len(c.Parameters))
+	for i := range c.Parameters {
+		out[i] = &c.Parameters[i]
+	}
+	return out
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//                              全局默认参数库
+////////////////////////////////////////////////////////////////////////////////
+
+var defaultCatalog atomic.Pointer[Catalog]
+
+func init() {
+	c, err := Parse(defaultCatalogJSON)
+	if err != nil {
+		panic(fmt.Sprintf("内置参数库无效: %v", err))
+	}
+	defaultCatalog.Store(c)
+}
+
+// Default 返回当前全局参数库
+func Default() *Catalog {
+	return defaultCatalog.Load()
+}
+
+// SetDefault 替换全局参数库（c 需已通过 Parse/Load 校验）
+func SetDefault(c *Catalog) {
+	defaultCatalog.Store(c)
+}
+
+// Lookup 在全局参数库中按 TM 代号查询
+func Lookup(code string) (*Parameter, bool) {
+	return Default().Lookup(code)
+}
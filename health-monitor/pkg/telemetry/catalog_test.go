@@ -0,0 +1,107 @@
+package telemetry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultCatalogLookup(t *testing.T) {
+	p, ok := Lookup("TMEZD01095")
+	if !ok {
+		t.Fatal("TMEZD01095 不在内置参数库中")
+	}
+	if p.Unit != "V" || p.Component != 0x03 || p.Field != "BatteryVoltage" {
+		t.Fatalf("参数定义错误: %+v", p)
+	}
+	if !p.InRange(25) || p.InRange(20.9) || p.InRange(29.5) {
+		t.Fatalf("范围判断错误: %s", p.RangeString())
+	}
+	if got := p.RangeString(); got != "[21,29.4]V" {
+		t.Fatalf("RangeString = %q", got)
+	}
+
+	byField, ok := Default().LookupField(0x06, "ThermalTemps[3]")
+	if !ok || byField.Code != "TMEZD01069" {
+		t.Fatalf("按字段查询错误: %+v", byField)
+	}
+	if len(Default().ByComponent(0x0B)) != 3 {
+		t.Fatal("动量轮参数数量错误")
+	}
+
+	pipe, ok := Default().LookupField(0x0D, "PipelineSwitch")
+	if !ok || !pipe.HasRange() || !pipe.InRange(1) || pipe.InRange(2) || pipe.RangeString() != "{0,1}" {
+		t.Fatalf("有效取值错误: %+v", pipe)
+	}
+
+	// 无来源的自定义代号不带限值
+	mode, ok := Default().LookupField(0x07, "ControlMode")
+	if !ok || mode.HasRange() || !mode.InRange(9) {
+		t.Fatalf("模式码不应有取值定义: %+v", mode)
+	}
+}
+
+func TestParseInvalidCatalog(t *testing.T) {
+	cases := map[string]string{
+		"缺少code": `{"parameters":[{"name":"x"}]}`,
+		"重复定义":   `{"parameters":[{"code":"A"},{"code":"A"}]}`,
+		"范围非法":   `{"parameters":[{"code":"A","min":2,"max":1}]}`,
+		"取值冲突":   `{"parameters":[{"code":"A","min":0,"valid":[1]}]}`,
+		"红线在内":   `{"parameters":[{"code":"A","min":1,"max":5,"hard_max":4}]}`,
+		"红线非法":   `{"parameters":[{"code":"A","hard_min":3,"hard_max":2}]}`,
+		"红线取值":   `{"parameters":[{"code":"A","hard_min":0,"valid":[1]}]}`,
+	}
+	for name, data := range cases {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: 期望解析失败", name)
+		}
+	}
+
+	c, err := Parse([]byte(`{"version":"t","parameters":[{"code":"A","unit":"A","min":1}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := c.Lookup("A")
+	if !strings.HasSuffix(p.RangeString(), "+∞]A") || p.InRange(0.5) {
+		t.Fatalf("单侧范围错误: %s", p.RangeString())
+	}
+}
+
+func TestLimitBands(t *testing.T) {
+	p, _ := Lookup("TMEZD01095")
+	cases := []struct {
+		v     float64
+		band  Band
+		limit float64
+	}{
+		{25, BandNominal, 0},
+		{20.5, BandSoftLow, 21},
+		{19, BandHardLow, 20},
+		{29.5, BandHardHigh, 29.4},
+	}
+	for _, c := range cases {
+		if band, limit := p.Check(c.v); band != c.band || limit != c.limit {
+			t.Errorf("%v: got %s/%v, want %s/%v", c.v, band, limit, c.band, c.limit)
+		}
+	}
+
+	// 只定义红线时以红线为正常范围
+	c, err := Parse([]byte(`{"parameters":[{"code":"A","unit":"g","min":100,"hard_min":50,"hard_max":900}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := c.Lookup("A")
+	if a.RangeString() != "[100,900]g" || !a.InRange(500) || a.InRange(950) {
+		t.Errorf("范围不符: %s", a.RangeString())
+	}
+	if band, _ := a.Check(950); band != BandHardHigh || !band.Hard() || band.String() != "hard_high" {
+		t.Errorf("超过红线上限: %s", band)
+	}
+	if band, _ := a.Check(80); band != BandSoftLow || band.Hard() {
+		t.Errorf("低于黄线下限: %s", band)
+	}
+
+	pipe, _ := Default().LookupField(0x0D, "PipelineSwitch")
+	if band, _ := pipe.Check(2); band != BandAbnormal || BandNominal.String() != "nominal" {
+		t.Errorf("非法取值: %s", band)
+	}
+}